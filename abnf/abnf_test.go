@@ -0,0 +1,140 @@
+package abnf_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/abnf"
+)
+
+// rfc3339 holds the date and time rules of https://www.rfc-editor.org/rfc/rfc3339#section-5.6
+const rfc3339 = `date-time = full-date "T" full-time
+full-date = date-fullyear "-" date-month "-" date-mday
+full-time = partial-time time-offset
+partial-time = time-hour ":" time-minute ":" time-second [time-secfrac]
+date-fullyear = 4DIGIT
+date-month = 2DIGIT ; 01-12
+date-mday = 2DIGIT ; 01-28, 01-29, 01-30, 01-31
+time-hour = 2DIGIT ; 00-23
+time-minute = 2DIGIT
+time-second = 2DIGIT
+time-secfrac = "." 1*DIGIT
+time-numoffset = ("+" / "-") time-hour ":" time-minute
+time-offset = "Z" / time-numoffset
+`
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		grammar string
+		matches []string
+		rejects []string
+	}{
+		{rfc3339, []string{"1985-04-12T23:20:50.52Z", "1996-12-19T16:39:57-08:00", "1990-12-31t23:59:60z"}, []string{"1985-04-12", "1985-04-12T23:20:50", "85-04-12T23:20:50Z"}},
+		{"full-date\n" + rfc3339, []string{"1985-04-12"}, []string{"1985-04-12T23:20:50Z"}},
+		{`a = "abc"`, []string{"abc", "ABC", "aBc"}, []string{"ab", "abcd"}},
+		{`a = %s"abc"`, []string{"abc"}, []string{"ABC"}},
+		{`a = %i"abc"`, []string{"AbC"}, []string{"abd"}},
+		{`a = %x41-43 %d72 %b1001001`, []string{"AHI", "CHI"}, []string{"DHI", "Ahi"}},
+		{`a = %x61.62.63`, []string{"abc"}, []string{"ABC"}},
+		{`a = 2*3"x"`, []string{"xx", "xxx"}, []string{"x", "xxxx"}},
+		{`a = *"x" "y"`, []string{"y", "xxy"}, []string{"x"}},
+		{`a = *2"x"`, []string{"", "xx"}, []string{"xxx"}},
+		{`a = ["x"] "y"`, []string{"y", "xy"}, []string{"xxy"}},
+		{`a = *("x" / "xy") "z"`, []string{"xxyz", "xyxz", "z"}, []string{"yz"}},
+		{"a = b / c\nb = \"x\"\nc = \"y\"\nb =/ \"w\"", []string{"x", "y", "w"}, []string{"z"}},
+		{"a = \"x\" ; a comment\n  \"y\"\n\n; another\nb = \"z\"", []string{"xy"}, []string{"x"}},
+		{`a = *(*"x")`, []string{"", "xxx"}, []string{"y"}},
+		{`a = %x263A "!"`, []string{"☺!"}, []string{"!"}},
+		{`a = ALPHA *(ALPHA / DIGIT / "-") WSP HEXDIG`, []string{"x-1 f", "ab\tA"}, []string{"1x 0", "ab g"}},
+		{"list = item *(\",\" item)\nitem = 1*DIGIT / \"[\" list \"]\"", []string{"1,[2,[3]],4"}, []string{"1,[2", "[]"}},
+		{"a = DIGIT\nDIGIT = %x30-31", []string{"1"}, []string{"2"}},
+	}
+
+	for _, tst := range tests {
+		g, err := abnf.Parse(tst.grammar)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.grammar, err)
+			continue
+		}
+		for _, s := range tst.matches {
+			if !g.Match(s) {
+				t.Errorf("%s: expected a match for %q", tst.grammar, s)
+			}
+		}
+		for _, s := range tst.rejects {
+			if g.Match(s) {
+				t.Errorf("%s: unexpected match for %q", tst.grammar, s)
+			}
+		}
+	}
+}
+
+func TestMatchBytes(t *testing.T) {
+	g := abnf.MustParse("oid = 1*arc\narc = *%x80-FF %x00-7F")
+	if !g.MatchBytes([]byte{0x2b, 0x06, 0x81, 0x01}) {
+		t.Error("expected a match of an oid")
+	}
+	if g.MatchBytes([]byte{0x2b, 0x81}) {
+		t.Error("unexpected match of an oid with a truncated arc")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		grammar string
+		err     string
+	}{
+		{"", "abnf: grammar defines no rules at line 1, column 1"},
+		{"a = b", "abnf: rule b is not defined at line 1, column 5"},
+		{"a = \"x\"\na = \"y\"", "abnf: rule a is already defined at line 2, column 1"},
+		{"a =/ \"x\"", "abnf: incremental alternative =/ of undefined rule a at line 1, column 1"},
+		{"a = (\"x\"", "abnf: missing ) at line 1, column 5"},
+		{"a = \"x", "abnf: unterminated string at line 1, column 5"},
+		{"a = %q1", "abnf: expected b, d or x after % at line 1, column 5"},
+		{"a = %x5-1", "abnf: value range is out of order at line 1, column 5"},
+		{"a = 3*1\"x\"", "abnf: repetition 3*1 has a maximum below its minimum at line 1, column 5"},
+		{"a = <prose>", "abnf: prose values are not supported at line 1, column 5"},
+		{"a = ", "abnf: missing element at line 1, column 5"},
+		{"a = \"x\" )", "abnf: unexpected ')' at line 1, column 9"},
+		{" a = \"x\"", "abnf: unexpected indentation at line 1, column 1"},
+		{"a = \"x\"\nb", "abnf: expected a rule definition at line 2, column 1"},
+	}
+
+	for _, tst := range tests {
+		_, err := abnf.Parse(tst.grammar)
+		if err == nil {
+			t.Errorf("%q: expected error `%s`", tst.grammar, tst.err)
+			continue
+		}
+		if err.Error() != tst.err {
+			t.Errorf("%q: expected `%s` got `%s`", tst.grammar, tst.err, err)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	grammars := []string{
+		rfc3339,
+		`a = %s"abc" / %x41-5A`,
+		"list = item *(\",\" item)\nitem = 1*DIGIT / \"[\" list \"]\"",
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for _, src := range grammars {
+		g := abnf.MustParse(src)
+		generated := 0
+		for i := 0; i < 20; i++ {
+			chars, err := g.Generate(r, 3)
+			if err != nil {
+				continue
+			}
+			generated++
+			if !g.Match(string(chars)) {
+				t.Errorf("%s: generated %q which does not match", src, string(chars))
+			}
+		}
+		if generated == 0 {
+			t.Errorf("%s: generated no strings", src)
+		}
+	}
+}
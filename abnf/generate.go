@@ -0,0 +1,81 @@
+package abnf
+
+import (
+	"errors"
+	"math/rand"
+)
+
+// maxDepth bounds the nesting of rule references while generating strings
+const maxDepth = 64
+
+// errTooDeep reports a generation that went through too many rule references
+var errTooDeep = errors.New("abnf: generation exceeds the nesting of rule references")
+
+// Generate returns random characters matched by the grammar. Unbounded repetitions repeat
+// at most maxRepeat times beyond their minimum. Recursive grammars may fail to generate a
+// string within the nesting of rules Generate allows, in which case trying again with
+// another random source may succeed.
+func (g *Grammar) Generate(r *rand.Rand, maxRepeat int) ([]rune, error) {
+	gen := &generator{rand: r, maxRepeat: maxRepeat}
+	if err := gen.generate(g.root, 0); err != nil {
+		return nil, err
+	}
+	return gen.out, nil
+}
+
+type generator struct {
+	rand      *rand.Rand
+	maxRepeat int
+	out       []rune
+}
+
+func (gen *generator) generate(n node, depth int) error {
+	switch val := n.(type) {
+	case alternation:
+		return gen.generate(val[gen.rand.Intn(len(val))], depth)
+	case concatenation:
+		for _, sub := range val {
+			if err := gen.generate(sub, depth); err != nil {
+				return err
+			}
+		}
+	case *repetition:
+		count := val.min
+		if val.max < 0 {
+			count += gen.rand.Intn(gen.maxRepeat + 1)
+		} else {
+			count += gen.rand.Intn(val.max - val.min + 1)
+		}
+		for i := 0; i < count; i++ {
+			if err := gen.generate(val.node, depth); err != nil {
+				return err
+			}
+		}
+	case *reference:
+		if depth >= maxDepth {
+			return errTooDeep
+		}
+		return gen.generate(val.rule.node, depth+1)
+	case *literal:
+		for _, c := range val.chars {
+			if !val.caseSensitive && gen.rand.Intn(2) == 0 {
+				c = swapCase(c)
+			}
+			gen.out = append(gen.out, c)
+		}
+	case *valueRange:
+		gen.out = append(gen.out, val.lo+rune(gen.rand.Int63n(int64(val.hi-val.lo)+1)))
+	}
+	return nil
+}
+
+// swapCase swaps the case of ASCII letters
+func swapCase(c rune) rune {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return c - 'a' + 'A'
+	case 'A' <= c && c <= 'Z':
+		return c - 'A' + 'a'
+	}
+	return c
+}
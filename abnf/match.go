@@ -0,0 +1,158 @@
+package abnf
+
+import (
+	"sort"
+	"unicode"
+)
+
+// Match reports whether the grammar matches the whole of a text string
+func (g *Grammar) Match(s string) bool {
+	return g.match([]rune(s))
+}
+
+// MatchBytes reports whether the grammar matches the whole of a byte string, taking each
+// byte as a character
+func (g *Grammar) MatchBytes(b []byte) bool {
+	chars := make([]rune, len(b))
+	for i, c := range b {
+		chars[i] = rune(c)
+	}
+	return g.match(chars)
+}
+
+func (g *Grammar) match(input []rune) bool {
+	m := &matcher{input: input, memo: map[ruleAt][]int{}, active: map[ruleAt]bool{}}
+	for _, end := range m.ends(g.root, 0) {
+		if end == len(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// matcher finds every way a node matches the input so that no backtracking is needed
+type matcher struct {
+	input []rune
+	// memo: the ends of the matches of rules by their start
+	memo map[ruleAt][]int
+	// active: rules being matched, to stop left recursion
+	active map[ruleAt]bool
+}
+
+type ruleAt struct {
+	rule *rule
+	pos  int
+}
+
+// ends returns the sorted positions at which matches of the node starting at pos end
+func (m *matcher) ends(n node, pos int) []int {
+	switch val := n.(type) {
+	case alternation:
+		set := map[int]bool{}
+		for _, sub := range val {
+			for _, end := range m.ends(sub, pos) {
+				set[end] = true
+			}
+		}
+		return sorted(set)
+	case concatenation:
+		current := []int{pos}
+		for _, sub := range val {
+			set := map[int]bool{}
+			for _, start := range current {
+				for _, end := range m.ends(sub, start) {
+					set[end] = true
+				}
+			}
+			if len(set) == 0 {
+				return nil
+			}
+			current = sorted(set)
+		}
+		return current
+	case *repetition:
+		return m.repeat(val, pos)
+	case *reference:
+		key := ruleAt{val.rule, pos}
+		if ends, ok := m.memo[key]; ok {
+			return ends
+		}
+		if m.active[key] {
+			return nil
+		}
+		m.active[key] = true
+		ends := m.ends(val.rule.node, pos)
+		delete(m.active, key)
+		m.memo[key] = ends
+		return ends
+	case *literal:
+		if pos+len(val.chars) > len(m.input) {
+			return nil
+		}
+		for i, c := range val.chars {
+			if !sameChar(m.input[pos+i], c, val.caseSensitive) {
+				return nil
+			}
+		}
+		return []int{pos + len(val.chars)}
+	case *valueRange:
+		if pos < len(m.input) && val.lo <= m.input[pos] && m.input[pos] <= val.hi {
+			return []int{pos + 1}
+		}
+	}
+	return nil
+}
+
+// repeat matches the node of a repetition between its minimum and maximum times
+func (m *matcher) repeat(rep *repetition, pos int) []int {
+	result := map[int]bool{}
+	if rep.min == 0 {
+		result[pos] = true
+	}
+	seen := map[int]bool{pos: true}
+	current := []int{pos}
+	for count := 1; rep.max < 0 || count <= rep.max; count++ {
+		set := map[int]bool{}
+		for _, start := range current {
+			for _, end := range m.ends(rep.node, start) {
+				// unbounded repetitions only continue from new positions so empty matches
+				// cannot repeat forever
+				if rep.max < 0 && count > rep.min && seen[end] {
+					continue
+				}
+				set[end] = true
+			}
+		}
+		if len(set) == 0 {
+			break
+		}
+		current = sorted(set)
+		for _, end := range current {
+			seen[end] = true
+			if count >= rep.min {
+				result[end] = true
+			}
+		}
+	}
+	return sorted(result)
+}
+
+// sameChar compares characters, ignoring the case of ASCII letters unless case sensitive
+func sameChar(a, b rune, caseSensitive bool) bool {
+	if a == b {
+		return true
+	}
+	if caseSensitive || a > unicode.MaxASCII || b > unicode.MaxASCII {
+		return false
+	}
+	return unicode.ToLower(a) == unicode.ToLower(b)
+}
+
+func sorted(set map[int]bool) []int {
+	out := make([]int, 0, len(set))
+	for n := range set {
+		out = append(out, n)
+	}
+	sort.Ints(out)
+	return out
+}
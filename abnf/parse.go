@@ -0,0 +1,579 @@
+// Package abnf implements the Augmented BNF of https://www.rfc-editor.org/rfc/rfc5234 with
+// the case-sensitive strings of https://www.rfc-editor.org/rfc/rfc7405, as used by the
+// `.abnf` and `.abnfb` controls of https://www.rfc-editor.org/rfc/rfc9165#section-3.
+//
+// A grammar matches strings as sequences of characters: the Unicode code points of a text
+// string or the bytes of a byte string. Strings are matched against the first rule of the
+// grammar unless the grammar starts with an expression before its first rule, as in the
+// text `"oid" .det rules` uses to select the rule oid of rules.
+package abnf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError describes an invalid grammar
+type SyntaxError struct {
+	Line, Column int
+	Msg          string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("abnf: %s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
+
+// Grammar is a parsed ABNF rule list
+type Grammar struct {
+	// rules: the rules by their lower case name, as rule names are case-insensitive
+	rules map[string]*rule
+	// root: the expression strings must match
+	root node
+}
+
+type rule struct {
+	name string
+	node node
+}
+
+type (
+	// node is an element of an ABNF expression
+	node interface{}
+
+	alternation   []node
+	concatenation []node
+	repetition    struct {
+		min, max int // max is -1 for no upper limit
+		node     node
+	}
+	reference struct {
+		name      string
+		line, col int
+		rule      *rule
+	}
+	// literal matches the characters of a quoted string or a sequence of numeric values
+	literal struct {
+		chars         []rune
+		caseSensitive bool
+	}
+	// valueRange matches one character within lo..hi
+	valueRange struct {
+		lo, hi rune
+	}
+)
+
+// Parse parses an ABNF rule list and resolves the references of its rules. The core rules
+// of RFC 5234 Appendix B are available unless the grammar defines rules of the same name.
+func Parse(src string) (*Grammar, error) {
+	g, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if g.root == nil {
+		return nil, &SyntaxError{Line: 1, Column: 1, Msg: "grammar defines no rules"}
+	}
+	for name, r := range coreGrammar.rules {
+		if _, ok := g.rules[name]; !ok {
+			g.rules[name] = r
+		}
+	}
+	if err := g.resolve(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// MustParse is like Parse but panics if the grammar is invalid
+func MustParse(src string) *Grammar {
+	g, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func parse(src string) (*Grammar, error) {
+	p := &parser{src: src, line: 1, col: 1}
+	g := &Grammar{rules: map[string]*rule{}}
+
+	var first *rule
+	for {
+		p.skipBlankLines()
+		if p.pos >= len(p.src) {
+			break
+		}
+		if p.peek() == ' ' || p.peek() == '\t' {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		start := *p
+		name, incremental, isRule := p.ruleHead()
+		if !isRule {
+			// an expression before the first rule selects the strings to match
+			*p = start
+			if first != nil || g.root != nil {
+				return nil, p.errorf("expected a rule definition")
+			}
+			expr, err := p.elements()
+			if err != nil {
+				return nil, err
+			}
+			g.root = expr
+			if err := p.endOfRule(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		expr, err := p.elements()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfRule(); err != nil {
+			return nil, err
+		}
+
+		key := strings.ToLower(name)
+		existing, defined := g.rules[key]
+		switch {
+		case incremental && !defined:
+			return nil, start.errorf("incremental alternative =/ of undefined rule %s", name)
+		case incremental:
+			alts, ok := existing.node.(alternation)
+			if !ok {
+				alts = alternation{existing.node}
+			}
+			existing.node = append(alts, expr)
+		case defined:
+			return nil, start.errorf("rule %s is already defined", name)
+		default:
+			r := &rule{name: name, node: expr}
+			g.rules[key] = r
+			if first == nil {
+				first = r
+			}
+		}
+	}
+
+	if g.root == nil && first != nil {
+		g.root = &reference{name: first.name, rule: first}
+	}
+	return g, nil
+}
+
+// resolve binds references to their rules
+func (g *Grammar) resolve() error {
+	var err error
+	var visit func(n node)
+	visit = func(n node) {
+		switch val := n.(type) {
+		case alternation:
+			for _, sub := range val {
+				visit(sub)
+			}
+		case concatenation:
+			for _, sub := range val {
+				visit(sub)
+			}
+		case *repetition:
+			visit(val.node)
+		case *reference:
+			if val.rule != nil {
+				return
+			}
+			r, ok := g.rules[strings.ToLower(val.name)]
+			if !ok && err == nil {
+				err = &SyntaxError{Line: val.line, Column: val.col, Msg: fmt.Sprintf("rule %s is not defined", val.name)}
+			}
+			val.rule = r
+		}
+	}
+
+	visit(g.root)
+	for _, r := range g.rules {
+		visit(r.node)
+	}
+	return err
+}
+
+type parser struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Line: p.line, Column: p.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// peek returns the byte at the current position or 0 at the end of the grammar
+func (p *parser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) next() byte {
+	c := p.peek()
+	if p.pos < len(p.src) {
+		p.pos++
+		p.col++
+		if c == '\n' {
+			p.line++
+			p.col = 1
+		}
+	}
+	return c
+}
+
+// newline consumes a line break of LF or CRLF
+func (p *parser) newline() bool {
+	if p.peek() == '\r' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n' {
+		p.next()
+	}
+	if p.peek() == '\n' {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// comment consumes a comment up to the end of its line
+func (p *parser) comment() {
+	for p.pos < len(p.src) && p.peek() != '\n' && p.peek() != '\r' {
+		p.next()
+	}
+}
+
+// skipBlankLines skips lines holding only whitespace and comments
+func (p *parser) skipBlankLines() {
+	for p.pos < len(p.src) {
+		start := *p
+		for p.peek() == ' ' || p.peek() == '\t' {
+			p.next()
+		}
+		if p.peek() == ';' {
+			p.comment()
+		}
+		if p.pos >= len(p.src) {
+			return
+		}
+		if !p.newline() {
+			*p = start
+			return
+		}
+	}
+}
+
+// cwsp skips whitespace, comments and line breaks followed by whitespace, which continue
+// a rule on the next line. It reports whether anything was skipped.
+func (p *parser) cwsp() bool {
+	skipped := false
+	for {
+		switch p.peek() {
+		case ' ', '\t':
+			p.next()
+		case ';':
+			p.comment()
+		case '\r', '\n':
+			start := *p
+			p.newline()
+			p.skipBlankLines()
+			if p.peek() != ' ' && p.peek() != '\t' {
+				*p = start
+				return skipped
+			}
+		default:
+			return skipped
+		}
+		skipped = true
+	}
+}
+
+// endOfRule consumes the end of the line of a rule
+func (p *parser) endOfRule() error {
+	p.cwsp()
+	if p.pos < len(p.src) && !p.newline() {
+		return p.errorf("unexpected %q", p.peek())
+	}
+	return nil
+}
+
+// ruleHead parses `rulename =` or `rulename =/` and reports false if the line is not the
+// definition of a rule
+func (p *parser) ruleHead() (name string, incremental, ok bool) {
+	name = p.ruleName()
+	if name == "" {
+		return "", false, false
+	}
+	p.cwsp()
+	if p.next() != '=' {
+		return "", false, false
+	}
+	if p.peek() == '/' {
+		p.next()
+		incremental = true
+	}
+	p.cwsp()
+	return name, incremental, true
+}
+
+func (p *parser) ruleName() string {
+	start := p.pos
+	if !isAlpha(p.peek()) {
+		return ""
+	}
+	for isAlpha(p.peek()) || isDigit(p.peek()) || p.peek() == '-' {
+		p.next()
+	}
+	return p.src[start:p.pos]
+}
+
+// elements parses an alternation
+func (p *parser) elements() (node, error) {
+	var alts alternation
+	for {
+		concat, err := p.concatenation()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, concat)
+
+		start := *p
+		p.cwsp()
+		if p.peek() != '/' {
+			*p = start
+			break
+		}
+		p.next()
+		p.cwsp()
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return alts, nil
+}
+
+func (p *parser) concatenation() (node, error) {
+	var concat concatenation
+	for {
+		rep, err := p.repetition()
+		if err != nil {
+			return nil, err
+		}
+		concat = append(concat, rep)
+
+		start := *p
+		if !p.cwsp() || !startsElement(p.peek()) {
+			*p = start
+			break
+		}
+	}
+	if len(concat) == 1 {
+		return concat[0], nil
+	}
+	return concat, nil
+}
+
+func startsElement(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.IndexByte(`*("[%<`, c) >= 0
+}
+
+func (p *parser) repetition() (node, error) {
+	start := *p
+	min, hasMin := p.number()
+	max := min
+	if p.peek() == '*' {
+		p.next()
+		if !hasMin {
+			min = 0
+		}
+		if max, hasMax := p.number(); hasMax {
+			if max < min {
+				return nil, start.errorf("repetition %d*%d has a maximum below its minimum", min, max)
+			}
+			return p.repeat(min, max)
+		}
+		return p.repeat(min, -1)
+	}
+	if hasMin {
+		return p.repeat(min, max)
+	}
+	return p.element()
+}
+
+func (p *parser) repeat(min, max int) (node, error) {
+	elem, err := p.element()
+	if err != nil {
+		return nil, err
+	}
+	return &repetition{min: min, max: max, node: elem}, nil
+}
+
+func (p *parser) number() (int, bool) {
+	start := p.pos
+	for isDigit(p.peek()) {
+		p.next()
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	return n, err == nil
+}
+
+func (p *parser) element() (node, error) {
+	start := *p
+	switch c := p.peek(); {
+	case isAlpha(c):
+		return &reference{name: p.ruleName(), line: start.line, col: start.col}, nil
+	case c == '(' || c == '[':
+		p.next()
+		p.cwsp()
+		expr, err := p.elements()
+		if err != nil {
+			return nil, err
+		}
+		p.cwsp()
+		closing := byte(')')
+		if c == '[' {
+			closing = ']'
+		}
+		if p.next() != closing {
+			return nil, start.errorf("missing %c", closing)
+		}
+		if c == '[' {
+			return &repetition{min: 0, max: 1, node: expr}, nil
+		}
+		return expr, nil
+	case c == '"':
+		return p.quoted(false)
+	case c == '%':
+		p.next()
+		switch p.peek() {
+		case 's', 'S':
+			p.next()
+			return p.quoted(true)
+		case 'i', 'I':
+			p.next()
+			return p.quoted(false)
+		}
+		return p.numVal(start)
+	case c == '<':
+		// prose describes strings in words which cannot be matched
+		return nil, p.errorf("prose values are not supported")
+	case c == 0 || c == '\r' || c == '\n':
+		return nil, p.errorf("missing element")
+	}
+	return nil, p.errorf("unexpected %q", p.peek())
+}
+
+// quoted parses a quoted string of printable ASCII characters
+func (p *parser) quoted(caseSensitive bool) (node, error) {
+	start := *p
+	if p.next() != '"' {
+		return nil, start.errorf("expected a quoted string")
+	}
+	begin := p.pos
+	for p.peek() != '"' {
+		if c := p.peek(); c < 0x20 || c > 0x7e {
+			return nil, start.errorf("unterminated string")
+		}
+		p.next()
+	}
+	text := p.src[begin:p.pos]
+	p.next()
+	return &literal{chars: []rune(text), caseSensitive: caseSensitive}, nil
+}
+
+// numVal parses %b, %d and %x values after the percent sign at start
+func (p *parser) numVal(start parser) (node, error) {
+	base := 0
+	switch p.next() {
+	case 'b', 'B':
+		base = 2
+	case 'd', 'D':
+		base = 10
+	case 'x', 'X':
+		base = 16
+	default:
+		return nil, start.errorf("expected b, d or x after %%")
+	}
+
+	first, err := p.value(base, start)
+	if err != nil {
+		return nil, err
+	}
+	switch p.peek() {
+	case '-':
+		p.next()
+		last, err := p.value(base, start)
+		if err != nil {
+			return nil, err
+		}
+		if last < first {
+			return nil, start.errorf("value range is out of order")
+		}
+		return &valueRange{lo: first, hi: last}, nil
+	case '.':
+		chars := []rune{first}
+		for p.peek() == '.' {
+			p.next()
+			c, err := p.value(base, start)
+			if err != nil {
+				return nil, err
+			}
+			chars = append(chars, c)
+		}
+		return &literal{chars: chars, caseSensitive: true}, nil
+	}
+	return &literal{chars: []rune{first}, caseSensitive: true}, nil
+}
+
+func (p *parser) value(base int, start parser) (rune, error) {
+	begin := p.pos
+	for isDigit(p.peek()) || base == 16 && strings.IndexByte("abcdefABCDEF", p.peek()) >= 0 {
+		p.next()
+	}
+	n, err := strconv.ParseUint(p.src[begin:p.pos], base, 32)
+	if err != nil || n > utf8.MaxRune {
+		return 0, start.errorf("invalid numeric value %s", p.src[start.pos:p.pos])
+	}
+	return rune(n), nil
+}
+
+func isAlpha(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// coreGrammar holds the core rules of RFC 5234 Appendix B.1
+var coreGrammar = func() *Grammar {
+	g, err := parse(`ALPHA = %x41-5A / %x61-7A
+BIT = "0" / "1"
+CHAR = %x01-7F
+CR = %x0D
+CRLF = CR LF
+CTL = %x00-1F / %x7F
+DIGIT = %x30-39
+DQUOTE = %x22
+HEXDIG = DIGIT / "A" / "B" / "C" / "D" / "E" / "F"
+HTAB = %x09
+LF = %x0A
+LWSP = *(WSP / CRLF WSP)
+OCTET = %x00-FF
+SP = %x20
+VCHAR = %x21-7E
+WSP = SP / HTAB
+`)
+	if err != nil {
+		panic(err)
+	}
+	if err := g.resolve(); err != nil {
+		panic(err)
+	}
+	return g
+}()
@@ -0,0 +1,19 @@
+package ast
+
+import "github.com/HannesKimara/cddlc/token"
+
+// AnyType represents the AST Node for the `any` type matching any single data item
+type AnyType struct {
+	Pos   token.Position
+	Token token.Token
+}
+
+func (at *AnyType) Start() token.Position {
+	return at.Pos
+}
+
+func (at *AnyType) End() token.Position {
+	return at.Pos.To(3) // length of `any`
+}
+
+func (at *AnyType) groupEntry() {}
@@ -19,3 +19,5 @@ func (a *Array) End() token.Position {
 	}
 	return a.Rules[len(a.Rules)-1].End()
 }
+
+func (a *Array) groupEntry() {}
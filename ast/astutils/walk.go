@@ -17,20 +17,40 @@ func Walk(v Visitor, node ast.Node) {
 
 	switch n := node.(type) {
 
+	case *ast.AnyType:
+		// pass
+
 	case *ast.Array:
 		for _, rule := range n.Rules {
 			Walk(v, rule)
 		}
 
+	case *ast.BadNode:
+		// pass
+
+	case *ast.Bits:
+		if n.Base != nil {
+			Walk(v, n.Base)
+		}
+		if n.Contstraint != nil {
+			Walk(v, n.Contstraint)
+		}
+
 	case *ast.BooleanType:
 		// pass
 
+	case *ast.BooleanLiteral:
+		// pass
+
 	case *ast.BytesType:
 		// pass
 
 	case *ast.BstrType:
 		// pass
 
+	case *ast.BytesLiteral:
+		// pass
+
 	case *ast.CDDL:
 		for _, rule := range n.Rules {
 			Walk(v, rule)
@@ -39,6 +59,11 @@ func Walk(v Visitor, node ast.Node) {
 	case *ast.Comment:
 		// pass
 
+	case *ast.CommentGroup:
+		for _, comment := range n.List {
+			Walk(v, comment)
+		}
+
 	case *ast.ComparatorOpControl:
 		if n.Left != nil {
 			Walk(v, n.Left)
@@ -47,6 +72,14 @@ func Walk(v Visitor, node ast.Node) {
 			Walk(v, n.Right)
 		}
 
+	case *ast.ComputedOpControl:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
 	case *ast.Regexp:
 		if n.Base != nil {
 			Walk(v, n.Base)
@@ -55,6 +88,38 @@ func Walk(v Visitor, node ast.Node) {
 			Walk(v, n.Regex)
 		}
 
+	case *ast.ABNF:
+		if n.Base != nil {
+			Walk(v, n.Base)
+		}
+		if n.Grammar != nil {
+			Walk(v, n.Grammar)
+		}
+
+	case *ast.CBORControl:
+		if n.Base != nil {
+			Walk(v, n.Base)
+		}
+		if n.Controller != nil {
+			Walk(v, n.Controller)
+		}
+
+	case *ast.DefaultControl:
+		if n.Base != nil {
+			Walk(v, n.Base)
+		}
+		if n.Default != nil {
+			Walk(v, n.Default)
+		}
+
+	case *ast.FeatureControl:
+		if n.Base != nil {
+			Walk(v, n.Base)
+		}
+		if n.Feature != nil {
+			Walk(v, n.Feature)
+		}
+
 	case *ast.SizeOperatorControl:
 		if n.Type != nil {
 			Walk(v, n.Type)
@@ -74,12 +139,25 @@ func Walk(v Visitor, node ast.Node) {
 			Walk(v, n.TrailingComment)
 		}
 
+	case *ast.Enumeration:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
 	case *ast.FloatType:
 		// pass
 
 	case *ast.FloatLiteral:
 		// pass
 
+	case *ast.Generic:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+
 	case *ast.Group:
 		for _, rule := range n.Entries {
 			Walk(v, rule)
@@ -141,6 +219,9 @@ func Walk(v Visitor, node ast.Node) {
 		if n.Name != nil {
 			Walk(v, n.Name)
 		}
+		for _, param := range n.Params {
+			Walk(v, param)
+		}
 		if n.Value != nil {
 			Walk(v, n.Value)
 		}
@@ -173,6 +254,9 @@ func Walk(v Visitor, node ast.Node) {
 			Walk(v, n.Second)
 		}
 
+	case *ast.UintType:
+		// pass
+
 	case *ast.UintLiteral:
 		// pass
 
@@ -16,6 +16,8 @@ func (b *BooleanType) End() token.Position {
 	return b.Pos.To(4)
 }
 
+func (b *BooleanType) groupEntry() {}
+
 type BooleanLiteral struct {
 	Range token.PositionRange
 	Bool  bool
@@ -28,3 +30,5 @@ func (bl *BooleanLiteral) Start() token.Position {
 func (bl *BooleanLiteral) End() token.Position {
 	return bl.Range.End
 }
+
+func (bl *BooleanLiteral) groupEntry() {}
@@ -22,6 +22,8 @@ func (b *BytesType) End() token.Position {
 	return b.Pos.To(5) // length of `bytes`
 }
 
+func (b *BytesType) groupEntry() {}
+
 func (b *BstrType) Start() token.Position {
 	return b.Pos
 }
@@ -29,3 +31,27 @@ func (b *BstrType) Start() token.Position {
 func (b *BstrType) End() token.Position {
 	return b.Pos.To(5) // length of `bytes`
 }
+
+func (b *BstrType) groupEntry() {}
+
+// BytesLiteral represents the AST Node for a byte string literal i.e. 'text', h'00ff' or b64'AP8='
+type BytesLiteral struct {
+	Pos   token.Position
+	Token token.Token
+
+	// Literal: the decoded bytes
+	Literal []byte
+
+	// Source: the literal as written in the source including the prefix and quotes
+	Source string
+}
+
+func (bl *BytesLiteral) Start() token.Position {
+	return bl.Pos
+}
+
+func (bl *BytesLiteral) End() token.Position {
+	return bl.Pos.To(len(bl.Source))
+}
+
+func (bl *BytesLiteral) groupEntry() {}
@@ -0,0 +1,28 @@
+package ast
+
+import "github.com/HannesKimara/cddlc/token"
+
+// ABNF represents the AST Node for `.abnf` and `.abnfb` control operators which restrict
+// text and byte strings to those matched by an ABNF grammar
+type ABNF struct {
+	// Pos: the position of the .abnf or .abnfb token
+	Pos token.Position
+	// Token: the token responsible for the node, ABNF or ABNFB
+	Token token.Token
+	// Base: the string type to apply the grammar to
+	Base Node
+	// Grammar: the text or byte string holding the grammar
+	Grammar Node
+}
+
+// Start returns the start of the base type
+func (a *ABNF) Start() token.Position {
+	return a.Base.Start()
+}
+
+// End returns the end of the grammar
+func (a *ABNF) End() token.Position {
+	return a.Grammar.End()
+}
+
+func (a *ABNF) groupEntry() {}
@@ -24,3 +24,5 @@ func (r *Bits) Start() token.Position {
 func (r *Bits) End() token.Position {
 	return r.Contstraint.End()
 }
+
+func (r *Bits) groupEntry() {}
@@ -0,0 +1,37 @@
+package ast
+
+import "github.com/HannesKimara/cddlc/token"
+
+// CBORControl represents the AST Node for the `.cbor` and `.cborseq` control operators which
+// restrict byte strings to the encodings of data items matched by the controller type.
+//
+// A `.det` control on the byte string types, `bstr .det T` or `bytes .det T`, is also a
+// CBORControl. It restricts byte strings to the core deterministic encoding of a data item
+// matched by T. This meaning is specific to cddlc: RFC 9165 defines `.det` as the dedenting
+// concatenation of text or byte string values, which the parser keeps for any other left
+// operand, e.g. `'a' .det 'b'`, as an *ast.ComputedOpControl. The two never overlap since
+// the concatenation only applies to values and not to the bstr and bytes types.
+type CBORControl struct {
+	// Pos: the position of the control operator token
+	Pos token.Position
+	// Token: the token responsible for the node, one of CBOR, CBORSEQ or DET
+	Token token.Token
+	// Base: the byte string type holding the encoded data items
+	Base Node
+	// Controller: the type of the data items. For .cbor and .det, the type of the single
+	// data item encoded. For .cborseq, an array type matched by the items of the sequence
+	// as if they were the elements of an array
+	Controller Node
+}
+
+// Start returns the start of the base type
+func (c *CBORControl) Start() token.Position {
+	return c.Base.Start()
+}
+
+// End returns the end of the controller type
+func (c *CBORControl) End() token.Position {
+	return c.Controller.End()
+}
+
+func (c *CBORControl) groupEntry() {}
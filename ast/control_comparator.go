@@ -20,3 +20,5 @@ func (cc *ComparatorOpControl) Start() token.Position {
 func (cc *ComparatorOpControl) End() token.Position {
 	return cc.Right.End()
 }
+
+func (cc *ComparatorOpControl) groupEntry() {}
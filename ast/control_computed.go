@@ -0,0 +1,26 @@
+package ast
+
+import "github.com/HannesKimara/cddlc/token"
+
+// ComputedOpControl represents the AST Node for the RFC 9165 operators `.plus, .cat, .det` that
+// compute a new literal from the values of the left and right operands.
+type ComputedOpControl struct {
+	// Pos: the position of the operator token
+	Pos token.Position
+
+	// Token: the token responsible for the node
+	Token token.Token
+
+	// Left, Right: the target and controller operands
+	Left, Right Node
+}
+
+func (co *ComputedOpControl) Start() token.Position {
+	return co.Left.Start()
+}
+
+func (co *ComputedOpControl) End() token.Position {
+	return co.Right.End()
+}
+
+func (co *ComputedOpControl) groupEntry() {}
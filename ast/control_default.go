@@ -0,0 +1,32 @@
+package ast
+
+import "github.com/HannesKimara/cddlc/token"
+
+// DefaultControl represents the AST Node for the `.default` control operator. It does not
+// restrict the base type but documents the value assumed when an optional member of that
+// type is absent.
+type DefaultControl struct {
+	// Pos: the position of the .default token
+	Pos token.Position
+
+	// Token: the token responsible for the node
+	Token token.Token
+
+	// Base: the type of the values
+	Base Node
+
+	// Default: the value of absent members
+	Default Node
+}
+
+// Start returns the start of the base type
+func (d *DefaultControl) Start() token.Position {
+	return d.Base.Start()
+}
+
+// End returns the end of the default value
+func (d *DefaultControl) End() token.Position {
+	return d.Default.End()
+}
+
+func (d *DefaultControl) groupEntry() {}
@@ -0,0 +1,32 @@
+package ast
+
+import "github.com/HannesKimara/cddlc/token"
+
+// FeatureControl represents the AST Node for the `.feature` control operator of RFC 9165.
+// Data items match the base type as usual and use the feature when they do.
+type FeatureControl struct {
+	// Pos: the position of the .feature token
+	Pos token.Position
+
+	// Token: the token responsible for the node
+	Token token.Token
+
+	// Base: the type of the values
+	Base Node
+
+	// Feature: the name of the feature, a text string or an array starting with the name
+	// followed by details
+	Feature Node
+}
+
+// Start returns the start of the base type
+func (f *FeatureControl) Start() token.Position {
+	return f.Base.Start()
+}
+
+// End returns the end of the feature
+func (f *FeatureControl) End() token.Position {
+	return f.Feature.End()
+}
+
+func (f *FeatureControl) groupEntry() {}
@@ -17,3 +17,5 @@ func (r *Regexp) Start() token.Position {
 func (r *Regexp) End() token.Position {
 	return r.Regex.End()
 }
+
+func (r *Regexp) groupEntry() {}
@@ -26,3 +26,5 @@ func (sc *SizeOperatorControl) Start() token.Position {
 func (sc *SizeOperatorControl) End() token.Position {
 	return sc.Size.End()
 }
+
+func (sc *SizeOperatorControl) groupEntry() {}
@@ -6,6 +6,7 @@ import "github.com/HannesKimara/cddlc/token"
 // It maps the name of the type to the type
 type Entry struct {
 	Pos             token.Position
+	Token           token.Token // the member key operator; one of : or =>
 	Name            *Identifier
 	Value           Node
 	TrailingComment *Comment
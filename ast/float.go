@@ -8,7 +8,9 @@ import (
 type FloatType struct {
 	Pos   token.Position
 	Token token.Token
-	Base  int // 16, 32, 64
+	// Base: the precision in bits the float must be representable in; one of 16, 32 or 64.
+	// Zero for `float`, which allows any precision
+	Base int
 }
 
 func (ft *FloatType) Start() token.Position {
@@ -16,9 +18,11 @@ func (ft *FloatType) Start() token.Position {
 }
 
 func (ft *FloatType) End() token.Position {
-	return ft.Pos.To(5) // TODO: support bases float64, 32, 16
+	return ft.Pos.To(len(ft.Token.String()))
 }
 
+func (ft *FloatType) groupEntry() {}
+
 // FloatLiteral represesnts the AST Node for float type token i.e. 3.412
 type FloatLiteral struct {
 	Range   token.PositionRange
@@ -33,3 +37,5 @@ func (fl *FloatLiteral) Start() token.Position {
 func (fl *FloatLiteral) End() token.Position {
 	return fl.Range.End
 }
+
+func (fl *FloatLiteral) groupEntry() {}
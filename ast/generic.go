@@ -0,0 +1,28 @@
+package ast
+
+import "github.com/HannesKimara/cddlc/token"
+
+// Generic represents the AST Node for a reference to a generic rule with arguments i.e. `message<tstr, uint>`
+type Generic struct {
+	// Pos: the position of the `<` token
+	Pos token.Position
+
+	// Name: the name of the referenced generic rule
+	Name *Identifier
+
+	// Args: the arguments in order of the rule parameters
+	Args []Node
+
+	// Closing: the position of the `>` token
+	Closing token.Position
+}
+
+func (g *Generic) Start() token.Position {
+	return g.Name.Start()
+}
+
+func (g *Generic) End() token.Position {
+	return g.Closing.To(1)
+}
+
+func (g *Generic) groupEntry() {}
@@ -2,6 +2,7 @@ package ast
 
 import "github.com/HannesKimara/cddlc/token"
 
+// GroupChoice represents the AST Node for `//` group choice operator
 type GroupChoice struct {
 	Pos           token.Position
 	Token         token.Token
@@ -15,3 +16,5 @@ func (gc *GroupChoice) Start() token.Position {
 func (gc *GroupChoice) End() token.Position {
 	return gc.Second.End()
 }
+
+func (gc *GroupChoice) groupEntry() {}
@@ -20,6 +20,8 @@ func (it *IntegerType) End() token.Position {
 	return it.Pos.To(3) // length of `int`
 }
 
+func (it *IntegerType) groupEntry() {}
+
 type NegativeIntegerType struct {
 	Pos   token.Position
 	Token token.Token
@@ -33,6 +35,8 @@ func (nt *NegativeIntegerType) End() token.Position {
 	return nt.Pos.To(4) // length of `nint`
 }
 
+func (nt *NegativeIntegerType) groupEntry() {}
+
 // IntegerLiteral represents the AST Node for an integer literal i.e 3
 type IntegerLiteral struct {
 	Pos     token.Position
@@ -47,3 +51,5 @@ func (il *IntegerLiteral) Start() token.Position {
 func (il *IntegerLiteral) End() token.Position {
 	return il.Pos.To(len(fmt.Sprintf("%d", il.Literal)))
 }
+
+func (il *IntegerLiteral) groupEntry() {}
@@ -18,3 +18,5 @@ func (m *Map) End() token.Position {
 	}
 	return m.Rules[len(m.Rules)-1].End()
 }
+
+func (m *Map) groupEntry() {}
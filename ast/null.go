@@ -15,3 +15,5 @@ func (nt *NullType) Start() token.Position {
 func (nt *NullType) End() token.Position {
 	return nt.Pos.To(4) // lenth of `null`
 }
+
+func (nt *NullType) groupEntry() {}
@@ -6,7 +6,9 @@ import "github.com/HannesKimara/cddlc/token"
 // It maps the name of the type to the type
 type Rule struct {
 	Pos             token.Position
+	Token           token.Token // the assignment operator; one of =, /= or //=
 	Name            *Identifier
+	Params          []*Identifier // generic parameters i.e. `<t, v>`
 	Value           Node
 	TrailingComment *Comment
 }
@@ -14,5 +14,10 @@ func (t *Tag) Start() token.Position {
 }
 
 func (t *Tag) End() token.Position {
+	if t.Item == nil {
+		return t.Pos.To(1) // length of `#`
+	}
 	return t.Item.End().To(1) // add )
 }
+
+func (t *Tag) groupEntry() {}
@@ -17,6 +17,8 @@ func (tl *TextLiteral) End() token.Position {
 	return tl.Pos.To(len(tl.Literal))
 }
 
+func (tl *TextLiteral) groupEntry() {}
+
 // TstrType represents the AST Node for the `tstr` type definition token
 type TstrType struct {
 	Pos   token.Position
@@ -30,3 +32,5 @@ func (tt *TstrType) Start() token.Position {
 func (tt *TstrType) End() token.Position {
 	return tt.Pos.To(4)
 }
+
+func (tt *TstrType) groupEntry() {}
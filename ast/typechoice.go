@@ -16,3 +16,5 @@ func (tc *TypeChoice) Start() token.Position {
 func (tc *TypeChoice) End() token.Position {
 	return tc.Second.End()
 }
+
+func (tc *TypeChoice) groupEntry() {}
@@ -15,3 +15,5 @@ func (ut *UintType) Start() token.Position {
 func (ut *UintType) End() token.Position {
 	return ut.Range.End
 }
+
+func (ut *UintType) groupEntry() {}
@@ -15,3 +15,5 @@ func (u *Unwrap) Start() token.Position {
 func (u *Unwrap) End() token.Position {
 	return u.Item.End()
 }
+
+func (u *Unwrap) groupEntry() {}
@@ -0,0 +1,308 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/HannesKimara/cddlc/runtime/half"
+)
+
+// MaxDepth is the maximum nesting of arrays, maps and tags accepted by the decoder
+const MaxDepth = 512
+
+// SyntaxError describes malformed input
+type SyntaxError struct {
+	// Offset: the offset in the input the error was found at
+	Offset int
+
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("cbor: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Decoder reads data items from a byte slice
+type Decoder struct {
+	data []byte
+	off  int
+
+	// base: the offset of data in a larger input, added to the offsets reported
+	base int
+}
+
+// NewDecoder returns a decoder reading from data
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// More returns true if there is input left to decode
+func (d *Decoder) More() bool {
+	return d.off < len(d.data)
+}
+
+// Offset returns the offset of the next data item in the input
+func (d *Decoder) Offset() int {
+	return d.off
+}
+
+// Decode reads the next data item
+func (d *Decoder) Decode() (*Item, error) {
+	return d.decode(0)
+}
+
+// Decode decodes data containing exactly one data item
+func Decode(data []byte) (*Item, error) {
+	d := NewDecoder(data)
+	item, err := d.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, d.error("unexpected data after the first item")
+	}
+	return item, nil
+}
+
+func (d *Decoder) error(format string, args ...interface{}) error {
+	return d.errorAt(d.off, format, args...)
+}
+
+// breakCode is returned by head when it reads the break stop code
+const breakCode = 0xff
+
+// head reads the initial byte and argument of an item. indefinite is true if the
+// additional information is 31.
+func (d *Decoder) head() (major Major, info byte, arg uint64, indefinite bool, err error) {
+	if d.off >= len(d.data) {
+		return 0, 0, 0, false, d.error("unexpected end of input")
+	}
+	initial := d.data[d.off]
+	major, info = Major(initial>>5), initial&0x1f
+	d.off++
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if d.off+n > len(d.data) {
+			d.off--
+			return 0, 0, 0, false, d.error("unexpected end of input")
+		}
+		buf := d.data[d.off : d.off+n]
+		d.off += n
+		switch n {
+		case 1:
+			arg = uint64(buf[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(buf))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(buf))
+		default:
+			arg = binary.BigEndian.Uint64(buf)
+		}
+		return major, info, arg, false, nil
+	case info == 31:
+		return major, info, 0, true, nil
+	}
+	d.off--
+	return 0, 0, 0, false, d.error("reserved additional information %d", info)
+}
+
+func (d *Decoder) decode(depth int) (*Item, error) {
+	if depth > MaxDepth {
+		return nil, d.error("maximum nesting depth %d exceeded", MaxDepth)
+	}
+
+	offset := d.off
+	major, info, arg, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	item := &Item{Major: major, Arg: arg, Indefinite: indefinite, Offset: d.base + offset}
+	if major != MajorSimple {
+		item.ArgSize = argSize(info, arg)
+	}
+
+	switch major {
+	case MajorUint, MajorNint:
+		if indefinite {
+			return nil, d.errorAt(offset, "indefinite length %s", major)
+		}
+	case MajorBytes, MajorText:
+		content, err := d.decodeString(item, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == MajorText {
+			if !utf8.Valid(content) {
+				return nil, d.errorAt(offset, "invalid UTF-8 in text string")
+			}
+			item.Text = string(content)
+		} else {
+			item.Bytes = content
+		}
+	case MajorArray:
+		err = d.decodeElements(arg, indefinite, func() error {
+			elem, err := d.decode(depth + 1)
+			if err != nil {
+				return err
+			}
+			item.Items = append(item.Items, elem)
+			return nil
+		})
+	case MajorMap:
+		err = d.decodeElements(arg, indefinite, func() error {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return err
+			}
+			if d.atBreak() {
+				return d.error("missing value for map key")
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return err
+			}
+			item.Pairs = append(item.Pairs, &Pair{Key: key, Value: value})
+			return nil
+		})
+	case MajorTag:
+		if indefinite {
+			return nil, d.errorAt(offset, "indefinite length tag")
+		}
+		item.Content, err = d.decode(depth + 1)
+	case MajorSimple:
+		err = d.decodeSimple(item, info, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (d *Decoder) errorAt(offset int, format string, args ...interface{}) error {
+	return &SyntaxError{Offset: d.base + offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// atBreak returns true if the next byte is the break stop code
+func (d *Decoder) atBreak() bool {
+	return d.off < len(d.data) && d.data[d.off] == breakCode
+}
+
+// decodeElements calls fn for every element of an array or map
+func (d *Decoder) decodeElements(n uint64, indefinite bool, fn func() error) error {
+	if indefinite {
+		for !d.atBreak() {
+			if !d.More() {
+				return d.error("unexpected end of input")
+			}
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		d.off++
+		return nil
+	}
+
+	// every element takes at least one byte
+	if n > uint64(len(d.data)-d.off) {
+		return d.error("length %d exceeds the remaining input", n)
+	}
+	for i := uint64(0); i < n; i++ {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeString reads the content of byte and text strings. Indefinite length strings
+// are concatenated from their chunks.
+func (d *Decoder) decodeString(item *Item, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		n := item.Arg
+		if n > uint64(len(d.data)-d.off) {
+			return nil, d.error("length %d exceeds the remaining input", n)
+		}
+		content := d.data[d.off : d.off+int(n)]
+		d.off += int(n)
+		return append([]byte{}, content...), nil
+	}
+
+	content := []byte{}
+	for !d.atBreak() {
+		chunkOffset := d.off
+		chunkMajor, info, chunkLen, chunkIndefinite, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != item.Major || chunkIndefinite {
+			return nil, d.errorAt(chunkOffset, "invalid chunk in indefinite length %s", item.Major)
+		}
+		chunk := &Item{Major: chunkMajor, Arg: chunkLen, ArgSize: argSize(info, chunkLen), Offset: d.base + chunkOffset}
+		b, err := d.decodeString(chunk, false)
+		if err != nil {
+			return nil, err
+		}
+		if item.Major == MajorText {
+			if !utf8.Valid(b) {
+				return nil, d.errorAt(chunkOffset, "invalid UTF-8 in text string chunk")
+			}
+			chunk.Text = string(b)
+		} else {
+			chunk.Bytes = b
+		}
+		item.Chunks = append(item.Chunks, chunk)
+		content = append(content, b...)
+	}
+	d.off++
+	return content, nil
+}
+
+// argSize returns the size of an argument encoded in a longer form than needed and zero
+// for arguments in their shortest form
+func argSize(info byte, arg uint64) int {
+	if info < 24 || info > 27 {
+		return 0
+	}
+	shortest := byte(24)
+	switch {
+	case arg < 24:
+		shortest = 0
+	case arg > math.MaxUint32:
+		shortest = 27
+	case arg > math.MaxUint16:
+		shortest = 26
+	case arg > math.MaxUint8:
+		shortest = 25
+	}
+	if info == shortest {
+		return 0
+	}
+	return 1 << (info - 24)
+}
+
+func (d *Decoder) decodeSimple(item *Item, info byte, offset int) error {
+	switch info {
+	case 24:
+		if item.Arg < 32 {
+			return d.errorAt(offset, "invalid two byte encoding of simple value %d", item.Arg)
+		}
+	case 25:
+		item.Width, item.Float = 16, half.ToFloat64(uint16(item.Arg))
+	case 26:
+		item.Width, item.Float = 32, float64(math.Float32frombits(uint32(item.Arg)))
+	case 27:
+		item.Width, item.Float = 64, math.Float64frombits(item.Arg)
+	case 31:
+		return d.errorAt(offset, "unexpected break stop code")
+	}
+	if item.Width != 0 {
+		item.Arg = 0
+	}
+	return nil
+}
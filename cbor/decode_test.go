@@ -0,0 +1,170 @@
+package cbor_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		hex  string
+		diag string
+	}{
+		{"00", "0"},
+		{"17", "23"},
+		{"1818", "24"},
+		{"1903e8", "1000"},
+		{"1bffffffffffffffff", "18446744073709551615"},
+		{"20", "-1"},
+		{"3863", "-100"},
+		{"3bffffffffffffffff", "-18446744073709551616"},
+		{"40", "h''"},
+		{"4401020304", "h'01020304'"},
+		{"60", `""`},
+		{"6449455446", `"IETF"`},
+		{"62c3bc", `"ü"`},
+		{"80", "[]"},
+		{"83010203", "[1, 2, 3]"},
+		{"8301820203820405", "[1, [2, 3], [4, 5]]"},
+		{"a0", "{}"},
+		{"a201020304", "{1: 2, 3: 4}"},
+		{"a26161016162820203", `{"a": 1, "b": [2, 3]}`},
+		{"c11a514b67b0", "1(1363896240)"},
+		{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", `32("http://www.example.com")`},
+		{"f4", "false"},
+		{"f5", "true"},
+		{"f6", "null"},
+		{"f7", "undefined"},
+		{"f0", "simple(16)"},
+		{"f8ff", "simple(255)"},
+		{"f90000", "0.0"},
+		{"f93c00", "1.0"},
+		{"f97bff", "65504.0"},
+		{"f90001", "5.960464477539063e-08"},
+		{"f9c400", "-4.0"},
+		{"f97c00", "Infinity"},
+		{"f97e00", "NaN"},
+		{"fa47c35000", "100000.0"},
+		{"fb3ff199999999999a", "1.1"},
+
+		// indefinite lengths
+		{"5f42010243030405ff", "h'0102030405'"},
+		{"7f657374726561646d696e67ff", `"streaming"`},
+		{"9fff", "[]"},
+		{"9f018202039f0405ffff", "[1, [2, 3], [4, 5]]"},
+		{"bf61610161629f0203ffff", `{"a": 1, "b": [2, 3]}`},
+	}
+
+	for _, tst := range tests {
+		item, err := cbor.Decode(decodeHex(t, tst.hex))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.hex, err)
+			continue
+		}
+		if item.String() != tst.diag {
+			t.Errorf("%s: expected `%s` got `%s`", tst.hex, tst.diag, item)
+		}
+	}
+}
+
+func TestDecodeDetails(t *testing.T) {
+	item, err := cbor.Decode(decodeHex(t, "f93c00"))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !item.IsFloat() || item.Width != 16 {
+		t.Errorf("expected a 16 bit float got %s with width %d", item.Kind(), item.Width)
+	}
+
+	item, err = cbor.Decode(decodeHex(t, "9f01ff"))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !item.Indefinite || item.Major != cbor.MajorArray {
+		t.Errorf("expected an indefinite length array got %s", item.Kind())
+	}
+	if item.Items[0].Offset != 1 {
+		t.Errorf("expected element offset 1 got %d", item.Items[0].Offset)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		hex string
+		err string
+	}{
+		{"", "cbor: unexpected end of input at offset 0"},
+		{"19 01", "cbor: unexpected end of input at offset 0"},
+		{"1c", "cbor: reserved additional information 28 at offset 0"},
+		{"1f", "cbor: indefinite length unsigned integer at offset 0"},
+		{"43 0102", "cbor: length 3 exceeds the remaining input at offset 1"},
+		{"62 c328", "cbor: invalid UTF-8 in text string at offset 0"},
+		{"82 01", "cbor: length 2 exceeds the remaining input at offset 1"},
+		{"a1 01", "cbor: unexpected end of input at offset 2"},
+		{"bf 01ff", "cbor: missing value for map key at offset 2"},
+		{"9f 01", "cbor: unexpected end of input at offset 2"},
+		{"5f 6161 ff", "cbor: invalid chunk in indefinite length byte string at offset 1"},
+		{"ff", "cbor: unexpected break stop code at offset 0"},
+		{"f8 10", "cbor: invalid two byte encoding of simple value 16 at offset 0"},
+		{"01 02", "cbor: unexpected data after the first item at offset 1"},
+	}
+
+	for _, tst := range tests {
+		_, err := cbor.Decode(decodeHex(t, tst.hex))
+		if err == nil {
+			t.Errorf("%s: expected error `%s`", tst.hex, tst.err)
+			continue
+		}
+		var syntax *cbor.SyntaxError
+		if !errors.As(err, &syntax) {
+			t.Errorf("%s: expected a syntax error got %T", tst.hex, err)
+		}
+		if err.Error() != tst.err {
+			t.Errorf("%s: expected `%s` got `%s`", tst.hex, tst.err, err)
+		}
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	data := make([]byte, cbor.MaxDepth+2)
+	for i := range data {
+		data[i] = 0x81
+	}
+	data[len(data)-1] = 0x00
+	if _, err := cbor.Decode(data); err == nil {
+		t.Errorf("expected an error for nesting beyond the maximum depth")
+	}
+}
+
+func TestDecoderMore(t *testing.T) {
+	d := cbor.NewDecoder(decodeHex(t, "01 6161 80"))
+	var items []string
+	for d.More() {
+		item, err := d.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		items = append(items, item.String())
+	}
+	if len(items) != 3 || items[0] != "1" || items[1] != `"a"` || items[2] != "[]" {
+		t.Errorf("unexpected items %v", items)
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	clean := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			clean = append(clean, s[i])
+		}
+	}
+	data, err := hex.DecodeString(string(clean))
+	if err != nil {
+		t.Fatalf("invalid hex %s: %s", s, err)
+	}
+	return data
+}
@@ -0,0 +1,143 @@
+package cbor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/HannesKimara/cddlc/runtime/half"
+)
+
+// DeterminismError describes a data item that does not follow the core deterministic
+// encoding requirements of https://www.rfc-editor.org/rfc/rfc8949#section-4.2.1
+type DeterminismError struct {
+	// Offset: the offset of the head of the offending data item in the decoded input
+	Offset int
+
+	Msg string
+}
+
+func (e *DeterminismError) Error() string {
+	return fmt.Sprintf("cbor: %s at offset %d", e.Msg, e.Offset)
+}
+
+// CheckDeterministic reports whether the data item was decoded from its core deterministic
+// encoding. Arguments and lengths must take their shortest form, lengths must be definite,
+// floats must take the shortest width preserving their value and map keys must be sorted
+// by the bytewise order of their encodings without duplicates. It returns a
+// *DeterminismError for the first data item breaking one of these requirements.
+func CheckDeterministic(item *Item) error {
+	if item.ArgSize != 0 {
+		return &DeterminismError{Offset: item.Offset, Msg: fmt.Sprintf("argument %d of %s is not in its shortest form", headArg(item), item.Kind())}
+	}
+	if item.Indefinite {
+		return &DeterminismError{Offset: item.Offset, Msg: fmt.Sprintf("%s has an indefinite length", item.Kind())}
+	}
+
+	switch item.Major {
+	case MajorArray:
+		for _, elem := range item.Items {
+			if err := CheckDeterministic(elem); err != nil {
+				return err
+			}
+		}
+	case MajorMap:
+		var prev []byte
+		for _, pair := range item.Pairs {
+			if err := CheckDeterministic(pair.Key); err != nil {
+				return err
+			}
+			key := Encode(pair.Key)
+			if prev != nil {
+				switch cmp := bytes.Compare(prev, key); {
+				case cmp == 0:
+					return &DeterminismError{Offset: pair.Key.Offset, Msg: fmt.Sprintf("duplicate map key %s", pair.Key)}
+				case cmp > 0:
+					return &DeterminismError{Offset: pair.Key.Offset, Msg: fmt.Sprintf("map key %s is not in sorted order", pair.Key)}
+				}
+			}
+			prev = key
+			if err := CheckDeterministic(pair.Value); err != nil {
+				return err
+			}
+		}
+	case MajorTag:
+		if item.Content != nil {
+			return CheckDeterministic(item.Content)
+		}
+	case MajorSimple:
+		if item.Width > 0 && preferredWidth(item.Float) < item.Width {
+			return &DeterminismError{Offset: item.Offset, Msg: fmt.Sprintf("float %s is not in its shortest form", item)}
+		}
+	}
+	return nil
+}
+
+// EncodeDeterministic returns the core deterministic encoding of the data item regardless
+// of the encoding it was decoded from
+func EncodeDeterministic(item *Item) []byte {
+	return Encode(deterministic(item))
+}
+
+// deterministic returns a copy of the data item in its core deterministic encoding
+func deterministic(item *Item) *Item {
+	out := &Item{Major: item.Major, Arg: item.Arg, Bytes: item.Bytes, Text: item.Text, Float: item.Float, Width: item.Width}
+	switch item.Major {
+	case MajorArray:
+		out.Items = make([]*Item, len(item.Items))
+		for i, elem := range item.Items {
+			out.Items[i] = deterministic(elem)
+		}
+	case MajorMap:
+		type entry struct {
+			key  []byte
+			pair *Pair
+		}
+		entries := make([]entry, len(item.Pairs))
+		for i, pair := range item.Pairs {
+			key := deterministic(pair.Key)
+			entries[i] = entry{Encode(key), &Pair{Key: key, Value: deterministic(pair.Value)}}
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+		out.Pairs = make([]*Pair, len(entries))
+		for i, e := range entries {
+			out.Pairs[i] = e.pair
+		}
+	case MajorTag:
+		if item.Content != nil {
+			out.Content = deterministic(item.Content)
+		}
+	case MajorSimple:
+		if item.Width > 0 {
+			out.Width = preferredWidth(item.Float)
+		}
+	}
+	return out
+}
+
+// preferredWidth returns the shortest width of a float that preserves its value. NaNs take
+// the half-precision quiet NaN.
+func preferredWidth(f float64) int {
+	switch {
+	case half.Exact(f):
+		return 16
+	case half.Exact32(f):
+		return 32
+	}
+	return 64
+}
+
+// headArg returns the argument of the head of a data item
+func headArg(item *Item) uint64 {
+	switch item.Major {
+	case MajorBytes:
+		return uint64(len(item.Bytes))
+	case MajorText:
+		return uint64(len(item.Text))
+	case MajorArray:
+		return uint64(len(item.Items))
+	case MajorMap:
+		return uint64(len(item.Pairs))
+	}
+	return item.Arg
+}
@@ -0,0 +1,99 @@
+package cbor_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+func TestCheckDeterministic(t *testing.T) {
+	tests := []struct {
+		hex string
+		err string
+	}{
+		{"00", ""},
+		{"1818", ""},
+		{"3bffffffffffffffff", ""},
+		{"83 01 820203 820405", ""},
+		{"a3 01 02 20 03 6161 04", ""},
+		{"a2 0a 01 820100 02", ""},
+		{"c1 1a514b67b0", ""},
+		{"f93e00 f97e00", ""},
+		{"fa47c35001", ""},
+		{"fb3ff199999999999a", ""},
+		{"1800", "cbor: argument 0 of unsigned integer is not in its shortest form at offset 0"},
+		{"82 01 590001 61", "cbor: argument 1 of byte string is not in its shortest form at offset 2"},
+		{"d9000101", "cbor: argument 1 of tag is not in its shortest form at offset 0"},
+		{"81 9f01ff", "cbor: array has an indefinite length at offset 1"},
+		{"7f6161ff", "cbor: text string has an indefinite length at offset 0"},
+		{"a2 6161 01 01 02", "cbor: map key 1 is not in sorted order at offset 4"},
+		{"a2 6162 01 6161 02", `cbor: map key "a" is not in sorted order at offset 4`},
+		{"a2 01 01 01 02", "cbor: duplicate map key 1 at offset 3"},
+		{"a1 01 1800", "cbor: argument 0 of unsigned integer is not in its shortest form at offset 2"},
+		{"fa3fc00000", "cbor: float 1.5 is not in its shortest form at offset 0"},
+		{"fb3ff8000000000000", "cbor: float 1.5 is not in its shortest form at offset 0"},
+		{"fb3f50624de0000000", "cbor: float 0.0010000000474974513 is not in its shortest form at offset 0"},
+		{"fa7fc00000", "cbor: float NaN is not in its shortest form at offset 0"},
+	}
+
+	for _, tst := range tests {
+		d := cbor.NewDecoder(decodeHex(t, tst.hex))
+		var err error
+		for d.More() && err == nil {
+			item, derr := d.Decode()
+			if derr != nil {
+				t.Fatalf("%s: %s", tst.hex, derr)
+			}
+			err = cbor.CheckDeterministic(item)
+		}
+		switch {
+		case tst.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %s", tst.hex, err)
+		case tst.err != "" && err == nil:
+			t.Errorf("%s: expected error `%s`", tst.hex, tst.err)
+		case err != nil && err.Error() != tst.err:
+			t.Errorf("%s: expected `%s` got `%s`", tst.hex, tst.err, err)
+		}
+		var derr *cbor.DeterminismError
+		if err != nil && !errors.As(err, &derr) {
+			t.Errorf("%s: expected a *cbor.DeterminismError got %T", tst.hex, err)
+		}
+	}
+}
+
+func TestEncodeDeterministic(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want string
+	}{
+		{"1800", "00"},
+		{"9f 01 5f4101ff ff", "82014101"},
+		{"a3 6161 01 0a 02 20 03", "a30a0220036161 01"},
+		{"bf 820100 01 0a 02 ff", "a20a02820100 01"},
+		{"fb3ff8000000000000", "f93e00"},
+		{"fb3ff199999999999a", "fb3ff199999999999a"},
+		{"fa47c35000", "fa47c35000"},
+		{"fb7ff8000000000000", "f97e00"},
+		{"c1 fb3ff8000000000000", "c1f93e00"},
+	}
+
+	for _, tst := range tests {
+		item, err := cbor.Decode(decodeHex(t, tst.hex))
+		if err != nil {
+			t.Fatalf("%s: %s", tst.hex, err)
+		}
+		encoded := cbor.EncodeDeterministic(item)
+		if want := hex.EncodeToString(decodeHex(t, tst.want)); hex.EncodeToString(encoded) != want {
+			t.Errorf("%s: expected %s got %x", tst.hex, want, encoded)
+		}
+		decoded, err := cbor.Decode(encoded)
+		if err != nil {
+			t.Fatalf("%s: %s", tst.hex, err)
+		}
+		if err := cbor.CheckDeterministic(decoded); err != nil {
+			t.Errorf("%s: expected a deterministic encoding got %s", tst.hex, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/HannesKimara/cddlc/runtime/half"
+)
+
+// Encode returns the encoding of the data item. Heads use the shortest form of their
+// argument unless the item records a longer one and floats are encoded with the width
+// recorded in the item, or 64 bits if it has none. Items marked indefinite are encoded with
+// an indefinite length. Indefinite strings without chunks are encoded as a single chunk.
+func Encode(item *Item) []byte {
+	return appendItem(nil, item)
+}
+
+func appendItem(buf []byte, item *Item) []byte {
+	switch item.Major {
+	case MajorUint, MajorNint, MajorTag:
+		buf = appendHead(buf, item.Major, item.Arg, item.ArgSize)
+		if item.Major == MajorTag && item.Content != nil {
+			buf = appendItem(buf, item.Content)
+		}
+	case MajorBytes, MajorText:
+		content := item.Bytes
+		if item.Major == MajorText {
+			content = []byte(item.Text)
+		}
+		if !item.Indefinite {
+			buf = appendHead(buf, item.Major, uint64(len(content)), item.ArgSize)
+			buf = append(buf, content...)
+			break
+		}
+		buf = append(buf, byte(item.Major)<<5|31)
+		if item.Chunks == nil {
+			buf = appendHead(buf, item.Major, uint64(len(content)), 0)
+			buf = append(buf, content...)
+		}
+		for _, chunk := range item.Chunks {
+			buf = appendItem(buf, chunk)
+		}
+		buf = append(buf, 0xff)
+	case MajorArray:
+		if item.Indefinite {
+			buf = append(buf, byte(MajorArray)<<5|31)
+		} else {
+			buf = appendHead(buf, MajorArray, uint64(len(item.Items)), item.ArgSize)
+		}
+		for _, elem := range item.Items {
+			buf = appendItem(buf, elem)
+		}
+		if item.Indefinite {
+			buf = append(buf, 0xff)
+		}
+	case MajorMap:
+		if item.Indefinite {
+			buf = append(buf, byte(MajorMap)<<5|31)
+		} else {
+			buf = appendHead(buf, MajorMap, uint64(len(item.Pairs)), item.ArgSize)
+		}
+		for _, pair := range item.Pairs {
+			buf = appendItem(buf, pair.Key)
+			buf = appendItem(buf, pair.Value)
+		}
+		if item.Indefinite {
+			buf = append(buf, 0xff)
+		}
+	case MajorSimple:
+		buf = appendSimple(buf, item)
+	}
+	return buf
+}
+
+// appendHead appends the initial byte and the argument in its shortest form or in size
+// bytes if size is set
+func appendHead(buf []byte, major Major, arg uint64, size int) []byte {
+	mt := byte(major) << 5
+	switch {
+	case size == 1:
+		return append(buf, mt|24, byte(arg))
+	case size == 2:
+		return binary.BigEndian.AppendUint16(append(buf, mt|25), uint16(arg))
+	case size == 4:
+		return binary.BigEndian.AppendUint32(append(buf, mt|26), uint32(arg))
+	case size == 8:
+		return binary.BigEndian.AppendUint64(append(buf, mt|27), arg)
+	case arg < 24:
+		return append(buf, mt|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(buf, mt|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, mt|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, mt|26), uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, mt|27), arg)
+}
+
+func appendSimple(buf []byte, item *Item) []byte {
+	mt := byte(MajorSimple) << 5
+	switch item.Width {
+	case 0:
+		if item.Arg < 24 {
+			return append(buf, mt|byte(item.Arg))
+		}
+		return append(buf, mt|24, byte(item.Arg))
+	case 16:
+		return binary.BigEndian.AppendUint16(append(buf, mt|25), half.FromFloat64(item.Float))
+	case 32:
+		return binary.BigEndian.AppendUint32(append(buf, mt|26), math.Float32bits(float32(item.Float)))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, mt|27), math.Float64bits(item.Float))
+}
@@ -0,0 +1,65 @@
+package cbor_test
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []string{
+		"00", "17", "1818", "1903e8", "1a000f4240", "1bffffffffffffffff",
+		"20", "3863", "3bffffffffffffffff",
+		"40", "4401020304", "60", "6449455446",
+		"80", "83010203", "8301820203820405", "a0", "a201020304",
+		"c11a514b67b0", "d8206161",
+		"f4", "f5", "f6", "f7", "f0", "f8ff",
+		"f90000", "f93c00", "f97bff", "f90001", "f9c400", "f97c00", "f97e00",
+		"fa47c35000", "fb3ff199999999999a",
+		"5f42010243030405ff", "9fff", "9f018202039f0405ffff", "bf61610161629f0203ffff",
+		"7f657374726561646d696e67ff",
+
+		// arguments longer than needed
+		"1800", "190001", "3a00000000", "580101", "9800", "b900010102", "d9000101",
+		"5f5801ff43010203ff",
+	}
+
+	for _, tst := range tests {
+		item, err := cbor.Decode(decodeHex(t, tst))
+		if err != nil {
+			t.Fatalf("%s: %s", tst, err)
+		}
+		encoded := cbor.Encode(item)
+		if hex.EncodeToString(encoded) != tst {
+			t.Errorf("%s: expected the same encoding got %x", tst, encoded)
+		}
+	}
+}
+
+func TestEncodeHalf(t *testing.T) {
+	tests := []struct {
+		value float64
+		hex   string
+	}{
+		{1, "f93c00"},
+		{-2.5, "f9c100"},
+		{65504, "f97bff"},
+		{65520, "f97c00"},
+		{math.Ldexp(1, -24), "f90001"},
+		{math.Ldexp(1, -26), "f90000"},
+		{math.Inf(-1), "f9fc00"},
+		// ties round to even
+		{1 + math.Ldexp(1, -11), "f93c00"},
+		{1 + 3*math.Ldexp(1, -11), "f93c02"},
+		{1 + math.Ldexp(1, -11) + math.Ldexp(1, -40), "f93c01"},
+	}
+
+	for _, tst := range tests {
+		encoded := cbor.Encode(&cbor.Item{Major: cbor.MajorSimple, Float: tst.value, Width: 16})
+		if hex.EncodeToString(encoded) != tst.hex {
+			t.Errorf("%v: expected %s got %x", tst.value, tst.hex, encoded)
+		}
+	}
+}
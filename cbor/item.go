@@ -0,0 +1,226 @@
+// Package cbor implements a decoder for the Concise Binary Object Representation
+// defined in RFC 8949.
+//
+// Decoded data items are represented by Item, a generic data model independent of Go
+// types that keeps the details validation depends on such as the encoded width of floats
+// and the order of map entries.
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Major is the major type of a data item
+type Major uint8
+
+const (
+	MajorUint   Major = 0
+	MajorNint   Major = 1
+	MajorBytes  Major = 2
+	MajorText   Major = 3
+	MajorArray  Major = 4
+	MajorMap    Major = 5
+	MajorTag    Major = 6
+	MajorSimple Major = 7
+)
+
+var majors = [...]string{
+	MajorUint:   "unsigned integer",
+	MajorNint:   "negative integer",
+	MajorBytes:  "byte string",
+	MajorText:   "text string",
+	MajorArray:  "array",
+	MajorMap:    "map",
+	MajorTag:    "tag",
+	MajorSimple: "simple value",
+}
+
+func (m Major) String() string {
+	if int(m) < len(majors) {
+		return majors[m]
+	}
+	return "major(" + strconv.Itoa(int(m)) + ")"
+}
+
+// simple values with a meaning assigned in RFC 8949
+const (
+	SimpleFalse     = 20
+	SimpleTrue      = 21
+	SimpleNull      = 22
+	SimpleUndefined = 23
+)
+
+// Item represents a decoded data item
+type Item struct {
+	// Major: the major type of the item
+	Major Major
+
+	// Arg: the argument of the item head. This is the value of unsigned integers, n for the
+	// negative integer -1-n, the tag number of tags and the value of simple values
+	Arg uint64
+
+	// Bytes: the content of byte strings
+	Bytes []byte
+
+	// Text: the content of text strings
+	Text string
+
+	// Items: the elements of arrays
+	Items []*Item
+
+	// Pairs: the entries of maps in encoded order
+	Pairs []*Pair
+
+	// Content: the tagged data item of tags
+	Content *Item
+
+	// Float: the value of floating point numbers
+	Float float64
+
+	// Width: the encoded size in bits of floating point numbers; one of 16, 32 or 64. Zero for other items
+	Width int
+
+	// Indefinite: true if the string, array or map was encoded with an indefinite length
+	Indefinite bool
+
+	// ArgSize: the size in bytes of the argument if it was encoded in a longer form than
+	// needed; one of 1, 2, 4 or 8. Zero for arguments in their shortest form
+	ArgSize int
+
+	// Chunks: the chunks of indefinite length strings. Bytes and Text hold their concatenation
+	Chunks []*Item
+
+	// Offset: the offset of the item head in the decoded input
+	Offset int
+}
+
+// Pair represents a single map entry
+type Pair struct {
+	Key, Value *Item
+}
+
+// IsInteger returns true for unsigned and negative integers
+func (i *Item) IsInteger() bool {
+	return i.Major == MajorUint || i.Major == MajorNint
+}
+
+// IsFloat returns true for floating point numbers
+func (i *Item) IsFloat() bool {
+	return i.Major == MajorSimple && i.Width != 0
+}
+
+// IsBool returns true for the simple values false and true
+func (i *Item) IsBool() bool {
+	return i.Major == MajorSimple && i.Width == 0 && (i.Arg == SimpleFalse || i.Arg == SimpleTrue)
+}
+
+// IsNull returns true for the simple value null
+func (i *Item) IsNull() bool {
+	return i.Major == MajorSimple && i.Width == 0 && i.Arg == SimpleNull
+}
+
+// Int returns the value of integers. It returns nil for other items.
+func (i *Item) Int() *big.Int {
+	switch i.Major {
+	case MajorUint:
+		return new(big.Int).SetUint64(i.Arg)
+	case MajorNint:
+		n := new(big.Int).SetUint64(i.Arg)
+		return n.Neg(n).Sub(n, big.NewInt(1))
+	}
+	return nil
+}
+
+// Kind returns a short description of the kind of item for messages
+func (i *Item) Kind() string {
+	switch {
+	case i.IsFloat():
+		return "float"
+	case i.IsBool():
+		return "boolean"
+	case i.IsNull():
+		return "null"
+	case i.Major == MajorSimple && i.Arg == SimpleUndefined:
+		return "undefined"
+	}
+	return i.Major.String()
+}
+
+// String formats the item in diagnostic notation
+func (i *Item) String() string {
+	var sb strings.Builder
+	i.format(&sb)
+	return sb.String()
+}
+
+func (i *Item) format(sb *strings.Builder) {
+	switch i.Major {
+	case MajorUint, MajorNint:
+		sb.WriteString(i.Int().String())
+	case MajorBytes:
+		sb.WriteString("h'" + hex.EncodeToString(i.Bytes) + "'")
+	case MajorText:
+		sb.WriteString(strconv.Quote(i.Text))
+	case MajorArray:
+		sb.WriteByte('[')
+		for n, item := range i.Items {
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+			item.format(sb)
+		}
+		sb.WriteByte(']')
+	case MajorMap:
+		sb.WriteByte('{')
+		for n, pair := range i.Pairs {
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+			pair.Key.format(sb)
+			sb.WriteString(": ")
+			pair.Value.format(sb)
+		}
+		sb.WriteByte('}')
+	case MajorTag:
+		sb.WriteString(strconv.FormatUint(i.Arg, 10) + "(")
+		if i.Content != nil {
+			i.Content.format(sb)
+		}
+		sb.WriteByte(')')
+	case MajorSimple:
+		sb.WriteString(i.formatSimple())
+	}
+}
+
+func (i *Item) formatSimple() string {
+	if i.IsFloat() {
+		switch {
+		case math.IsNaN(i.Float):
+			return "NaN"
+		case math.IsInf(i.Float, 1):
+			return "Infinity"
+		case math.IsInf(i.Float, -1):
+			return "-Infinity"
+		}
+		s := strconv.FormatFloat(i.Float, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	}
+	switch i.Arg {
+	case SimpleFalse:
+		return "false"
+	case SimpleTrue:
+		return "true"
+	case SimpleNull:
+		return "null"
+	case SimpleUndefined:
+		return "undefined"
+	}
+	return "simple(" + strconv.FormatUint(i.Arg, 10) + ")"
+}
@@ -0,0 +1,178 @@
+package cbor
+
+import (
+	"bufio"
+	"io"
+)
+
+// readChunk is the most read at once for the content of strings so that lengths exceeding
+// the input fail without allocating the length claimed
+const readChunk = 64 << 10
+
+// Reader reads the data items of a CBOR sequence, https://www.rfc-editor.org/rfc/rfc8742,
+// from an io.Reader one at a time. Only the encoding of the item being read is held in
+// memory, so sequences of any length are read in memory bounded by their largest item.
+type Reader struct {
+	r   *bufio.Reader
+	buf []byte
+
+	// off: the offset of the next data item in the sequence
+	off int
+
+	// maxSize: the largest encoded data item read or zero for no limit
+	maxSize int
+}
+
+// WithMaxItemSize limits the size of the encoding of the data items read. Larger items are
+// reported as a *SyntaxError. Defaults to no limit.
+func WithMaxItemSize(n int) func(*Reader) {
+	return func(r *Reader) {
+		r.maxSize = n
+	}
+}
+
+// NewReader returns a reader of the CBOR sequence read from r
+func NewReader(r io.Reader, opts ...func(*Reader)) *Reader {
+	reader := &Reader{r: bufio.NewReader(r)}
+	for _, opt := range opts {
+		opt(reader)
+	}
+	return reader
+}
+
+// Offset returns the offset of the next data item in the sequence
+func (r *Reader) Offset() int {
+	return r.off
+}
+
+// Next reads the next data item. It returns io.EOF at the end of the sequence. Malformed
+// and truncated data items are reported as a *SyntaxError holding the offset in the
+// sequence, after which the sequence cannot be read further.
+func (r *Reader) Next() (*Item, error) {
+	if _, err := r.r.Peek(1); err == io.EOF {
+		return nil, io.EOF
+	}
+
+	r.buf = r.buf[:0]
+	if err := r.scan(0); err != nil {
+		return nil, err
+	}
+	d := &Decoder{data: r.buf, base: r.off}
+	item, err := d.Decode()
+	if err != nil {
+		return nil, err
+	}
+	r.off += len(r.buf)
+	return item, nil
+}
+
+func (r *Reader) error(msg string) error {
+	return &SyntaxError{Offset: r.off + len(r.buf), Msg: msg}
+}
+
+// read appends the next n bytes of the input to the buffer
+func (r *Reader) read(n uint64) error {
+	for n > 0 {
+		if r.maxSize > 0 && uint64(r.maxSize-len(r.buf)) < n {
+			return &SyntaxError{Offset: r.off, Msg: "data item exceeds the maximum size"}
+		}
+		chunk := n
+		if chunk > readChunk {
+			chunk = readChunk
+		}
+		start := len(r.buf)
+		r.buf = append(r.buf, make([]byte, chunk)...)
+		if read, err := io.ReadFull(r.r, r.buf[start:]); err != nil {
+			r.buf = r.buf[:start+read]
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return r.error("unexpected end of input")
+			}
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// scan reads the encoding of a data item into the buffer. It follows the structure of the
+// item only as far as needed to find its end and leaves malformed items to the decoder.
+func (r *Reader) scan(depth int) error {
+	if depth > MaxDepth {
+		return r.error("maximum nesting depth exceeded")
+	}
+
+	if err := r.read(1); err != nil {
+		return err
+	}
+	initial := r.buf[len(r.buf)-1]
+	major, info := Major(initial>>5), initial&0x1f
+
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if err := r.read(uint64(n)); err != nil {
+			return err
+		}
+		for _, b := range r.buf[len(r.buf)-n:] {
+			arg = arg<<8 | uint64(b)
+		}
+	case info == 31:
+		return r.scanIndefinite(major, depth)
+	default:
+		// reserved additional information
+		return nil
+	}
+
+	switch major {
+	case MajorBytes, MajorText:
+		return r.read(arg)
+	case MajorArray:
+		return r.scanElements(arg, depth)
+	case MajorMap:
+		for i := uint64(0); i < arg; i++ {
+			if err := r.scanElements(2, depth); err != nil {
+				return err
+			}
+		}
+	case MajorTag:
+		return r.scan(depth + 1)
+	}
+	return nil
+}
+
+func (r *Reader) scanElements(n uint64, depth int) error {
+	for i := uint64(0); i < n; i++ {
+		if err := r.scan(depth + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanIndefinite reads the chunks or elements of an indefinite length item up to and
+// including the break stop code
+func (r *Reader) scanIndefinite(major Major, depth int) error {
+	switch major {
+	case MajorBytes, MajorText, MajorArray, MajorMap:
+	default:
+		return nil
+	}
+	for {
+		b, err := r.r.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return r.error("unexpected end of input")
+			}
+			return err
+		}
+		if b[0] == breakCode {
+			return r.read(1)
+		}
+		if err := r.scan(depth + 1); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package cbor_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		hex     string
+		items   []string
+		offsets []int
+	}{
+		{"", nil, nil},
+		{"01 6161 80", []string{"1", `"a"`, "[]"}, []int{0, 1, 3}},
+		{"9f 01 5f 4101 ff ff a1 6161 c1 00 f93c00", []string{`[1, h'01']`, `{"a": 1(0)}`, "1.0"}, []int{0, 7, 12}},
+		{"bf 01 02 ff 7f 6161 6162 ff", []string{"{1: 2}", `"ab"`}, []int{0, 4}},
+		{"1bffffffffffffffff 3b0000000000000000", []string{"18446744073709551615", "-1"}, []int{0, 9}},
+	}
+
+	for _, tst := range tests {
+		// a reader returning one byte at a time reads items split across reads
+		r := cbor.NewReader(iotest.OneByteReader(bytes.NewReader(decodeHex(t, tst.hex))))
+		var items []string
+		var offsets []int
+		for {
+			offset := r.Offset()
+			item, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: unexpected error %s", tst.hex, err)
+			}
+			items, offsets = append(items, item.String()), append(offsets, offset)
+			if item.Offset != offset {
+				t.Errorf("%s: expected item offset %d got %d", tst.hex, offset, item.Offset)
+			}
+		}
+		if len(items) != len(tst.items) {
+			t.Errorf("%s: expected items %v got %v", tst.hex, tst.items, items)
+			continue
+		}
+		for i := range items {
+			if items[i] != tst.items[i] || offsets[i] != tst.offsets[i] {
+				t.Errorf("%s: expected %s at %d got %s at %d", tst.hex, tst.items[i], tst.offsets[i], items[i], offsets[i])
+			}
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		hex   string
+		items int
+		err   string
+	}{
+		{"01 19 01", 1, "cbor: unexpected end of input at offset 3"},
+		{"01 43 0102", 1, "cbor: unexpected end of input at offset 4"},
+		{"01 82 01", 1, "cbor: unexpected end of input at offset 3"},
+		{"01 9f 01", 1, "cbor: unexpected end of input at offset 3"},
+		{"01 62 c328", 1, "cbor: invalid UTF-8 in text string at offset 1"},
+		{"80 1c", 1, "cbor: reserved additional information 28 at offset 1"},
+		{"01 02 ff", 2, "cbor: unexpected break stop code at offset 2"},
+		{"01 5f 6161 ff", 1, "cbor: invalid chunk in indefinite length byte string at offset 2"},
+		{"01 5b ffffffffffffffff 00", 1, "cbor: unexpected end of input at offset 11"},
+	}
+
+	for _, tst := range tests {
+		r := cbor.NewReader(bytes.NewReader(decodeHex(t, tst.hex)))
+		items := 0
+		var err error
+		for {
+			if _, err = r.Next(); err != nil {
+				break
+			}
+			items++
+		}
+		var syntax *cbor.SyntaxError
+		if !errors.As(err, &syntax) {
+			t.Errorf("%s: expected a syntax error got %v", tst.hex, err)
+			continue
+		}
+		if items != tst.items || err.Error() != tst.err {
+			t.Errorf("%s: expected `%s` after %d items got `%s` after %d", tst.hex, tst.err, tst.items, err, items)
+		}
+	}
+}
+
+func TestReaderMaxItemSize(t *testing.T) {
+	r := cbor.NewReader(bytes.NewReader(decodeHex(t, "43 010203 44 01020304")), cbor.WithMaxItemSize(4))
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	_, err := r.Next()
+	if err == nil || err.Error() != "cbor: data item exceeds the maximum size at offset 4" {
+		t.Errorf("expected an error for an item above the maximum size got %v", err)
+	}
+}
+
+func TestReaderLargeItem(t *testing.T) {
+	content := bytes.Repeat([]byte{0xab}, 200<<10)
+	data := append([]byte{0x5a, 0x00, 0x03, 0x20, 0x00}, content...)
+	data = append(data, 0x01)
+
+	r := cbor.NewReader(bytes.NewReader(data))
+	item, err := r.Next()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !bytes.Equal(item.Bytes, content) {
+		t.Errorf("expected %d bytes got %d", len(content), len(item.Bytes))
+	}
+	if item, err = r.Next(); err != nil || item.Arg != 1 || r.Offset() != len(data) {
+		t.Errorf("expected 1 at the end of the sequence got %v, %v", item, err)
+	}
+}
@@ -0,0 +1,370 @@
+// Package checker implements semantic analysis of a parsed CDDL tree.
+//
+// The checker infers whether each rule denotes a type or a group and reports
+// constructs that use one where the other is expected.
+package checker
+
+import (
+	"fmt"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+type Checker struct {
+	// rules maps the rule names to their definitions. Sockets may be defined more than once
+	rules map[string][]*ast.Rule
+
+	// names holds the rule names in order of first definition
+	names []string
+
+	// kinds caches the inferred kind of each rule
+	kinds map[string]Kind
+
+	// visiting marks the rules whose kind is being inferred to break reference cycles
+	visiting map[string]bool
+
+	// errors contains the diagnostics in order of discovery
+	errors parser.ErrorList
+}
+
+// NewChecker returns a Checker for the rules of the provided tree
+func NewChecker(cddl *ast.CDDL) *Checker {
+	c := &Checker{
+		rules:    make(map[string][]*ast.Rule),
+		kinds:    make(map[string]Kind),
+		visiting: make(map[string]bool),
+	}
+
+	if cddl == nil {
+		return c
+	}
+	for _, entry := range cddl.Rules {
+		rule, ok := entry.(*ast.Rule)
+		if !ok || rule.Name == nil || rule.Value == nil {
+			continue
+		}
+		if _, ok := c.rules[rule.Name.Name]; !ok {
+			c.names = append(c.names, rule.Name.Name)
+		}
+		c.rules[rule.Name.Name] = append(c.rules[rule.Name.Name], rule)
+	}
+	return c
+}
+
+// Check infers the kind of every rule and returns the misuses found
+func (c *Checker) Check() parser.ErrorList {
+	for _, name := range c.names {
+		c.KindOf(name)
+	}
+	for _, name := range c.names {
+		for _, rule := range c.rules[name] {
+			c.checkRule(rule)
+		}
+	}
+	return c.errors.Collect()
+}
+
+// Kinds returns the inferred kind of every rule by name
+func (c *Checker) Kinds() map[string]Kind {
+	out := make(map[string]Kind, len(c.names))
+	for _, name := range c.names {
+		out[name] = c.KindOf(name)
+	}
+	return out
+}
+
+// KindOf returns the inferred kind of the named rule. Undefined sockets are types
+// when prefixed with `$` and groups when prefixed with `$$`.
+func (c *Checker) KindOf(name string) Kind {
+	if kind, ok := c.kinds[name]; ok {
+		return kind
+	}
+	defs, ok := c.rules[name]
+	if !ok {
+		ident := &ast.Identifier{Name: name}
+		switch {
+		case ident.IsPlug():
+			return Group
+		case ident.IsSocket():
+			return Type
+		}
+		return Unknown
+	}
+	if c.visiting[name] {
+		return Unknown
+	}
+
+	c.visiting[name] = true
+	kind := Unknown
+	for i, rule := range defs {
+		ruleKind := c.ruleKind(rule)
+		joined := join(kind, ruleKind)
+		if i > 0 && joined == Unknown && kind != Unknown {
+			c.error(fmt.Sprintf("%s is defined as a %s here but as a %s at line %d, column %d", name, ruleKind, kind, defs[0].Start().Line, defs[0].Start().Column), rule.Name.Start(), rule.Name.End())
+		}
+		kind = joined
+	}
+	delete(c.visiting, name)
+
+	c.kinds[name] = kind
+	return kind
+}
+
+func (c *Checker) ruleKind(rule *ast.Rule) Kind {
+	switch rule.Token {
+	case token.TYPE_CHOICE_ASSIGN:
+		return Type
+	case token.GROUP_CHOICE_ASSIGN:
+		return Group
+	}
+	return c.kindOf(rule.Value)
+}
+
+// kindOf returns the kind denoted by a node
+func (c *Checker) kindOf(node ast.Node) Kind {
+	switch val := node.(type) {
+	case nil:
+		return Unknown
+	case *ast.Identifier:
+		return c.KindOf(val.Name)
+	case *ast.Generic:
+		return c.KindOf(val.Name.Name)
+	case *ast.Entry, *ast.Optional, *ast.NMOccurrence, *ast.GroupChoice, *ast.Unwrap:
+		return Group
+	case *ast.Group:
+		if len(val.Entries) != 1 {
+			return Group
+		}
+		switch c.kindOf(val.Entries[0]) {
+		case Type, Ambiguous:
+			return Ambiguous
+		case Group:
+			return Group
+		}
+		return Unknown
+	case *ast.BadNode, *ast.Comment, *ast.CommentGroup:
+		return Unknown
+	}
+	return Type
+}
+
+func (c *Checker) checkRule(rule *ast.Rule) {
+	if rule.Token == token.TYPE_CHOICE_ASSIGN && c.kindOf(rule.Value) == Group {
+		c.error(fmt.Sprintf("operator %s can only be applied to a type, found %s", rule.Token, c.describeRef(rule.Value)), rule.Value.Start(), rule.Value.End())
+	}
+	c.check(rule.Value)
+}
+
+// check recursively reports nodes used in a context that does not accept their kind
+func (c *Checker) check(node ast.Node) {
+	switch val := node.(type) {
+	case *ast.Entry:
+		c.expectType(val.Value, fmt.Sprintf("as the value of member %s", val.Name.Name))
+		c.check(val.Value)
+	case *ast.TypeChoice:
+		c.expectType(val.First, "in type choice")
+		c.expectType(val.Second, "in type choice")
+		c.check(val.First)
+		c.check(val.Second)
+	case *ast.GroupChoice:
+		c.check(val.First)
+		c.check(val.Second)
+	case *ast.Group:
+		for _, entry := range val.Entries {
+			c.check(entry)
+		}
+	case *ast.Array:
+		for _, entry := range val.Rules {
+			c.check(entry)
+		}
+	case *ast.Map:
+		for _, entry := range val.Rules {
+			c.check(entry)
+		}
+	case *ast.Optional:
+		c.check(val.Item)
+	case *ast.NMOccurrence:
+		c.check(val.Item)
+	case *ast.Unwrap:
+		switch c.resolve(val.Item).(type) {
+		case nil, *ast.Map, *ast.Array:
+		default:
+			c.error(fmt.Sprintf("operator %s can only be applied to a map or an array, found %s", val.Token, c.describeRef(val.Item)), val.Start(), val.End())
+		}
+	case *ast.Enumeration:
+		if c.kindOf(val.Value) == Type {
+			c.error(fmt.Sprintf("operator %s can only be applied to a group, found %s", val.Token, c.describeRef(val.Value)), val.Start(), val.End())
+		}
+		c.check(val.Value)
+	case *ast.Tag:
+		if val.Item != nil {
+			c.expectType(val.Item, "as tagged data item")
+			c.check(val.Item)
+		}
+	case *ast.Range:
+		c.expectType(val.From, fmt.Sprintf("as lower bound of operator %s", val.Token))
+		c.expectType(val.To, fmt.Sprintf("as upper bound of operator %s", val.Token))
+	case *ast.SizeOperatorControl:
+		if !c.sizeable(val.Type) {
+			c.error(fmt.Sprintf("operator %s cannot be applied to %s", val.Token, c.describeRef(val.Type)), val.Type.Start(), val.Type.End())
+		}
+		c.check(val.Size)
+	case *ast.Generic:
+		for _, arg := range val.Args {
+			c.expectType(arg, fmt.Sprintf("as argument to generic %s", val.Name.Name))
+			c.check(arg)
+		}
+	case *ast.Bits:
+		c.check(val.Base)
+		c.check(val.Contstraint)
+	case *ast.DefaultControl:
+		c.check(val.Base)
+		c.expectType(val.Default, fmt.Sprintf("as value of operator %s", val.Token))
+	case *ast.FeatureControl:
+		c.check(val.Base)
+		c.expectType(val.Feature, fmt.Sprintf("as feature of operator %s", val.Token))
+	case *ast.ComparatorOpControl:
+		c.check(val.Right)
+	case *ast.ComputedOpControl:
+		c.expectType(val.Left, fmt.Sprintf("as operand of operator %s", val.Token))
+		c.expectType(val.Right, fmt.Sprintf("as operand of operator %s", val.Token))
+	}
+}
+
+// expectType reports the node if it denotes a group
+func (c *Checker) expectType(node ast.Node, context string) {
+	if node == nil || c.kindOf(node) != Group {
+		return
+	}
+	c.error(fmt.Sprintf("group %s used %s", describe(node), context), node.Start(), node.End())
+}
+
+// sizeable returns true if the .size control can be applied to the node. Nodes
+// that cannot be resolved are assumed valid since they are reported by the parser.
+func (c *Checker) sizeable(node ast.Node) bool {
+	switch val := c.resolve(node).(type) {
+	case nil, *ast.TstrType, *ast.BstrType, *ast.BytesType, *ast.UintType:
+		return true
+	case *ast.TypeChoice:
+		return c.sizeable(val.First) && c.sizeable(val.Second)
+	}
+	return false
+}
+
+// resolve follows identifiers and control operators to the node defining the base type.
+// It returns nil if the node cannot be resolved to a single definition.
+func (c *Checker) resolve(node ast.Node) ast.Node {
+	seen := map[string]bool{}
+	for {
+		switch val := node.(type) {
+		case *ast.Identifier:
+			defs := c.rules[val.Name]
+			if len(defs) != 1 || seen[val.Name] {
+				return nil
+			}
+			seen[val.Name] = true
+			node = defs[0].Value
+		case *ast.Generic:
+			node = val.Name
+		case *ast.SizeOperatorControl:
+			node = val.Type
+		case *ast.Regexp:
+			node = val.Base
+		case *ast.ABNF:
+			node = val.Base
+		case *ast.CBORControl:
+			node = val.Base
+		case *ast.DefaultControl:
+			node = val.Base
+		case *ast.FeatureControl:
+			node = val.Base
+		case *ast.Bits:
+			node = val.Base
+		case *ast.ComparatorOpControl:
+			node = val.Left
+		case *ast.Group:
+			if len(val.Entries) != 1 || c.kindOf(val) != Ambiguous {
+				return val
+			}
+			node = val.Entries[0]
+		default:
+			return val
+		}
+	}
+}
+
+func (c *Checker) error(msg string, start, end token.Position) {
+	err := parser.NewError(msg, start, end)
+	err.Prefix = "checker"
+	c.errors = append(c.errors, err)
+}
+
+// describeRef describes the node an identifier resolves to followed by the identifier name
+func (c *Checker) describeRef(node ast.Node) string {
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		return describe(node)
+	}
+	base := c.resolve(ident)
+	if base == nil {
+		return describe(ident)
+	}
+	return describe(base) + " " + describe(ident)
+}
+
+// describe returns a short human readable name for the node used in diagnostics
+func describe(node ast.Node) string {
+	switch val := node.(type) {
+	case *ast.Identifier:
+		return "`" + val.Name + "`"
+	case *ast.Generic:
+		return describe(val.Name)
+	case *ast.Map:
+		return "map"
+	case *ast.Array:
+		return "array"
+	case *ast.Group:
+		return "group"
+	case *ast.Entry:
+		return "member " + val.Name.Name
+	case *ast.Optional, *ast.NMOccurrence:
+		return "group entry"
+	case *ast.GroupChoice:
+		return "group choice"
+	case *ast.Unwrap:
+		return "unwrapped group"
+	case *ast.TypeChoice:
+		return "type choice"
+	case *ast.Enumeration:
+		return "enumeration"
+	case *ast.Tag:
+		return "tag"
+	case *ast.Range:
+		return "range"
+	case *ast.TstrType:
+		return "text string"
+	case *ast.BstrType, *ast.BytesType:
+		return "byte string"
+	case *ast.UintType:
+		return "unsigned integer"
+	case *ast.IntegerType:
+		return "integer"
+	case *ast.NegativeIntegerType:
+		return "negative integer"
+	case *ast.FloatType:
+		return "float"
+	case *ast.BooleanType:
+		return "boolean"
+	case *ast.NullType:
+		return "null"
+	case *ast.AnyType:
+		return "any"
+	case *ast.IntegerLiteral, *ast.UintLiteral, *ast.FloatLiteral, *ast.TextLiteral, *ast.BytesLiteral, *ast.BooleanLiteral:
+		return "literal"
+	case *ast.ComputedOpControl:
+		return "computed literal"
+	}
+	return fmt.Sprintf("%T", node)
+}
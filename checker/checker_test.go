@@ -0,0 +1,98 @@
+package checker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/checker"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+)
+
+func parse(t *testing.T, src string) *ast.CDDL {
+	t.Helper()
+	l := lexer.NewLexer([]byte(src))
+	p := parser.NewParser(l)
+
+	cddl, errs := p.ParseFile()
+	if len(errs) != 0 {
+		t.Fatalf("%s: -> %s", src, errs)
+	}
+	return cddl
+}
+
+func TestKinds(t *testing.T) {
+	tests := []struct {
+		src  string
+		name string
+		kind checker.Kind
+	}{
+		{"a = tstr", "a", checker.Type},
+		{"a = {name: tstr}", "a", checker.Type},
+		{"a = [* uint]", "a", checker.Type},
+		{"a = 1..10", "a", checker.Type},
+		{"a = #6.32(tstr)", "a", checker.Type},
+		{"a = (name: tstr, age: uint)", "a", checker.Group},
+		{"a = (tstr)", "a", checker.Ambiguous},
+		{"a = ()", "a", checker.Group},
+		{"a = (name: tstr // age: uint)", "a", checker.Group},
+		{"a = b b = (c) c = uint", "a", checker.Ambiguous},
+		{"a = b b = (x: uint)", "a", checker.Group},
+		{"a = b b = a", "a", checker.Unknown},
+		{"a = [a]", "a", checker.Type},
+		{"$$ext //= (x: uint)", "$$ext", checker.Group},
+		{"$ext /= tstr", "$ext", checker.Type},
+		{"a = {* $$ext}", "$$ext", checker.Group},
+	}
+
+	for _, tst := range tests {
+		cddl := parse(t, tst.src)
+		c := checker.NewChecker(cddl)
+		c.Check()
+
+		if kind := c.KindOf(tst.name); kind != tst.kind {
+			t.Errorf("%s: expected %s to be %s got %s", tst.src, tst.name, tst.kind, kind)
+		}
+	}
+}
+
+func TestCheckMisuse(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"a = tstr / uint", nil},
+		{"a = b / tstr b = (x: uint)", []string{"group `b` used in type choice"}},
+		{"a = {x: b} b = (y: uint)", []string{"group `b` used as the value of member x"}},
+		{"a = [~b] b = [uint]", nil},
+		{"a = {~b} b = tstr", []string{"operator ~ can only be applied to a map or an array, found text string `b`"}},
+		{"a = {~b} b = (x: uint)", []string{"operator ~ can only be applied to a map or an array, found group `b`"}},
+		{"a = b .size 4 b = tstr", nil},
+		{"a = b .size 4 b = {x: uint}", []string{"operator .size cannot be applied to map `b`"}},
+		{"a = &b b = (x: 1, y: 2)", nil},
+		{"a = &b b = uint", []string{"operator & can only be applied to a group, found unsigned integer `b`"}},
+		{"$a /= (x: uint)", []string{"operator /= can only be applied to a type, found group"}},
+		{"$$a //= (x: uint) $$a //= tstr", nil},
+		{"a = (x: uint) a //= (y: uint)", nil},
+		{"a = tstr a //= (y: uint)", []string{"a is defined as a group here but as a type at line 1, column 1"}},
+	}
+
+	for _, tst := range tests {
+		cddl := parse(t, tst.src)
+		errs := checker.NewChecker(cddl).Check()
+
+		if len(errs) != len(tst.errs) {
+			t.Errorf("%s: expected %d errors got %d: %s", tst.src, len(tst.errs), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if !strings.HasPrefix(err.Error(), "checker error: ") {
+				t.Errorf("%s: expected checker error got %s", tst.src, err)
+			}
+			if msg := strings.TrimPrefix(err.Error(), "checker error: "); msg != tst.errs[i] {
+				t.Errorf("%s: expected error `%s` got `%s`", tst.src, tst.errs[i], msg)
+			}
+		}
+	}
+}
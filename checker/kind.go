@@ -0,0 +1,62 @@
+package checker
+
+// Kind describes what a rule denotes. A rule may define a type, a group or, when
+// written as a single parenthesized type such as `a = (tstr)`, either of the two.
+//
+// See https://www.rfc-editor.org/rfc/rfc8610#section-2.1
+type Kind int
+
+const (
+	// Unknown is the kind of rules that could not be inferred e.g. undefined or cyclic references
+	Unknown Kind = iota
+
+	// Type is the kind of rules that define a set of data item values
+	Type
+
+	// Group is the kind of rules that define a sequence of name/value pairs
+	Group
+
+	// Ambiguous is the kind of rules that are valid both as a type and a group
+	Ambiguous
+)
+
+var kinds = [...]string{
+	Unknown:   "unknown",
+	Type:      "type",
+	Group:     "group",
+	Ambiguous: "ambiguous",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return kinds[Unknown]
+}
+
+// IsType returns true if the kind can be used where a type is expected
+func (k Kind) IsType() bool {
+	return k == Type || k == Ambiguous
+}
+
+// IsGroup returns true if the kind can be used where a group is expected
+func (k Kind) IsGroup() bool {
+	return k == Group || k == Ambiguous
+}
+
+// join returns the kind of a rule extended with multiple definitions
+func join(a, b Kind) Kind {
+	switch {
+	case a == Unknown:
+		return b
+	case b == Unknown:
+		return a
+	case a == b:
+		return a
+	case a == Ambiguous:
+		return b
+	case b == Ambiguous:
+		return a
+	}
+	return Unknown
+}
@@ -9,8 +9,11 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/HannesKimara/cddlc/checker"
+	"github.com/HannesKimara/cddlc/evaluator"
 	"github.com/HannesKimara/cddlc/lexer"
 	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/resolver"
 	"github.com/HannesKimara/cddlc/token"
 	"github.com/urfave/cli/v2"
 )
@@ -74,9 +77,16 @@ func ParseCmd(cCtx *cli.Context) error {
 		return err
 	}
 	lex := lexer.NewLexer(src)
-	prs := parser.NewParser(lex)
+	prs := parser.NewParser(lex, parser.WithFilename(cCtx.Args().First()))
 
 	ast, errs := prs.ParseFile()
+	if len(errs) == 0 {
+		errs = checker.NewChecker(ast).Check()
+		_, resolveErrs := resolver.Resolve(ast)
+		errs = append(errs, resolveErrs...)
+		_, evalErrs := evaluator.NewEvaluator(ast).Evaluate()
+		errs = append(errs, evalErrs...)
+	}
 
 	if len(errs) > 0 {
 		outs := errorStringer(src, errs)
@@ -0,0 +1,186 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/cmd/cddlc/commands"
+	"github.com/HannesKimara/cddlc/validator"
+	"github.com/urfave/cli/v2"
+)
+
+// app returns the commands under test with the flags they are registered with in main
+func app() *cli.App {
+	return &cli.App{
+		Name: "cddl",
+		Commands: []*cli.Command{
+			{
+				Name:   "validate",
+				Action: commands.ValidateCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "rule"},
+					&cli.StringFlag{Name: "format", Value: "cbor"},
+					&cli.BoolFlag{Name: "deterministic"},
+					&cli.StringSliceFlag{Name: "feature"},
+					&cli.IntFlag{Name: "max-item-size", Value: validator.DefaultMaxItemSize},
+				},
+			},
+			{
+				Name:   "diff",
+				Action: commands.DiffCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "require", Value: "any"},
+				},
+			},
+			{
+				Name:   "example",
+				Action: commands.ExampleCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "rule"},
+					&cli.IntFlag{Name: "count", Value: 1},
+					&cli.StringFlag{Name: "format", Value: "diag"},
+					&cli.Int64Flag{Name: "seed"},
+					&cli.IntFlag{Name: "depth", Value: 8},
+					&cli.BoolFlag{Name: "invalid"},
+				},
+			},
+		},
+	}
+}
+
+// run runs the command line and returns what it printed to stdout
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, null
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	err = app().Run(append([]string{"cddl"}, args...))
+	b, rerr := os.ReadFile(out.Name())
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	return string(b), err
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidatePrelude(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeFile(t, dir, "schema.cddl", "event = [ts: tdate, link: uri, amount: bigint]\n")
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{`[0("2013-03-21T20:04:00Z"), 32("https://a.org"), 2(h'0100')]`, ""},
+		// the content of the tags is checked by the prelude tag checkers
+		{`[0("2013-03-21"), 32("https://a.org"), 2(h'0100')]`, "is not a valid event"},
+		{`[0("2013-03-21T20:04:00Z"), 32("/path"), 2(h'0100')]`, "is not a valid event"},
+	}
+
+	for _, tst := range tests {
+		data := writeFile(t, dir, "data.diag", tst.data)
+		out, err := run(t, "validate", "--format", "diag", schema, data)
+		switch {
+		case tst.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %s", tst.data, err)
+		case tst.err == "" && !strings.Contains(out, "is a valid event"):
+			t.Errorf("%s: unexpected output %q", tst.data, out)
+		case tst.err != "" && (err == nil || !strings.HasSuffix(err.Error(), tst.err)):
+			t.Errorf("%s: expected error `%s` got %v", tst.data, tst.err, err)
+		}
+	}
+}
+
+func TestExamplePrelude(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeFile(t, dir, "schema.cddl", "ts = tdate\n")
+
+	out, err := run(t, "example", "--count", "3", "--seed", "1", schema)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 instances got %q", out)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, `0("`) {
+			t.Errorf("expected a tag 0 instance got %s", line)
+		}
+		data := writeFile(t, dir, "data.diag", line)
+		if _, err := run(t, "validate", "--format", "diag", schema, data); err != nil {
+			t.Errorf("%s: expected the instance to be valid got %s", line, err)
+		}
+	}
+}
+
+func TestValidateMaxItemSize(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeFile(t, dir, "schema.cddl", "a = uint / bstr\n")
+	data := writeFile(t, dir, "data.cborseq", "\x01\x43\x01\x02\x03\x44\x01\x02\x03\x04")
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--format", "cborseq"}, ""},
+		{[]string{"--format", "cborseq", "--max-item-size", "4"}, "item 2 at offset 5: cbor: data item exceeds the maximum size at offset 5"},
+		{[]string{"--format", "cborseq", "--max-item-size", "0"}, "--max-item-size must be a positive number of bytes"},
+		{[]string{"--format", "cbor", "--max-item-size", "4"}, "--max-item-size only applies to the cborseq format"},
+	}
+
+	for _, tst := range tests {
+		args := append(append([]string{"validate"}, tst.args...), schema, data)
+		_, err := run(t, args...)
+		if (err == nil && tst.err != "") || (err != nil && err.Error() != tst.err) {
+			t.Errorf("%v: expected error `%s` got `%v`", tst.args, tst.err, err)
+		}
+	}
+}
+
+func TestDiffSummary(t *testing.T) {
+	dir := t.TempDir()
+	old := writeFile(t, dir, "old.cddl", "a = {x: uint}\n")
+
+	tests := []struct {
+		new     string
+		summary string
+	}{
+		{"a = {x: uint, ? y: tstr}\n", "\n1 change, "},
+		{"a = {x: uint, ? y: tstr, ? z: bool}\n", "\n2 changes, "},
+		{"a = {x: uint}\n", "no changes\n"},
+	}
+
+	for _, tst := range tests {
+		new := writeFile(t, dir, "new.cddl", tst.new)
+		out, err := run(t, "diff", old, new)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", tst.new, err)
+		}
+		if !strings.Contains(out, tst.summary) {
+			t.Errorf("%s: expected %q in the output got %q", tst.new, tst.summary, out)
+		}
+	}
+}
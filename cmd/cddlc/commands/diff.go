@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/compat"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/urfave/cli/v2"
+)
+
+// requirements maps the values of the --require flag to the compatibility they demand
+var requirements = map[string]compat.Compatibility{
+	"any":      compat.Breaking,
+	"backward": compat.Backward,
+	"forward":  compat.Forward,
+	"full":     compat.Compatible,
+}
+
+// DiffCmd reports the changes between two versions of a schema. It fails if the changes
+// taken together are breaking or do not meet the compatibility set with --require.
+func DiffCmd(cCtx *cli.Context) error {
+	if !checkArgs(cCtx, 2) {
+		return errors.New("expected two arguments: old.cddl new.cddl")
+	}
+	required, ok := requirements[cCtx.String("require")]
+	if !ok {
+		return fmt.Errorf("unknown compatibility %s, expected one of any, backward, forward or full", cCtx.String("require"))
+	}
+
+	old, err := parseSchema(cCtx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	new, err := parseSchema(cCtx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	changes := compat.Diff(old, new)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+		return nil
+	}
+
+	summary := compat.Summary(changes)
+	noun := "changes"
+	if len(changes) == 1 {
+		noun = "change"
+	}
+	fmt.Printf("\n%d %s, %s\n", len(changes), noun, summary)
+	switch {
+	case summary == compat.Breaking:
+		return errors.New("schema has breaking changes")
+	case summary&required != required:
+		return fmt.Errorf("schema changes are not %s", required)
+	}
+	return nil
+}
+
+func parseSchema(path string) (*ast.CDDL, error) {
+	src, err := readSource(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(lexer.NewLexer(src), parser.WithFilename(path))
+	cddl, errs := p.ParseFile()
+	if errs.HasErrors() {
+		printErrors(src, errs)
+		return nil, fmt.Errorf("could not parse %s", path)
+	}
+	return cddl, nil
+}
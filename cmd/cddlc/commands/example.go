@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/edn"
+	"github.com/HannesKimara/cddlc/resolver"
+	"github.com/HannesKimara/cddlc/validator"
+	"github.com/urfave/cli/v2"
+)
+
+// ExampleCmd prints random instances of a rule of a schema. The first rule of the schema is
+// used unless --rule is set. Instances are written one per line in diagnostic notation or
+// JSON, or as a CBOR sequence. With --invalid, the instances derived from each valid
+// instance by breaking one constraint are written instead, labelled with the constraint.
+func ExampleCmd(cCtx *cli.Context) error {
+	if !checkArgs(cCtx, 1) {
+		return errors.New("expected one argument: schema.cddl")
+	}
+	format := cCtx.String("format")
+	if format != "diag" && format != "cbor" && format != "json" {
+		return fmt.Errorf("unknown format %s, expected one of diag, cbor or json", format)
+	}
+	if cCtx.Bool("invalid") && format == "cbor" {
+		return errors.New("invalid instances are labelled and need the diag or json format")
+	}
+
+	cddl, err := parseSchema(cCtx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	rule := cCtx.String("rule")
+	if rule == "" {
+		rule = resolver.NewGraph(cddl).Root()
+		if rule == "" {
+			return fmt.Errorf("%s defines no rules", cCtx.Args().Get(0))
+		}
+	}
+
+	opts := []func(*validator.Generator){validator.WithDepthLimit(cCtx.Int("depth"))}
+	if cCtx.IsSet("seed") {
+		opts = append(opts, validator.WithSeed(cCtx.Int64("seed")))
+	}
+	if format == "json" {
+		opts = append(opts, validator.WithJSON())
+	}
+	g := validator.NewGenerator(cddl, opts...)
+
+	for n := 0; n < cCtx.Int("count"); n++ {
+		if cCtx.Bool("invalid") {
+			if err := writeViolations(g, rule, format); err != nil {
+				return err
+			}
+			continue
+		}
+		item, err := g.Generate(rule)
+		if err != nil {
+			return err
+		}
+		if err := writeInstance(item, format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeInstance(item *cbor.Item, format string) error {
+	switch format {
+	case "cbor":
+		_, err := os.Stdout.Write(cbor.Encode(item))
+		return err
+	case "json":
+		data, err := validator.EncodeJSON(item)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		fmt.Println(edn.Print(item))
+	}
+	return nil
+}
+
+// writeViolations writes the invalid instances derived from a valid instance. In diagnostic
+// notation the label precedes the instance as a `#` comment line and in JSON both are
+// members of an object.
+func writeViolations(g *validator.Generator, rule, format string) error {
+	_, violations, err := g.GenerateInvalid(rule)
+	if err != nil {
+		return err
+	}
+	for _, v := range violations {
+		if format == "diag" {
+			fmt.Printf("# %s\n%s\n", v, edn.Print(v.Instance))
+			continue
+		}
+		instance, err := validator.EncodeJSON(v.Instance)
+		if err != nil {
+			return err
+		}
+		label, _ := json.Marshal(map[string]string{
+			"kind":       v.Kind.String(),
+			"path":       v.Path,
+			"rule":       v.Rule,
+			"constraint": v.Constraint,
+		})
+		fmt.Printf("{\"violation\":%s,\"instance\":%s}\n", label, instance)
+	}
+	return nil
+}
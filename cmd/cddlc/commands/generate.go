@@ -10,9 +10,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/HannesKimara/cddlc/checker"
 	"github.com/HannesKimara/cddlc/config"
+	"github.com/HannesKimara/cddlc/evaluator"
 	"github.com/HannesKimara/cddlc/lexer"
 	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/resolver"
 	gogen "github.com/HannesKimara/cddlc/transforms/codegen/golang"
 
 	"github.com/urfave/cli/v2"
@@ -124,18 +127,26 @@ func generateFile(cCtx *cli.Context, pkgName, filepath, outPath string) error {
 	defer out.Close()
 
 	lex := lexer.NewLexer(src)
-	p := parser.NewParser(lex)
+	p := parser.NewParser(lex, parser.WithFilename(filepath))
 	cddl, errs := p.ParseFile()
 
 	if len(errs) > 0 {
-		outs := errorStringer(src, errs)
-		fmt.Fprintln(os.Stderr)
-		for _, out := range outs {
-			fmt.Fprintln(os.Stderr, out)
-		}
+		printErrors(src, errs)
 		return errors.New("parser failed with errors above")
 	}
 
+	errs = checker.NewChecker(cddl).Check()
+	_, resolveErrs := resolver.Resolve(cddl)
+	errs = append(errs, resolveErrs...)
+	_, evalErrs := evaluator.NewEvaluator(cddl).Evaluate()
+	errs = append(errs, evalErrs...)
+	if len(errs) > 0 {
+		printErrors(src, errs)
+	}
+	if errs.HasErrors() {
+		return errors.New("semantic analysis failed with errors above")
+	}
+
 	gen.Visit(cddl)
 
 	err = addBuildHeader(out)
@@ -156,6 +167,14 @@ func addBuildHeader(f io.Writer) error {
 	return nil
 }
 
+func printErrors(src []byte, errs parser.ErrorList) {
+	outs := errorStringer(src, errs)
+	fmt.Fprintln(os.Stderr)
+	for _, out := range outs {
+		fmt.Fprintln(os.Stderr, out)
+	}
+}
+
 func errorStringer(src []byte, errs parser.ErrorList) []string {
 	lines := bytes.Split(src, []byte{'\n'})
 	lCount := len(lines)
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/HannesKimara/cddlc/config"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/lint"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/urfave/cli/v2"
+)
+
+// LintCmd runs the lint checks over the files passed as arguments or, if none are passed,
+// over the sources of the configured builds
+func LintCmd(cCtx *cli.Context) error {
+	if cCtx.Bool("checks") {
+		for _, check := range lint.Checks() {
+			fmt.Printf("%-20s %-8s %s\n", check.Name, check.Severity, check.Doc)
+		}
+		return nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	conf, err := loadConfigFromDir(wd)
+	if errors.Is(err, errNoConfiguration) {
+		conf = config.NewDefaultConfig()
+	} else if err != nil {
+		return err
+	}
+
+	linter := lint.NewLinter()
+	if err := linter.Configure(conf.Lint.Rules); err != nil {
+		return err
+	}
+
+	files := cCtx.Args().Slice()
+	if len(files) == 0 {
+		for _, build := range conf.Builds {
+			matches, err := filepath.Glob(filepath.Join(build.SourceDir, "*.cddl"))
+			if err != nil {
+				return err
+			}
+			files = append(files, matches...)
+		}
+	}
+
+	failed := false
+	for _, file := range files {
+		ok, err := lintFile(linter, file)
+		if err != nil {
+			return err
+		}
+		failed = failed || !ok
+	}
+
+	if failed {
+		return errors.New("lint failed with errors above")
+	}
+	return nil
+}
+
+// lintFile prints the diagnostics for a single file. It returns false if the file
+// failed to parse or has lint errors.
+func lintFile(linter *lint.Linter, path string) (bool, error) {
+	src, err := readSource(path)
+	if err != nil {
+		return false, err
+	}
+
+	p := parser.NewParser(lexer.NewLexer(src), parser.WithFilename(path))
+	cddl, errs := p.ParseFile()
+	if len(errs) == 0 {
+		errs = linter.Lint(src, cddl)
+	}
+
+	if len(errs) > 0 {
+		fmt.Fprintln(os.Stderr, path)
+		printErrors(src, errs)
+		fmt.Fprintln(os.Stderr)
+	}
+	return !errs.HasErrors(), nil
+}
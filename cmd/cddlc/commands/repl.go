@@ -24,7 +24,7 @@ func Repl(cCtx *cli.Context) error {
 	parseVerbose := false
 
 	fmt.Println("Welcome to the cddlc quick repl.")
-	environ := env.NewEnvironment()
+	environ := env.NewEnclosedEnvironment(env.NewPrelude())
 
 	for {
 		fmt.Printf("%s ", PROMPT)
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/HannesKimara/cddlc/edn"
+	"github.com/HannesKimara/cddlc/resolver"
+	"github.com/HannesKimara/cddlc/token"
+	"github.com/HannesKimara/cddlc/validator"
+	"github.com/urfave/cli/v2"
+)
+
+// ValidateCmd validates a CBOR encoded data item, a JSON document, a data item in
+// diagnostic notation or each data item of a CBOR sequence against a rule of a schema. The
+// first rule of the schema is used unless --rule is set. With --deterministic, CBOR data
+// must take the core deterministic encoding as well. Each --feature enables a feature of
+// `.feature` controls and data using any other feature is rejected. The data items of a
+// CBOR sequence are limited to --max-item-size bytes each.
+func ValidateCmd(cCtx *cli.Context) error {
+	if !checkArgs(cCtx, 2) {
+		return errors.New("expected two arguments: schema.cddl data")
+	}
+	format := cCtx.String("format")
+	if format != "cbor" && format != "json" && format != "diag" && format != "cborseq" {
+		return fmt.Errorf("unknown format %s, expected one of cbor, json, diag or cborseq", format)
+	}
+	var opts []func(*validator.Validator)
+	if cCtx.Bool("deterministic") {
+		if format == "json" {
+			return errors.New("--deterministic only applies to CBOR data")
+		}
+		opts = append(opts, validator.WithDeterministic())
+	}
+	if cCtx.IsSet("feature") {
+		opts = append(opts, validator.WithFeatures(cCtx.StringSlice("feature")...))
+	}
+	if cCtx.IsSet("max-item-size") {
+		if format != "cborseq" {
+			return errors.New("--max-item-size only applies to the cborseq format")
+		}
+		if cCtx.Int("max-item-size") <= 0 {
+			return errors.New("--max-item-size must be a positive number of bytes")
+		}
+		opts = append(opts, validator.WithMaxItemSize(cCtx.Int("max-item-size")))
+	}
+
+	cddl, err := parseSchema(cCtx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	rule := cCtx.String("rule")
+	if rule == "" {
+		rule = resolver.NewGraph(cddl).Root()
+		if rule == "" {
+			return fmt.Errorf("%s defines no rules", cCtx.Args().Get(0))
+		}
+	}
+
+	if format == "cborseq" {
+		return validateSequence(cCtx, validator.Compile(cddl, opts...), rule)
+	}
+
+	data, err := readSource(cCtx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	v := validator.NewValidator(cddl, opts...)
+	switch format {
+	case "json":
+		err = v.ValidateJSON(rule, data)
+	case "diag":
+		item, perr := edn.Parse(data)
+		if perr != nil {
+			return perr
+		}
+		err = v.Validate(rule, item)
+	default:
+		err = v.ValidateCBOR(rule, data)
+	}
+	if verr, ok := err.(*validator.Error); ok {
+		// the schema parsed above so reading it again only fails if it changed meanwhile
+		src, _ := readSource(cCtx.Args().Get(0))
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, validationErrorStringer(src, verr))
+		return fmt.Errorf("%s is not a valid %s", cCtx.Args().Get(1), rule)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is a valid %s\n", cCtx.Args().Get(1), rule)
+	return nil
+}
+
+// validateSequence streams the data items of a CBOR sequence from the data file and reports
+// every item that does not match the rule
+func validateSequence(cCtx *cli.Context, prog *validator.Program, rule string) error {
+	f, err := os.Open(cCtx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// the schema parsed above so reading it again only fails if it changed meanwhile
+	src, _ := readSource(cCtx.Args().Get(0))
+	failures := 0
+	err = prog.ValidateSequence(rule, f, func(serr *validator.SequenceError) bool {
+		failures++
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintf(os.Stderr, "item %d at offset %d: ", serr.Index, serr.Offset)
+		if verr, ok := serr.Err.(*validator.Error); ok {
+			fmt.Fprintln(os.Stderr, validationErrorStringer(src, verr))
+		} else {
+			fmt.Fprintln(os.Stderr, serr.Err)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("%s has %d items that are not a valid %s", cCtx.Args().Get(1), failures, rule)
+	}
+	fmt.Printf("%s is a sequence of valid %s\n", cCtx.Args().Get(1), rule)
+	return nil
+}
+
+// validationErrorStringer renders a validation error with the line of the schema defining
+// the mismatched type followed by the failures of each alternative of a choice
+func validationErrorStringer(src []byte, err *validator.Error) string {
+	lines := bytes.Split(src, []byte{'\n'})
+	out := err.String()
+	if err.Rule != "" {
+		out += fmt.Sprintf(" (rule %s)", err.Rule)
+	}
+	out += snippet(lines, err.Start(), TAB)
+
+	for _, alt := range err.Alternatives {
+		out += fmt.Sprintf("\n%s- %s", TAB, alt.Type)
+		if alt.Err != nil {
+			out += ": " + alt.Err.Message
+			if alt.Err.Path != err.Path {
+				out += fmt.Sprintf(" at %s", alt.Err.Path)
+			}
+		}
+		out += snippet(lines, alt.Range.Start, TAB+TAB)
+	}
+	return out
+}
+
+// snippet returns the schema line at the position with a marker under the column. Types
+// of the standard prelude have no position and no snippet.
+func snippet(lines [][]byte, pos token.Position, indent string) string {
+	if pos.Line <= 0 || pos.Line > len(lines) {
+		return ""
+	}
+	lPrefix := fmt.Sprintf("%s%d | ", indent, pos.Line)
+	return fmt.Sprintf("\n%s%s\n%*s", lPrefix, lines[pos.Line-1], pos.Column+len(lPrefix), "˜")
+}
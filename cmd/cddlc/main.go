@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/HannesKimara/cddlc/cmd/cddlc/commands"
+	"github.com/HannesKimara/cddlc/validator"
 	"github.com/urfave/cli/v2"
 )
 
@@ -30,6 +31,96 @@ func main() {
 				Aliases: []string{"gen"},
 				Action:  commands.GenerateCmd,
 			},
+			{
+				Name:      "lint",
+				Usage:     "Report common smells in definition files",
+				ArgsUsage: "[files...]",
+				Action:    commands.LintCmd,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "checks",
+						Usage: "list the available checks and their default severity",
+					},
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "Report compatibility of changes between two schema versions",
+				ArgsUsage: "old.cddl new.cddl",
+				Action:    commands.DiffCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "require",
+						Value: "any",
+						Usage: "compatibility the changes must keep; one of any, backward, forward or full",
+					},
+				},
+			},
+			{
+				Name:      "validate",
+				Usage:     "Validate CBOR or JSON data against a schema rule",
+				ArgsUsage: "schema.cddl data",
+				Action:    commands.ValidateCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "rule",
+						Usage: "rule the data must match; defaults to the first rule of the schema",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "cbor",
+						Usage: "encoding of the data; one of cbor, json, diag or cborseq",
+					},
+					&cli.BoolFlag{
+						Name:  "deterministic",
+						Usage: "require the core deterministic encoding of CBOR data",
+					},
+					&cli.StringSliceFlag{
+						Name:  "feature",
+						Usage: "enable a feature of .feature controls; once set, data using other features is rejected",
+					},
+					&cli.IntFlag{
+						Name:  "max-item-size",
+						Value: validator.DefaultMaxItemSize,
+						Usage: "largest data item of a cborseq sequence in bytes",
+					},
+				},
+			},
+			{
+				Name:      "example",
+				Usage:     "Generate random instances of a schema rule",
+				ArgsUsage: "schema.cddl",
+				Action:    commands.ExampleCmd,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "rule",
+						Usage: "rule to generate instances of; defaults to the first rule of the schema",
+					},
+					&cli.IntFlag{
+						Name:  "count",
+						Value: 1,
+						Usage: "number of instances to generate",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "diag",
+						Usage: "output of the instances; one of diag, cbor or json",
+					},
+					&cli.Int64Flag{
+						Name:  "seed",
+						Usage: "seed of the random source to reproduce instances",
+					},
+					&cli.IntFlag{
+						Name:  "depth",
+						Value: 8,
+						Usage: "nesting from which recursive types are cut short",
+					},
+					&cli.BoolFlag{
+						Name:  "invalid",
+						Usage: "generate instances that each break one constraint, labelled with the constraint",
+					},
+				},
+			},
 			{
 				Name:   "lex",
 				Usage:  "Export tokens from cddl source code",
@@ -0,0 +1,652 @@
+package compat
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// differ holds the state of a comparison between two schema versions
+type differ struct {
+	oldEval, newEval *evaluator.Evaluator
+
+	// rule is the name of the rule being compared
+	rule    string
+	changes []*Change
+}
+
+func (d *differ) add(change *Change) {
+	d.changes = append(d.changes, change)
+}
+
+func (d *differ) report(kind Kind, path string, compat Compatibility, old, new ast.Node, format string, args ...interface{}) {
+	d.add(&Change{
+		Kind:          kind,
+		Rule:          d.rule,
+		Path:          path,
+		Message:       fmt.Sprintf(format, args...),
+		Compatibility: compat,
+		Old:           old,
+		New:           new,
+	})
+}
+
+func (d *differ) compareRule(old, new *definition) {
+	if oldParams, newParams := paramList(old.params), paramList(new.params); oldParams != newParams {
+		d.report(ParamsChanged, d.rule, Breaking, old.node, new.node, "generic parameters changed from <%s> to <%s>", oldParams, newParams)
+	}
+	d.compareType(d.rule, old.node, new.node)
+}
+
+func paramList(params []*ast.Identifier) string {
+	names := make([]string, 0, len(params))
+	for _, param := range params {
+		names = append(names, param.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// compareType reports the changes between two types at the path
+func (d *differ) compareType(path string, old, new ast.Node) {
+	if isChoice(old) || isChoice(new) {
+		d.compareChoice(path, old, new)
+		return
+	}
+
+	o, n := d.operand(old, d.oldEval), d.operand(new, d.newEval)
+	if o.value == nil || n.value == nil {
+		if equal(old, new) {
+			return
+		}
+		switch ov := old.(type) {
+		case *ast.Map:
+			if nv, ok := new.(*ast.Map); ok {
+				d.compareMembers(path, ov.Rules, nv.Rules)
+				return
+			}
+		case *ast.Group:
+			if nv, ok := new.(*ast.Group); ok {
+				d.compareMembers(path, entryNodes(ov.Entries), entryNodes(nv.Entries))
+				return
+			}
+		case *ast.Array:
+			if nv, ok := new.(*ast.Array); ok {
+				d.compareArray(path, entryNodes(ov.Rules), entryNodes(nv.Rules))
+				return
+			}
+		case *ast.Tag:
+			if nv, ok := new.(*ast.Tag); ok && equal(ov.Major, nv.Major) && equal(ov.TagNumber, nv.TagNumber) {
+				d.compareType(path, ov.Item, nv.Item)
+				return
+			}
+		}
+	} else if o.value.String() == n.value.String() {
+		return
+	}
+
+	kind, noun := TypeChanged, "type"
+	if _, ok := o.value.(*evaluator.Range); ok {
+		kind, noun = RangeChanged, "range"
+	}
+	if _, ok := n.value.(*evaluator.Range); ok {
+		kind, noun = RangeChanged, "range"
+	}
+	if kind == TypeChanged && o.value != nil && n.value != nil {
+		noun = "value"
+	}
+
+	switch compat := compatibility(o, n); compat {
+	case Backward:
+		d.report(kind, path, compat, old, new, "%s widened from %s to %s", noun, o, n)
+	case Forward:
+		d.report(kind, path, compat, old, new, "%s narrowed from %s to %s", noun, o, n)
+	default:
+		d.report(kind, path, compat, old, new, "%s changed from %s to %s", noun, o, n)
+	}
+}
+
+// compareChoice matches the alternatives of two choices. Alternatives matching only
+// values of an alternative in the other version are not reported.
+func (d *differ) compareChoice(path string, old, new ast.Node) {
+	oldAlts, newAlts := d.operands(flattenChoice(old), d.oldEval), d.operands(flattenChoice(new), d.newEval)
+
+	removed := []operand{}
+	for _, o := range oldAlts {
+		if !containedBy(o, newAlts) {
+			removed = append(removed, o)
+		}
+	}
+	added := []operand{}
+	for _, n := range newAlts {
+		if !containedBy(n, oldAlts) {
+			added = append(added, n)
+		}
+	}
+
+	if len(removed) == 1 && len(added) == 1 && !(isScalar(removed[0]) && isScalar(added[0])) {
+		// a single alternative was modified
+		d.compareType(path, removed[0].node, added[0].node)
+		return
+	}
+	for _, o := range removed {
+		if n, ok := partner(o, newAlts, contains); ok {
+			// the alternative was narrowed
+			d.compareType(path, o.node, n.node)
+			continue
+		}
+		d.report(AlternativeRemoved, path, Forward, o.node, nil, "choice alternative %s removed", o)
+	}
+	for _, n := range added {
+		if o, ok := partner(n, oldAlts, contains); ok {
+			// the alternative was widened
+			d.compareType(path, o.node, n.node)
+			continue
+		}
+		d.report(AlternativeAdded, path, Backward, nil, n.node, "choice alternative %s added", n)
+	}
+}
+
+// partner returns the first alternative for which match(op, alternative) holds
+func partner(op operand, alternatives []operand, match func(a, b operand) bool) (operand, bool) {
+	for _, alt := range alternatives {
+		if match(op, alt) {
+			return alt, true
+		}
+	}
+	return operand{}, false
+}
+
+func containedBy(op operand, alternatives []operand) bool {
+	for _, alt := range alternatives {
+		if contains(alt, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// isScalar returns true if the operand is a single constant value
+func isScalar(op operand) bool {
+	if op.value == nil {
+		return false
+	}
+	_, ok := op.value.(*evaluator.Range)
+	return !ok
+}
+
+func isChoice(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.TypeChoice, *ast.GroupChoice:
+		return true
+	}
+	return false
+}
+
+// flattenChoice returns the alternatives of nested type or group choices in order
+func flattenChoice(node ast.Node) []ast.Node {
+	switch val := node.(type) {
+	case *ast.TypeChoice:
+		return append(flattenChoice(val.First), flattenChoice(val.Second)...)
+	case *ast.GroupChoice:
+		return append(flattenChoice(val.First), flattenChoice(val.Second)...)
+	}
+	return []ast.Node{node}
+}
+
+// member represents an entry of a map, group or array
+type member struct {
+	// key is the member key. Empty for entries without a key such as group references
+	key      string
+	keyKind  string
+	min, max int64 // max is -1 if unbounded
+	node     ast.Node
+	value    ast.Node
+}
+
+// label returns how the member is referred to in messages
+func (m *member) label() string {
+	if m.key != "" {
+		return m.key
+	}
+	return describeNode(m.value)
+}
+
+// members returns the entries with their occurrence unwrapped
+func members(nodes []ast.Node) []*member {
+	out := []*member{}
+	for _, node := range nodes {
+		m := &member{min: 1, max: 1, node: node}
+		item := node
+		switch val := node.(type) {
+		case *ast.Optional:
+			m.min, m.max, item = 0, 1, val.Item
+		case *ast.NMOccurrence:
+			m.min, m.max, item = 0, -1, val.Item
+			if val.N != nil {
+				m.min = int64(val.N.Literal)
+			}
+			if val.M != nil {
+				m.max = int64(val.M.Literal)
+			}
+		}
+		m.value = item
+		if entry, ok := item.(*ast.Entry); ok && entry.Name != nil {
+			m.key = entry.Name.Name
+			m.keyKind = keyKind(entry)
+			m.value = entry.Value
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// keyKind returns the kind of value matched by a member key
+func keyKind(entry *ast.Entry) string {
+	if _, err := strconv.ParseInt(entry.Name.Name, 10, 64); err == nil {
+		return "integer"
+	}
+	if entry.Token == token.COLON {
+		return "text"
+	}
+	return "type"
+}
+
+// compareMembers matches the members of a map or group by key. Members without
+// a key are matched by equality.
+func (d *differ) compareMembers(path string, old, new []ast.Node) {
+	oldMembers, newMembers := members(old), members(new)
+	matched := map[*member]bool{}
+
+	for _, o := range oldMembers {
+		var n *member
+		for _, candidate := range newMembers {
+			if matched[candidate] {
+				continue
+			}
+			if (o.key != "" && o.key == candidate.key) || (o.key == "" && candidate.key == "" && equal(o.value, candidate.value)) {
+				n = candidate
+				break
+			}
+		}
+		memberPath := path + "." + o.label()
+		if n == nil {
+			d.removed(memberPath, "member", o)
+			continue
+		}
+		matched[n] = true
+
+		if o.keyKind != n.keyKind {
+			d.report(KeyTypeChanged, memberPath, Breaking, o.node, n.node, "key of member %s changed from %s to %s", o.key, o.keyKind, n.keyKind)
+			continue
+		}
+		d.compareOccurrence(memberPath, "member "+o.label(), o, n)
+		d.compareType(memberPath, o.value, n.value)
+	}
+
+	for _, n := range newMembers {
+		if !matched[n] {
+			d.added(path+"."+n.label(), "member", n)
+		}
+	}
+}
+
+// compareArray matches the entries of arrays by position
+func (d *differ) compareArray(path string, old, new []ast.Node) {
+	oldMembers, newMembers := members(old), members(new)
+	for i, o := range oldMembers {
+		entryPath := fmt.Sprintf("%s[%d]", path, i)
+		if i >= len(newMembers) {
+			d.removed(entryPath, "entry", o)
+			continue
+		}
+		n := newMembers[i]
+		d.compareOccurrence(entryPath, fmt.Sprintf("entry %d", i), o, n)
+		d.compareType(entryPath, o.value, n.value)
+	}
+	for i := len(oldMembers); i < len(newMembers); i++ {
+		d.added(fmt.Sprintf("%s[%d]", path, i), "entry", newMembers[i])
+	}
+}
+
+func (d *differ) removed(path, noun string, m *member) {
+	if m.min == 0 {
+		d.report(MemberRemoved, path, Forward, m.node, nil, "optional %s %s removed", noun, m.label())
+		return
+	}
+	d.report(MemberRemoved, path, Breaking, m.node, nil, "required %s %s removed", noun, m.label())
+}
+
+func (d *differ) added(path, noun string, m *member) {
+	if m.min == 0 {
+		d.report(MemberAdded, path, Backward, nil, m.node, "optional %s %s added", noun, m.label())
+		return
+	}
+	d.report(MemberAdded, path, Breaking, nil, m.node, "required %s %s added", noun, m.label())
+}
+
+func (d *differ) compareOccurrence(path, label string, old, new *member) {
+	if old.min == new.min && old.max == new.max {
+		return
+	}
+
+	compat := Breaking
+	if occurrenceContains(new, old) {
+		compat |= Backward
+	}
+	if occurrenceContains(old, new) {
+		compat |= Forward
+	}
+
+	switch {
+	case old.min == 0 && new.min > 0 && old.max == new.max:
+		d.report(OccurrenceChanged, path, compat, old.node, new.node, "%s made required", label)
+	case old.min > 0 && new.min == 0 && old.max == new.max:
+		d.report(OccurrenceChanged, path, compat, old.node, new.node, "%s made optional", label)
+	default:
+		d.report(OccurrenceChanged, path, compat, old.node, new.node, "occurrence of %s changed from %s to %s", label, occurrence(old), occurrence(new))
+	}
+}
+
+// occurrenceContains returns true if every occurrence count allowed by inner is allowed by outer
+func occurrenceContains(outer, inner *member) bool {
+	if outer.min > inner.min {
+		return false
+	}
+	return outer.max == -1 || (inner.max != -1 && inner.max <= outer.max)
+}
+
+// occurrence formats the occurrence indicator of the member
+func occurrence(m *member) string {
+	switch {
+	case m.min == 1 && m.max == 1:
+		return "1"
+	case m.min == 0 && m.max == 1:
+		return "?"
+	case m.min == 0 && m.max == -1:
+		return "*"
+	case m.min == 1 && m.max == -1:
+		return "+"
+	case m.max == -1:
+		return fmt.Sprintf("%d*", m.min)
+	}
+	return fmt.Sprintf("%d*%d", m.min, m.max)
+}
+
+func entryNodes(entries []ast.GroupEntry) []ast.Node {
+	out := make([]ast.Node, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, entry)
+	}
+	return out
+}
+
+// operand holds a type together with its constant value or prelude type if known
+type operand struct {
+	node  ast.Node
+	value evaluator.Value
+	base  token.Token
+}
+
+func (d *differ) operand(node ast.Node, eval *evaluator.Evaluator) operand {
+	return operand{node: node, value: eval.Eval(node), base: base(node)}
+}
+
+func (d *differ) operands(nodes []ast.Node, eval *evaluator.Evaluator) []operand {
+	out := make([]operand, 0, len(nodes))
+	for _, node := range nodes {
+		out = append(out, d.operand(node, eval))
+	}
+	return out
+}
+
+func (o operand) String() string {
+	if o.value != nil {
+		return o.value.String()
+	}
+	return describeNode(o.node)
+}
+
+// compatibility returns the compatibility of changing the old type to the new type
+func compatibility(old, new operand) Compatibility {
+	compat := Breaking
+	if contains(new, old) {
+		compat |= Backward
+	}
+	if contains(old, new) {
+		compat |= Forward
+	}
+	return compat
+}
+
+// contains returns true if every value matched by inner is known to be matched by outer
+func contains(outer, inner operand) bool {
+	switch {
+	case outer.value != nil && inner.value != nil:
+		return valueContains(outer.value, inner.value)
+	case inner.value != nil && outer.base != token.ILLEGAL:
+		return typeContains(outer.base, inner.value)
+	case outer.base != token.ILLEGAL && inner.base != token.ILLEGAL:
+		return tokenContains(outer.base, inner.base)
+	case outer.value == nil && inner.value == nil:
+		return equal(outer.node, inner.node)
+	}
+	return false
+}
+
+// base returns the prelude type token of the node or ILLEGAL if the node is not a prelude type
+func base(node ast.Node) token.Token {
+	switch val := node.(type) {
+	case *ast.UintType:
+		return token.UINT
+	case *ast.IntegerType:
+		return token.INT
+	case *ast.NegativeIntegerType:
+		return token.NINT
+	case *ast.FloatType:
+		if val.Token.IsNumeric() {
+			return val.Token
+		}
+		return token.FLOAT
+	case *ast.TstrType:
+		return token.TSTR
+	case *ast.BstrType, *ast.BytesType:
+		return token.BSTR
+	case *ast.BooleanType:
+		return token.BOOL
+	case *ast.NullType:
+		return token.NULL
+	}
+	return token.ILLEGAL
+}
+
+// tokenContains returns true if the prelude type outer matches every value of inner
+func tokenContains(outer, inner token.Token) bool {
+	if outer == inner {
+		return true
+	}
+	switch outer {
+	case token.INT:
+		return inner == token.UINT || inner == token.NINT
+	case token.FLOAT:
+		return inner == token.FLOAT16 || inner == token.FLOAT32 || inner == token.FLOAT64
+	case token.FLOAT64:
+		return inner == token.FLOAT16 || inner == token.FLOAT32
+	case token.FLOAT32:
+		return inner == token.FLOAT16
+	}
+	return false
+}
+
+// typeContains returns true if the prelude type matches the constant value
+func typeContains(tok token.Token, v evaluator.Value) bool {
+	switch val := v.(type) {
+	case *evaluator.Integer:
+		switch tok {
+		case token.INT:
+			return true
+		case token.UINT:
+			return val.Int.Sign() >= 0
+		case token.NINT:
+			return val.Int.Sign() < 0
+		}
+	case *evaluator.Float:
+		return tok == token.FLOAT || tok == token.FLOAT16 || tok == token.FLOAT32 || tok == token.FLOAT64
+	case *evaluator.Text:
+		return tok == token.TSTR
+	case *evaluator.Bytes:
+		return tok == token.BSTR
+	case *evaluator.Bool:
+		return tok == token.BOOL
+	case *evaluator.Range:
+		return typeContains(tok, val.From) && typeContains(tok, val.To)
+	}
+	return false
+}
+
+// valueContains returns true if every value in inner lies within outer
+func valueContains(outer, inner evaluator.Value) bool {
+	r, ok := outer.(*evaluator.Range)
+	if !ok {
+		return outer.String() == inner.String()
+	}
+	ir, ok := inner.(*evaluator.Range)
+	if !ok {
+		return rangeContains(r, inner)
+	}
+	r, ir = inclusive(r), inclusive(ir)
+
+	lower, upper := compare(r.From, ir.From), compare(ir.To, r.To)
+	if lower == nil || upper == nil || *lower > 0 {
+		return false
+	}
+	// an exclusive inner bound may equal the outer bound
+	return *upper < 0 || (*upper == 0 && (!r.Exclusive || ir.Exclusive))
+}
+
+// inclusive returns integer ranges with an exclusive upper bound as the equivalent inclusive range
+func inclusive(r *evaluator.Range) *evaluator.Range {
+	to, ok := r.To.(*evaluator.Integer)
+	if !ok || !r.Exclusive {
+		return r
+	}
+	return &evaluator.Range{From: r.From, To: &evaluator.Integer{Int: new(big.Int).Sub(to.Int, big.NewInt(1))}}
+}
+
+// rangeContains returns true if the numeric value lies within the range
+func rangeContains(r *evaluator.Range, v evaluator.Value) bool {
+	lower, upper := compare(r.From, v), compare(v, r.To)
+	if lower == nil || upper == nil {
+		return false
+	}
+	return *lower <= 0 && (*upper < 0 || (*upper == 0 && !r.Exclusive))
+}
+
+// compare returns the ordering of two numeric values of the same type or nil
+func compare(a, b evaluator.Value) *int {
+	var out int
+	switch x := a.(type) {
+	case *evaluator.Integer:
+		y, ok := b.(*evaluator.Integer)
+		if !ok {
+			return nil
+		}
+		out = x.Int.Cmp(y.Int)
+	case *evaluator.Float:
+		y, ok := b.(*evaluator.Float)
+		if !ok {
+			return nil
+		}
+		switch {
+		case x.Float < y.Float:
+			out = -1
+		case x.Float > y.Float:
+			out = 1
+		}
+	default:
+		return nil
+	}
+	return &out
+}
+
+// describeNode returns a short description of the node for messages
+func describeNode(node ast.Node) string {
+	switch val := node.(type) {
+	case nil:
+		return "nothing"
+	case *ast.Identifier:
+		return val.Name
+	case *ast.Generic:
+		return val.Name.Name + "<...>"
+	case *ast.Map:
+		return "map"
+	case *ast.Array:
+		return "array"
+	case *ast.Group:
+		return "group"
+	case *ast.Tag:
+		return "tagged " + describeNode(val.Item)
+	}
+	if tok := base(node); tok != token.ILLEGAL {
+		return tok.String()
+	}
+	return strings.ToLower(strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."))
+}
+
+var (
+	positionType      = reflect.TypeOf(token.Position{})
+	positionRangeType = reflect.TypeOf(token.PositionRange{})
+	commentType       = reflect.TypeOf(&ast.Comment{})
+)
+
+// equal returns true if two nodes are structurally equal ignoring positions and comments
+func equal(a, b ast.Node) bool {
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func equalValues(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalValues(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			switch a.Type().Field(i).Type {
+			case positionType, positionRangeType, commentType:
+				continue
+			}
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	if a.CanInterface() && b.CanInterface() {
+		return reflect.DeepEqual(a.Interface(), b.Interface())
+	}
+	return false
+}
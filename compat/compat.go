@@ -0,0 +1,189 @@
+// Package compat compares two versions of a CDDL schema and classifies each change by
+// whether messages remain valid across versions.
+//
+// A change is backward compatible if every message valid under the old schema is valid
+// under the new one and forward compatible if every message valid under the new schema
+// is valid under the old one. Changes that are neither are breaking.
+//
+// Subsumes decides whether every instance of one rule is an instance of another, for
+// example to prove a restricted profile of a schema only produces valid base messages.
+package compat
+
+import (
+	"fmt"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// Compatibility classifies a change by the direction messages remain valid in
+type Compatibility int
+
+const (
+	// Breaking changes invalidate messages in both directions
+	Breaking Compatibility = 0
+
+	// Backward compatible changes keep messages of the old schema valid under the new schema
+	Backward Compatibility = 1
+
+	// Forward compatible changes keep messages of the new schema valid under the old schema
+	Forward Compatibility = 2
+
+	// Compatible changes keep messages valid in both directions
+	Compatible = Backward | Forward
+)
+
+func (c Compatibility) String() string {
+	switch c {
+	case Backward:
+		return "backward compatible"
+	case Forward:
+		return "forward compatible"
+	case Compatible:
+		return "compatible"
+	}
+	return "breaking"
+}
+
+// Kind describes what changed between the versions
+type Kind int
+
+const (
+	RuleAdded Kind = iota
+	RuleRemoved
+	ParamsChanged
+	MemberAdded
+	MemberRemoved
+	OccurrenceChanged
+	KeyTypeChanged
+	RangeChanged
+	AlternativeAdded
+	AlternativeRemoved
+	TypeChanged
+)
+
+var kinds = [...]string{
+	RuleAdded:          "rule added",
+	RuleRemoved:        "rule removed",
+	ParamsChanged:      "generic parameters changed",
+	MemberAdded:        "member added",
+	MemberRemoved:      "member removed",
+	OccurrenceChanged:  "occurrence changed",
+	KeyTypeChanged:     "key type changed",
+	RangeChanged:       "range changed",
+	AlternativeAdded:   "alternative added",
+	AlternativeRemoved: "alternative removed",
+	TypeChanged:        "type changed",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Change represents a single difference between two schema versions
+type Change struct {
+	// Kind: what changed
+	Kind Kind
+
+	// Rule: the name of the rule containing the change
+	Rule string
+
+	// Path: the location of the change within the rule i.e. `person.address[0]`
+	Path string
+
+	// Message: a description of the change
+	Message string
+
+	// Compatibility: the direction messages remain valid in
+	Compatibility Compatibility
+
+	// Old, New: the nodes compared. One of them is nil for additions and removals
+	Old, New ast.Node
+}
+
+func (c *Change) String() string {
+	return fmt.Sprintf("%s: %s: %s", c.Compatibility, c.Path, c.Message)
+}
+
+// Breaking returns true if the change invalidates messages in both directions
+func (c *Change) Breaking() bool {
+	return c.Compatibility == Breaking
+}
+
+// Summary returns the compatibility of a set of changes taken together. An empty set is compatible.
+func Summary(changes []*Change) Compatibility {
+	out := Compatible
+	for _, change := range changes {
+		out &= change.Compatibility
+	}
+	return out
+}
+
+// Diff compares the rules of two schema versions. Rules are matched by name; changes
+// are returned in the order the rules are defined in the old version followed by rules
+// added in the new version.
+func Diff(old, new *ast.CDDL) []*Change {
+	d := &differ{
+		oldEval: evaluator.NewEvaluator(old),
+		newEval: evaluator.NewEvaluator(new),
+	}
+
+	oldRules, oldNames := definitions(old)
+	newRules, newNames := definitions(new)
+
+	for _, name := range oldNames {
+		o := oldRules[name]
+		n, ok := newRules[name]
+		if !ok {
+			d.add(&Change{Kind: RuleRemoved, Rule: name, Path: name, Message: "rule " + name + " removed", Compatibility: Breaking, Old: o.node})
+			continue
+		}
+		d.rule = name
+		d.compareRule(o, n)
+	}
+	for _, name := range newNames {
+		if _, ok := oldRules[name]; !ok {
+			d.add(&Change{Kind: RuleAdded, Rule: name, Path: name, Message: "rule " + name + " added", Compatibility: Backward, New: newRules[name].node})
+		}
+	}
+	return d.changes
+}
+
+// definition holds the merged definitions of a rule
+type definition struct {
+	params []*ast.Identifier
+	node   ast.Node
+}
+
+// definitions merges the rules extended with `/=` and `//=` into a single choice. It
+// returns the definitions and the rule names in order of definition.
+func definitions(cddl *ast.CDDL) (map[string]*definition, []string) {
+	defs := map[string]*definition{}
+	names := []string{}
+	if cddl == nil {
+		return defs, names
+	}
+
+	for _, entry := range cddl.Rules {
+		rule, ok := entry.(*ast.Rule)
+		if !ok || rule.Name == nil || rule.Value == nil {
+			continue
+		}
+		def, ok := defs[rule.Name.Name]
+		if !ok {
+			defs[rule.Name.Name] = &definition{params: rule.Params, node: rule.Value}
+			names = append(names, rule.Name.Name)
+			continue
+		}
+		if rule.Token == token.GROUP_CHOICE_ASSIGN {
+			def.node = &ast.GroupChoice{Pos: rule.Pos, Token: token.GROUP_CHOICE, First: def.node, Second: rule.Value}
+		} else {
+			def.node = &ast.TypeChoice{Pos: rule.Pos, Token: token.TYPE_CHOICE, First: def.node, Second: rule.Value}
+		}
+	}
+	return defs, names
+}
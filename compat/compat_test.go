@@ -0,0 +1,119 @@
+package compat_test
+
+import (
+	"testing"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/compat"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+)
+
+func parse(t *testing.T, src string) *ast.CDDL {
+	t.Helper()
+	l := lexer.NewLexer([]byte(src))
+	p := parser.NewParser(l)
+
+	cddl, errs := p.ParseFile()
+	if len(errs) != 0 {
+		t.Fatalf("%s: -> %s", src, errs)
+	}
+	return cddl
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old, new string
+		changes  []string
+	}{
+		{"a = uint", "a = uint", []string{}},
+		{"a = uint ; comment", "a =   uint", []string{}},
+		{"a = uint", "a = uint b = tstr", []string{"backward compatible: b: rule b added"}},
+		{"a = uint b = tstr", "a = uint", []string{"breaking: b: rule b removed"}},
+		{"a<t> = [t]", "a<t, v> = [t]", []string{"breaking: a: generic parameters changed from <t> to <t, v>"}},
+
+		// types
+		{"a = uint", "a = int", []string{"backward compatible: a: type widened from uint to int"}},
+		{"a = int", "a = nint", []string{"forward compatible: a: type narrowed from int to nint"}},
+		{"a = uint", "a = tstr", []string{"breaking: a: type changed from uint to tstr"}},
+		{"a = 1", "a = uint", []string{"backward compatible: a: type widened from 1 to uint"}},
+		{"a = 1", "a = 2", []string{"breaking: a: value changed from 1 to 2"}},
+		{"a = float16", "a = float", []string{"backward compatible: a: type widened from float16 to float"}},
+
+		// ranges
+		{"a = 0..10", "a = 0..20", []string{"backward compatible: a: range widened from 0..10 to 0..20"}},
+		{"a = 0..10", "a = 0...10", []string{"forward compatible: a: range narrowed from 0..10 to 0...10"}},
+		{"a = 0..10", "a = 5..20", []string{"breaking: a: range changed from 0..10 to 5..20"}},
+		{"a = 0..max max = 10", "a = 0..max max = 10", []string{}},
+		{"a = 0..max max = 10", "a = 0..max max = 5", []string{
+			"forward compatible: a: range narrowed from 0..10 to 0..5",
+			"breaking: max: value changed from 10 to 5",
+		}},
+
+		// choices
+		{`a = "x" / "y"`, `a = "x"`, []string{`forward compatible: a: choice alternative "y" removed`}},
+		{`a = "x"`, `a = "x" / "y"`, []string{`backward compatible: a: choice alternative "y" added`}},
+		{`a = "x" / "y"`, `a = "y" / "x"`, []string{}},
+		{`a = "x" / "y"`, `a = tstr`, []string{"backward compatible: a: type widened from \"x\" to tstr"}},
+		{"a = 1 / 2 / 3", "a = uint", []string{"backward compatible: a: type widened from 1 to uint"}},
+		{"a = 1 / 2 / 3", "a = 1 / 2 / 3 / tstr", []string{"backward compatible: a: choice alternative tstr added"}},
+		{"a = tstr / 0..10", "a = tstr / 0..5", []string{"forward compatible: a: range narrowed from 0..10 to 0..5"}},
+		{"$a /= uint $a /= tstr", "$a /= uint", []string{"forward compatible: $a: choice alternative tstr removed"}},
+
+		// maps
+		{"a = {x: uint}", "a = {x: uint, ? y: tstr}", []string{"backward compatible: a.y: optional member y added"}},
+		{"a = {x: uint}", "a = {x: uint, y: tstr}", []string{"breaking: a.y: required member y added"}},
+		{"a = {x: uint, ? y: tstr}", "a = {x: uint}", []string{"forward compatible: a.y: optional member y removed"}},
+		{"a = {x: uint, y: tstr}", "a = {x: uint}", []string{"breaking: a.y: required member y removed"}},
+		{"a = {? x: uint}", "a = {x: uint}", []string{"forward compatible: a.x: member x made required"}},
+		{"a = {x: uint}", "a = {? x: uint}", []string{"backward compatible: a.x: member x made optional"}},
+		{"a = {? x: uint}", "a = {* x: uint}", []string{"backward compatible: a.x: occurrence of member x changed from ? to *"}},
+		{"a = {x: 0..10}", "a = {x: 0..5}", []string{"forward compatible: a.x: range narrowed from 0..10 to 0..5"}},
+		{"a = {x: {y: uint}}", "a = {x: {y: int}}", []string{"backward compatible: a.x.y: type widened from uint to int"}},
+		{"a = {1: uint}", "a = {1 => uint}", []string{}},
+		{"a = {x: uint} x = tstr", "a = {x => uint} x = tstr", []string{"breaking: a.x: key of member x changed from text to type"}},
+		{"a = {x: uint, y: tstr}", "a = {y: tstr, x: uint}", []string{}},
+
+		// groups
+		{"a = (x: uint)", "a = (x: uint, ? y: uint)", []string{"backward compatible: a.y: optional member y added"}},
+
+		// arrays
+		{"a = [uint, tstr]", "a = [uint, tstr, ? bool]", []string{"backward compatible: a[2]: optional entry bool added"}},
+		{"a = [uint, tstr]", "a = [uint]", []string{"breaking: a[1]: required entry tstr removed"}},
+		{"a = [uint, tstr]", "a = [uint, bool]", []string{"breaking: a[1]: type changed from tstr to bool"}},
+		{"a = [x: uint]", "a = [y: uint]", []string{}},
+		{"a = [* uint]", "a = [+ uint]", []string{"forward compatible: a[0]: entry 0 made required"}},
+	}
+
+	for _, tst := range tests {
+		changes := compat.Diff(parse(t, tst.old), parse(t, tst.new))
+		if len(changes) != len(tst.changes) {
+			t.Errorf("%s -> %s: expected %d changes got %d: %v", tst.old, tst.new, len(tst.changes), len(changes), changes)
+			continue
+		}
+		for i, change := range changes {
+			if change.String() != tst.changes[i] {
+				t.Errorf("%s -> %s: expected `%s` got `%s`", tst.old, tst.new, tst.changes[i], change)
+			}
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		old, new string
+		summary  compat.Compatibility
+	}{
+		{"a = uint", "a = uint", compat.Compatible},
+		{"a = {x: uint}", "a = {x: uint, ? y: tstr}", compat.Backward},
+		{"a = {? x: uint}", "a = {x: uint}", compat.Forward},
+		// each change is compatible in one direction only
+		{`a = {? x: uint} b = "x" / "y"`, `a = {x: uint} b = "x" / "y" / "z"`, compat.Breaking},
+	}
+
+	for _, tst := range tests {
+		if summary := compat.Summary(compat.Diff(parse(t, tst.old), parse(t, tst.new))); summary != tst.summary {
+			t.Errorf("%s -> %s: expected %s got %s", tst.old, tst.new, tst.summary, summary)
+		}
+	}
+}
@@ -0,0 +1,772 @@
+package compat
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// Relation is the outcome of a subsumption check between two types A and B
+type Relation int
+
+const (
+	// Unknown: the relation could not be decided
+	Unknown Relation = iota
+
+	// Subset: every instance of A is an instance of B
+	Subset
+
+	// Overlap: some instances of A are instances of B but not all
+	Overlap
+
+	// Disjoint: no instance of A is an instance of B
+	Disjoint
+)
+
+func (r Relation) String() string {
+	switch r {
+	case Subset:
+		return "subset"
+	case Overlap:
+		return "overlap"
+	case Disjoint:
+		return "disjoint"
+	}
+	return "unknown"
+}
+
+// Result holds the relation between two types and the reason it was decided
+type Result struct {
+	// Relation: the relation of A to B
+	Relation Relation
+
+	// Path: the location in A the relation was decided at. Empty for subsets
+	Path string
+
+	// Reason: an explanation of the relation. For overlapping and disjoint types it names
+	// an instance of A that is not an instance of B where one could be found.
+	Reason string
+}
+
+func (r *Result) String() string {
+	if r.Path == "" {
+		return r.Relation.String()
+	}
+	return fmt.Sprintf("%s: at %s: %s", r.Relation, r.Path, r.Reason)
+}
+
+// Subsumes decides whether every instance of rule a in schema sa is an instance of rule b
+// in schema sb. Both rules may be taken from the same schema. Recursive types are assumed
+// to be subsets wherever the recursion is reached again.
+func Subsumes(sa *ast.CDDL, a string, sb *ast.CDDL, b string) (*Result, error) {
+	s := &subsumption{
+		a:        newSide(sa),
+		b:        newSide(sb),
+		visiting: map[[2]ast.Node]bool{},
+	}
+
+	defA, ok := s.a.defs[a]
+	if !ok {
+		return nil, fmt.Errorf("rule %s is not defined", a)
+	}
+	defB, ok := s.b.defs[b]
+	if !ok {
+		return nil, fmt.Errorf("rule %s is not defined", b)
+	}
+	return s.check(a, defA.node, defB.node), nil
+}
+
+// side holds the definitions one of the compared types is resolved in
+type side struct {
+	defs map[string]*definition
+	eval *evaluator.Evaluator
+}
+
+func newSide(cddl *ast.CDDL) *side {
+	defs, _ := definitions(cddl)
+	return &side{defs: defs, eval: evaluator.NewEvaluator(cddl)}
+}
+
+// resolve replaces references to non generic rules and parenthesized types with their
+// definition. Defaults and features are dropped as they do not restrict their base type.
+func (s *side) resolve(node ast.Node) ast.Node {
+	seen := map[string]bool{}
+	for {
+		switch val := node.(type) {
+		case *ast.Identifier:
+			def, ok := s.defs[val.Name]
+			if !ok || len(def.params) > 0 || seen[val.Name] {
+				return node
+			}
+			seen[val.Name] = true
+			node = def.node
+		case *ast.DefaultControl:
+			node = val.Base
+		case *ast.FeatureControl:
+			node = val.Base
+		case *ast.Group:
+			if len(val.Entries) != 1 || isMember(val.Entries[0]) {
+				return node
+			}
+			node = val.Entries[0]
+		default:
+			return node
+		}
+	}
+}
+
+// isMember returns true if the group entry is a member rather than a parenthesized type
+func isMember(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.Entry, *ast.Optional, *ast.NMOccurrence, *ast.Unwrap:
+		return true
+	}
+	return false
+}
+
+// expand inlines the entries of referenced and unwrapped groups and maps
+func (s *side) expand(nodes []ast.Node) []ast.Node {
+	out := []ast.Node{}
+	for _, node := range nodes {
+		item := node
+		if unwrap, ok := node.(*ast.Unwrap); ok {
+			item = unwrap.Item
+		}
+		if _, ok := item.(*ast.Identifier); !ok {
+			out = append(out, node)
+			continue
+		}
+		switch val := s.resolve(item).(type) {
+		case *ast.Group:
+			out = append(out, s.expand(entryNodes(val.Entries))...)
+		case *ast.Map:
+			out = append(out, s.expand(val.Rules)...)
+		default:
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// subsumption holds the state of a single subsumption check
+type subsumption struct {
+	a, b *side
+
+	// visiting holds the pairs of types being compared to stop at recursive types
+	visiting map[[2]ast.Node]bool
+}
+
+func subset() *Result {
+	return &Result{Relation: Subset}
+}
+
+func unknown(path, format string, args ...interface{}) *Result {
+	return &Result{Relation: Unknown, Path: path, Reason: fmt.Sprintf(format, args...)}
+}
+
+// check returns the relation of type a to type b
+func (s *subsumption) check(path string, a, b ast.Node) *Result {
+	a, b = s.a.resolve(a), s.b.resolve(b)
+	if a == nil || b == nil {
+		return unknown(path, "missing type")
+	}
+
+	if _, ok := b.(*ast.AnyType); ok {
+		return subset()
+	}
+
+	pair := [2]ast.Node{a, b}
+	if s.visiting[pair] {
+		return subset()
+	}
+	s.visiting[pair] = true
+	defer delete(s.visiting, pair)
+
+	if isChoice(a) {
+		results := []*Result{}
+		for _, alt := range flattenChoice(a) {
+			results = append(results, s.check(path, alt, b))
+		}
+		return union(results)
+	}
+	if isChoice(b) {
+		return s.checkAlternatives(path, a, flattenChoice(b))
+	}
+
+	da, okA := s.a.domain(a)
+	db, okB := s.b.domain(b)
+	if okA && okB {
+		return leafRelation(path, da, db)
+	}
+	if equal(a, b) {
+		return subset()
+	}
+
+	switch va := a.(type) {
+	case *ast.Map:
+		if vb, ok := b.(*ast.Map); ok {
+			return s.checkMembers(path, s.a.expand(va.Rules), s.b.expand(vb.Rules))
+		}
+	case *ast.Group:
+		if vb, ok := b.(*ast.Group); ok {
+			return s.checkMembers(path, s.a.expand(entryNodes(va.Entries)), s.b.expand(entryNodes(vb.Entries)))
+		}
+	case *ast.Array:
+		if vb, ok := b.(*ast.Array); ok {
+			return s.checkArray(path, s.a.expand(entryNodes(va.Rules)), s.b.expand(entryNodes(vb.Rules)))
+		}
+	case *ast.Tag:
+		if vb, ok := b.(*ast.Tag); ok {
+			if !equal(va.TagNumber, vb.TagNumber) {
+				return &Result{Relation: Disjoint, Path: path, Reason: fmt.Sprintf("tag %s is not tag %s", tagNumber(va), tagNumber(vb))}
+			}
+			return s.check(path, va.Item, vb.Item)
+		}
+	}
+
+	// refinements such as .regexp are within their base type
+	if upper := refined(a); upper != nil {
+		r := s.check(path, upper, b)
+		if r.Relation == Subset || r.Relation == Disjoint {
+			return r
+		}
+		return unknown(path, "cannot decide whether %s is within %s", describeNode(a), describeNode(b))
+	}
+
+	ka, kb := kindOf(a, okA), kindOf(b, okB)
+	if ka != "" && kb != "" && ka != kb {
+		return &Result{Relation: Disjoint, Path: path, Reason: fmt.Sprintf("%s is not a %s", describeNode(a), kb)}
+	}
+	return unknown(path, "cannot compare %s with %s", describeNode(a), describeNode(b))
+}
+
+// checkAlternatives returns the relation of a type to a choice. A type is a subset of the
+// choice if it is a subset of one of its alternatives.
+func (s *subsumption) checkAlternatives(path string, a ast.Node, alternatives []ast.Node) *Result {
+	results := []*Result{}
+	for _, alt := range alternatives {
+		r := s.check(path, a, alt)
+		if r.Relation == Subset {
+			return r
+		}
+		results = append(results, r)
+	}
+
+	disjoint := true
+	for _, r := range results {
+		disjoint = disjoint && r.Relation == Disjoint
+	}
+	da, ok := s.a.domain(a)
+	if disjoint {
+		if samples := da.samples(); ok && len(samples) > 0 {
+			return &Result{Relation: Disjoint, Path: path, Reason: fmt.Sprintf("%s matches %s but none of the alternatives", samples[0], da)}
+		}
+		return results[0]
+	}
+
+	// look for an instance of a matched by none of the alternatives
+	if !ok {
+		return unknown(path, "cannot decide whether %s is covered by the alternatives", describeNode(a))
+	}
+	domains := []domain{}
+	for _, alt := range alternatives {
+		db, ok := s.b.domain(s.b.resolve(alt))
+		if !ok {
+			return unknown(path, "cannot decide whether %s is covered by the alternatives", describeNode(a))
+		}
+		domains = append(domains, db)
+	}
+	for _, v := range da.samples() {
+		if !memberOfAny(domains, v) {
+			return &Result{Relation: Overlap, Path: path, Reason: fmt.Sprintf("%s matches %s but none of the alternatives", v, da)}
+		}
+	}
+	return unknown(path, "cannot decide whether %s is covered by the alternatives", da)
+}
+
+func memberOfAny(domains []domain, v evaluator.Value) bool {
+	for _, d := range domains {
+		if d.member(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// union combines the relations of the alternatives of a choice to the same type
+func union(results []*Result) *Result {
+	counts := map[Relation]int{}
+	var first = map[Relation]*Result{}
+	for _, r := range results {
+		counts[r.Relation]++
+		if first[r.Relation] == nil {
+			first[r.Relation] = r
+		}
+	}
+
+	switch {
+	case counts[Subset] == len(results):
+		return subset()
+	case counts[Disjoint] == len(results):
+		return first[Disjoint]
+	case counts[Unknown] > 0:
+		return first[Unknown]
+	case counts[Overlap] > 0:
+		return first[Overlap]
+	}
+	// alternatives are either subsets or disjoint
+	r := *first[Disjoint]
+	r.Relation = Overlap
+	return &r
+}
+
+// intersection combines the relations of the parts of a map, group or array. Parts that
+// are disjoint make the whole disjoint.
+func intersection(results []*Result) *Result {
+	for _, rel := range []Relation{Disjoint, Unknown, Overlap} {
+		for _, r := range results {
+			if r.Relation == rel {
+				return r
+			}
+		}
+	}
+	return subset()
+}
+
+// checkMembers returns the relation of two maps or groups matching members by key
+func (s *subsumption) checkMembers(path string, a, b []ast.Node) *Result {
+	membersA, membersB := members(a), members(b)
+	matched := map[*member]bool{}
+	results := []*Result{}
+
+	for _, ma := range membersA {
+		memberPath := path + "." + ma.label()
+		var mb *member
+		for _, candidate := range membersB {
+			if !matched[candidate] && ma.key != "" && ma.key == candidate.key && ma.keyKind == candidate.keyKind {
+				mb = candidate
+				break
+			}
+			if !matched[candidate] && ma.key == "" && candidate.key == "" && equal(ma.value, candidate.value) {
+				mb = candidate
+				break
+			}
+		}
+
+		switch {
+		case mb == nil && ma.key == "":
+			results = append(results, unknown(memberPath, "cannot match %s to a member", ma.label()))
+		case mb == nil && ma.min > 0:
+			results = append(results, &Result{Relation: Disjoint, Path: memberPath, Reason: fmt.Sprintf("member %s is required but not allowed", ma.label())})
+		case mb == nil:
+			results = append(results, &Result{Relation: Overlap, Path: memberPath, Reason: fmt.Sprintf("member %s is optional but not allowed", ma.label())})
+		default:
+			matched[mb] = true
+			results = append(results, s.checkMember(memberPath, ma, mb))
+		}
+	}
+
+	for _, mb := range membersB {
+		if matched[mb] || mb.min == 0 {
+			continue
+		}
+		label := mb.label()
+		if hasUnkeyed(membersA) {
+			results = append(results, unknown(path+"."+label, "cannot decide whether required member %s is present", label))
+			continue
+		}
+		results = append(results, &Result{Relation: Disjoint, Path: path + "." + label, Reason: fmt.Sprintf("required member %s is missing", label)})
+	}
+	return intersection(results)
+}
+
+func hasUnkeyed(members []*member) bool {
+	for _, m := range members {
+		if m.key == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMember returns the relation of two matched members or array entries
+func (s *subsumption) checkMember(path string, ma, mb *member) *Result {
+	if !occurrenceContains(mb, ma) {
+		if ma.min > mb.max && mb.max != -1 || ma.max != -1 && ma.max < mb.min {
+			return &Result{Relation: Disjoint, Path: path, Reason: fmt.Sprintf("occurrence %s does not intersect %s", occurrence(ma), occurrence(mb))}
+		}
+		return &Result{Relation: Overlap, Path: path, Reason: fmt.Sprintf("occurrence %s is not within %s", occurrence(ma), occurrence(mb))}
+	}
+
+	r := s.check(path, ma.value, mb.value)
+	if r.Relation == Disjoint && ma.min == 0 {
+		// instances without the member are still valid
+		out := *r
+		out.Relation = Overlap
+		return &out
+	}
+	return r
+}
+
+// checkArray returns the relation of two arrays matching entries by position
+func (s *subsumption) checkArray(path string, a, b []ast.Node) *Result {
+	membersA, membersB := members(a), members(b)
+
+	if len(membersA) != len(membersB) && fixed(membersA) && fixed(membersB) {
+		return &Result{Relation: Disjoint, Path: path, Reason: fmt.Sprintf("arrays of %d entries are not arrays of %d entries", len(membersA), len(membersB))}
+	}
+	if len(membersA) > len(membersB) {
+		return unknown(path, "cannot match %d entries to %d entries", len(membersA), len(membersB))
+	}
+	for _, mb := range membersB[len(membersA):] {
+		if mb.min > 0 {
+			if fixed(membersA) {
+				return &Result{Relation: Disjoint, Path: path, Reason: fmt.Sprintf("required entry %s is missing", mb.label())}
+			}
+			return unknown(path, "cannot match %d entries to %d entries", len(membersA), len(membersB))
+		}
+	}
+
+	results := []*Result{}
+	for i, ma := range membersA {
+		results = append(results, s.checkMember(fmt.Sprintf("%s[%d]", path, i), ma, membersB[i]))
+	}
+	return intersection(results)
+}
+
+// fixed returns true if all members occur exactly once
+func fixed(members []*member) bool {
+	for _, m := range members {
+		if m.min != 1 || m.max != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// refined returns the type a control operator restricts or nil
+func refined(node ast.Node) ast.Node {
+	switch val := node.(type) {
+	case *ast.Regexp:
+		if val.Base != nil {
+			return val.Base
+		}
+		return &ast.TstrType{Token: token.TSTR}
+	case *ast.ABNF:
+		return val.Base
+	case *ast.CBORControl:
+		return val.Base
+	case *ast.SizeOperatorControl:
+		return val.Type
+	case *ast.ComparatorOpControl:
+		return val.Left
+	}
+	return nil
+}
+
+// kindOf returns the major kind of values matched by the node or an empty string if unknown
+func kindOf(node ast.Node, leaf bool) string {
+	switch node.(type) {
+	case *ast.Map:
+		return "map"
+	case *ast.Array:
+		return "array"
+	case *ast.Tag:
+		return "tag"
+	}
+	if leaf {
+		return "value"
+	}
+	return ""
+}
+
+func tagNumber(t *ast.Tag) string {
+	if t.TagNumber == nil {
+		return "#6"
+	}
+	return fmt.Sprintf("#6.%d", t.TagNumber.Literal)
+}
+
+var (
+	maxUint = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 64), big.NewInt(1))
+	minNint = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64))
+)
+
+// domain represents the values matched by a leaf type: either a constant, a range or
+// a prelude type optionally restricted in size
+type domain struct {
+	value evaluator.Value
+	base  token.Token
+
+	// size restricts the length in bytes of text and byte strings
+	size *evaluator.Range
+}
+
+func (d domain) String() string {
+	if d.value != nil {
+		return d.value.String()
+	}
+	if d.size != nil && d.size.From.String() == d.size.To.String() {
+		return d.base.String() + " .size " + d.size.From.String()
+	}
+	if d.size != nil {
+		return d.base.String() + " .size " + d.size.String()
+	}
+	return d.base.String()
+}
+
+// domain returns the values matched by a leaf type
+func (s *side) domain(node ast.Node) (domain, bool) {
+	if v := s.eval.Eval(node); v != nil {
+		return domain{value: v}, true
+	}
+	if tok := base(node); tok != token.ILLEGAL {
+		return domain{base: tok}, true
+	}
+
+	switch val := node.(type) {
+	case *ast.SizeOperatorControl:
+		inner, ok := s.domain(s.resolve(val.Type))
+		if !ok || inner.value != nil {
+			return domain{}, false
+		}
+		size := s.eval.Eval(val.Size)
+		switch inner.base {
+		case token.UINT:
+			// the value fits in the number of bytes
+			n, ok := size.(*evaluator.Integer)
+			if !ok || n.Int.Sign() < 0 || n.Int.Cmp(big.NewInt(8)) > 0 {
+				return domain{}, false
+			}
+			max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(8*n.Int.Int64())), big.NewInt(1))
+			return domain{value: intRange(big.NewInt(0), max)}, true
+		case token.TSTR, token.BSTR:
+			switch sv := size.(type) {
+			case *evaluator.Integer:
+				return domain{base: inner.base, size: &evaluator.Range{From: sv, To: sv}}, true
+			case *evaluator.Range:
+				return domain{base: inner.base, size: sv}, true
+			}
+		}
+	case *ast.ComparatorOpControl:
+		inner, ok := s.domain(s.resolve(val.Left))
+		n, isInt := s.eval.Eval(val.Right).(*evaluator.Integer)
+		if !ok || !isInt || inner.value != nil {
+			return domain{}, false
+		}
+		lo, hi := minNint, maxUint
+		switch inner.base {
+		case token.UINT:
+			lo = big.NewInt(0)
+		case token.NINT:
+			hi = big.NewInt(-1)
+		case token.INT:
+		default:
+			return domain{}, false
+		}
+		switch val.Token {
+		case token.LT:
+			hi = minBig(hi, new(big.Int).Sub(n.Int, big.NewInt(1)))
+		case token.LE:
+			hi = minBig(hi, n.Int)
+		case token.GT:
+			lo = maxBig(lo, new(big.Int).Add(n.Int, big.NewInt(1)))
+		case token.GE:
+			lo = maxBig(lo, n.Int)
+		case token.EQ:
+			lo, hi = maxBig(lo, n.Int), minBig(hi, n.Int)
+		default:
+			return domain{}, false
+		}
+		if lo.Cmp(hi) > 0 {
+			return domain{}, false
+		}
+		return domain{value: intRange(lo, hi)}, true
+	}
+	return domain{}, false
+}
+
+func intRange(from, to *big.Int) *evaluator.Range {
+	return &evaluator.Range{From: &evaluator.Integer{Int: from}, To: &evaluator.Integer{Int: to}}
+}
+
+func minBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) > 0 {
+		return a
+	}
+	return b
+}
+
+// member returns true if the constant value is matched by the domain
+func (d domain) member(v evaluator.Value) bool {
+	if d.value != nil {
+		return valueContains(d.value, v)
+	}
+	if !typeContains(d.base, v) {
+		return false
+	}
+	if d.size == nil {
+		return true
+	}
+	switch val := v.(type) {
+	case *evaluator.Text:
+		return rangeContains(d.size, &evaluator.Integer{Int: big.NewInt(int64(len(val.Text)))})
+	case *evaluator.Bytes:
+		return rangeContains(d.size, &evaluator.Integer{Int: big.NewInt(int64(len(val.Bytes)))})
+	}
+	return false
+}
+
+// samples returns instances of the domain used to look for counterexamples
+func (d domain) samples() []evaluator.Value {
+	candidates := []evaluator.Value{}
+	switch val := d.value.(type) {
+	case nil:
+	case *evaluator.Range:
+		candidates = append(candidates, val.From, val.To)
+		if i, ok := val.To.(*evaluator.Integer); ok && val.Exclusive {
+			candidates = append(candidates, &evaluator.Integer{Int: new(big.Int).Sub(i.Int, big.NewInt(1))})
+		}
+	default:
+		candidates = append(candidates, val)
+	}
+
+	ints := func(values ...*big.Int) {
+		for _, v := range values {
+			candidates = append(candidates, &evaluator.Integer{Int: v})
+		}
+	}
+	lengths := []int64{0, 1}
+	if d.size != nil {
+		for _, bound := range []evaluator.Value{d.size.From, d.size.To} {
+			if i, ok := bound.(*evaluator.Integer); ok && i.Int.IsInt64() {
+				lengths = append(lengths, i.Int.Int64(), i.Int.Int64()+1)
+			}
+		}
+	}
+
+	switch d.base {
+	case token.UINT:
+		ints(big.NewInt(0), big.NewInt(1), big.NewInt(256), maxUint)
+	case token.NINT:
+		ints(big.NewInt(-1), big.NewInt(-256), minNint)
+	case token.INT:
+		ints(big.NewInt(0), big.NewInt(-1), big.NewInt(256), maxUint, minNint)
+	case token.FLOAT, token.FLOAT16, token.FLOAT32, token.FLOAT64:
+		candidates = append(candidates, &evaluator.Float{Float: 0.5}, &evaluator.Float{Float: -1.5})
+	case token.TSTR:
+		for _, n := range lengths {
+			candidates = append(candidates, &evaluator.Text{Text: strings.Repeat("a", int(n))})
+		}
+	case token.BSTR:
+		for _, n := range lengths {
+			candidates = append(candidates, &evaluator.Bytes{Bytes: make([]byte, n)})
+		}
+	case token.BOOL:
+		candidates = append(candidates, &evaluator.Bool{Bool: false}, &evaluator.Bool{Bool: true})
+	}
+
+	out := []evaluator.Value{}
+	for _, v := range candidates {
+		if d.member(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// leafRelation returns the relation between two leaf domains
+func leafRelation(path string, a, b domain) *Result {
+	var outside evaluator.Value
+	inside := false
+	for _, v := range a.samples() {
+		if b.member(v) {
+			inside = true
+		} else if outside == nil {
+			outside = v
+		}
+	}
+
+	_, isRange := a.value.(*evaluator.Range)
+	contained := outside == nil && (a.value != nil && b.value != nil && valueContains(b.value, a.value) ||
+		a.value != nil && b.value == nil && (!isRange || b.size == nil) ||
+		a.value == nil && b.value == nil && tokenContains(b.base, a.base) && sizeContains(b.size, a.size))
+	if contained {
+		return subset()
+	}
+	if outside == nil {
+		return unknown(path, "cannot decide whether %s is within %s", a, b)
+	}
+	if !inside && !intersects(a, b) {
+		return &Result{Relation: Disjoint, Path: path, Reason: fmt.Sprintf("%s matches %s but not %s", outside, a, b)}
+	}
+	return &Result{Relation: Overlap, Path: path, Reason: fmt.Sprintf("%s matches %s but not %s", outside, a, b)}
+}
+
+// sizeContains returns true if every length allowed by inner is allowed by outer
+func sizeContains(outer, inner *evaluator.Range) bool {
+	if outer == nil {
+		return true
+	}
+	if inner == nil {
+		return false
+	}
+	return valueContains(outer, inner)
+}
+
+// intersects returns true if the domains may share a value the samples did not find
+func intersects(a, b domain) bool {
+	switch {
+	case a.value != nil && b.value != nil:
+		return valuesOverlap(a.value, b.value)
+	case a.value != nil:
+		return rangeIntersectsType(a.value, b)
+	case b.value != nil:
+		return rangeIntersectsType(b.value, a)
+	}
+	if !tokenContains(a.base, b.base) && !tokenContains(b.base, a.base) {
+		return false
+	}
+	return a.size == nil || b.size == nil || valuesOverlap(a.size, b.size)
+}
+
+// rangeIntersectsType returns true if a value of the range is matched by the domain
+func rangeIntersectsType(v evaluator.Value, d domain) bool {
+	r, ok := v.(*evaluator.Range)
+	if !ok {
+		return d.member(v)
+	}
+	if d.member(r.From) || d.member(r.To) {
+		return true
+	}
+	// an integer range spanning zero intersects both uint and nint
+	from, ok1 := r.From.(*evaluator.Integer)
+	to, ok2 := r.To.(*evaluator.Integer)
+	return ok1 && ok2 && from.Int.Sign() < 0 && to.Int.Sign() >= 0 && (d.base == token.UINT || d.base == token.NINT)
+}
+
+// valuesOverlap returns true if two constant values share a common value
+func valuesOverlap(a, b evaluator.Value) bool {
+	ra, aRange := a.(*evaluator.Range)
+	rb, bRange := b.(*evaluator.Range)
+	switch {
+	case aRange && bRange:
+		return rangeContains(rb, ra.From) || rangeContains(ra, rb.From)
+	case aRange:
+		return rangeContains(ra, b)
+	case bRange:
+		return rangeContains(rb, a)
+	}
+	return a.String() == b.String()
+}
@@ -0,0 +1,120 @@
+package compat_test
+
+import (
+	"testing"
+
+	"github.com/HannesKimara/cddlc/compat"
+)
+
+func TestSubsumes(t *testing.T) {
+	tests := []struct {
+		src    string
+		result string
+	}{
+		// primitives and literals
+		{"a = uint b = int", "subset"},
+		{"a = int b = uint", "overlap: at a: -1 matches int but not uint"},
+		{"a = uint b = nint", "disjoint: at a: 0 matches uint but not nint"},
+		{"a = uint b = tstr", "disjoint: at a: 0 matches uint but not tstr"},
+		{"a = float16 b = float", "subset"},
+		{`a = "x" b = tstr`, "subset"},
+		{"a = 3 b = uint", "subset"},
+		{"a = -3 b = uint", "disjoint: at a: -3 matches -3 but not uint"},
+		{"a = true b = bool", "subset"},
+		{"a = {x: uint} b = any", "subset"},
+		{"a = bool b = true", "overlap: at a: false matches bool but not true"},
+
+		// ranges
+		{"a = 0..10 b = 0..20", "subset"},
+		{"a = 0..20 b = 0..10", "overlap: at a: 20 matches 0..20 but not 0..10"},
+		{"a = 5..20 b = 0..3", "disjoint: at a: 5 matches 5..20 but not 0..3"},
+		{"a = 0...10 b = 0..9", "subset"},
+		{"a = 0..10 b = uint", "subset"},
+		{"a = -5..5 b = uint", "overlap: at a: -5 matches -5..5 but not uint"},
+		{"a = uint b = 0..255", "overlap: at a: 256 matches uint but not 0..255"},
+
+		// .size and comparisons
+		{"a = tstr .size (0..5) b = tstr .size (0..10)", "subset"},
+		{"a = tstr .size (0..10) b = tstr .size (0..5)", `overlap: at a: "aaaaaaaaaa" matches tstr .size 0..10 but not tstr .size 0..5`},
+		{"a = tstr .size 3 b = tstr", "subset"},
+		{"a = tstr b = tstr .size 3", `overlap: at a: "" matches tstr but not tstr .size 3`},
+		{`a = "abc" b = tstr .size (0..2)`, `disjoint: at a: "abc" matches "abc" but not tstr .size 0..2`},
+		{"a = uint .size 1 b = 0..255", "subset"},
+		{"a = uint .size 2 b = 0..255", "overlap: at a: 65535 matches 0..65535 but not 0..255"},
+		{"a = uint .le 10 b = 0..10", "subset"},
+		{`a = tstr .regexp "[a-z]+" b = tstr`, "subset"},
+		{`a = tstr .regexp "[a-z]+" b = uint`, "disjoint: at a: \"\" matches tstr but not uint"},
+
+		// choices
+		{`a = "x" / "y" b = tstr`, "subset"},
+		{`a = "x" b = "x" / "y"`, "subset"},
+		{`a = "x" / "z" b = "x" / "y"`, `overlap: at a: "z" matches "z" but none of the alternatives`},
+		{"a = uint b = 0..10 / 11..20", "overlap: at a: 256 matches uint but none of the alternatives"},
+		{"a = 1 / 2 b = tstr / bool", "disjoint: at a: 1 matches 1 but none of the alternatives"},
+
+		// maps and groups
+		{"a = {x: uint} b = {x: int}", "subset"},
+		{"a = {x: uint} b = {x: int, ? y: tstr}", "subset"},
+		{"a = {x: int} b = {x: uint}", "overlap: at a.x: -1 matches int but not uint"},
+		{"a = {x: tstr} b = {x: uint}", "disjoint: at a.x: \"\" matches tstr but not uint"},
+		{"a = {? x: tstr} b = {? x: uint}", "overlap: at a.x: \"\" matches tstr but not uint"},
+		{"a = {x: uint, y: uint} b = {x: uint}", "disjoint: at a.y: member y is required but not allowed"},
+		{"a = {x: uint, ? y: uint} b = {x: uint}", "overlap: at a.y: member y is optional but not allowed"},
+		{"a = {x: uint} b = {x: uint, y: uint}", "disjoint: at a.y: required member y is missing"},
+		{"a = {? x: uint} b = {x: uint}", "overlap: at a.x: occurrence ? is not within 1"},
+		{"a = {x: uint} b = {? x: uint}", "subset"},
+		{"a = {~c} b = {x: uint, y: tstr} c = (x: uint, y: tstr)", "subset"},
+		{"a = {x: {y: 0..5}} b = {x: {y: uint}}", "subset"},
+
+		// arrays
+		{"a = [uint, tstr] b = [int, tstr]", "subset"},
+		{"a = [uint] b = [uint, uint]", "disjoint: at a: arrays of 1 entries are not arrays of 2 entries"},
+		{"a = [uint] b = [uint, ? tstr]", "subset"},
+		{"a = [+ uint] b = [* int]", "subset"},
+		{"a = [* uint] b = [+ uint]", "overlap: at a[0]: occurrence * is not within +"},
+		{"a = [uint] b = {x: uint}", "disjoint: at a: array is not a map"},
+
+		// tags and recursion
+		{"a = #6.1(uint) b = #6.1(int)", "subset"},
+		{"a = #6.1(uint) b = #6.2(uint)", "disjoint: at a: tag #6.1 is not tag #6.2"},
+		{"a = [* a] b = [* b]", "subset"},
+		{"a = [* a] b = [* b / uint]", "subset"},
+	}
+
+	for _, tst := range tests {
+		cddl := parse(t, tst.src)
+		result, err := compat.Subsumes(cddl, "a", cddl, "b")
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.src, err)
+			continue
+		}
+		if result.String() != tst.result {
+			t.Errorf("%s: expected `%s` got `%s`", tst.src, tst.result, result)
+		}
+	}
+}
+
+func TestSubsumesAcrossSchemas(t *testing.T) {
+	base := parse(t, `message = {type: tstr, ? body: bstr, ? ttl: uint}`)
+	profile := parse(t, `message = {type: "ping" / "pong", ttl: 0..60}`)
+
+	result, err := compat.Subsumes(profile, "message", base, "message")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if result.Relation != compat.Subset {
+		t.Errorf("expected profile to be a subset of the base got %s", result)
+	}
+
+	result, err = compat.Subsumes(base, "message", profile, "message")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if result.Relation == compat.Subset {
+		t.Errorf("expected base not to be a subset of the profile")
+	}
+
+	if _, err := compat.Subsumes(base, "missing", profile, "message"); err == nil {
+		t.Errorf("expected an error for an undefined rule")
+	}
+}
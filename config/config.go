@@ -56,11 +56,19 @@ type BuildConfig struct {
 	// Exclude   []string `json:"exclude" yaml:"exclude"`
 }
 
+// LintConfig configures the checks run by `cddlc lint`
+type LintConfig struct {
+	// Rules maps check names to their severity; one of error, warning or off.
+	// Checks not listed run with their default severity.
+	Rules map[string]string `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
 // Config contains configuration values for the cddlc tool
 type Config struct {
 	Version string         `json:"version" yaml:"version"`
 	Plugins []*Plugin      `json:"plugins" yaml:"plugins"`
 	Builds  []*BuildConfig `json:"builds" yaml:"builds"`
+	Lint    LintConfig     `json:"lint" yaml:"lint"`
 
 	Options map[string]interface{} `json:"options" yaml:"options"`
 }
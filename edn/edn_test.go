@@ -0,0 +1,182 @@
+package edn_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/edn"
+)
+
+func TestToCBOR(t *testing.T) {
+	tests := []struct {
+		edn string
+		hex string
+	}{
+		{"0", "00"},
+		{"1000", "1903e8"},
+		{"-1", "20"},
+		{"-18446744073709551616", "3bffffffffffffffff"},
+		{"0x1f", "181f"},
+		{"0b101", "05"},
+		{"-0o17", "2e"},
+		{"1.5", "f93e00"},
+		{"100000.0", "fa47c35000"},
+		{"1.1", "fb3ff199999999999a"},
+		{"-4.0", "f9c400"},
+		{"1e300", "fb7e37e43c8800759c"},
+		{"NaN", "f97e00"},
+		{"-Infinity", "f9fc00"},
+		{"true", "f5"},
+		{"null", "f6"},
+		{"undefined", "f7"},
+		{"simple(16)", "f0"},
+		{"simple(255)", "f8ff"},
+		{`"IETF"`, "6449455446"},
+		{`"ü😀\n"`, "67c3bcf09f98800a"},
+		{"h'01 02 / two / 03'", "43010203"},
+		{"b64'AQID'", "43010203"},
+		{"b64'-_8='", "42fbff"},
+		{"b32'AEBAG'", "43010203"},
+		{"h32'04106'", "43010203"},
+		{`'a\'b'`, "43612762"},
+		{`"ab" "cd"`, "6461626364"},
+		{"h'01' h'02'", "420102"},
+		{"[1, [2, 3], [4, 5]]", "8301820203820405"},
+		{"[1, 2,]", "820102"},
+		{`{"a": 1, "b": [2, 3]}`, "a26161016162820203"},
+		{"32(\"http://x\")", "d82068687474703a2f2f78"},
+		{"<<1, 2>>", "420102"},
+		{"24(<<[1]>>)", "d818428101"},
+
+		// encoding indicators
+		{"1_0", "1801"},
+		{"1_1", "190001"},
+		{"1_2", "1a00000001"},
+		{"1_3", "1b0000000000000001"},
+		{"-1_0", "3800"},
+		{"1.5_2", "fa3fc00000"},
+		{"1.5_3", "fb3ff8000000000000"},
+		{"[_ 1, 2]", "9f0102ff"},
+		{"[_]", "9fff"},
+		{"[_0 1]", "980101"},
+		{"{_ 1: 2}", "bf0102ff"},
+		{"{_1}", "b90000"},
+		{`"a"_0`, "780161"},
+		{"(_ h'01', h'0203')", "5f41014202 03ff"},
+		{`(_ "a", "bc")`, "7f61616262 63ff"},
+		{"1_1(2)", "d9000102"},
+
+		// comments
+		{"# a comment\n[1, / inline / 2]", "820102"},
+	}
+
+	for _, tst := range tests {
+		data, err := edn.ToCBOR([]byte(tst.edn))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.edn, err)
+			continue
+		}
+		if expected := strings.ReplaceAll(tst.hex, " ", ""); hex.EncodeToString(data) != expected {
+			t.Errorf("%s: expected %s got %x", tst.edn, expected, data)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		hex string
+		edn string
+	}{
+		{"1903e8", "1000"},
+		{"3863", "-100"},
+		{"f93e00", "1.5"},
+		{"fa3fc00000", "1.5_2"},
+		{"fb3ff199999999999a", "1.1"},
+		{"f97c00", "Infinity"},
+		{"4401020304", "h'01020304'"},
+		{"6449455446", `"IETF"`},
+		{"6322000a", `"\"\u0000\n"`},
+		{"a26161016162820203", `{"a": 1, "b": [2, 3]}`},
+		{"c11a514b67b0", "1(1363896240)"},
+		{"f0", "simple(16)"},
+
+		// encoding indicators
+		{"1801", "1_0"},
+		{"190001", "1_1"},
+		{"9f0102ff", "[_ 1, 2]"},
+		{"980101", "[_0 1]"},
+		{"bfff", "{_}"},
+		{"5f41014202 03ff", "(_ h'01', h'0203')"},
+		{"7f6161ff", `(_ "a")`},
+		{"d9000102", "1_1(2)"},
+	}
+
+	for _, tst := range tests {
+		data, err := hex.DecodeString(strings.ReplaceAll(tst.hex, " ", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := edn.FromCBOR(data)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.hex, err)
+			continue
+		}
+		if out != tst.edn {
+			t.Errorf("%s: expected `%s` got `%s`", tst.hex, tst.edn, out)
+		}
+
+		// printing and parsing keeps the encoding
+		back, err := edn.ToCBOR([]byte(out))
+		if err != nil {
+			t.Errorf("%s: cannot parse the output: %s", out, err)
+			continue
+		}
+		if hex.EncodeToString(back) != hex.EncodeToString(data) {
+			t.Errorf("%s: expected the encoding %x got %x", out, data, back)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		edn string
+		err string
+	}{
+		{"", "edn: expected a single data item, found 0 at line 1, column 1"},
+		{"1, 2", "edn: expected a single data item, found 2 at line 1, column 1"},
+		{"[1, 2", "edn: expected , or ] at line 1, column 6"},
+		{"{1 2}", "edn: expected : after map key at line 1, column 4"},
+		{`"abc`, "edn: unterminated string at line 1, column 5"},
+		{"h'0'", "edn: invalid h byte string: encoding/hex: odd length hex string at line 1, column 2"},
+		{"foo", "edn: unknown name foo at line 1, column 4"},
+		{"18446744073709551616", "edn: integer 18446744073709551616 is out of range at line 1, column 1"},
+		{"256_0", "edn: integer 256 does not fit encoding indicator at line 1, column 1"},
+		{"1_4", "edn: unsupported encoding indicator _4 at line 1, column 4"},
+		{`"a" h'01'`, "edn: cannot concatenate a text string and a byte string at line 1, column 10"},
+		{`(_ "a", h'01')`, "edn: chunks of an indefinite length string must be of the same type at line 1, column 14"},
+		{"simple(24)", "edn: invalid simple value 24 at line 1, column 10"},
+		{"[1]\n]", "edn: unexpected ']' at line 2, column 1"},
+	}
+
+	for _, tst := range tests {
+		_, err := edn.Parse([]byte(tst.edn))
+		if err == nil {
+			t.Errorf("%s: expected error `%s`", tst.edn, tst.err)
+			continue
+		}
+		if err.Error() != tst.err {
+			t.Errorf("%s: expected `%s` got `%s`", tst.edn, tst.err, err)
+		}
+	}
+}
+
+func TestParseSequence(t *testing.T) {
+	items, err := edn.ParseSequence([]byte("1, \"a\", [2]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || edn.Print(items[2]) != "[2]" {
+		t.Errorf("unexpected sequence %v", items)
+	}
+}
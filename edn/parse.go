@@ -0,0 +1,709 @@
+// Package edn implements the Extended Diagnostic Notation of CBOR described in
+// https://www.rfc-editor.org/rfc/rfc8949#section-8 and
+// https://www.rfc-editor.org/rfc/rfc8610#appendix-G.
+//
+// Parse reads diagnostic notation into data items that encode to CBOR with cbor.Encode,
+// keeping encoding indicators such as `_1` and indefinite lengths. Print writes data items
+// back to diagnostic notation.
+package edn
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+// SyntaxError describes malformed diagnostic notation
+type SyntaxError struct {
+	Line, Column int
+
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("edn: %s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
+
+// Parse reads a single data item in diagnostic notation
+func Parse(src []byte) (*cbor.Item, error) {
+	items, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) != 1 {
+		return nil, &SyntaxError{Line: 1, Column: 1, Msg: fmt.Sprintf("expected a single data item, found %d", len(items))}
+	}
+	return items[0], nil
+}
+
+// ParseSequence reads a sequence of data items separated by commas
+func ParseSequence(src []byte) ([]*cbor.Item, error) {
+	return parse(src)
+}
+
+// ToCBOR converts a data item in diagnostic notation to its encoding
+func ToCBOR(src []byte) ([]byte, error) {
+	item, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Encode(item), nil
+}
+
+func parse(src []byte) ([]*cbor.Item, error) {
+	p := &parser{src: src, line: 1, col: 1}
+	items, err := p.sequence(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.off < len(p.src) {
+		return nil, p.error("unexpected %q", p.peekRune())
+	}
+	return items, nil
+}
+
+// parser reads diagnostic notation from a byte slice
+type parser struct {
+	src       []byte
+	off       int
+	line, col int
+}
+
+func (p *parser) error(format string, args ...interface{}) error {
+	return &SyntaxError{Line: p.line, Column: p.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) peekRune() rune {
+	r, _ := utf8.DecodeRune(p.src[p.off:])
+	return r
+}
+
+func (p *parser) next() rune {
+	r, size := utf8.DecodeRune(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line, p.col = p.line+1, 1
+	} else {
+		p.col++
+	}
+	return r
+}
+
+func (p *parser) peek(s string) bool {
+	return strings.HasPrefix(string(p.src[p.off:]), s)
+}
+
+// consume skips s if the input continues with it
+func (p *parser) consume(s string) bool {
+	if !p.peek(s) {
+		return false
+	}
+	for range s {
+		p.next()
+	}
+	return true
+}
+
+// skip skips whitespace, `/ block /` comments and `#` comments to the end of the line
+func (p *parser) skip() {
+	for p.off < len(p.src) {
+		switch p.src[p.off] {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		case '/':
+			p.next()
+			for p.off < len(p.src) && p.next() != '/' {
+			}
+		case '#':
+			for p.off < len(p.src) && p.next() != '\n' {
+			}
+		default:
+			return
+		}
+	}
+}
+
+// sequence reads items separated by commas up to the end of the input or a closing
+// delimiter. A trailing comma is allowed.
+func (p *parser) sequence(depth int) ([]*cbor.Item, error) {
+	items := []*cbor.Item{}
+	for {
+		p.skip()
+		if p.off >= len(p.src) || p.peek("]") || p.peek(")") || p.peek(">>") {
+			return items, nil
+		}
+		item, err := p.item(depth)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skip()
+		if !p.consume(",") {
+			return items, nil
+		}
+	}
+}
+
+func (p *parser) item(depth int) (*cbor.Item, error) {
+	if depth > cbor.MaxDepth {
+		return nil, p.error("maximum nesting depth %d exceeded", cbor.MaxDepth)
+	}
+
+	p.skip()
+	if p.off >= len(p.src) {
+		return nil, p.error("unexpected end of input")
+	}
+	switch c := p.src[p.off]; {
+	case c == '[':
+		return p.array(depth)
+	case c == '{':
+		return p.mapItem(depth)
+	case p.peek("(_"):
+		return p.indefiniteString()
+	case p.peek("<<"):
+		return p.embedded(depth)
+	case c == '"' || c == '\'' || p.peek("h'") || p.peek("b64'") || p.peek("b32'") || p.peek("h32'"):
+		return p.strings()
+	case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+		return p.number(depth)
+	}
+
+	word := p.word()
+	switch word {
+	case "false":
+		return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleFalse}, nil
+	case "true":
+		return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleTrue}, nil
+	case "null":
+		return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleNull}, nil
+	case "undefined":
+		return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleUndefined}, nil
+	case "NaN", "Infinity":
+		value := math.NaN()
+		if word == "Infinity" {
+			value = math.Inf(1)
+		}
+		return p.float(value)
+	case "simple":
+		return p.simple()
+	case "":
+		return nil, p.error("unexpected %q", p.peekRune())
+	}
+	return nil, p.error("unknown name %s", word)
+}
+
+// word reads a name made of letters and digits starting with a letter
+func (p *parser) word() string {
+	start := p.off
+	for p.off < len(p.src) {
+		c := p.src[p.off]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || (p.off > start && c >= '0' && c <= '9')) {
+			break
+		}
+		p.next()
+	}
+	return string(p.src[start:p.off])
+}
+
+// indicator reads an encoding indicator `_n` and returns the argument size it denotes, or
+// -1 for `_` alone marking an indefinite length. It returns 0 without an indicator.
+func (p *parser) indicator() (int, error) {
+	if !p.peek("_") {
+		return 0, nil
+	}
+	p.next()
+	if p.off >= len(p.src) || p.src[p.off] < '0' || p.src[p.off] > '9' {
+		return -1, nil
+	}
+	n := p.next() - '0'
+	if n > 3 {
+		return 0, p.error("unsupported encoding indicator _%d", n)
+	}
+	return 1 << n, nil
+}
+
+func (p *parser) array(depth int) (*cbor.Item, error) {
+	p.next()
+	item := &cbor.Item{Major: cbor.MajorArray, Items: []*cbor.Item{}}
+	if err := p.containerIndicator(item); err != nil {
+		return nil, err
+	}
+	items, err := p.sequence(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume("]") {
+		return nil, p.error("expected , or ]")
+	}
+	item.Items = items
+	if !fits(uint64(len(items)), item.ArgSize) {
+		return nil, p.error("array length %d does not fit encoding indicator", len(items))
+	}
+	return item, nil
+}
+
+func (p *parser) mapItem(depth int) (*cbor.Item, error) {
+	p.next()
+	item := &cbor.Item{Major: cbor.MajorMap, Pairs: []*cbor.Pair{}}
+	if err := p.containerIndicator(item); err != nil {
+		return nil, err
+	}
+	for {
+		p.skip()
+		if p.consume("}") {
+			if !fits(uint64(len(item.Pairs)), item.ArgSize) {
+				return nil, p.error("map length %d does not fit encoding indicator", len(item.Pairs))
+			}
+			return item, nil
+		}
+		key, err := p.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		p.skip()
+		if !p.consume(":") {
+			return nil, p.error("expected : after map key")
+		}
+		value, err := p.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		item.Pairs = append(item.Pairs, &cbor.Pair{Key: key, Value: value})
+		p.skip()
+		if !p.consume(",") && !p.peek("}") {
+			return nil, p.error("expected , or }")
+		}
+	}
+}
+
+// containerIndicator reads the encoding indicator after the opening bracket of arrays and maps
+func (p *parser) containerIndicator(item *cbor.Item) error {
+	size, err := p.indicator()
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		item.Indefinite = true
+	} else {
+		item.ArgSize = size
+	}
+	return nil
+}
+
+// indefiniteString reads `(_ chunk, ...)` of either byte or text strings
+func (p *parser) indefiniteString() (*cbor.Item, error) {
+	p.consume("(_")
+	item := &cbor.Item{Indefinite: true, Chunks: []*cbor.Item{}}
+	content := []byte{}
+	for {
+		p.skip()
+		if p.consume(")") {
+			break
+		}
+		chunk, err := p.strings()
+		if err != nil {
+			return nil, err
+		}
+		if len(item.Chunks) == 0 {
+			item.Major = chunk.Major
+		} else if chunk.Major != item.Major {
+			return nil, p.error("chunks of an indefinite length string must be of the same type")
+		}
+		item.Chunks = append(item.Chunks, chunk)
+		content = append(content, chunk.Bytes...)
+		content = append(content, chunk.Text...)
+		p.skip()
+		if !p.consume(",") && !p.peek(")") {
+			return nil, p.error("expected , or )")
+		}
+	}
+	if len(item.Chunks) == 0 {
+		// the chunk type of an empty indefinite string is unknown; assume a byte string
+		item.Major = cbor.MajorBytes
+	}
+	if item.Major == cbor.MajorText {
+		item.Text = string(content)
+	} else {
+		item.Bytes = content
+	}
+	return item, nil
+}
+
+// embedded reads `<< item, ... >>`, a byte string holding the encoding of the items
+func (p *parser) embedded(depth int) (*cbor.Item, error) {
+	p.consume("<<")
+	items, err := p.sequence(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume(">>") {
+		return nil, p.error("expected , or >>")
+	}
+	content := []byte{}
+	for _, item := range items {
+		content = append(content, cbor.Encode(item)...)
+	}
+	return &cbor.Item{Major: cbor.MajorBytes, Bytes: content}, nil
+}
+
+// strings reads a string followed by an encoding indicator. Adjacent strings of the same
+// type are concatenated.
+func (p *parser) strings() (*cbor.Item, error) {
+	var item *cbor.Item
+	for {
+		major, content, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case item == nil:
+			item = &cbor.Item{Major: major}
+		case item.Major != major:
+			return nil, p.error("cannot concatenate a %s and a %s", item.Major, major)
+		}
+		if major == cbor.MajorText {
+			item.Text += string(content)
+		} else {
+			item.Bytes = append(item.Bytes, content...)
+		}
+
+		size, err := p.indicator()
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, p.error("use (_ ...) for indefinite length strings")
+		}
+		item.ArgSize = size
+		if !fits(uint64(len(item.Bytes)+len(item.Text)), size) {
+			return nil, p.error("string length does not fit encoding indicator")
+		}
+
+		p.skip()
+		if p.off >= len(p.src) || !(p.src[p.off] == '"' || p.src[p.off] == '\'' || p.peek("h'") || p.peek("b64'") || p.peek("b32'") || p.peek("h32'")) {
+			break
+		}
+	}
+	if item.Major == cbor.MajorBytes && item.Bytes == nil {
+		item.Bytes = []byte{}
+	}
+	return item, nil
+}
+
+// stringLiteral reads a single text string or byte string literal
+func (p *parser) stringLiteral() (cbor.Major, []byte, error) {
+	if p.src[p.off] == '"' {
+		text, err := p.quoted('"')
+		return cbor.MajorText, []byte(text), err
+	}
+	if p.src[p.off] == '\'' {
+		text, err := p.quoted('\'')
+		return cbor.MajorBytes, []byte(text), err
+	}
+
+	prefix := p.word()
+	line, col := p.line, p.col
+	p.next()
+	start := p.off
+	for p.off < len(p.src) && p.src[p.off] != '\'' {
+		p.next()
+	}
+	if p.off >= len(p.src) {
+		return 0, nil, p.error("unterminated byte string")
+	}
+	body := string(p.src[start:p.off])
+	p.next()
+
+	var (
+		content []byte
+		err     error
+	)
+	switch prefix {
+	case "h":
+		content, err = hex.DecodeString(stripHex(body))
+	case "b64":
+		content, err = decodeBase64(strings.Join(strings.Fields(body), ""))
+	case "b32":
+		content, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(strings.Join(strings.Fields(body), ""), "="))
+	case "h32":
+		content, err = base32.HexEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(strings.Join(strings.Fields(body), ""), "="))
+	}
+	if err != nil {
+		return 0, nil, &SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf("invalid %s byte string: %s", prefix, err)}
+	}
+	return cbor.MajorBytes, content, nil
+}
+
+// stripHex removes whitespace and `/ comments /` from the body of a hex byte string
+func stripHex(body string) string {
+	var sb strings.Builder
+	comment := false
+	for _, r := range body {
+		switch {
+		case r == '/':
+			comment = !comment
+		case comment, r == ' ', r == '\t', r == '\r', r == '\n':
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// decodeBase64 accepts the standard and URL alphabets with or without padding
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// quoted reads a string in quotes with the escapes of JSON
+func (p *parser) quoted(quote rune) (string, error) {
+	p.next()
+	var sb strings.Builder
+	for {
+		if p.off >= len(p.src) {
+			return "", p.error("unterminated string")
+		}
+		r := p.next()
+		switch r {
+		case quote:
+			return sb.String(), nil
+		case '\\':
+			if p.off >= len(p.src) {
+				return "", p.error("unterminated string")
+			}
+			switch e := p.next(); e {
+			case '"', '\'', '\\', '/':
+				sb.WriteRune(e)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				r, err := p.unicodeEscape()
+				if err != nil {
+					return "", err
+				}
+				sb.WriteRune(r)
+			default:
+				return "", p.error("invalid escape \\%c", e)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+// unicodeEscape reads the digits of `\uXXXX`, combining surrogate pairs
+func (p *parser) unicodeEscape() (rune, error) {
+	hex4 := func() (rune, error) {
+		if p.off+4 > len(p.src) {
+			return 0, p.error("invalid unicode escape")
+		}
+		n, err := strconv.ParseUint(string(p.src[p.off:p.off+4]), 16, 16)
+		if err != nil {
+			return 0, p.error("invalid unicode escape")
+		}
+		for i := 0; i < 4; i++ {
+			p.next()
+		}
+		return rune(n), nil
+	}
+
+	r, err := hex4()
+	if err != nil || !utf16.IsSurrogate(r) {
+		return r, err
+	}
+	if !p.consume("\\u") {
+		return 0, p.error("unpaired surrogate in unicode escape")
+	}
+	low, err := hex4()
+	if err != nil {
+		return 0, err
+	}
+	combined := utf16.DecodeRune(r, low)
+	if combined == utf8.RuneError {
+		return 0, p.error("invalid surrogate pair in unicode escape")
+	}
+	return combined, nil
+}
+
+// number reads integers, floats and tags
+func (p *parser) number(depth int) (*cbor.Item, error) {
+	start := p.off
+	line, col := p.line, p.col
+	for p.off < len(p.src) && strings.IndexByte("+-0123456789abcdefABCDEFxXoO.pP", p.src[p.off]) >= 0 {
+		// a sign only follows an exponent
+		if c := p.src[p.off]; (c == '+' || c == '-') && p.off > start && !strings.ContainsRune("eEpP", rune(p.src[p.off-1])) {
+			break
+		}
+		p.next()
+	}
+	literal := string(p.src[start:p.off])
+
+	if literal == "-" && p.peek("Infinity") {
+		p.word()
+		return p.float(math.Inf(-1))
+	}
+	errorAt := func(format string, args ...interface{}) error {
+		return &SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	if n, ok := parseInteger(literal); ok {
+		size, err := p.indicator()
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, errorAt("indefinite length integer")
+		}
+
+		var item *cbor.Item
+		switch {
+		case n.Sign() >= 0 && n.IsUint64():
+			item = &cbor.Item{Major: cbor.MajorUint, Arg: n.Uint64()}
+		case n.Sign() < 0:
+			arg := new(big.Int).Neg(n)
+			arg.Sub(arg, big.NewInt(1))
+			if !arg.IsUint64() {
+				return nil, errorAt("integer %s is out of range", literal)
+			}
+			item = &cbor.Item{Major: cbor.MajorNint, Arg: arg.Uint64()}
+		default:
+			return nil, errorAt("integer %s is out of range", literal)
+		}
+		item.ArgSize = size
+		if !fits(item.Arg, size) {
+			return nil, errorAt("integer %s does not fit encoding indicator", literal)
+		}
+
+		p.skip()
+		if !p.peek("(") {
+			return item, nil
+		}
+		if item.Major != cbor.MajorUint {
+			return nil, errorAt("tag number %s is negative", literal)
+		}
+		p.next()
+		content, err := p.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		p.skip()
+		if !p.consume(")") {
+			return nil, p.error("expected ) after tag content")
+		}
+		return &cbor.Item{Major: cbor.MajorTag, Arg: item.Arg, ArgSize: size, Content: content}, nil
+	}
+
+	value, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, errorAt("invalid number %s", literal)
+	}
+	return p.float(value)
+}
+
+// fits reports whether an argument can be encoded in size bytes. Zero is the shortest form.
+func fits(arg uint64, size int) bool {
+	return size == 0 || size == 8 || arg < 1<<(8*size)
+}
+
+// parseInteger parses decimal integers and integers with the prefixes 0x, 0o and 0b
+func parseInteger(literal string) (*big.Int, bool) {
+	digits := strings.TrimPrefix(literal, "-")
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			digits = digits[2:]
+		}
+	}
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return nil, false
+	}
+	if strings.HasPrefix(literal, "-") {
+		n.Neg(n)
+	}
+	return n, true
+}
+
+// float completes a float with its encoding indicator. Floats without one take the
+// shortest width that preserves their value.
+func (p *parser) float(value float64) (*cbor.Item, error) {
+	size, err := p.indicator()
+	if err != nil {
+		return nil, err
+	}
+	item := &cbor.Item{Major: cbor.MajorSimple, Float: value, Width: shortestWidth(value)}
+	switch size {
+	case 0:
+	case 2, 4, 8:
+		item.Width = size * 8
+	default:
+		return nil, p.error("invalid encoding indicator for a float")
+	}
+	return item, nil
+}
+
+// shortestWidth returns the smallest float width that represents the value exactly
+func shortestWidth(f float64) int {
+	switch {
+	case math.IsNaN(f) || math.IsInf(f, 0):
+		return 16
+	case float64(float32(f)) != f:
+		return 64
+	}
+	item, _ := cbor.Decode(cbor.Encode(&cbor.Item{Major: cbor.MajorSimple, Float: f, Width: 16}))
+	if item != nil && item.Float == f {
+		return 16
+	}
+	return 32
+}
+
+// simple reads `simple(n)`
+func (p *parser) simple() (*cbor.Item, error) {
+	p.skip()
+	if !p.consume("(") {
+		return nil, p.error("expected ( after simple")
+	}
+	p.skip()
+	start := p.off
+	for p.off < len(p.src) && p.src[p.off] >= '0' && p.src[p.off] <= '9' {
+		p.next()
+	}
+	n, err := strconv.ParseUint(string(p.src[start:p.off]), 10, 8)
+	if err != nil || (n >= 24 && n < 32) {
+		return nil, p.error("invalid simple value %s", p.src[start:p.off])
+	}
+	p.skip()
+	if !p.consume(")") {
+		return nil, p.error("expected ) after simple value")
+	}
+	return &cbor.Item{Major: cbor.MajorSimple, Arg: n}, nil
+}
@@ -0,0 +1,192 @@
+package edn
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+// Print formats a data item in diagnostic notation. Encoding indicators are written for
+// arguments encoded in a longer form than needed, for floats wider than needed to keep
+// their value and for indefinite lengths, so that parsing the output gives the same
+// encoding.
+func Print(item *cbor.Item) string {
+	var sb strings.Builder
+	format(&sb, item)
+	return sb.String()
+}
+
+// FromCBOR decodes a single data item and formats it in diagnostic notation
+func FromCBOR(data []byte) (string, error) {
+	item, err := cbor.Decode(data)
+	if err != nil {
+		return "", err
+	}
+	return Print(item), nil
+}
+
+func format(sb *strings.Builder, item *cbor.Item) {
+	switch item.Major {
+	case cbor.MajorUint, cbor.MajorNint:
+		sb.WriteString(item.Int().String())
+		writeIndicator(sb, item.ArgSize)
+	case cbor.MajorBytes, cbor.MajorText:
+		if !item.Indefinite {
+			formatString(sb, item)
+			break
+		}
+		sb.WriteString("(_ ")
+		chunks := item.Chunks
+		if chunks == nil {
+			chunks = []*cbor.Item{{Major: item.Major, Bytes: item.Bytes, Text: item.Text}}
+		}
+		for n, chunk := range chunks {
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+			formatString(sb, chunk)
+		}
+		sb.WriteByte(')')
+	case cbor.MajorArray:
+		sb.WriteByte('[')
+		writeContainerIndicator(sb, item, len(item.Items))
+		for n, elem := range item.Items {
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+			format(sb, elem)
+		}
+		sb.WriteByte(']')
+	case cbor.MajorMap:
+		sb.WriteByte('{')
+		writeContainerIndicator(sb, item, len(item.Pairs))
+		for n, pair := range item.Pairs {
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+			format(sb, pair.Key)
+			sb.WriteString(": ")
+			format(sb, pair.Value)
+		}
+		sb.WriteByte('}')
+	case cbor.MajorTag:
+		sb.WriteString(strconv.FormatUint(item.Arg, 10))
+		writeIndicator(sb, item.ArgSize)
+		sb.WriteByte('(')
+		if item.Content != nil {
+			format(sb, item.Content)
+		}
+		sb.WriteByte(')')
+	case cbor.MajorSimple:
+		formatSimple(sb, item)
+	}
+}
+
+func writeIndicator(sb *strings.Builder, size int) {
+	switch size {
+	case 1:
+		sb.WriteString("_0")
+	case 2:
+		sb.WriteString("_1")
+	case 4:
+		sb.WriteString("_2")
+	case 8:
+		sb.WriteString("_3")
+	}
+}
+
+// writeContainerIndicator writes the indicator after the opening bracket of arrays and maps
+func writeContainerIndicator(sb *strings.Builder, item *cbor.Item, length int) {
+	switch {
+	case item.Indefinite:
+		sb.WriteByte('_')
+	case item.ArgSize != 0:
+		writeIndicator(sb, item.ArgSize)
+	default:
+		return
+	}
+	if length > 0 {
+		sb.WriteByte(' ')
+	}
+}
+
+func formatString(sb *strings.Builder, item *cbor.Item) {
+	if item.Major == cbor.MajorText {
+		sb.WriteString(quote(item.Text))
+	} else {
+		sb.WriteString("h'" + hex.EncodeToString(item.Bytes) + "'")
+	}
+	writeIndicator(sb, item.ArgSize)
+}
+
+// quote returns a text string in double quotes with the escapes of JSON
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func formatSimple(sb *strings.Builder, item *cbor.Item) {
+	if !item.IsFloat() {
+		switch item.Arg {
+		case cbor.SimpleFalse:
+			sb.WriteString("false")
+		case cbor.SimpleTrue:
+			sb.WriteString("true")
+		case cbor.SimpleNull:
+			sb.WriteString("null")
+		case cbor.SimpleUndefined:
+			sb.WriteString("undefined")
+		default:
+			fmt.Fprintf(sb, "simple(%d)", item.Arg)
+		}
+		return
+	}
+
+	switch {
+	case math.IsNaN(item.Float):
+		sb.WriteString("NaN")
+	case math.IsInf(item.Float, 1):
+		sb.WriteString("Infinity")
+	case math.IsInf(item.Float, -1):
+		sb.WriteString("-Infinity")
+	default:
+		s := strconv.FormatFloat(item.Float, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		sb.WriteString(s)
+	}
+	if item.Width != shortestWidth(item.Float) {
+		writeIndicator(sb, item.Width/8)
+	}
+}
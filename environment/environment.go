@@ -1,46 +1,166 @@
 // Package environment implements a symbol table for the CDDL parser and compiler.
+//
+// Environments are nested. Lookups start in the innermost scope and continue in the
+// enclosing scopes, allowing generic parameters to shadow global names within their rule.
+// Imported modules are kept in separate namespaces on the outermost environment.
 
 package environment
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/prelude"
+	"github.com/HannesKimara/cddlc/token"
 )
 
 var (
 	ErrSymbolExists = errors.New("symbol already exists")
+	ErrModuleExists = errors.New("module already exists")
 )
 
+// Symbol holds a declared name and where it was declared
+type Symbol struct {
+	// Name: the declared identifier
+	Name string
+
+	// Value: the node the name is bound to
+	Value ast.Node
+
+	// Pos: the position of the declaration
+	Pos token.Position
+
+	// File: the source file containing the declaration. Empty for sources that are not files
+	File string
+}
+
 type Environment struct {
-	symbols map[string]ast.Node
+	// parent is the enclosing scope. nil for the global scope
+	parent *Environment
+
+	symbols map[string]*Symbol
+
+	// modules holds the namespaces of imported modules. Only set on the global scope
+	modules map[string]*Environment
 }
 
-// Add a new symbol to the symbol table with a pointer to its Node
+// Add a new symbol to the symbol table with a pointer to its Node. As with Declare, the
+// symbol is positioned at the declared name: the name of a rule or an identifier. Other
+// nodes leave the position unknown rather than pointing at the value.
 func (e *Environment) Add(ident string, value ast.Node) error {
-	if e.Exists(ident) {
+	sym := &Symbol{Name: ident, Value: value}
+	switch val := value.(type) {
+	case *ast.Rule:
+		if val.Name != nil {
+			sym.Pos = val.Name.Pos
+		}
+	case *ast.Identifier:
+		sym.Pos = val.Pos
+	}
+	sym.File = sym.Pos.Filename
+	return e.Declare(sym)
+}
+
+// Declare adds a symbol to the current scope. It returns ErrSymbolExists if the name is
+// already declared in the current scope. Names declared in enclosing scopes are shadowed.
+func (e *Environment) Declare(sym *Symbol) error {
+	if _, ok := e.symbols[sym.Name]; ok {
 		return ErrSymbolExists
 	}
-	e.symbols[ident] = value
+	e.symbols[sym.Name] = sym
 	return nil
 }
 
-// Exists checks whether the symbol exists in the symbol table
+// Exists checks whether the symbol exists in the symbol table or any enclosing scope
 func (e *Environment) Exists(ident string) bool {
+	_, ok := e.Lookup(ident)
+	return ok
+}
+
+// ExistsInScope checks whether the symbol is declared in the current scope
+func (e *Environment) ExistsInScope(ident string) bool {
 	_, ok := e.symbols[ident]
 	return ok
 }
 
 func (e *Environment) Get(ident string) ast.Node {
-	if e.Exists(ident) {
-		return e.symbols[ident]
+	if sym, ok := e.Lookup(ident); ok {
+		return sym.Value
 	}
 	return nil
 }
 
+// Lookup returns the innermost symbol declared with the name
+func (e *Environment) Lookup(ident string) (*Symbol, bool) {
+	for scope := e; scope != nil; scope = scope.parent {
+		if sym, ok := scope.symbols[ident]; ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+// Parent returns the enclosing scope or nil for the global scope
+func (e *Environment) Parent() *Environment {
+	return e.parent
+}
+
+// AddModule registers the environment of an imported module under the namespace name
+func (e *Environment) AddModule(name string, module *Environment) error {
+	root := e.root()
+	if _, ok := root.modules[name]; ok {
+		return ErrModuleExists
+	}
+	root.modules[name] = module
+	return nil
+}
+
+// Module returns the environment of the module imported under the namespace name or nil
+func (e *Environment) Module(name string) *Environment {
+	return e.root().modules[name]
+}
+
+// Modules returns the sorted namespaces of the imported modules
+func (e *Environment) Modules() []string {
+	names := []string{}
+	for name := range e.root().modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *Environment) root() *Environment {
+	scope := e
+	for scope.parent != nil {
+		scope = scope.parent
+	}
+	return scope
+}
+
 // NewEnvironment returns a new Environment
 func NewEnvironment() *Environment {
 	return &Environment{
-		symbols: make(map[string]ast.Node),
+		symbols: make(map[string]*Symbol),
+		modules: make(map[string]*Environment),
+	}
+}
+
+// NewPrelude returns a global environment declaring the names of the standard prelude.
+// Rules are declared in an environment enclosed by it so that they may redefine prelude names.
+func NewPrelude() *Environment {
+	e := NewEnvironment()
+	for _, name := range prelude.Names() {
+		e.symbols[name] = &Symbol{Name: name, File: "prelude"}
+	}
+	return e
+}
+
+// NewEnclosedEnvironment returns a new scope enclosed by the outer environment
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	return &Environment{
+		parent:  outer,
+		symbols: make(map[string]*Symbol),
 	}
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/HannesKimara/cddlc/ast"
 	env "github.com/HannesKimara/cddlc/environment"
+	"github.com/HannesKimara/cddlc/token"
 )
 
 type EnvInitializer func() *env.Environment
@@ -49,3 +50,121 @@ func TestEnvGetEmpty(t *testing.T) {
 		t.Fatalf("Expected nil item for %s got %+v", ident, item)
 	}
 }
+
+func TestEnvScopes(t *testing.T) {
+	global := env.NewEnvironment()
+	if err := global.Add("t", &ast.TstrType{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := global.Add("name", &ast.TstrType{}); err != nil {
+		t.Fatal(err)
+	}
+
+	scope := env.NewEnclosedEnvironment(global)
+	param := &ast.Identifier{Name: "t"}
+	if err := scope.Declare(&env.Symbol{Name: "t", Value: param}); err != nil {
+		t.Fatalf("expected parameter to shadow global symbol got %s", err)
+	}
+	if err := scope.Declare(&env.Symbol{Name: "t", Value: param}); err != env.ErrSymbolExists {
+		t.Fatalf("expected %s got %v", env.ErrSymbolExists, err)
+	}
+
+	if item := scope.Get("t"); item != param {
+		t.Errorf("expected t to resolve to the parameter got %+v", item)
+	}
+	if item := global.Get("t"); item == param {
+		t.Error("expected parameter not to be visible in the global scope")
+	}
+	if !scope.Exists("name") || scope.ExistsInScope("name") {
+		t.Error("expected name to be found in the enclosing scope only")
+	}
+	if scope.Parent() != global || global.Parent() != nil {
+		t.Error("unexpected scope parents")
+	}
+}
+
+func TestEnvPrelude(t *testing.T) {
+	global := env.NewEnclosedEnvironment(env.NewPrelude())
+	if !global.Exists("tdate") || global.ExistsInScope("tdate") {
+		t.Error("expected tdate to be declared in the prelude scope")
+	}
+	rule := &ast.Identifier{Name: "tdate"}
+	if err := global.Declare(&env.Symbol{Name: "tdate", Value: rule}); err != nil {
+		t.Fatalf("expected rule to shadow the prelude got %s", err)
+	}
+	if global.Get("tdate") != rule {
+		t.Error("expected tdate to resolve to the rule")
+	}
+}
+
+func TestEnvLookup(t *testing.T) {
+	environ := env.NewEnvironment()
+	pos := token.Position{Line: 3, Column: 1}
+	err := environ.Declare(&env.Symbol{Name: "person", Value: &ast.Map{}, Pos: pos, File: "person.cddl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sym, ok := environ.Lookup("person")
+	if !ok {
+		t.Fatal("expected symbol person to exist")
+	}
+	if sym.Pos != pos || sym.File != "person.cddl" {
+		t.Errorf("expected declaration at person.cddl %s got %s %s", pos, sym.File, sym.Pos)
+	}
+
+	if _, ok := environ.Lookup("missing"); ok {
+		t.Error("expected lookup of missing symbol to fail")
+	}
+}
+
+func TestEnvAddPosition(t *testing.T) {
+	environ := env.NewEnvironment()
+	namePos := token.Position{Filename: "person.cddl", Offset: 10, Line: 2, Column: 1}
+	rule := &ast.Rule{
+		Name:  &ast.Identifier{Pos: namePos, Name: "person"},
+		Value: &ast.TstrType{Pos: token.Position{Filename: "person.cddl", Offset: 19, Line: 2, Column: 10}},
+	}
+	if err := environ.Add("person", rule); err != nil {
+		t.Fatal(err)
+	}
+	if err := environ.Add("name", &ast.TstrType{Pos: token.Position{Line: 4, Column: 8}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if sym, _ := environ.Lookup("person"); sym.Pos != namePos || sym.File != "person.cddl" {
+		t.Errorf("expected the declaration at the rule name %s got %s %s", namePos, sym.File, sym.Pos)
+	}
+	if sym, _ := environ.Lookup("name"); sym.Pos != (token.Position{}) {
+		t.Errorf("expected no position for a value without a name got %s", sym.Pos)
+	}
+}
+
+func TestEnvModules(t *testing.T) {
+	global := env.NewEnvironment()
+	cose := env.NewEnvironment()
+	if err := cose.Add("key", &ast.Map{}); err != nil {
+		t.Fatal(err)
+	}
+
+	scope := env.NewEnclosedEnvironment(global)
+	if err := scope.AddModule("cose", cose); err != nil {
+		t.Fatal(err)
+	}
+	if err := global.AddModule("cose", env.NewEnvironment()); err != env.ErrModuleExists {
+		t.Fatalf("expected %s got %v", env.ErrModuleExists, err)
+	}
+
+	if global.Module("cose") != cose || scope.Module("cose") != cose {
+		t.Fatal("expected module to be registered on the global scope")
+	}
+	if global.Exists("key") {
+		t.Error("expected module symbols to be kept in their namespace")
+	}
+	if !global.Module("cose").Exists("key") {
+		t.Error("expected key to exist in module cose")
+	}
+	if mods := global.Modules(); len(mods) != 1 || mods[0] != "cose" {
+		t.Errorf("unexpected modules %v", mods)
+	}
+}
@@ -0,0 +1,363 @@
+// Package evaluator implements constant evaluation of CDDL rules.
+//
+// Rules denoting a single value such as literals, ranges with constant bounds and the
+// results of the control operators `.plus`, `.cat` and `.det` defined in
+// https://www.rfc-editor.org/rfc/rfc9165#section-2 are folded into a Value.
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+var (
+	// minInteger is the smallest integer representable in CBOR -2^64
+	minInteger = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64))
+
+	// maxInteger is the largest integer representable in CBOR 2^64-1
+	maxInteger = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 64), big.NewInt(1))
+)
+
+// evaluation states of the rules
+const (
+	pending = iota + 1
+	done
+	failed
+)
+
+// Evaluator computes the values of the constant rules of a CDDL tree
+type Evaluator struct {
+	// rules maps the names of rules with a single `=` definition and no generic parameters
+	// to their definitions. Sockets and generic rules are never constant.
+	rules map[string]*ast.Rule
+
+	// names holds the names of the rules in order of definition
+	names []string
+
+	values map[string]Value
+	state  map[string]int
+	errors parser.ErrorList
+
+	// reported holds the nodes with an error in errors. Nodes evaluated again, as inline
+	// constants are for each data item a validator checks, are reported once.
+	reported map[ast.Node]bool
+}
+
+// NewEvaluator returns a new evaluator for the rules in the tree
+func NewEvaluator(cddl *ast.CDDL) *Evaluator {
+	e := &Evaluator{
+		rules:    make(map[string]*ast.Rule),
+		values:   make(map[string]Value),
+		state:    make(map[string]int),
+		reported: make(map[ast.Node]bool),
+	}
+	if cddl == nil {
+		return e
+	}
+
+	defs := map[string]int{}
+	for _, entry := range cddl.Rules {
+		if rule, ok := entry.(*ast.Rule); ok && rule.Name != nil {
+			defs[rule.Name.Name]++
+		}
+	}
+	for _, entry := range cddl.Rules {
+		rule, ok := entry.(*ast.Rule)
+		if !ok || rule.Name == nil || defs[rule.Name.Name] != 1 {
+			continue
+		}
+		if rule.Token != token.ASSIGN || len(rule.Params) != 0 {
+			continue
+		}
+		e.rules[rule.Name.Name] = rule
+		e.names = append(e.names, rule.Name.Name)
+	}
+	return e
+}
+
+// Evaluate computes the value of every constant rule. It returns the values by rule name and
+// the overflows and type mismatches found.
+func (e *Evaluator) Evaluate() (map[string]Value, parser.ErrorList) {
+	for _, name := range e.names {
+		e.evalRule(name)
+	}
+
+	out := make(map[string]Value, len(e.values))
+	for name, v := range e.values {
+		out[name] = v
+	}
+	return out, e.errors.Collect()
+}
+
+// Value returns the value of the named rule or nil if the rule is not a constant
+func (e *Evaluator) Value(name string) Value {
+	v, _ := e.evalRule(name)
+	return v
+}
+
+// Eval returns the value of the node or nil if the node is not a constant. Errors found are
+// included in the list returned by Evaluate.
+func (e *Evaluator) Eval(node ast.Node) Value {
+	v, _ := e.eval(node)
+	return v
+}
+
+// evalRule evaluates the named rule once. The second result is false if the rule cannot be
+// evaluated because it is undefined, recursive or erroneous.
+func (e *Evaluator) evalRule(name string) (Value, bool) {
+	rule, ok := e.rules[name]
+	if !ok {
+		return nil, false
+	}
+	switch e.state[name] {
+	case pending, failed:
+		return nil, false
+	case done:
+		return e.values[name], true
+	}
+
+	e.state[name] = pending
+	v, ok := e.eval(rule.Value)
+	if !ok {
+		e.state[name] = failed
+		return nil, false
+	}
+	e.state[name] = done
+	if v != nil {
+		e.values[name] = v
+	}
+	return v, true
+}
+
+// eval returns the value of a node. Nodes that do not denote a single value return nil. The
+// second result is false if the value is unknown because of an error reported earlier in which
+// case the caller should not report the operand again.
+func (e *Evaluator) eval(node ast.Node) (Value, bool) {
+	switch val := node.(type) {
+	case *ast.IntegerLiteral:
+		return &Integer{Int: big.NewInt(val.Literal)}, true
+	case *ast.UintLiteral:
+		return &Integer{Int: new(big.Int).SetUint64(val.Literal)}, true
+	case *ast.FloatLiteral:
+		return &Float{Float: val.Literal}, true
+	case *ast.TextLiteral:
+		return &Text{Text: val.Literal}, true
+	case *ast.BytesLiteral:
+		return &Bytes{Bytes: val.Literal}, true
+	case *ast.BooleanLiteral:
+		return &Bool{Bool: val.Bool}, true
+	case *ast.Identifier:
+		return e.evalIdentifier(val)
+	case *ast.Group:
+		// parenthesized expressions i.e. `(1 .plus 2)`
+		if len(val.Entries) == 1 {
+			switch val.Entries[0].(type) {
+			case *ast.Entry, *ast.Optional, *ast.NMOccurrence, *ast.Unwrap:
+				return nil, true
+			}
+			return e.eval(val.Entries[0])
+		}
+	case *ast.Range:
+		return e.evalRange(val)
+	case *ast.ComputedOpControl:
+		return e.evalComputed(val)
+	}
+	return nil, true
+}
+
+func (e *Evaluator) evalIdentifier(ident *ast.Identifier) (Value, bool) {
+	if _, ok := e.rules[ident.Name]; !ok {
+		return nil, true
+	}
+	return e.evalRule(ident.Name)
+}
+
+func (e *Evaluator) evalRange(r *ast.Range) (Value, bool) {
+	from, okFrom := e.eval(r.From)
+	to, okTo := e.eval(r.To)
+	if !okFrom || !okTo {
+		return nil, false
+	}
+	if from == nil || to == nil {
+		return nil, true
+	}
+
+	exclusive := r.Token == token.EXCLUSIVE_BOUND
+	var cmp int
+	switch lower := from.(type) {
+	case *Integer:
+		upper, ok := to.(*Integer)
+		if !ok {
+			return e.mismatch(r, fmt.Sprintf("operator %s expected bounds of the same type, found %s and %s", r.Token, describe(from), describe(to)))
+		}
+		cmp = lower.Int.Cmp(upper.Int)
+	case *Float:
+		upper, ok := to.(*Float)
+		if !ok {
+			return e.mismatch(r, fmt.Sprintf("operator %s expected bounds of the same type, found %s and %s", r.Token, describe(from), describe(to)))
+		}
+		switch {
+		case lower.Float < upper.Float:
+			cmp = -1
+		case lower.Float > upper.Float:
+			cmp = 1
+		}
+	default:
+		return e.mismatch(r, fmt.Sprintf("operator %s expected numeric bounds, found %s", r.Token, describe(from)))
+	}
+
+	if cmp > 0 || (cmp == 0 && exclusive) {
+		return e.mismatch(r, fmt.Sprintf("range %s%s%s is empty", from, r.Token, to))
+	}
+	return &Range{From: from, To: to, Exclusive: exclusive}, true
+}
+
+func (e *Evaluator) evalComputed(op *ast.ComputedOpControl) (Value, bool) {
+	left, okLeft := e.eval(op.Left)
+	right, okRight := e.eval(op.Right)
+	if !okLeft || !okRight {
+		return nil, false
+	}
+	if left == nil {
+		return e.mismatch(op, fmt.Sprintf("operator %s expected a constant target", op.Token))
+	}
+	if right == nil {
+		return e.mismatch(op, fmt.Sprintf("operator %s expected a constant controller", op.Token))
+	}
+
+	switch op.Token {
+	case token.PLUS:
+		return e.plus(op, left, right)
+	case token.CAT:
+		return e.cat(op, left, right)
+	case token.DET:
+		return e.cat(op, dedent(left), dedent(right))
+	}
+	return nil, true
+}
+
+// plus adds numeric values of the same type
+func (e *Evaluator) plus(op *ast.ComputedOpControl, left, right Value) (Value, bool) {
+	switch l := left.(type) {
+	case *Integer:
+		if r, ok := right.(*Integer); ok {
+			sum := new(big.Int).Add(l.Int, r.Int)
+			if sum.Cmp(minInteger) < 0 || sum.Cmp(maxInteger) > 0 {
+				return e.mismatch(op, fmt.Sprintf("integer overflow in %s %s %s", l, op.Token, r))
+			}
+			return &Integer{Int: sum}, true
+		}
+	case *Float:
+		if r, ok := right.(*Float); ok {
+			sum := l.Float + r.Float
+			if math.IsInf(sum, 0) && !math.IsInf(l.Float, 0) && !math.IsInf(r.Float, 0) {
+				return e.mismatch(op, fmt.Sprintf("float overflow in %s %s %s", l, op.Token, r))
+			}
+			return &Float{Float: sum}, true
+		}
+	default:
+		return e.mismatch(op, fmt.Sprintf("operator %s expected a numeric target, found %s", op.Token, describe(left)))
+	}
+	return e.mismatch(op, fmt.Sprintf("operator %s expected operands of the same type, found %s and %s", op.Token, describe(left), describe(right)))
+}
+
+// cat concatenates text and byte strings. The result has the type of the target. A byte string
+// controller is only accepted for a text target if it is valid UTF-8.
+func (e *Evaluator) cat(op *ast.ComputedOpControl, left, right Value) (Value, bool) {
+	switch l := left.(type) {
+	case *Text:
+		switch r := right.(type) {
+		case *Text:
+			return &Text{Text: l.Text + r.Text}, true
+		case *Bytes:
+			if !utf8.Valid(r.Bytes) {
+				return e.mismatch(op, fmt.Sprintf("operator %s cannot append byte string %s to text string: invalid UTF-8", op.Token, r))
+			}
+			return &Text{Text: l.Text + string(r.Bytes)}, true
+		}
+	case *Bytes:
+		switch r := right.(type) {
+		case *Text:
+			return &Bytes{Bytes: append(bytes.Clone(l.Bytes), r.Text...)}, true
+		case *Bytes:
+			return &Bytes{Bytes: append(bytes.Clone(l.Bytes), r.Bytes...)}, true
+		}
+	default:
+		return e.mismatch(op, fmt.Sprintf("operator %s expected a string target, found %s", op.Token, describe(left)))
+	}
+	return e.mismatch(op, fmt.Sprintf("operator %s expected a string controller, found %s", op.Token, describe(right)))
+}
+
+// dedent removes the leading whitespace common to all non-blank lines of text and byte strings
+func dedent(v Value) Value {
+	switch val := v.(type) {
+	case *Text:
+		return &Text{Text: dedentString(val.Text)}
+	case *Bytes:
+		return &Bytes{Bytes: []byte(dedentString(string(val.Bytes)))}
+	}
+	return v
+}
+
+func dedentString(s string) string {
+	lines := strings.Split(s, "\n")
+	indent := -1
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return s
+	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *Evaluator) mismatch(node ast.Node, msg string) (Value, bool) {
+	if e.reported[node] {
+		return nil, false
+	}
+	e.reported[node] = true
+	err := parser.NewError(msg, node.Start(), node.End())
+	err.Prefix = "evaluator"
+	e.errors = append(e.errors, err)
+	return nil, false
+}
+
+// describe returns a short human readable name for the type of a value used in diagnostics
+func describe(v Value) string {
+	switch v.(type) {
+	case *Integer:
+		return "integer " + v.String()
+	case *Float:
+		return "float " + v.String()
+	case *Text:
+		return "text string " + v.String()
+	case *Bytes:
+		return "byte string " + v.String()
+	case *Bool:
+		return "boolean " + v.String()
+	case *Range:
+		return "range " + v.String()
+	}
+	return fmt.Sprintf("%T", v)
+}
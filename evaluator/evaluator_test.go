@@ -0,0 +1,168 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+func parse(t *testing.T, src string) *ast.CDDL {
+	t.Helper()
+	l := lexer.NewLexer([]byte(src))
+	p := parser.NewParser(l)
+
+	cddl, errs := p.ParseFile()
+	if len(errs) != 0 {
+		t.Fatalf("%s: -> %s", src, errs)
+	}
+	return cddl
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		src   string
+		name  string
+		value string
+	}{
+		{"a = 1", "a", "1"},
+		{"a = -34", "a", "-34"},
+		{"a = 0x10", "a", "16"},
+		{"a = 18446744073709551615", "a", "18446744073709551615"},
+		{"a = 2.5", "a", "2.5"},
+		{"a = true", "a", "true"},
+		{`a = "text"`, "a", `"text"`},
+		{"a = h'00ff'", "a", "h'00ff'"},
+		{"a = b b = 3", "a", "3"},
+		{"byte = 0..max-byte max-byte = 255", "byte", "0..255"},
+		{"byte = 0...256", "byte", "0...256"},
+		{"a = -1.5..b b = 1.5", "a", "-1.5..1.5"},
+		{"a = 1 .plus 2", "a", "3"},
+		{"a = b .plus -5 b = 3", "a", "-2"},
+		{"a = 1.5 .plus 2.25", "a", "3.75"},
+		{"a = 18446744073709551615 .plus -1", "a", "18446744073709551614"},
+		{"a = lo .. hi lo = base .plus 1 hi = base .plus 10 base = 100", "a", "101..110"},
+		{`a = "foo" .cat "bar"`, "a", `"foobar"`},
+		{`a = "foo" .cat 'bar'`, "a", `"foobar"`},
+		{`a = h'00' .cat "A"`, "a", "h'0041'"},
+		{`a = 'ab' .cat h'ff'`, "a", "h'6162ff'"},
+		{`a = "x" .cat b b = "y" .cat "z"`, "a", `"xyz"`},
+		{`a = "  foo" .det "  bar"`, "a", `"foobar"`},
+		{"a = (1 .plus 2)", "a", "3"},
+	}
+
+	for _, tst := range tests {
+		values, errs := evaluator.NewEvaluator(parse(t, tst.src)).Evaluate()
+		if len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %s", tst.src, errs)
+			continue
+		}
+		v, ok := values[tst.name]
+		if !ok {
+			t.Errorf("%s: expected %s to be constant", tst.src, tst.name)
+			continue
+		}
+		if v.String() != tst.value {
+			t.Errorf("%s: expected %s to be %s got %s", tst.src, tst.name, tst.value, v)
+		}
+	}
+}
+
+func TestNotConstant(t *testing.T) {
+	tests := []struct {
+		src  string
+		name string
+	}{
+		{"a = tstr", "a"},
+		{"a = [1, 2]", "a"},
+		{"a = 1 / 2", "a"},
+		{"a = b b = uint", "a"},
+		{"$a /= 1", "$a"},
+		{"a = 1 a /= 2", "a"},
+		{"a<t> = 1", "a"},
+		{"a = a .plus 1", "a"},
+	}
+
+	for _, tst := range tests {
+		e := evaluator.NewEvaluator(parse(t, tst.src))
+		values, errs := e.Evaluate()
+		if len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %s", tst.src, errs)
+		}
+		if v, ok := values[tst.name]; ok {
+			t.Errorf("%s: expected %s not to be constant, got %s", tst.src, tst.name, v)
+		}
+		if v := e.Value(tst.name); v != nil {
+			t.Errorf("%s: expected no value for %s, got %s", tst.src, tst.name, v)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"a = 18446744073709551615 .plus 1", []string{"integer overflow in 18446744073709551615 .plus 1"}},
+		{"a = 1.5 .plus 2", []string{"operator .plus expected operands of the same type, found float 1.5 and integer 2"}},
+		{`a = "x" .plus 1`, []string{`operator .plus expected a numeric target, found text string "x"`}},
+		{`a = 1 .cat "x"`, []string{"operator .cat expected a string target, found integer 1"}},
+		{`a = "x" .cat 1`, []string{"operator .cat expected a string controller, found integer 1"}},
+		{`a = "x" .cat h'ff'`, []string{"operator .cat cannot append byte string h'ff' to text string: invalid UTF-8"}},
+		{`a = tstr .cat "x"`, []string{"operator .cat expected a constant target"}},
+		{`a = "x" .cat tstr`, []string{"operator .cat expected a constant controller"}},
+		{"a = 5..b b = 3", []string{"range 5..3 is empty"}},
+		{"a = 3...3", []string{"range 3...3 is empty"}},
+		{"a = b .. 1 b = 1.5", []string{"operator .. expected bounds of the same type, found float 1.5 and integer 1"}},
+		{
+			// errors are reported once where they occur rather than in every dependent rule
+			"a = b .plus 1 b = 18446744073709551615 .plus 1",
+			[]string{"integer overflow in 18446744073709551615 .plus 1"},
+		},
+	}
+
+	for _, tst := range tests {
+		_, errs := evaluator.NewEvaluator(parse(t, tst.src)).Evaluate()
+		if len(errs) != len(tst.errs) {
+			t.Errorf("%s: expected %d errors got %d: %s", tst.src, len(tst.errs), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if expected := "evaluator error: " + tst.errs[i]; err.Error() != expected {
+				t.Errorf("%s: expected `%s` got `%s`", tst.src, expected, err)
+			}
+		}
+	}
+}
+
+func TestEvalReportsOnce(t *testing.T) {
+	// inline constants are evaluated again for each data item a validator checks
+	cddl := parse(t, "a = [18446744073709551615 .plus 1]")
+	node := cddl.Rules[0].(*ast.Rule).Value.(*ast.Array).Rules[0]
+	e := evaluator.NewEvaluator(cddl)
+	for i := 0; i < 3; i++ {
+		if v := e.Eval(node); v != nil {
+			t.Fatalf("expected no value got %s", v)
+		}
+	}
+	if _, errs := e.Evaluate(); len(errs) != 1 {
+		t.Errorf("expected the overflow to be reported once got %s", errs)
+	}
+}
+
+func TestToNode(t *testing.T) {
+	e := evaluator.NewEvaluator(parse(t, `a = 1 .plus 2 b = "x" .cat "y" c = 0..a`))
+
+	if lit, ok := evaluator.ToNode(e.Value("a"), token.Position{}).(*ast.IntegerLiteral); !ok || lit.Literal != 3 {
+		t.Errorf("expected integer literal 3 got %+v", lit)
+	}
+	if lit, ok := evaluator.ToNode(e.Value("b"), token.Position{}).(*ast.TextLiteral); !ok || lit.Literal != "xy" {
+		t.Errorf("expected text literal xy got %+v", lit)
+	}
+	if r, ok := evaluator.ToNode(e.Value("c"), token.Position{}).(*ast.Range); !ok || r.Token != token.INCLUSIVE_BOUND {
+		t.Errorf("expected inclusive range got %+v", r)
+	}
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// Value represents a constant computed by the evaluator
+type Value interface {
+	// String returns the value formatted as a CDDL literal
+	String() string
+
+	value()
+}
+
+// Integer represents an integer constant. Integers are within the range representable
+// in CBOR [-2^64, 2^64-1]
+type Integer struct {
+	Int *big.Int
+}
+
+func (i *Integer) String() string {
+	return i.Int.String()
+}
+
+func (i *Integer) value() {}
+
+// Float represents a floating point constant
+type Float struct {
+	Float float64
+}
+
+func (f *Float) String() string {
+	s := strconv.FormatFloat(f.Float, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+func (f *Float) value() {}
+
+// Text represents a text string constant
+type Text struct {
+	Text string
+}
+
+func (t *Text) String() string {
+	return strconv.Quote(t.Text)
+}
+
+func (t *Text) value() {}
+
+// Bytes represents a byte string constant
+type Bytes struct {
+	Bytes []byte
+}
+
+func (b *Bytes) String() string {
+	return "h'" + hex.EncodeToString(b.Bytes) + "'"
+}
+
+func (b *Bytes) value() {}
+
+// Bool represents the constants `true` and `false`
+type Bool struct {
+	Bool bool
+}
+
+func (b *Bool) String() string {
+	return strconv.FormatBool(b.Bool)
+}
+
+func (b *Bool) value() {}
+
+// Range represents a range with constant bounds of the same numeric type i.e. `0..255`
+type Range struct {
+	From, To Value
+
+	// Exclusive: true if the upper bound is excluded i.e. `0...256`
+	Exclusive bool
+}
+
+func (r *Range) String() string {
+	if r.Exclusive {
+		return r.From.String() + "..." + r.To.String()
+	}
+	return r.From.String() + ".." + r.To.String()
+}
+
+func (r *Range) value() {}
+
+// ToNode converts a value to the equivalent literal AST Node positioned at pos. It returns nil
+// for integers that cannot be represented by the literal nodes.
+func ToNode(v Value, pos token.Position) ast.Node {
+	switch val := v.(type) {
+	case *Integer:
+		if val.Int.IsInt64() {
+			return &ast.IntegerLiteral{Pos: pos, Token: token.INT, Literal: val.Int.Int64()}
+		}
+		if val.Int.IsUint64() {
+			return &ast.UintLiteral{Pos: pos, Token: token.UINT, Literal: val.Int.Uint64()}
+		}
+	case *Float:
+		return &ast.FloatLiteral{
+			Range:   token.PositionRange{Start: pos, End: pos.To(len(val.String()) - 1)},
+			Token:   token.FLOAT,
+			Literal: val.Float,
+		}
+	case *Text:
+		return &ast.TextLiteral{Pos: pos, Token: token.TEXT_LITERAL, Literal: val.Text}
+	case *Bytes:
+		return &ast.BytesLiteral{Pos: pos, Token: token.BYTES_LITERAL, Literal: val.Bytes, Source: val.String()}
+	case *Bool:
+		lit := val.String()
+		return &ast.BooleanLiteral{Range: token.PositionRange{Start: pos, End: pos.To(len(lit) - 1)}, Bool: val.Bool}
+	case *Range:
+		from, to := ToNode(val.From, pos), ToNode(val.To, pos)
+		if from == nil || to == nil {
+			return nil
+		}
+		tok := token.INCLUSIVE_BOUND
+		if val.Exclusive {
+			tok = token.EXCLUSIVE_BOUND
+		}
+		return &ast.Range{Pos: pos, Token: tok, From: from, To: to}
+	}
+	return nil
+}
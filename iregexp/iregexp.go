@@ -0,0 +1,347 @@
+// Package iregexp translates the regular expressions of the `.regexp` control to the RE2
+// syntax of the regexp package. CDDL specifies them as XML Schema regular expressions
+// https://www.w3.org/TR/xmlschema-2/#regexs of which I-Regexp
+// https://www.rfc-editor.org/rfc/rfc9485 is the interoperable subset.
+//
+// The expressions always match the whole of a string, so the translation is anchored and
+// `^` and `$` are ordinary characters. The wildcard `.` does not match carriage returns and
+// newlines. Character class subtraction such as `[a-z-[aeiou]]` is expanded to explicit
+// ranges. The XML name escapes `\i`, `\I`, `\c` and `\C` and the block escapes
+// `\p{IsBasicLatin}` are not supported.
+package iregexp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxRepeat is the largest repetition count RE2 accepts
+const maxRepeat = 1000
+
+// Error describes an invalid or unsupported construct of a regular expression
+type Error struct {
+	// Offset: the byte offset of the construct in the expression
+	Offset int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("iregexp: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Translate returns the RE2 syntax matching the same strings as the whole of the pattern
+func Translate(pattern string) (string, error) {
+	for i, r := range pattern {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(pattern[i:]); size == 1 {
+				return "", &Error{Offset: i, Msg: "invalid UTF-8"}
+			}
+		}
+	}
+
+	t := &translator{src: pattern}
+	t.sb.WriteString("^(?:")
+	if err := t.regExp(); err != nil {
+		return "", err
+	}
+	if t.pos < len(t.src) {
+		// branches only stop early at an unbalanced parenthesis
+		return "", t.errorf(t.pos, "unexpected )")
+	}
+	t.sb.WriteString(")$")
+	return t.sb.String(), nil
+}
+
+// Compile translates the pattern and compiles the translation
+func Compile(pattern string) (*regexp.Regexp, error) {
+	translated, err := Translate(pattern)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(translated)
+	if err != nil {
+		// the translation only produces syntax RE2 accepts
+		return nil, &Error{Msg: err.Error()}
+	}
+	return re, nil
+}
+
+// MustCompile is like Compile but panics if the pattern is invalid
+func MustCompile(pattern string) *regexp.Regexp {
+	re, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+type translator struct {
+	src string
+	pos int
+	sb  strings.Builder
+}
+
+func (t *translator) errorf(offset int, format string, args ...interface{}) *Error {
+	return &Error{Offset: offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// peek returns the rune at the current position or -1 at the end of the pattern
+func (t *translator) peek() rune {
+	if t.pos >= len(t.src) {
+		return -1
+	}
+	r, _ := utf8.DecodeRuneInString(t.src[t.pos:])
+	return r
+}
+
+func (t *translator) next() rune {
+	if t.pos >= len(t.src) {
+		return -1
+	}
+	r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+	t.pos += size
+	return r
+}
+
+// regExp translates branches separated by `|`
+func (t *translator) regExp() error {
+	for {
+		if err := t.branch(); err != nil {
+			return err
+		}
+		if t.peek() != '|' {
+			return nil
+		}
+		t.next()
+		t.sb.WriteByte('|')
+	}
+}
+
+// branch translates pieces up to the end of a branch
+func (t *translator) branch() error {
+	for {
+		switch t.peek() {
+		case -1, '|', ')':
+			return nil
+		}
+		if err := t.piece(); err != nil {
+			return err
+		}
+	}
+}
+
+// piece translates an atom and its quantifier
+func (t *translator) piece() error {
+	start := t.pos
+	switch r := t.next(); r {
+	case '(':
+		t.sb.WriteString("(?:")
+		if err := t.regExp(); err != nil {
+			return err
+		}
+		if t.next() != ')' {
+			return t.errorf(start, "missing )")
+		}
+		t.sb.WriteByte(')')
+	case '[':
+		set, err := t.classExpr(start)
+		if err != nil {
+			return err
+		}
+		t.sb.WriteString(set.String())
+	case '.':
+		t.sb.WriteString(`[^\n\r]`)
+	case '\\':
+		set, err := t.escape(start)
+		if err != nil {
+			return err
+		}
+		t.sb.WriteString(set.String())
+	case '?', '*', '+', '{':
+		return t.errorf(start, "missing expression before quantifier %c", r)
+	case ']', '}':
+		return t.errorf(start, "unexpected %c", r)
+	default:
+		t.sb.WriteString(regexp.QuoteMeta(string(r)))
+	}
+	return t.quantifier()
+}
+
+func (t *translator) quantifier() error {
+	start := t.pos
+	switch t.peek() {
+	case '?', '*', '+':
+		t.sb.WriteRune(t.next())
+		return nil
+	case '{':
+		t.next()
+	default:
+		return nil
+	}
+
+	min, ok := t.number()
+	if !ok {
+		return t.errorf(start, "invalid quantifier")
+	}
+	max := min
+	if t.peek() == ',' {
+		t.next()
+		max = -1
+		if t.peek() != '}' {
+			if max, ok = t.number(); !ok {
+				return t.errorf(start, "invalid quantifier")
+			}
+		}
+	}
+	if t.next() != '}' {
+		return t.errorf(start, "invalid quantifier")
+	}
+	if max >= 0 && max < min {
+		return t.errorf(start, "quantifier {%d,%d} has a maximum below its minimum", min, max)
+	}
+	if min > maxRepeat || max > maxRepeat {
+		return t.errorf(start, "quantifiers above %d are not supported", maxRepeat)
+	}
+	t.sb.WriteString(t.src[start:t.pos])
+	return nil
+}
+
+func (t *translator) number() (int, bool) {
+	start := t.pos
+	for t.peek() >= '0' && t.peek() <= '9' {
+		t.next()
+	}
+	n, err := strconv.Atoi(t.src[start:t.pos])
+	return n, err == nil
+}
+
+// singleEscapes maps the single character escapes to their characters
+var singleEscapes = map[rune]rune{
+	'n': '\n', 'r': '\r', 't': '\t',
+	'\\': '\\', '|': '|', '.': '.', '-': '-', '^': '^', '?': '?', '*': '*', '+': '+',
+	'{': '{', '}': '}', '(': '(', ')': ')', '[': '[', ']': ']',
+}
+
+// escape translates the escape following a backslash at start to the set of characters it
+// matches
+func (t *translator) escape(start int) (runeSet, error) {
+	r := t.next()
+	if c, ok := singleEscapes[r]; ok {
+		return runeSet{c, c}, nil
+	}
+
+	switch r {
+	case 's', 'S':
+		set := runeSet{'\t', '\n', '\r', '\r', ' ', ' '}.normalize()
+		return set.negateIf(r == 'S'), nil
+	case 'd', 'D':
+		return fromTable(unicode.Nd).negateIf(r == 'D'), nil
+	case 'w', 'W':
+		// all characters but punctuation, separators and others
+		set := fromTable(unicode.P).union(fromTable(unicode.Z)).union(fromTable(unicode.C))
+		return set.negateIf(r == 'w'), nil
+	case 'i', 'I', 'c', 'C':
+		return nil, t.errorf(start, "XML name escape \\%c is not supported", r)
+	case 'p', 'P':
+		return t.category(start, r == 'P')
+	case -1:
+		return nil, t.errorf(start, "trailing backslash")
+	}
+	return nil, t.errorf(start, "unknown escape \\%c", r)
+}
+
+// category translates the property of a `\p{…}` or `\P{…}` escape
+func (t *translator) category(start int, negate bool) (runeSet, error) {
+	if t.next() != '{' {
+		return nil, t.errorf(start, "missing { after \\p")
+	}
+	end := strings.IndexByte(t.src[t.pos:], '}')
+	if end < 0 {
+		return nil, t.errorf(start, "missing } after \\p{")
+	}
+	name := t.src[t.pos : t.pos+end]
+	t.pos += end + 1
+
+	if strings.HasPrefix(name, "Is") {
+		return nil, t.errorf(start, "block escape \\p{%s} is not supported", name)
+	}
+	table, ok := unicode.Categories[name]
+	if !ok {
+		return nil, t.errorf(start, "unknown category %s", name)
+	}
+	return fromTable(table).negateIf(negate), nil
+}
+
+// classExpr translates a character class expression after its opening bracket at start
+func (t *translator) classExpr(start int) (runeSet, error) {
+	negate := false
+	if t.peek() == '^' {
+		t.next()
+		negate = true
+	}
+
+	var set runeSet
+	for first := true; ; first = false {
+		itemStart := t.pos
+		switch r := t.next(); {
+		case r == -1:
+			return nil, t.errorf(start, "missing ]")
+		case r == ']' && !first:
+			return set.normalize().negateIf(negate), nil
+		case r == '-' && t.peek() == '[' && !first:
+			t.next()
+			sub, err := t.classExpr(itemStart + 1)
+			if err != nil {
+				return nil, err
+			}
+			if t.next() != ']' {
+				return nil, t.errorf(itemStart, "class subtraction must end the class")
+			}
+			return set.normalize().negateIf(negate).subtract(sub), nil
+		case r == '[':
+			return nil, t.errorf(itemStart, "unescaped [ in character class")
+		case r == '\\' && strings.ContainsRune("sSdDwWiIcCpP", t.peek()):
+			esc, err := t.escape(itemStart)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, esc...)
+		default:
+			lo, err := t.classChar(r, itemStart)
+			if err != nil {
+				return nil, err
+			}
+			hi := lo
+			if t.peek() == '-' && t.pos+1 < len(t.src) && t.src[t.pos+1] != ']' && t.src[t.pos+1] != '[' {
+				t.next()
+				rangeStart := t.pos
+				if hi, err = t.classChar(t.next(), rangeStart); err != nil {
+					return nil, err
+				}
+				if hi < lo {
+					return nil, t.errorf(itemStart, "range %c-%c is out of order", lo, hi)
+				}
+			}
+			set = append(set, lo, hi)
+		}
+	}
+}
+
+// classChar returns the character of a class item or range bound starting with r
+func (t *translator) classChar(r rune, start int) (rune, error) {
+	switch r {
+	case '\\':
+		e := t.next()
+		if c, ok := singleEscapes[e]; ok {
+			return c, nil
+		}
+		return 0, t.errorf(start, "unknown escape \\%c in character class", e)
+	case '[', ']', -1:
+		return 0, t.errorf(start, "missing character in range")
+	}
+	return r, nil
+}
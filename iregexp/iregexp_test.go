@@ -0,0 +1,125 @@
+package iregexp_test
+
+import (
+	"testing"
+
+	"github.com/HannesKimara/cddlc/iregexp"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		matches []string
+		rejects []string
+	}{
+		{"[a-z]+", []string{"abc"}, []string{"123abc", "abc1", ""}},
+		{"a|bc", []string{"a", "bc"}, []string{"abc", "ac"}},
+		{"(ab)*c", []string{"c", "ababc"}, []string{"abc ", "aba"}},
+		{"^a$", []string{"^a$"}, []string{"a"}},
+		{"a.c", []string{"abc", "a c", "aéc"}, []string{"a\nc", "a\rc"}},
+		{"x{2,3}", []string{"xx", "xxx"}, []string{"x", "xxxx"}},
+		{"x{2,}", []string{"xx", "xxxxx"}, []string{"x"}},
+		{"x{0}y", []string{"y"}, []string{"xy"}},
+		{`\d+`, []string{"123", "١٢٣"}, []string{"12a"}},
+		{`\s\S`, []string{" a", "\tb"}, []string{"  ", "\fa"}},
+		{`\w+`, []string{"abcü1"}, []string{"a b", "a-b", "a_b"}},
+		{`\p{Lu}\P{Lu}`, []string{"Ab"}, []string{"AB", "ab"}},
+		{`\p{N}`, []string{"7"}, []string{"x"}},
+		{`[a-z-[aeiou]]+`, []string{"bcd"}, []string{"bad"}},
+		{`[^a-z-[XYZ]]`, []string{"A", "-"}, []string{"a", "X"}},
+		{`[\p{L}-[\p{Lu}]]`, []string{"a", "é"}, []string{"A", "1"}},
+		{`[-a]`, []string{"-", "a"}, []string{"b"}},
+		{`[a-]`, []string{"-", "a"}, []string{"b"}},
+		{`[\-\[\]\^]+`, []string{"-[]^"}, []string{"a"}},
+		{`[\d.]+`, []string{"1.5"}, []string{"1,5"}},
+		{`\.\?\*\+\(\)\{\}\|\\`, []string{`.?*+(){}|\`}, []string{"a"}},
+		{`\n\r\t`, []string{"\n\r\t"}, []string{"nrt"}},
+		{`[A-Za-z0-9]+@[A-Za-z0-9]+(\.[A-Za-z0-9]+)+`, []string{"a@b.org"}, []string{"a@b", "x a@b.org"}},
+		{"", []string{""}, []string{"a"}},
+		// an encoded U+FFFD is a valid character
+		{"a\uFFFDb", []string{"a\uFFFDb"}, []string{"ab"}},
+	}
+
+	for _, tst := range tests {
+		re, err := iregexp.Compile(tst.pattern)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.pattern, err)
+			continue
+		}
+		for _, s := range tst.matches {
+			if !re.MatchString(s) {
+				t.Errorf("%s: expected a match for %q", tst.pattern, s)
+			}
+		}
+		for _, s := range tst.rejects {
+			if re.MatchString(s) {
+				t.Errorf("%s: unexpected match for %q", tst.pattern, s)
+			}
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		translated string
+	}{
+		{"[a-z]+", "^(?:[a-z]+)$"},
+		{"a|b", "^(?:a|b)$"},
+		{"(a)?", "^(?:(?:a)?)$"},
+		{"^$", `^(?:\^\$)$`},
+		{".", `^(?:[^\n\r])$`},
+		{"x{1,2}", "^(?:x{1,2})$"},
+		{`[a-c-[b]]`, "^(?:[ac])$"},
+		{`\s`, `^(?:[\x{9}-\x{a}\x{d}\x{20}])$`},
+	}
+
+	for _, tst := range tests {
+		translated, err := iregexp.Translate(tst.pattern)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.pattern, err)
+			continue
+		}
+		if translated != tst.translated {
+			t.Errorf("%s: expected `%s` got `%s`", tst.pattern, tst.translated, translated)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		err     string
+	}{
+		{"(a", "iregexp: missing ) at offset 0"},
+		{"a)", "iregexp: unexpected ) at offset 1"},
+		{"*a", "iregexp: missing expression before quantifier * at offset 0"},
+		{"a**", "iregexp: missing expression before quantifier * at offset 2"},
+		{"a{2,1}", "iregexp: quantifier {2,1} has a maximum below its minimum at offset 1"},
+		{"a{,1}", "iregexp: invalid quantifier at offset 1"},
+		{"a{1001}", "iregexp: quantifiers above 1000 are not supported at offset 1"},
+		{"[abc", "iregexp: missing ] at offset 0"},
+		{"[z-a]", "iregexp: range z-a is out of order at offset 1"},
+		{"[a[b]", "iregexp: unescaped [ in character class at offset 2"},
+		{"[a-[b]c]", "iregexp: class subtraction must end the class at offset 2"},
+		{`\i`, `iregexp: XML name escape \i is not supported at offset 0`},
+		{`a\p{IsBasicLatin}`, `iregexp: block escape \p{IsBasicLatin} is not supported at offset 1`},
+		{`\p{Xx}`, "iregexp: unknown category Xx at offset 0"},
+		{`\q`, `iregexp: unknown escape \q at offset 0`},
+		{`a\`, "iregexp: trailing backslash at offset 1"},
+		{"a]", "iregexp: unexpected ] at offset 1"},
+		{"a\xffb", "iregexp: invalid UTF-8 at offset 1"},
+		{"[\xe2\x82]", "iregexp: invalid UTF-8 at offset 1"},
+	}
+
+	for _, tst := range tests {
+		_, err := iregexp.Translate(tst.pattern)
+		if err == nil {
+			t.Errorf("%s: expected error `%s`", tst.pattern, tst.err)
+			continue
+		}
+		if err.Error() != tst.err {
+			t.Errorf("%s: expected `%s` got `%s`", tst.pattern, tst.err, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package iregexp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// runeSet is a set of characters as pairs of inclusive bounds, like the ranges of the
+// character classes of regexp/syntax. Normalized sets are sorted and have no overlapping
+// or adjacent ranges.
+type runeSet []rune
+
+func fromTable(table *unicode.RangeTable) runeSet {
+	var set runeSet
+	for _, r := range table.R16 {
+		if r.Stride == 1 {
+			set = append(set, rune(r.Lo), rune(r.Hi))
+			continue
+		}
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			set = append(set, c, c)
+		}
+	}
+	for _, r := range table.R32 {
+		if r.Stride == 1 {
+			set = append(set, rune(r.Lo), rune(r.Hi))
+			continue
+		}
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			set = append(set, c, c)
+		}
+	}
+	return set.normalize()
+}
+
+func (s runeSet) normalize() runeSet {
+	pairs := make([][2]rune, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		pairs = append(pairs, [2]rune{s[i], s[i+1]})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+	out := runeSet{}
+	for _, p := range pairs {
+		if n := len(out); n > 0 && p[0] <= out[n-1]+1 {
+			if p[1] > out[n-1] {
+				out[n-1] = p[1]
+			}
+			continue
+		}
+		out = append(out, p[0], p[1])
+	}
+	return out
+}
+
+// negateIf returns the complement of a normalized set if negate is set
+func (s runeSet) negateIf(negate bool) runeSet {
+	if !negate {
+		return s
+	}
+	out := runeSet{}
+	next := rune(0)
+	for i := 0; i+1 < len(s); i += 2 {
+		if s[i] > next {
+			out = append(out, next, s[i]-1)
+		}
+		next = s[i+1] + 1
+	}
+	if next <= unicode.MaxRune {
+		out = append(out, next, unicode.MaxRune)
+	}
+	return out
+}
+
+func (s runeSet) union(other runeSet) runeSet {
+	return append(append(runeSet{}, s...), other...).normalize()
+}
+
+func (s runeSet) subtract(other runeSet) runeSet {
+	// a - b is the complement of the union of the complement of a and b
+	return s.negateIf(true).union(other).negateIf(true)
+}
+
+// String returns the set as an RE2 character class
+func (s runeSet) String() string {
+	if len(s) == 0 {
+		return `[^\x00-\x{10FFFF}]`
+	}
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i+1 < len(s); i += 2 {
+		sb.WriteString(classRune(s[i]))
+		if s[i+1] != s[i] {
+			sb.WriteByte('-')
+			sb.WriteString(classRune(s[i+1]))
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// classRune writes alphanumerics as they are and escapes all other characters
+func classRune(r rune) string {
+	if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return string(r)
+	}
+	return fmt.Sprintf(`\x{%x}`, r)
+}
@@ -80,6 +80,12 @@ func (l *Lexer) Scan() (tok token.Token, pos token.Position, lit string) {
 		} else {
 			tok = token.IDENT
 		}
+		// prefixed byte strings h'00ff' and b64'AP8='
+		if (lit == "h" || lit == "b64") && l.chr == '\'' {
+			l.next()
+			tok = token.BYTES_LITERAL
+			lit = lit + "'" + l.scanString('\'') + "'"
+		}
 	case isDigit(chr):
 		tok, lit = l.scanNumber()
 	default:
@@ -87,7 +93,12 @@ func (l *Lexer) Scan() (tok token.Token, pos token.Position, lit string) {
 		lit = string(chr)
 		switch chr {
 		case '-':
-			tok = token.MINUS
+			if isDigit(l.chr) {
+				tok, lit = l.scanNumber()
+				lit = "-" + lit
+			} else {
+				tok = token.MINUS
+			}
 		case '?':
 			tok = token.OPTIONAL
 		case ';':
@@ -177,13 +188,20 @@ func (l *Lexer) Scan() (tok token.Token, pos token.Position, lit string) {
 			}
 		case '"':
 			tok = token.TEXT_LITERAL
-			lit = l.scanString()
+			lit = l.scanString('"')
+		case '\'':
+			tok = token.BYTES_LITERAL
+			lit = "'" + l.scanString('\'') + "'"
 		case EOF:
 			tok = token.EOF
 			lit = ""
 		}
 	}
+	// the line of the start of the token, as byte strings may span lines
 	lenLines := len(l.lineOffsets)
+	for lenLines > 1 && l.lineOffsets[lenLines-1] > startOffset {
+		lenLines--
+	}
 
 	pos = token.Position{
 		Offset: startOffset,
@@ -316,15 +334,22 @@ func (l *Lexer) scanOctalDigits() (tok token.Token, lit string) {
 	return token.INT, "0o" + string(l.src[offsetPre:l.offset])
 }
 
-func (l *Lexer) scanString() string {
+// scanString scans the contents of a string up to the closing quote. Byte strings may span
+// lines, as in the grammars of `.abnfb`, while text strings may not.
+func (l *Lexer) scanString(quote rune) string {
 	offsetPre := l.offset
 
 	for {
+		if l.chr == '\n' && quote == '\'' {
+			l.addLineOffset(l.offset)
+			l.next()
+			continue
+		}
 		if l.chr < 0 || l.chr == '\n' {
 			l.error(l.offset, "unexpected newline character before string termination")
 			break
 		}
-		if l.chr == '"' {
+		if l.chr == quote {
 			l.next()
 			break
 		}
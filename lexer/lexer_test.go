@@ -112,6 +112,11 @@ func TestNumbers(t *testing.T) {
 		{token.INT, "0x0", "0x0", ""},
 		{token.INT, "0x01", "0x01", ""},
 		{token.INT, "0x0f755b863f", "0x0f755b863f", ""},
+
+		// Negative
+		{token.INT, "-34", "-34", ""},
+		{token.INT, "-0x10", "-0x10", ""},
+		{token.FLOAT, "-3.5", "-3.5", ""},
 	}
 
 	for _, tst := range nums {
@@ -134,6 +139,39 @@ func TestNumbers(t *testing.T) {
 
 }
 
+func TestBytesLiteral(t *testing.T) {
+	tests := []struct {
+		src string
+		lit string
+	}{
+		{"'text'", "'text'"},
+		{"''", "''"},
+		{"h'00ff'", "h'00ff'"},
+		{"h'00 ff'", "h'00 ff'"},
+		{"b64'AP8='", "b64'AP8='"},
+	}
+
+	for _, tst := range tests {
+		l := lexer.NewLexer([]byte(tst.src))
+		tok, _, lit := l.Scan()
+
+		if tok != token.BYTES_LITERAL {
+			t.Errorf("token mismatch, expected `%s` got `%s` in `%s`", token.BYTES_LITERAL, tok, tst.src)
+		}
+		if lit != tst.lit {
+			t.Errorf("literal mismatch, expected `%s` got `%s`", tst.lit, lit)
+		}
+	}
+
+	// h and b64 are identifiers unless immediately followed by a quote
+	l := lexer.NewLexer([]byte("h b64"))
+	for _, expected := range []string{"h", "b64"} {
+		if tok, _, lit := l.Scan(); tok != token.IDENT || lit != expected {
+			t.Errorf("expected identifier %s got %s `%s`", expected, tok, lit)
+		}
+	}
+}
+
 var seed int64
 
 func rootDir() string {
@@ -0,0 +1,480 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/HannesKimara/cddlc/abnf"
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/ast/astutils"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/iregexp"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/resolver"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+var identifierCasing = &Check{
+	Name:     "identifier-casing",
+	Doc:      "rule names that do not follow the casing used by most rules or differ only in case",
+	Severity: parser.SeverityWarning,
+	run:      checkIdentifierCasing,
+}
+
+var duplicateKey = &Check{
+	Name:     "duplicate-key",
+	Doc:      "member keys defined more than once in the same map or group",
+	Severity: parser.SeverityError,
+	run:      checkDuplicateKey,
+}
+
+var overlappingChoice = &Check{
+	Name:     "overlapping-choice",
+	Doc:      "type choice alternatives matching the same values",
+	Severity: parser.SeverityWarning,
+	run:      checkOverlappingChoice,
+}
+
+var invalidRegexp = &Check{
+	Name:     "invalid-regexp",
+	Doc:      "computed .regexp patterns, e.g. built with .cat, that are not valid XSD regular expressions",
+	Severity: parser.SeverityError,
+	run:      checkInvalidRegexp,
+}
+
+var invalidABNF = &Check{
+	Name:     "invalid-abnf",
+	Doc:      "computed .abnf and .abnfb grammars that are not valid ABNF",
+	Severity: parser.SeverityError,
+	run:      checkInvalidABNF,
+}
+
+var ambiguousOptional = &Check{
+	Name:     "ambiguous-optional",
+	Doc:      "optional array entries followed by entries of overlapping types, making positional decoding ambiguous",
+	Severity: parser.SeverityWarning,
+	run:      checkAmbiguousOptional,
+}
+
+var emptySocket = &Check{
+	Name:     "empty-socket",
+	Doc:      "sockets referenced without any extension",
+	Severity: parser.SeverityWarning,
+	run:      checkEmptySocket,
+}
+
+// casing styles of identifiers
+const (
+	caseLower  = "lowercase"
+	caseKebab  = "kebab-case"
+	caseSnake  = "snake_case"
+	caseCamel  = "camelCase"
+	casePascal = "PascalCase"
+	caseUpper  = "UPPER_CASE"
+	caseMixed  = "mixed case"
+)
+
+func checkIdentifierCasing(p *pass) {
+	rules := []*ast.Rule{}
+	seen := map[string]bool{}
+	for _, rule := range p.rules() {
+		if !seen[rule.Name.Name] {
+			seen[rule.Name.Name] = true
+			rules = append(rules, rule)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, rule := range rules {
+		counts[casing(rule.Name.Name)]++
+	}
+	dominant := ""
+	for _, style := range []string{caseKebab, caseSnake, caseCamel, casePascal, caseUpper} {
+		if counts[style] > counts[dominant] {
+			dominant = style
+		}
+	}
+
+	folded := map[string]string{}
+	for _, rule := range rules {
+		name := rule.Name.Name
+		if other, ok := folded[strings.ToLower(name)]; ok {
+			p.report(rule.Name, "identifier %s differs from %s only in case", name, other)
+			continue
+		}
+		folded[strings.ToLower(name)] = name
+
+		if style := casing(name); dominant != "" && !compatible(style, dominant) {
+			p.report(rule.Name, "identifier %s uses %s while most rules use %s", name, style, dominant)
+		}
+	}
+}
+
+// casing classifies the style of an identifier ignoring socket prefixes
+func casing(name string) string {
+	name = strings.TrimLeft(name, "$")
+	hasUpper := strings.IndexFunc(name, unicode.IsUpper) >= 0
+	hasLower := strings.IndexFunc(name, unicode.IsLower) >= 0
+	hasDash := strings.Contains(name, "-")
+	hasUnderscore := strings.Contains(name, "_")
+
+	switch {
+	case !hasUpper && hasDash && !hasUnderscore:
+		return caseKebab
+	case !hasUpper && hasUnderscore && !hasDash:
+		return caseSnake
+	case !hasUpper && !hasDash && !hasUnderscore:
+		return caseLower
+	case hasUpper && !hasLower && !hasDash:
+		return caseUpper
+	case hasUpper && hasLower && !hasDash && !hasUnderscore:
+		if unicode.IsUpper([]rune(name)[0]) {
+			return casePascal
+		}
+		return caseCamel
+	}
+	return caseMixed
+}
+
+// compatible returns true if an identifier of style can appear in a schema using the dominant
+// style. Single lowercase words are valid in all lowercase styles.
+func compatible(style, dominant string) bool {
+	if style == dominant {
+		return true
+	}
+	return style == caseLower && (dominant == caseKebab || dominant == caseSnake || dominant == caseCamel)
+}
+
+func checkDuplicateKey(p *pass) {
+	p.inspect(func(node ast.Node) {
+		switch val := node.(type) {
+		case *ast.Map:
+			reportDuplicateKeys(p, val.Rules)
+		case *ast.Group:
+			entries := make([]ast.Node, 0, len(val.Entries))
+			for _, entry := range val.Entries {
+				entries = append(entries, entry)
+			}
+			reportDuplicateKeys(p, entries)
+		}
+	})
+}
+
+func reportDuplicateKeys(p *pass, entries []ast.Node) {
+	seen := map[string]*ast.Entry{}
+	for _, entry := range memberEntries(entries) {
+		key := entry.Token.String() + entry.Name.Name
+		if first, ok := seen[key]; ok {
+			p.report(entry.Name, "duplicate key %s, first defined at line %d, column %d", entry.Name.Name, first.Name.Pos.Line, first.Name.Pos.Column)
+			continue
+		}
+		seen[key] = entry
+	}
+}
+
+// memberEntries returns the members of a sequence of group entries including optional
+// members. Choices are not descended into since alternatives may reuse keys.
+func memberEntries(entries []ast.Node) []*ast.Entry {
+	out := []*ast.Entry{}
+	for _, entry := range entries {
+		switch val := entry.(type) {
+		case *ast.Entry:
+			if val.Name != nil {
+				out = append(out, val)
+			}
+		case *ast.Optional:
+			out = append(out, memberEntries([]ast.Node{val.Item})...)
+		case *ast.NMOccurrence:
+			out = append(out, memberEntries([]ast.Node{val.Item})...)
+		}
+	}
+	return out
+}
+
+func checkOverlappingChoice(p *pass) {
+	// nested holds the choices already checked as part of an enclosing choice
+	nested := map[*ast.TypeChoice]bool{}
+	p.inspect(func(node ast.Node) {
+		choice, ok := node.(*ast.TypeChoice)
+		if !ok || nested[choice] {
+			return
+		}
+		for next, ok := choice.Second.(*ast.TypeChoice); ok; next, ok = next.Second.(*ast.TypeChoice) {
+			nested[next] = true
+		}
+		alternatives := flattenChoice(choice)
+		for j := 1; j < len(alternatives); j++ {
+			for i := 0; i < j; i++ {
+				if overlaps(p.eval, alternatives[i], alternatives[j]) {
+					p.report(alternatives[j], "choice alternative %s overlaps with %s", describe(p.eval, alternatives[j]), describe(p.eval, alternatives[i]))
+					break
+				}
+			}
+		}
+	})
+}
+
+// flattenChoice returns the alternatives of nested type choices in order
+func flattenChoice(node ast.Node) []ast.Node {
+	if choice, ok := node.(*ast.TypeChoice); ok {
+		return append(flattenChoice(choice.First), flattenChoice(choice.Second)...)
+	}
+	return []ast.Node{node}
+}
+
+// literal patterns, also when named by a rule, are parse errors and never reach the linter
+func checkInvalidRegexp(p *pass) {
+	p.inspect(func(node ast.Node) {
+		re, ok := node.(*ast.Regexp)
+		if !ok || re.Regex == nil {
+			return
+		}
+		text, ok := p.eval.Eval(re.Regex).(*evaluator.Text)
+		if !ok {
+			return
+		}
+		if _, err := iregexp.Translate(text.Text); err != nil {
+			p.report(re.Regex, "invalid regular expression %s: %s", text, err)
+		}
+	})
+}
+
+// literal grammars are parse errors and never reach the linter
+func checkInvalidABNF(p *pass) {
+	p.inspect(func(node ast.Node) {
+		op, ok := node.(*ast.ABNF)
+		if !ok || op.Grammar == nil {
+			return
+		}
+		var err error
+		grammar := p.eval.Eval(op.Grammar)
+		switch val := grammar.(type) {
+		case *evaluator.Text:
+			_, err = abnf.Parse(val.Text)
+		case *evaluator.Bytes:
+			_, err = abnf.Parse(string(val.Bytes))
+		default:
+			return
+		}
+		if err != nil {
+			p.report(op.Grammar, "invalid ABNF grammar %s: %s", grammar, err)
+		}
+	})
+}
+
+func checkAmbiguousOptional(p *pass) {
+	p.inspect(func(node ast.Node) {
+		arr, ok := node.(*ast.Array)
+		if !ok {
+			return
+		}
+		for i, entry := range arr.Rules {
+			item, ok := optionalItem(entry)
+			if !ok {
+				continue
+			}
+			for _, next := range arr.Rules[i+1:] {
+				nextItem, optional := optionalItem(next)
+				if !optional {
+					nextItem = entryType(next)
+				}
+				if nextItem != nil && overlaps(p.eval, entryType(item), entryType(nextItem)) {
+					p.report(entry, "optional entry %s is followed by %s of an overlapping type, making positional decoding ambiguous", describe(p.eval, entryType(item)), describe(p.eval, entryType(nextItem)))
+					break
+				}
+				if !optional {
+					break
+				}
+			}
+		}
+	})
+}
+
+// optionalItem returns the item of entries occurring a variable number of times
+func optionalItem(node ast.Node) (ast.Node, bool) {
+	switch val := node.(type) {
+	case *ast.Optional:
+		return val.Item, true
+	case *ast.NMOccurrence:
+		if val.M == nil || val.N == nil || val.N.Literal != val.M.Literal {
+			return val.Item, true
+		}
+	}
+	return nil, false
+}
+
+// entryType returns the type of an array member
+func entryType(node ast.Node) ast.Node {
+	if entry, ok := node.(*ast.Entry); ok {
+		return entry.Value
+	}
+	return node
+}
+
+func checkEmptySocket(p *pass) {
+	for _, ref := range resolver.NewGraph(p.cddl).EmptySockets() {
+		p.report(ref.Name, "socket %s is referenced but has no extensions", ref.Name.Name)
+	}
+}
+
+// overlaps returns true if both nodes are known to match a common value. Nodes whose
+// values cannot be determined are assumed not to overlap.
+func overlaps(eval *evaluator.Evaluator, a, b ast.Node) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if x, ok := a.(*ast.Identifier); ok {
+		if y, ok := b.(*ast.Identifier); ok && x.Name == y.Name {
+			return true
+		}
+	}
+
+	va, vb := eval.Eval(a), eval.Eval(b)
+	switch {
+	case va != nil && vb != nil:
+		return valuesOverlap(va, vb)
+	case va != nil:
+		return typeContains(base(b), va)
+	case vb != nil:
+		return typeContains(base(a), vb)
+	}
+
+	ta, tb := base(a), base(b)
+	if ta == token.ILLEGAL || tb == token.ILLEGAL {
+		return false
+	}
+	return ta == tb || (ta == token.UINT && tb == token.INT) || (ta == token.INT && tb == token.UINT)
+}
+
+// base returns the prelude type token of the node or ILLEGAL if the node is not a prelude type
+func base(node ast.Node) token.Token {
+	switch node.(type) {
+	case *ast.UintType:
+		return token.UINT
+	case *ast.IntegerType:
+		return token.INT
+	case *ast.NegativeIntegerType:
+		return token.NINT
+	case *ast.FloatType:
+		return token.FLOAT
+	case *ast.TstrType:
+		return token.TSTR
+	case *ast.BstrType, *ast.BytesType:
+		return token.BSTR
+	case *ast.BooleanType:
+		return token.BOOL
+	case *ast.NullType:
+		return token.NULL
+	}
+	return token.ILLEGAL
+}
+
+// valuesOverlap returns true if two constant values share a common value
+func valuesOverlap(a, b evaluator.Value) bool {
+	ra, aRange := a.(*evaluator.Range)
+	rb, bRange := b.(*evaluator.Range)
+	switch {
+	case aRange && bRange:
+		return rangeContains(rb, ra.From) || rangeContains(ra, rb.From)
+	case aRange:
+		return rangeContains(ra, b)
+	case bRange:
+		return rangeContains(rb, a)
+	}
+	return a.String() == b.String()
+}
+
+// rangeContains returns true if the numeric value lies within the range
+func rangeContains(r *evaluator.Range, v evaluator.Value) bool {
+	lower, upper := compare(r.From, v), compare(v, r.To)
+	if lower == nil || upper == nil {
+		return false
+	}
+	return *lower <= 0 && (*upper < 0 || (*upper == 0 && !r.Exclusive))
+}
+
+// compare returns the ordering of two numeric values of the same type or nil
+func compare(a, b evaluator.Value) *int {
+	var out int
+	switch x := a.(type) {
+	case *evaluator.Integer:
+		y, ok := b.(*evaluator.Integer)
+		if !ok {
+			return nil
+		}
+		out = x.Int.Cmp(y.Int)
+	case *evaluator.Float:
+		y, ok := b.(*evaluator.Float)
+		if !ok {
+			return nil
+		}
+		switch {
+		case x.Float < y.Float:
+			out = -1
+		case x.Float > y.Float:
+			out = 1
+		}
+	default:
+		return nil
+	}
+	return &out
+}
+
+// typeContains returns true if the prelude type matches the constant value
+func typeContains(tok token.Token, v evaluator.Value) bool {
+	switch val := v.(type) {
+	case *evaluator.Integer:
+		switch tok {
+		case token.INT:
+			return true
+		case token.UINT:
+			return val.Int.Sign() >= 0
+		case token.NINT:
+			return val.Int.Sign() < 0
+		}
+	case *evaluator.Float:
+		return tok == token.FLOAT
+	case *evaluator.Text:
+		return tok == token.TSTR
+	case *evaluator.Bytes:
+		return tok == token.BSTR
+	case *evaluator.Bool:
+		return tok == token.BOOL
+	case *evaluator.Range:
+		return typeContains(tok, val.From) && typeContains(tok, val.To)
+	}
+	return false
+}
+
+// describe returns a short description of the node for diagnostics
+func describe(eval *evaluator.Evaluator, node ast.Node) string {
+	switch val := node.(type) {
+	case *ast.Identifier:
+		return val.Name
+	case *ast.Generic:
+		return val.Name.Name
+	}
+	if v := eval.Eval(node); v != nil {
+		return v.String()
+	}
+	if tok := base(node); tok != token.ILLEGAL {
+		return tok.String()
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+// inspect calls fn for the node and all its descendants
+func inspect(node ast.Node, fn func(ast.Node)) {
+	astutils.Walk(inspector(fn), node)
+}
+
+type inspector func(ast.Node)
+
+func (f inspector) Visit(node ast.Node) astutils.Visitor {
+	if node == nil {
+		return nil
+	}
+	f(node)
+	return f
+}
@@ -0,0 +1,235 @@
+// Package lint implements configurable checks for common smells in CDDL schemas.
+//
+// Every check has a name used to configure its severity and to suppress its findings
+// with a comment of the form `; cddlc:ignore check-name`. A suppression comment trailing
+// an entry applies to its line, a comment on its own line applies to the following line.
+// Omitting the check names suppresses all checks.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+const (
+	// ignoreDirective is the comment prefix suppressing diagnostics
+	ignoreDirective = "cddlc:ignore"
+
+	// SeverityOff disables a check in the configuration
+	SeverityOff = "off"
+)
+
+// Check represents a single lint check
+type Check struct {
+	// Name: the name used in configuration and suppression comments
+	Name string
+
+	// Doc: a one line description of the smell reported
+	Doc string
+
+	// Severity: the default severity of the diagnostics
+	Severity parser.Severity
+
+	run func(*pass)
+}
+
+// Checks returns the available checks in the order they are run
+func Checks() []*Check {
+	return []*Check{
+		identifierCasing,
+		duplicateKey,
+		overlappingChoice,
+		invalidRegexp,
+		invalidABNF,
+		ambiguousOptional,
+		emptySocket,
+	}
+}
+
+// Linter runs the enabled checks over a CDDL tree
+type Linter struct {
+	checks   []*Check
+	severity map[string]parser.Severity
+	disabled map[string]bool
+}
+
+// NewLinter returns a linter running all checks with their default severity
+func NewLinter() *Linter {
+	l := &Linter{
+		checks:   Checks(),
+		severity: make(map[string]parser.Severity),
+		disabled: make(map[string]bool),
+	}
+	for _, check := range l.checks {
+		l.severity[check.Name] = check.Severity
+	}
+	return l
+}
+
+// Configure sets the severity of the named checks. Valid severities are error, warning and off.
+// It returns an error for unknown checks or severities.
+func (l *Linter) Configure(rules map[string]string) error {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := l.severity[name]; !ok {
+			return fmt.Errorf("unknown lint check %s", name)
+		}
+		switch sev := strings.ToLower(rules[name]); sev {
+		case SeverityOff:
+			l.disabled[name] = true
+		case parser.SeverityError.String():
+			l.disabled[name] = false
+			l.severity[name] = parser.SeverityError
+		case parser.SeverityWarning.String():
+			l.disabled[name] = false
+			l.severity[name] = parser.SeverityWarning
+		default:
+			return fmt.Errorf("unknown severity %s for lint check %s, expected one of error, warning or off", rules[name], name)
+		}
+	}
+	return nil
+}
+
+// Enabled returns true if the named check is run
+func (l *Linter) Enabled(name string) bool {
+	_, ok := l.severity[name]
+	return ok && !l.disabled[name]
+}
+
+// Lint runs the enabled checks over the tree parsed from src. The source is scanned
+// for suppression comments. Diagnostics are returned in source order.
+func (l *Linter) Lint(src []byte, cddl *ast.CDDL) parser.ErrorList {
+	if cddl == nil {
+		return nil
+	}
+	ignored := suppressions(src)
+	eval := evaluator.NewEvaluator(cddl)
+
+	errs := parser.ErrorList{}
+	for _, check := range l.checks {
+		if l.disabled[check.Name] {
+			continue
+		}
+		p := &pass{cddl: cddl, eval: eval, check: check, severity: l.severity[check.Name]}
+		check.run(p)
+
+		for _, err := range p.errors {
+			if ignored.matches(err.Start().Line, check.Name) {
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, b := errs[i].Start(), errs[j].Start()
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return errs.Collect()
+}
+
+// pass holds the state of a single check run over a tree
+type pass struct {
+	cddl     *ast.CDDL
+	eval     *evaluator.Evaluator
+	check    *Check
+	severity parser.Severity
+	errors   []*parser.Error
+}
+
+// report adds a diagnostic for the node. The check name is appended to the message.
+func (p *pass) report(node ast.Node, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...) + " [" + p.check.Name + "]"
+	err := parser.NewError(msg, node.Start(), node.End())
+	err.Prefix = "lint"
+	err.Severity = p.severity
+	p.errors = append(p.errors, err)
+}
+
+// rules returns the rules of the tree in order of definition
+func (p *pass) rules() []*ast.Rule {
+	out := []*ast.Rule{}
+	for _, entry := range p.cddl.Rules {
+		if rule, ok := entry.(*ast.Rule); ok && rule.Name != nil && rule.Value != nil {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
+
+// inspect calls fn for every node in the rule definitions
+func (p *pass) inspect(fn func(ast.Node)) {
+	for _, rule := range p.rules() {
+		inspect(rule.Value, fn)
+	}
+}
+
+// suppressionSet maps line numbers to the suppressed check names. An empty set suppresses all checks.
+type suppressionSet map[int]map[string]bool
+
+func (s suppressionSet) matches(line int, check string) bool {
+	names, ok := s[line]
+	return ok && (len(names) == 0 || names[check])
+}
+
+// suppressions scans the source for `cddlc:ignore` comments
+func suppressions(src []byte) suppressionSet {
+	set := suppressionSet{}
+	lex := lexer.NewLexer(src)
+
+	lastLine := 0
+	for {
+		tok, pos, lit := lex.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.COMMENT {
+			lastLine = pos.Line
+			continue
+		}
+
+		text := strings.TrimSpace(lit)
+		if !strings.HasPrefix(text, ignoreDirective) {
+			continue
+		}
+		names := map[string]bool{}
+		for _, name := range strings.FieldsFunc(strings.TrimPrefix(text, ignoreDirective), isSeparator) {
+			names[name] = true
+		}
+
+		line := pos.Line
+		if lastLine != pos.Line {
+			// a comment on its own line applies to the following line
+			line++
+		}
+		if set[line] == nil || len(names) == 0 {
+			set[line] = names
+			continue
+		}
+		if len(set[line]) > 0 {
+			for name := range names {
+				set[line][name] = true
+			}
+		}
+	}
+	return set
+}
+
+func isSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
@@ -0,0 +1,162 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/lint"
+	"github.com/HannesKimara/cddlc/parser"
+)
+
+func parse(t *testing.T, src string) *ast.CDDL {
+	t.Helper()
+	l := lexer.NewLexer([]byte(src))
+	p := parser.NewParser(l)
+
+	cddl, errs := p.ParseFile()
+	if len(errs) != 0 {
+		t.Fatalf("%s: -> %s", src, errs)
+	}
+	return cddl
+}
+
+func lintSource(t *testing.T, l *lint.Linter, src string) parser.ErrorList {
+	t.Helper()
+	return l.Lint([]byte(src), parse(t, src))
+}
+
+func expectErrors(t *testing.T, src string, errs parser.ErrorList, expected []string) {
+	t.Helper()
+	if len(errs) != len(expected) {
+		t.Errorf("%s: expected %d diagnostics got %d: %s", src, len(expected), len(errs), errs)
+		return
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("%s: expected `%s` got `%s`", src, expected[i], err)
+		}
+	}
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{
+			"foo-bar = uint baz-qux = tstr fooBar = int",
+			[]string{"lint warning: identifier fooBar uses camelCase while most rules use kebab-case [identifier-casing]"},
+		},
+		{"foo-bar = uint baz = tstr qux = int", []string{}},
+		{
+			"m = {a: uint, a: tstr}",
+			[]string{"lint error: duplicate key a, first defined at line 1, column 6 [duplicate-key]"},
+		},
+		{
+			"m = {? b: int, * b: int}",
+			[]string{"lint error: duplicate key b, first defined at line 1, column 8 [duplicate-key]"},
+		},
+		{"m = {a: uint // a: tstr}", []string{}},
+		{
+			"c = 1..10 / 5..20",
+			[]string{"lint warning: choice alternative 5..20 overlaps with 1..10 [overlapping-choice]"},
+		},
+		{"c = 1..10 / 11..20", []string{}},
+		{`c = tstr / "a"`, []string{`lint warning: choice alternative "a" overlaps with tstr [overlapping-choice]`}},
+		{
+			`d = tstr .regexp p p = "a" .cat "("`,
+			[]string{`lint error: invalid regular expression "a(": iregexp: missing ) at offset 1 [invalid-regexp]`},
+		},
+		{`d = tstr .regexp "a+"`, []string{}},
+		{
+			`d = tstr .abnf ("a = " .cat g) g = "b"`,
+			[]string{`lint error: invalid ABNF grammar "a = b": abnf: rule b is not defined at line 1, column 5 [invalid-abnf]`},
+		},
+		{`d = tstr .abnf ("a = " .cat g) g = "%x61"`, []string{}},
+		{
+			"e = [? uint, uint]",
+			[]string{"lint warning: optional entry uint is followed by uint of an overlapping type, making positional decoding ambiguous [ambiguous-optional]"},
+		},
+		{"e = [? uint, tstr]", []string{}},
+		{"e = [uint, ? uint]", []string{}},
+	}
+
+	for _, tst := range tests {
+		errs := lintSource(t, lint.NewLinter(), tst.src)
+		expectErrors(t, tst.src, errs, tst.errs)
+	}
+}
+
+func TestEmptySocket(t *testing.T) {
+	src := "a = {* $$ext} b = $$ext2 $$ext2 //= (c: uint)"
+	errs := lintSource(t, lint.NewLinter(), src)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 diagnostic got %d: %s", len(errs), errs)
+	}
+	if !parser.IsWarning(errs[0]) {
+		t.Errorf("expected a warning got %s", errs[0])
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	src := "m = {a: uint, a: tstr} c = 1..10 / 5..20"
+
+	l := lint.NewLinter()
+	if err := l.Configure(map[string]string{"duplicate-key": "off", "overlapping-choice": "error"}); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if l.Enabled("duplicate-key") {
+		t.Errorf("expected duplicate-key to be disabled")
+	}
+	if !l.Enabled("overlapping-choice") {
+		t.Errorf("expected overlapping-choice to be enabled")
+	}
+	expectErrors(t, src, lintSource(t, l, src), []string{
+		"lint error: choice alternative 5..20 overlaps with 1..10 [overlapping-choice]",
+	})
+
+	invalid := []map[string]string{
+		{"no-such-check": "error"},
+		{"duplicate-key": "fatal"},
+	}
+	for _, rules := range invalid {
+		if err := lint.NewLinter().Configure(rules); err == nil {
+			t.Errorf("%v: expected configuration error", rules)
+		}
+	}
+}
+
+func TestLiteralPatterns(t *testing.T) {
+	// invalid literal patterns and grammars are parse errors and never reach the linter
+	for _, src := range []string{`d = tstr .regexp "a("`, `d = tstr .regexp p p = "a("`, `d = tstr .abnf "a = b"`} {
+		_, errs := parser.NewParser(lexer.NewLexer([]byte(src))).ParseFile()
+		if !errs.HasErrors() {
+			t.Errorf("%s: expected a parse error", src)
+		}
+	}
+}
+
+func TestSuppression(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected int
+	}{
+		{"m = {a: uint, a: tstr} ; cddlc:ignore duplicate-key", 0},
+		{"m = {a: uint, a: tstr} ; cddlc:ignore", 0},
+		{"m = {a: uint, a: tstr} ; cddlc:ignore invalid-regexp", 1},
+		{`d = tstr .regexp p ; cddlc:ignore invalid-regexp
+p = "a" .cat "("`, 0},
+		{"; cddlc:ignore duplicate-key\nm = {a: uint, a: tstr}", 0},
+		{"; cddlc:ignore duplicate-key\n\nm = {a: uint, a: tstr}", 1},
+		{"m = {a: uint, a: tstr} c = 1..10 / 5..20 ; cddlc:ignore overlapping-choice, duplicate-key", 0},
+		{"m = {a: uint, a: tstr} ; just a comment", 1},
+	}
+
+	for _, tst := range tests {
+		errs := lintSource(t, lint.NewLinter(), tst.src)
+		if len(errs) != tst.expected {
+			t.Errorf("%q: expected %d diagnostics got %d: %s", tst.src, tst.expected, len(errs), errs)
+		}
+	}
+}
@@ -7,6 +7,21 @@ import (
 	"github.com/HannesKimara/cddlc/token"
 )
 
+// Severity classifies an Error as fatal or as a warning that does not prevent further processing
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
 type Error struct {
 	// Range - the range of positions in the source causing the error
 	Range token.PositionRange
@@ -16,13 +31,16 @@ type Error struct {
 
 	// Prefix - Prefix of the string source e.g. parser, lexer
 	Prefix string
+
+	// Severity - whether the error is fatal or a warning. Defaults to SeverityError
+	Severity Severity
 }
 
 // String returns the string representation of the Error in the form
 //
 // `module` error: msg
 func (e *Error) String() string {
-	return fmt.Sprintf("%s error: %s", e.Prefix, e.Msg)
+	return fmt.Sprintf("%s %s: %s", e.Prefix, e.Severity, e.Msg)
 }
 
 // Diagnostic returns string formatted error with position
@@ -57,6 +75,19 @@ func NewError(msg string, start token.Position, end token.Position) *Error {
 	}
 }
 
+// NewWarning returns an Error with SeverityWarning and the provided parameters.
+func NewWarning(msg string, start token.Position, end token.Position) *Error {
+	err := NewError(msg, start, end)
+	err.Severity = SeverityWarning
+	return err
+}
+
+// IsWarning returns true if the diagnostic is an Error with SeverityWarning
+func IsWarning(err errors.Diagnostic) bool {
+	e, ok := err.(*Error)
+	return ok && e.Severity == SeverityWarning
+}
+
 // ErrorList encapsulates a collection of related errors.
 type ErrorList []errors.Diagnostic
 
@@ -99,3 +130,13 @@ func (er ErrorList) Collect() ErrorList {
 	}
 	return er
 }
+
+// HasErrors returns true if the list contains at least one diagnostic that is not a warning
+func (er ErrorList) HasErrors() bool {
+	for _, err := range er {
+		if !IsWarning(err) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,13 +2,17 @@
 package parser
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 
+	"github.com/HannesKimara/cddlc/abnf"
 	"github.com/HannesKimara/cddlc/ast"
 	"github.com/HannesKimara/cddlc/errors"
+	"github.com/HannesKimara/cddlc/iregexp"
 	"github.com/HannesKimara/cddlc/lexer"
 	"github.com/HannesKimara/cddlc/token"
 
@@ -29,6 +33,13 @@ func WithEnv(val *env.Environment) func(*Parser) {
 	}
 }
 
+// WithFilename sets the name of the parsed file recorded in the environment declarations
+func WithFilename(name string) func(*Parser) {
+	return func(p *Parser) {
+		p.filename = name
+	}
+}
+
 type Parser struct {
 	// instance of lexer
 	lexer *lexer.Lexer
@@ -69,6 +80,12 @@ type Parser struct {
 	// the error handling function
 	errorHandler func(err errors.Diagnostic)
 
+	// the scope of the rule being parsed. Encloses the generic parameters of the rule if any
+	scope *env.Environment
+
+	// the name of the parsed file
+	filename string
+
 	// hold tasks to be run after the completed ast build.
 	// used mostly to check types in type specific operators that may not exist in the environment at first pass
 	tasks []taskFn
@@ -139,9 +156,29 @@ func (p *Parser) parseRule() (_ ast.CDDLEntry, err errors.Diagnostic) {
 	rule.Name = &ast.Identifier{Pos: p.pos, Name: p.currliteral}
 	p.next()
 
+	if p.currToken == token.LEFT_ANGLE_BRACKET {
+		params, err := p.parseGenericParams()
+		if err != nil {
+			return nil, err
+		}
+		rule.Params = params
+		p.next()
+	}
+	p.scope = p.environment
+	if len(rule.Params) > 0 {
+		p.scope = env.NewEnclosedEnvironment(p.environment)
+		for _, param := range rule.Params {
+			err := p.scope.Declare(&env.Symbol{Name: param.Name, Value: param, Pos: param.Pos, File: p.filename})
+			if err == env.ErrSymbolExists {
+				p.errorHandler(p.error(fmt.Sprintf("duplicate generic parameter %s in definition of %s", param.Name, rule.Name.Name), param.Start(), param.End()))
+			}
+		}
+	}
+
 	var entry ast.Node
 
 	tok := p.currToken
+	rule.Token = tok
 	switch tok {
 	case token.ASSIGN:
 		p.next()
@@ -150,13 +187,13 @@ func (p *Parser) parseRule() (_ ast.CDDLEntry, err errors.Diagnostic) {
 			return rule, err
 		}
 		defer func() {
-			err := p.environment.Add(rule.Name.Name, entry)
+			err := p.environment.Declare(&env.Symbol{Name: rule.Name.Name, Value: entry, Pos: rule.Name.Pos, File: p.filename})
 
 			// Since the only error returned is ErrSymbolExists, check for that and
 			// append a error that type is already decalred.
 			if err == env.ErrSymbolExists {
-				val := p.environment.Get(rule.Name.Name)
-				p.errors = append(p.errors, NewError(fmt.Sprintf("existing declaration for identifier %s at line %d, column %d", rule.Name.Name, val.Start().Line, val.Start().Column), rule.Name.Pos, rule.Name.Pos))
+				sym, _ := p.environment.Lookup(rule.Name.Name)
+				p.errors = append(p.errors, NewError(fmt.Sprintf("existing declaration for identifier %s at %s", rule.Name.Name, declaredAt(sym)), rule.Name.Pos, rule.Name.Pos))
 			}
 		}()
 
@@ -173,12 +210,20 @@ func (p *Parser) parseRule() (_ ast.CDDLEntry, err errors.Diagnostic) {
 	if p.peekToken == token.COMMENT && isSameLineTokens(p.pos, p.peekPos) {
 		p.next()
 		rule.TrailingComment = p.parseInnerComment()
-		p.next()
 	}
 
 	return rule, nil
 }
 
+// declaredAt describes the position of a declaration for diagnostics
+func declaredAt(sym *env.Symbol) string {
+	at := fmt.Sprintf("line %d, column %d", sym.Pos.Line, sym.Pos.Column)
+	if sym.File != "" {
+		at = sym.File + " " + at
+	}
+	return at
+}
+
 // ParseEntryShould returns a parsed entry if of expected value else returns the error
 // TODO(HannesKimara): func(p *Parser) parseEntryShould(precedence, should ast.Node) (ast.Node, error)
 
@@ -229,16 +274,71 @@ func (p *Parser) parseIdentifier() (ast.Node, errors.Diagnostic) {
 func (p *Parser) parseNamedIdentifier() (ast.Node, errors.Diagnostic) {
 	literal := p.currliteral
 	pos := p.pos
+	scope := p.scope
 	if literal[0] != '$' {
 		p.tasks = append(p.tasks, func() errors.Diagnostic {
-			if !p.environment.Exists(literal) {
+			if !scope.Exists(literal) {
 				return p.error(fmt.Sprintf("identifier %s referenced does not exist", literal), pos, pos)
 			}
 			return nil
 		})
 	}
 
-	return &ast.Identifier{Pos: p.pos, Name: p.currliteral}, nil
+	ident := &ast.Identifier{Pos: p.pos, Name: p.currliteral}
+	if p.peekToken == token.LEFT_ANGLE_BRACKET {
+		return p.parseGenericArgs(ident)
+	}
+	return ident, nil
+}
+
+// parseGenericParams parses the parameters of a generic rule definition i.e. `<t, v>`
+func (p *Parser) parseGenericParams() ([]*ast.Identifier, errors.Diagnostic) {
+	params := []*ast.Identifier{}
+
+	for {
+		p.next()
+		if p.currToken != token.IDENT {
+			return nil, p.errorTokenExpected(p.pos, token.IDENT)
+		}
+		params = append(params, &ast.Identifier{Pos: p.pos, Name: p.currliteral})
+
+		p.next()
+		switch p.currToken {
+		case token.COMMA:
+		case token.RIGHT_ANGLE_BRACKET:
+			return params, nil
+		default:
+			return nil, p.errorTokenExpected(p.pos, token.RIGHT_ANGLE_BRACKET)
+		}
+	}
+}
+
+// parseGenericArgs parses the arguments to a generic rule reference i.e. `message<tstr, uint>`
+func (p *Parser) parseGenericArgs(name *ast.Identifier) (ast.Node, errors.Diagnostic) {
+	p.next()
+	generic := &ast.Generic{
+		Pos:  p.pos,
+		Name: name,
+	}
+
+	for {
+		p.next()
+		arg, err := p.parseEntry(token.COMMA.Precedence())
+		if err != nil {
+			return generic, err
+		}
+		generic.Args = append(generic.Args, arg)
+
+		p.next()
+		switch p.currToken {
+		case token.COMMA:
+		case token.RIGHT_ANGLE_BRACKET:
+			generic.Closing = p.pos
+			return generic, nil
+		default:
+			return generic, p.errorTokenExpected(p.pos, token.RIGHT_ANGLE_BRACKET)
+		}
+	}
 }
 
 func (p *Parser) parseBooleanType() (ast.Node, errors.Diagnostic) {
@@ -271,7 +371,16 @@ func (p *Parser) parseFloatType() (ast.Node, errors.Diagnostic) {
 	if p.currToken.IsLiteral(p.currliteral) {
 		return p.parseFloatLiteral()
 	}
-	return &ast.FloatType{Pos: p.pos, Token: p.currToken}, nil
+	ft := &ast.FloatType{Pos: p.pos, Token: p.currToken}
+	switch ft.Token {
+	case token.FLOAT16:
+		ft.Base = 16
+	case token.FLOAT32:
+		ft.Base = 32
+	case token.FLOAT64:
+		ft.Base = 64
+	}
+	return ft, nil
 }
 
 func (p *Parser) parseFloatLiteral() (ast.Node, errors.Diagnostic) {
@@ -312,6 +421,12 @@ func (p *Parser) parseUintLiteral() (*ast.UintLiteral, errors.Diagnostic) {
 
 func (p *Parser) parseIntegerType() (ast.Node, errors.Diagnostic) {
 	if p.currToken.IsLiteral(p.currliteral) {
+		// literals in (2^63-1, 2^64-1] are only representable as uint
+		if _, err := strconv.ParseInt(p.currliteral, 0, 64); err != nil {
+			if _, err := strconv.ParseUint(p.currliteral, 0, 64); err == nil {
+				return p.parseUintLiteral()
+			}
+		}
 		return p.parseIntegerLiteral()
 	}
 	return &ast.IntegerType{Pos: p.pos, Token: p.currToken}, nil
@@ -329,6 +444,10 @@ func (p *Parser) parseBytesType() (ast.Node, errors.Diagnostic) {
 	return &ast.BytesType{Pos: p.pos, Token: p.currToken}, nil
 }
 
+func (p *Parser) parseAnyType() (ast.Node, errors.Diagnostic) {
+	return &ast.AnyType{Pos: p.pos, Token: p.currToken}, nil
+}
+
 func (p *Parser) parseNullType() (ast.Node, errors.Diagnostic) {
 	return &ast.NullType{Pos: p.pos, Token: p.currToken}, nil
 }
@@ -348,12 +467,14 @@ func (p *Parser) parseColon(left ast.Node) (ast.Node, errors.Diagnostic) {
 		return nil, err
 	}
 	rule := &ast.Entry{
-		Pos:  p.pos,
-		Name: ident,
+		Pos:   p.pos,
+		Token: p.currToken,
+		Name:  ident,
 	}
+	precedence := p.currToken.Precedence()
 	p.next()
 
-	val, err := p.parseEntry(p.currToken.Precedence())
+	val, err := p.parseEntry(precedence)
 	if err != nil {
 		return val, err
 	}
@@ -377,7 +498,8 @@ func (p *Parser) parseTypeChoice(left ast.Node) (ast.Node, errors.Diagnostic) {
 		First: left,
 	}
 	p.next()
-	sec, err := p.parseEntry(p.currToken.Precedence()) // TODO: Check is type
+	// whether the alternatives are types is checked after parsing by the checker package
+	sec, err := p.parseEntry(tc.Token.Precedence() - 1)
 	if err != nil {
 		return sec, err
 	}
@@ -386,13 +508,14 @@ func (p *Parser) parseTypeChoice(left ast.Node) (ast.Node, errors.Diagnostic) {
 }
 
 func (p *Parser) parseGroupChoice(left ast.Node) (ast.Node, errors.Diagnostic) {
-	gc := &ast.TypeChoice{
+	gc := &ast.GroupChoice{
 		Pos:   p.pos,
 		Token: p.currToken,
 		First: left,
 	}
 	p.next()
-	sec, err := p.parseEntry(p.currToken.Precedence()) // TODO: Check is group
+	// whether the alternatives are groups is checked after parsing by the checker package
+	sec, err := p.parseEntry(gc.Token.Precedence() - 1)
 	if err != nil {
 		return sec, err
 	}
@@ -543,7 +666,10 @@ func (p *Parser) parseSizeOperator(left ast.Node) (ast.Node, errors.Diagnostic)
 	}
 
 	switch val := left.(type) {
-	case *ast.BstrType, *ast.UintType, *ast.TstrType:
+	case *ast.BstrType, *ast.BytesType, *ast.UintType, *ast.TstrType:
+		sop.Type = val
+	case *ast.Identifier:
+		// the referenced type is checked after parsing by the checker package
 		sop.Type = val
 	default:
 		err := p.errorUnsupportedTypes(sop.Pos, p.currliteral, token.TSTR, token.BSTR, token.UINT)
@@ -551,7 +677,7 @@ func (p *Parser) parseSizeOperator(left ast.Node) (ast.Node, errors.Diagnostic)
 	}
 	p.next()
 
-	right, err := p.parseEntry(p.currToken.Precedence())
+	right, err := p.parseEntry(sop.Token.Precedence())
 	if err != nil {
 		return sop, err
 	}
@@ -560,8 +686,10 @@ func (p *Parser) parseSizeOperator(left ast.Node) (ast.Node, errors.Diagnostic)
 	return sop, nil
 }
 
-// TODO :: Evaluate that the regex is valid and compiles according to
-// https://www.rfc-editor.org/rfc/rfc8610#section-3.8.3
+// parseRegexp parses the `.regexp` control and checks that the expression is a valid XSD
+// regular expression https://www.rfc-editor.org/rfc/rfc8610#section-3.8.3 supported by the
+// translation to RE2. Expressions named by an identifier are checked once all rules are
+// parsed.
 func (p *Parser) parseRegexp(left ast.Node) (ast.Node, errors.Diagnostic) {
 	var base *ast.TstrType
 	if b, ok := left.(*ast.TstrType); ok {
@@ -578,14 +706,178 @@ func (p *Parser) parseRegexp(left ast.Node) (ast.Node, errors.Diagnostic) {
 		return r, p.errorTokenExpected(p.pos, token.TEXT_LITERAL)
 	}
 	p.next()
-	regex, err := p.parseEntry(p.currToken.Precedence())
+	regex, err := p.parseEntry(r.Token.Precedence())
 	if err != nil {
 		return r, err
 	}
 	r.Regex = regex
+
+	switch val := regex.(type) {
+	case *ast.TextLiteral:
+		return r, p.checkRegexp(val)
+	case *ast.Identifier:
+		scope := p.scope
+		p.tasks = append(p.tasks, func() errors.Diagnostic {
+			if lit, ok := scope.Get(val.Name).(*ast.TextLiteral); ok {
+				return p.checkRegexp(lit)
+			}
+			return nil
+		})
+	}
 	return r, nil
 }
 
+// checkRegexp reports the first unsupported construct of a regular expression at its
+// position in the text literal
+func (p *Parser) checkRegexp(lit *ast.TextLiteral) errors.Diagnostic {
+	_, err := iregexp.Translate(lit.Literal)
+	if err == nil {
+		return nil
+	}
+	rerr := err.(*iregexp.Error)
+	// the literal starts after its opening quote
+	pos := lit.Pos.To(rerr.Offset + 1)
+	return p.error("invalid regular expression: "+rerr.Msg, pos, pos)
+}
+
+// parseABNF parses the `.abnf` and `.abnfb` controls of RFC 9165 and checks grammars given
+// as literals, or as identifiers of literals once all rules are parsed. Grammars computed
+// with `.cat` or `.det` are checked when evaluated.
+func (p *Parser) parseABNF(left ast.Node) (ast.Node, errors.Diagnostic) {
+	a := &ast.ABNF{
+		Pos:   p.pos,
+		Token: p.currToken,
+		Base:  left,
+	}
+	// the grammar is parsed past a base of the wrong type so that parsing resumes after it
+	var typeErr errors.Diagnostic
+	switch left.(type) {
+	case *ast.TstrType:
+		if a.Token != token.ABNF {
+			typeErr = p.errorUnsupportedTypes(a.Pos, p.currliteral, token.BSTR, token.BYTES)
+		}
+	case *ast.BstrType, *ast.BytesType:
+		if a.Token != token.ABNFB {
+			typeErr = p.errorUnsupportedTypes(a.Pos, p.currliteral, token.TSTR)
+		}
+	default:
+		if a.Token == token.ABNF {
+			typeErr = p.errorUnsupportedTypes(a.Pos, p.currliteral, token.TSTR)
+		} else {
+			typeErr = p.errorUnsupportedTypes(a.Pos, p.currliteral, token.BSTR, token.BYTES)
+		}
+	}
+
+	p.next()
+	grammar, err := p.parseEntry(a.Token.Precedence())
+	if err != nil {
+		return a, err
+	}
+	a.Grammar = grammar
+	if typeErr != nil {
+		return a, typeErr
+	}
+
+	if ident, ok := grammar.(*ast.Identifier); ok {
+		scope := p.scope
+		p.tasks = append(p.tasks, func() errors.Diagnostic {
+			return p.checkABNF(scope.Get(ident.Name))
+		})
+		return a, nil
+	}
+	return a, p.checkABNF(grammar)
+}
+
+// checkABNF reports the first error of a grammar given as a literal at its position in the
+// literal
+func (p *Parser) checkABNF(node ast.Node) errors.Diagnostic {
+	var src string
+	var start token.Position
+	switch lit := node.(type) {
+	case *ast.TextLiteral:
+		src, start = lit.Literal, lit.Pos.To(1)
+	case *ast.BytesLiteral:
+		src, start = string(lit.Literal), lit.Pos
+		if strings.HasPrefix(lit.Source, "'") {
+			start = lit.Pos.To(1)
+		}
+	default:
+		return nil
+	}
+
+	_, err := abnf.Parse(src)
+	if err == nil {
+		return nil
+	}
+	serr := err.(*abnf.SyntaxError)
+	pos := start
+	if serr.Line == 1 {
+		pos = start.To(serr.Column - 1)
+	} else {
+		lines := strings.SplitAfterN(src, "\n", serr.Line)
+		offset := len(src) - len(lines[len(lines)-1])
+		pos = token.Position{Offset: start.Offset + offset + serr.Column - 1, Line: start.Line + serr.Line - 1, Column: serr.Column}
+	}
+	return p.error("invalid ABNF grammar: "+serr.Msg, pos, pos)
+}
+
+func (p *Parser) parseCBORControl(left ast.Node) (ast.Node, errors.Diagnostic) {
+	c := &ast.CBORControl{
+		Pos:   p.pos,
+		Token: p.currToken,
+		Base:  left,
+	}
+	var typeErr errors.Diagnostic
+	switch left.(type) {
+	case *ast.BstrType, *ast.BytesType:
+	default:
+		typeErr = p.errorUnsupportedTypes(c.Pos, p.currliteral, token.BSTR, token.BYTES)
+	}
+
+	p.next()
+	controller, err := p.parseEntry(c.Token.Precedence())
+	if err != nil {
+		return c, err
+	}
+	c.Controller = controller
+	return c, typeErr
+}
+
+// parseDefault parses the `.default` control. The default value is evaluated where it is
+// used by the validator and the code generator.
+func (p *Parser) parseDefault(left ast.Node) (ast.Node, errors.Diagnostic) {
+	d := &ast.DefaultControl{
+		Pos:   p.pos,
+		Token: p.currToken,
+		Base:  left,
+	}
+
+	p.next()
+	value, err := p.parseEntry(d.Token.Precedence())
+	if err != nil {
+		return d, err
+	}
+	d.Default = value
+	return d, nil
+}
+
+// parseFeature parses the RFC 9165 `.feature` control
+func (p *Parser) parseFeature(left ast.Node) (ast.Node, errors.Diagnostic) {
+	f := &ast.FeatureControl{
+		Pos:   p.pos,
+		Token: p.currToken,
+		Base:  left,
+	}
+
+	p.next()
+	feature, err := p.parseEntry(f.Token.Precedence())
+	if err != nil {
+		return f, err
+	}
+	f.Feature = feature
+	return f, nil
+}
+
 func (p *Parser) parseBits(left ast.Node) (ast.Node, errors.Diagnostic) {
 	b := &ast.Bits{
 		Pos:   p.pos,
@@ -594,7 +886,7 @@ func (p *Parser) parseBits(left ast.Node) (ast.Node, errors.Diagnostic) {
 	}
 
 	p.next()
-	constraint, err := p.parseEntry(p.currToken.Precedence())
+	constraint, err := p.parseEntry(b.Token.Precedence())
 	if err != nil {
 		b.Base = wrapBadNode(constraint)
 		return b, err
@@ -603,6 +895,33 @@ func (p *Parser) parseBits(left ast.Node) (ast.Node, errors.Diagnostic) {
 	return b, nil
 }
 
+// parseComputedOp parses the RFC 9165 operators `.plus, .cat, .det`. The operands are
+// evaluated after parsing by the evaluator package
+func (p *Parser) parseComputedOp(left ast.Node) (ast.Node, errors.Diagnostic) {
+	// `.det` on a byte string type requires the deterministic encoding of a data item rather
+	// than dedenting text
+	if p.currToken == token.DET {
+		switch left.(type) {
+		case *ast.BstrType, *ast.BytesType:
+			return p.parseCBORControl(left)
+		}
+	}
+
+	op := &ast.ComputedOpControl{
+		Pos:   p.pos,
+		Token: p.currToken,
+		Left:  left,
+	}
+	p.next()
+
+	right, err := p.parseEntry(op.Token.Precedence())
+	if err != nil {
+		return op, err
+	}
+	op.Right = right
+	return op, nil
+}
+
 func (p *Parser) parseIntegerLiteral() (*ast.IntegerLiteral, errors.Diagnostic) {
 	if p.currToken.IsLiteral(p.currliteral) {
 		lit, err := strconv.ParseInt(p.currliteral, 0, 64)
@@ -622,6 +941,37 @@ func (p *Parser) parseTextLiteral() (ast.Node, errors.Diagnostic) {
 	return &ast.TextLiteral{Pos: p.pos, Token: p.currToken, Literal: p.currliteral}, nil
 }
 
+// parseBytesLiteral parses and decodes the byte string literals 'text', h'00ff' and b64'AP8='
+func (p *Parser) parseBytesLiteral() (ast.Node, errors.Diagnostic) {
+	bl := &ast.BytesLiteral{Pos: p.pos, Token: p.currToken, Source: p.currliteral}
+
+	prefix, quoted, _ := strings.Cut(p.currliteral, "'")
+	content := strings.TrimSuffix(quoted, "'")
+
+	var err error
+	switch prefix {
+	case "":
+		bl.Literal = []byte(content)
+	case "h":
+		bl.Literal, err = hex.DecodeString(stripWhitespace(content))
+	case "b64":
+		content = stripWhitespace(content)
+		bl.Literal, err = base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			bl.Literal, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(content, "="))
+		}
+	}
+	if err != nil {
+		return bl, p.error(fmt.Sprintf("invalid byte string literal %s: %s", p.currliteral, err), p.pos, bl.End())
+	}
+	return bl, nil
+}
+
+// stripWhitespace removes the whitespace allowed between the digits of hex and base64 literals
+func stripWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
 func (p *Parser) parseGroup() (ast.Node, errors.Diagnostic) {
 	g := &ast.Group{}
 	g.Pos = p.pos
@@ -726,8 +1076,6 @@ func (p *Parser) parseInnerComment() *ast.Comment {
 }
 
 func (p *Parser) parseComparatorOp(left ast.Node) (ast.Node, errors.Diagnostic) {
-	var leftI *ast.IntegerType
-
 	switch left.(type) {
 	case *ast.UintType, *ast.IntegerType, *ast.FloatType:
 	default:
@@ -737,7 +1085,7 @@ func (p *Parser) parseComparatorOp(left ast.Node) (ast.Node, errors.Diagnostic)
 	op := &ast.ComparatorOpControl{
 		Pos:      p.pos,
 		Token:    p.currToken,
-		Left:     leftI,
+		Left:     left,
 		Operator: p.currliteral,
 	}
 	if !p.peekToken.IsNumeric() {
@@ -745,9 +1093,9 @@ func (p *Parser) parseComparatorOp(left ast.Node) (ast.Node, errors.Diagnostic)
 	}
 	p.next()
 
-	right, err := p.parseEntry(p.currToken.Precedence())
+	right, err := p.parseEntry(op.Token.Precedence())
 	if err != nil {
-		return &ast.BadNode{Base: leftI, Token: p.currToken}, err
+		return &ast.BadNode{Base: left, Token: p.currToken}, err
 	}
 	op.Right = right
 
@@ -817,6 +1165,7 @@ func (p *Parser) parseOccurrence(left ast.Node) (ast.Node, errors.Diagnostic) {
 }
 
 func (p *Parser) parseIdentBound(left *ast.Identifier) (*ast.Range, errors.Diagnostic) {
+	scope := p.scope
 	b := &ast.Range{
 		Pos:   p.pos,
 		Token: p.currToken,
@@ -824,18 +1173,18 @@ func (p *Parser) parseIdentBound(left *ast.Identifier) (*ast.Range, errors.Diagn
 	}
 
 	p.next()
-	to, err := p.parseEntry(p.currToken.Precedence())
+	to, err := p.parseEntry(b.Token.Precedence())
 	if err != nil {
 		return b, err
 	}
 	b.To = to
 
 	p.tasks = append(p.tasks, func() errors.Diagnostic {
-		valLeft := p.environment.Get(left.Name)
+		valLeft := scope.Get(left.Name)
 		to := b.To
 		switch val := to.(type) {
 		case *ast.Identifier:
-			valRight := p.environment.Get(val.Name)
+			valRight := scope.Get(val.Name)
 			if !(reflect.TypeOf(valLeft) == reflect.TypeOf(valRight)) {
 				return p.error(
 					fmt.Sprintf("operator %s expected same type min, max values. The values of %s and %s resolve to %+v and %+v", b.Token, left.Name, val.Name, valLeft, valRight),
@@ -848,6 +1197,7 @@ func (p *Parser) parseIdentBound(left *ast.Identifier) (*ast.Range, errors.Diagn
 }
 
 func (p *Parser) parseIntBound(left *ast.IntegerLiteral) (*ast.Range, errors.Diagnostic) {
+	scope := p.scope
 	b := &ast.Range{
 		Pos:   p.pos,
 		Token: p.currToken,
@@ -855,7 +1205,7 @@ func (p *Parser) parseIntBound(left *ast.IntegerLiteral) (*ast.Range, errors.Dia
 	}
 
 	p.next()
-	to, err := p.parseEntry(p.currToken.Precedence())
+	to, err := p.parseEntry(b.Token.Precedence())
 	if err != nil {
 		return b, err
 	}
@@ -873,13 +1223,13 @@ func (p *Parser) parseIntBound(left *ast.IntegerLiteral) (*ast.Range, errors.Dia
 		identEnd := right.End()
 
 		p.tasks = append(p.tasks, func() errors.Diagnostic {
-			if !p.environment.Exists(right.Name) {
+			if !scope.Exists(right.Name) {
 				return p.error(fmt.Sprintf("identifier %s referenced does not exist", ident), identStart, identEnd)
 			}
-			val := p.environment.Get(ident)
+			val := scope.Get(ident)
 			switch val.(type) {
-			case *ast.IntegerLiteral:
-				// pass
+			case *ast.IntegerLiteral, *ast.UintLiteral, *ast.ComputedOpControl, *ast.Identifier:
+				// pass. computed values, aliases and generic parameters are checked by the evaluator
 			case *ast.FloatLiteral:
 				return p.error("cannot use float literal as upper bound to int range", identStart, identEnd)
 			default:
@@ -887,12 +1237,15 @@ func (p *Parser) parseIntBound(left *ast.IntegerLiteral) (*ast.Range, errors.Dia
 			}
 			return nil
 		})
+	default:
+		b.To = right
 	}
 
 	return b, nil
 }
 
 func (p *Parser) parseFloatBound(left *ast.FloatLiteral) (*ast.Range, errors.Diagnostic) {
+	scope := p.scope
 	b := &ast.Range{
 		Pos:   p.pos,
 		Token: p.currToken,
@@ -900,7 +1253,7 @@ func (p *Parser) parseFloatBound(left *ast.FloatLiteral) (*ast.Range, errors.Dia
 	}
 
 	p.next()
-	to, err := p.parseEntry(p.currToken.Precedence())
+	to, err := p.parseEntry(b.Token.Precedence())
 	if err != nil {
 		return b, err
 	}
@@ -918,13 +1271,13 @@ func (p *Parser) parseFloatBound(left *ast.FloatLiteral) (*ast.Range, errors.Dia
 		identEnd := right.End()
 
 		p.tasks = append(p.tasks, func() errors.Diagnostic {
-			if !p.environment.Exists(right.Name) {
+			if !scope.Exists(right.Name) {
 				return p.error(fmt.Sprintf("identifier %s referenced does not exist", ident), identStart, identEnd)
 			}
-			val := p.environment.Get(ident)
+			val := scope.Get(ident)
 			switch val.(type) {
-			case *ast.FloatLiteral:
-				// pass
+			case *ast.FloatLiteral, *ast.ComputedOpControl, *ast.Identifier:
+				// pass. computed values, aliases and generic parameters are checked by the evaluator
 			case *ast.IntegerLiteral:
 				return p.error("cannot use integer literal as upper bound to float range", identStart, identEnd)
 			default:
@@ -932,6 +1285,8 @@ func (p *Parser) parseFloatBound(left *ast.FloatLiteral) (*ast.Range, errors.Dia
 			}
 			return nil
 		})
+	default:
+		b.To = right
 	}
 
 	return b, nil
@@ -986,8 +1341,9 @@ func NewParser(lexer *lexer.Lexer, opts ...ConfigOpts) *Parser {
 	}
 
 	if p.environment == nil {
-		p.environment = env.NewEnvironment()
+		p.environment = env.NewEnclosedEnvironment(env.NewPrelude())
 	}
+	p.scope = p.environment
 	p.error = func(msg string, start, end token.Position) errors.Diagnostic {
 		return NewError(msg, start, end)
 	}
@@ -1006,6 +1362,7 @@ func NewParser(lexer *lexer.Lexer, opts ...ConfigOpts) *Parser {
 	p.registerNud(token.TSTR, p.parseTstrType)
 	p.registerNud(token.TEXT, p.parseTstrType)
 	p.registerNud(token.TEXT_LITERAL, p.parseTextLiteral)
+	p.registerNud(token.BYTES_LITERAL, p.parseBytesLiteral)
 	p.registerNud(token.FLOAT, p.parseFloatType)
 	p.registerNud(token.FLOAT16, p.parseFloatType)
 	p.registerNud(token.FLOAT32, p.parseFloatType)
@@ -1017,6 +1374,7 @@ func NewParser(lexer *lexer.Lexer, opts ...ConfigOpts) *Parser {
 	p.registerNud(token.BYTES, p.parseBytesType)
 	p.registerNud(token.NULL, p.parseNullType)
 	p.registerNud(token.NIL, p.parseNullType)
+	p.registerNud(token.ANY, p.parseAnyType)
 	p.registerNud(token.LBRACE, p.parseMap)
 	p.registerNud(token.LPAREN, p.parseGroup)
 	p.registerNud(token.LBRACK, p.parseArray)
@@ -1040,6 +1398,15 @@ func NewParser(lexer *lexer.Lexer, opts ...ConfigOpts) *Parser {
 	p.leds[token.SIZE] = p.parseSizeOperator
 	p.leds[token.REGEXP] = p.parseRegexp
 	p.leds[token.BITS] = p.parseBits
+	p.leds[token.ABNF] = p.parseABNF
+	p.leds[token.ABNFB] = p.parseABNF
+	p.leds[token.CBOR] = p.parseCBORControl
+	p.leds[token.CBORSEQ] = p.parseCBORControl
+	p.leds[token.DEFAULT] = p.parseDefault
+	p.leds[token.FEATURE] = p.parseFeature
+	p.leds[token.PLUS] = p.parseComputedOp
+	p.leds[token.CAT] = p.parseComputedOp
+	p.leds[token.DET] = p.parseComputedOp
 
 	p.leds[token.INCLUSIVE_BOUND] = p.parseBound
 	p.leds[token.EXCLUSIVE_BOUND] = p.parseBound
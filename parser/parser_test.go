@@ -1,6 +1,7 @@
 package parser_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -92,6 +93,39 @@ func testWalk(t *testing.T, valid, parsed ast.Node) {
 			t.Fatalf("expected node of type %T but found %T", valid, parsed)
 			return
 		}
+	case *ast.BytesLiteral:
+		if p, ok := parsed.(*ast.BytesLiteral); ok {
+			if !reflect.DeepEqual(val.Literal, p.Literal) {
+				t.Errorf("Bytes literals do not match. Expected %x got %x", val.Literal, p.Literal)
+			}
+		} else {
+			t.Fatalf("expected node of type %T but found %T", valid, parsed)
+			return
+		}
+	case *ast.ComputedOpControl:
+		if p, ok := parsed.(*ast.ComputedOpControl); ok && val.Token == p.Token {
+			testWalk(t, val.Left, p.Left)
+			testWalk(t, val.Right, p.Right)
+		} else {
+			t.Fatalf("expected node of type %T with operator %s but found %T", valid, val.Token, parsed)
+			return
+		}
+	case *ast.DefaultControl:
+		if p, ok := parsed.(*ast.DefaultControl); ok {
+			testWalk(t, val.Base, p.Base)
+			testWalk(t, val.Default, p.Default)
+		} else {
+			t.Fatalf("expected node of type %T but found %T", valid, parsed)
+			return
+		}
+	case *ast.FeatureControl:
+		if p, ok := parsed.(*ast.FeatureControl); ok {
+			testWalk(t, val.Base, p.Base)
+			testWalk(t, val.Feature, p.Feature)
+		} else {
+			t.Fatalf("expected node of type %T but found %T", valid, parsed)
+			return
+		}
 	case *ast.Regexp:
 		if p, ok := parsed.(*ast.Regexp); ok {
 			testWalk(t, val.Base, p.Base)
@@ -145,6 +179,17 @@ func testWalk(t *testing.T, valid, parsed ast.Node) {
 		if p, ok := parsed.(*ast.TypeChoice); ok {
 			testWalk(t, val.First, p.First)
 			testWalk(t, val.Second, p.Second)
+		} else {
+			t.Fatalf("expected node of type %T but found %T", valid, parsed)
+			return
+		}
+	case *ast.GroupChoice:
+		if p, ok := parsed.(*ast.GroupChoice); ok {
+			testWalk(t, val.First, p.First)
+			testWalk(t, val.Second, p.Second)
+		} else {
+			t.Fatalf("expected node of type %T but found %T", valid, parsed)
+			return
 		}
 
 	case *ast.SizeOperatorControl:
@@ -169,6 +214,16 @@ func testWalk(t *testing.T, valid, parsed ast.Node) {
 			t.Fatalf("expected node of type %T but found %T", valid, parsed)
 			return
 		}
+	case *ast.Generic:
+		if p, ok := parsed.(*ast.Generic); ok && len(val.Args) == len(p.Args) {
+			testWalk(t, val.Name, p.Name)
+			for i := 0; i < len(val.Args); i++ {
+				testWalk(t, val.Args[i], p.Args[i])
+			}
+		} else {
+			t.Fatalf("expected node of type %T with %d args but found %T", valid, len(val.Args), parsed)
+			return
+		}
 	case *ast.Enumeration:
 		if p, ok := parsed.(*ast.Enumeration); ok {
 			if val.Value != nil && p.Value != nil {
@@ -204,9 +259,9 @@ func TestParseIdentiferToTypeRule(t *testing.T) {
 		{"name = nint", &ast.NegativeIntegerType{Pos: typePos, Token: token.NINT}, nil},
 		{"name = int", &ast.IntegerType{Pos: typePos, Token: token.INT}, nil},
 		{"name = float", &ast.FloatType{Pos: typePos, Token: token.FLOAT}, nil},
-		{"name = float16", &ast.FloatType{Pos: typePos, Token: token.FLOAT16}, nil},
-		{"name = float32", &ast.FloatType{Pos: typePos, Token: token.FLOAT32}, nil},
-		{"name = float64", &ast.FloatType{Pos: typePos, Token: token.FLOAT64}, nil},
+		{"name = float16", &ast.FloatType{Pos: typePos, Token: token.FLOAT16, Base: 16}, nil},
+		{"name = float32", &ast.FloatType{Pos: typePos, Token: token.FLOAT32, Base: 32}, nil},
+		{"name = float64", &ast.FloatType{Pos: typePos, Token: token.FLOAT64, Base: 64}, nil},
 
 		{"name = bytes", &ast.BytesType{Pos: typePos, Token: token.BYTES}, nil},
 		{"name = bstr", &ast.BstrType{Pos: typePos, Token: token.BSTR}, nil},
@@ -270,6 +325,9 @@ func TestNumericLiteral(t *testing.T) {
 		{"num = 2.4", &ast.FloatLiteral{Literal: 2.4, Token: token.FLOAT}, parser.ErrorList{}},
 		{"num = 0x10", &ast.IntegerLiteral{Literal: 16, Token: token.INT}, parser.ErrorList{}},
 		{"num = 0.10", &ast.FloatLiteral{Literal: 0.1, Token: token.FLOAT}, parser.ErrorList{}},
+		{"num = -34", &ast.IntegerLiteral{Literal: -34, Token: token.INT}, parser.ErrorList{}},
+		{"num = -2.5", &ast.FloatLiteral{Literal: -2.5, Token: token.FLOAT}, parser.ErrorList{}},
+		{"num = 18446744073709551615", &ast.UintLiteral{Literal: 18446744073709551615, Token: token.UINT}, parser.ErrorList{}},
 	}
 
 	for _, tst := range tests {
@@ -288,6 +346,141 @@ func TestNumericLiteral(t *testing.T) {
 	}
 }
 
+func TestBytesLiteral(t *testing.T) {
+	name := &ast.Identifier{Name: "b"}
+	tests := []struct {
+		src   string
+		value ast.Node
+	}{
+		{"b = 'text'", &ast.BytesLiteral{Literal: []byte("text")}},
+		{"b = h'00ff'", &ast.BytesLiteral{Literal: []byte{0x00, 0xff}}},
+		{"b = h'00 ff 10'", &ast.BytesLiteral{Literal: []byte{0x00, 0xff, 0x10}}},
+		{"b = b64'AP8='", &ast.BytesLiteral{Literal: []byte{0x00, 0xff}}},
+		{"b = b64'AP8'", &ast.BytesLiteral{Literal: []byte{0x00, 0xff}}},
+	}
+
+	for _, tst := range tests {
+		trueAst := &ast.CDDL{Rules: []ast.CDDLEntry{&ast.Rule{Name: name, Value: tst.value}}}
+		parsed, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != 0 {
+			t.Fatalf("%s: unexpected errors %s", tst.src, errs)
+		}
+		testWalk(t, trueAst, parsed)
+	}
+
+	_, errs := parser.NewParser(lexer.NewLexer([]byte("b = h'0g'"))).ParseFile()
+	if len(errs) != 1 {
+		t.Errorf("expected invalid hex literal to be reported, got %s", errs)
+	}
+}
+
+func TestComputedOperators(t *testing.T) {
+	name := &ast.Identifier{Name: "a"}
+	tests := []struct {
+		src   string
+		value ast.Node
+	}{
+		{"a = 1 .plus 2", &ast.ComputedOpControl{
+			Token: token.PLUS,
+			Left:  &ast.IntegerLiteral{Literal: 1},
+			Right: &ast.IntegerLiteral{Literal: 2},
+		}},
+		{`a = "foo" .cat 'bar'`, &ast.ComputedOpControl{
+			Token: token.CAT,
+			Left:  &ast.TextLiteral{Literal: "foo"},
+			Right: &ast.BytesLiteral{Literal: []byte("bar")},
+		}},
+		{`a = "foo" .det "bar"`, &ast.ComputedOpControl{
+			Token: token.DET,
+			Left:  &ast.TextLiteral{Literal: "foo"},
+			Right: &ast.TextLiteral{Literal: "bar"},
+		}},
+		// only the bstr and bytes types make .det a deterministic encoding control
+		{`a = 'foo' .det 'bar'`, &ast.ComputedOpControl{
+			Token: token.DET,
+			Left:  &ast.BytesLiteral{Literal: []byte("foo")},
+			Right: &ast.BytesLiteral{Literal: []byte("bar")},
+		}},
+	}
+
+	for _, tst := range tests {
+		trueAst := &ast.CDDL{Rules: []ast.CDDLEntry{&ast.Rule{Name: name, Value: tst.value}}}
+		parsed, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != 0 {
+			t.Fatalf("%s: unexpected errors %s", tst.src, errs)
+		}
+		testWalk(t, trueAst, parsed)
+	}
+}
+
+func TestDefaultControl(t *testing.T) {
+	name := &ast.Identifier{Name: "a"}
+	basePos := token.Position{Offset: 4, Line: 1, Column: 5}
+	uintPos := token.Position{Offset: 19, Line: 1, Column: 20}
+	tests := []struct {
+		src   string
+		value ast.Node
+	}{
+		{"a = uint .default 80", &ast.DefaultControl{
+			Base:    &ast.UintType{Range: token.PositionRange{Start: basePos, End: basePos.To(4)}, Token: token.UINT},
+			Default: &ast.IntegerLiteral{Literal: 80},
+		}},
+		{`a = tstr .default "localhost"`, &ast.DefaultControl{
+			Base:    &ast.TstrType{Pos: basePos, Token: token.TSTR},
+			Default: &ast.TextLiteral{Literal: "localhost"},
+		}},
+		{`a = tstr .size 3 / uint .default 1`, &ast.TypeChoice{
+			First: &ast.SizeOperatorControl{
+				Type: &ast.TstrType{Pos: basePos, Token: token.TSTR},
+				Size: &ast.IntegerLiteral{Literal: 3},
+			},
+			Second: &ast.DefaultControl{
+				Base:    &ast.UintType{Range: token.PositionRange{Start: uintPos, End: uintPos.To(4)}, Token: token.UINT},
+				Default: &ast.IntegerLiteral{Literal: 1},
+			},
+		}},
+	}
+
+	for _, tst := range tests {
+		trueAst := &ast.CDDL{Rules: []ast.CDDLEntry{&ast.Rule{Name: name, Value: tst.value}}}
+		parsed, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != 0 {
+			t.Fatalf("%s: unexpected errors %s", tst.src, errs)
+		}
+		testWalk(t, trueAst, parsed)
+	}
+}
+
+func TestFeatureControl(t *testing.T) {
+	name := &ast.Identifier{Name: "a"}
+	basePos := token.Position{Offset: 4, Line: 1, Column: 5}
+	tests := []struct {
+		src   string
+		value ast.Node
+	}{
+		{`a = tstr .feature "ext"`, &ast.FeatureControl{
+			Base:    &ast.TstrType{Pos: basePos, Token: token.TSTR},
+			Feature: &ast.TextLiteral{Literal: "ext"},
+		}},
+		{`a = tstr .feature ["ext", "text"]`, &ast.FeatureControl{
+			Base: &ast.TstrType{Pos: basePos, Token: token.TSTR},
+			Feature: &ast.Array{Rules: []ast.GroupEntry{
+				&ast.TextLiteral{Literal: "ext"},
+				&ast.TextLiteral{Literal: "text"},
+			}},
+		}},
+	}
+
+	for _, tst := range tests {
+		trueAst := &ast.CDDL{Rules: []ast.CDDLEntry{&ast.Rule{Name: name, Value: tst.value}}}
+		parsed, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != 0 {
+			t.Fatalf("%s: unexpected errors %s", tst.src, errs)
+		}
+		testWalk(t, trueAst, parsed)
+	}
+}
+
 func TestEnumeration(t *testing.T) {
 	name := &ast.Identifier{Name: "name"}
 	tests := []struct {
@@ -347,6 +540,65 @@ func TestRegexpOperator(t *testing.T) {
 	}
 }
 
+func TestRegexpErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err parser.ErrorList
+	}{
+		{`some-text = tstr .regexp "[a-z]+-[^0-9]"`, parser.ErrorList{}},
+		{`some-text = tstr .regexp "a{2,1}"`, parser.ErrorList{
+			parser.NewError("invalid regular expression: quantifier {2,1} has a maximum below its minimum", token.Position{Line: 1, Column: 28}, token.Position{Line: 1, Column: 28}),
+		}},
+		{"pattern = \"a\\i\"\nsome-text = tstr .regexp pattern", parser.ErrorList{
+			parser.NewError("invalid regular expression: XML name escape \\i is not supported", token.Position{Line: 1, Column: 13}, token.Position{Line: 1, Column: 13}),
+		}},
+	}
+
+	for _, tst := range tests {
+		_, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != len(tst.err) {
+			t.Errorf("%s: expected %d errors got %v", tst.src, len(tst.err), errs)
+			continue
+		}
+		for i := 0; i < len(errs); i++ {
+			assertEqualDiagnostic(t, tst.err[i], errs[i])
+		}
+	}
+}
+
+func TestABNFErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err parser.ErrorList
+	}{
+		{`date = tstr .abnf "date = 4DIGIT %x2D 2DIGIT"`, parser.ErrorList{}},
+		{"oid = bytes .abnfb '\noid = 1*arc\narc = *%x80-FF %x00-7F\n'", parser.ErrorList{}},
+		{`date = tstr .abnf "date = 4DIGIT month"`, parser.ErrorList{
+			parser.NewError("invalid ABNF grammar: rule month is not defined", token.Position{Line: 1, Column: 34}, token.Position{Line: 1, Column: 34}),
+		}},
+		{"oid = bytes .abnfb '\noid = 1*arc\narc = *%x80-FF %x00-7F z\n'", parser.ErrorList{
+			parser.NewError("invalid ABNF grammar: rule z is not defined", token.Position{Line: 3, Column: 24}, token.Position{Line: 3, Column: 24}),
+		}},
+		{"rules = 'a = (\"x\"'\nsome-text = tstr .abnf rules", parser.ErrorList{
+			parser.NewError("invalid ABNF grammar: missing )", token.Position{Line: 1, Column: 14}, token.Position{Line: 1, Column: 14}),
+		}},
+		{`some-text = uint .abnf "a = %x61"`, parser.ErrorList{
+			parser.NewError("operator .abnf only supports tokens tstr", token.Position{Line: 1, Column: 18}, token.Position{Line: 1, Column: 18}),
+		}},
+	}
+
+	for _, tst := range tests {
+		_, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != len(tst.err) {
+			t.Errorf("%s: expected %d errors got %v", tst.src, len(tst.err), errs)
+			continue
+		}
+		for i := 0; i < len(errs); i++ {
+			assertEqualDiagnostic(t, tst.err[i], errs[i])
+		}
+	}
+}
+
 func TestCommentGroups(t *testing.T) {
 	tests := []struct {
 		src   string
@@ -444,6 +696,11 @@ func TestTypeChoice(t *testing.T) {
 			},
 		}, parser.ErrorList{}},
 		{`choice = 6 / 17`, &ast.TypeChoice{First: &ast.IntegerLiteral{Literal: 6}, Second: &ast.IntegerLiteral{Literal: 17}}, parser.ErrorList{}},
+		// range bounds bind tighter than the choice
+		{`choice = 0..10 / 5..20`, &ast.TypeChoice{
+			First:  &ast.Range{From: &ast.IntegerLiteral{Literal: 0}, To: &ast.IntegerLiteral{Literal: 10}},
+			Second: &ast.Range{From: &ast.IntegerLiteral{Literal: 5}, To: &ast.IntegerLiteral{Literal: 20}},
+		}, parser.ErrorList{}},
 	}
 
 	for _, tst := range tests {
@@ -461,8 +718,137 @@ func TestTypeChoice(t *testing.T) {
 	}
 }
 
+func TestGroupChoice(t *testing.T) {
+	name := &ast.Identifier{Name: "choice"}
+	tests := []struct {
+		src   string
+		value ast.Node
+		err   parser.ErrorList
+	}{
+		{`choice = (a: tstr // b: uint)`, &ast.Group{Entries: []ast.GroupEntry{
+			&ast.GroupChoice{
+				First:  &ast.Entry{Name: &ast.Identifier{Name: "a"}, Value: &ast.TstrType{Pos: token.Position{Offset: 13, Line: 1, Column: 14}, Token: token.TSTR}},
+				Second: &ast.Entry{Name: &ast.Identifier{Name: "b"}, Value: &ast.UintType{Range: token.PositionRange{Start: token.Position{Offset: 24, Line: 1, Column: 25}, End: token.Position{Offset: 28, Line: 1, Column: 29}}, Token: token.UINT}},
+			},
+		}}, parser.ErrorList{}},
+		// type choices bind tighter than group choices
+		{`choice = (1 / 2 // 3 / 4)`, &ast.Group{Entries: []ast.GroupEntry{
+			&ast.GroupChoice{
+				First:  &ast.TypeChoice{First: &ast.IntegerLiteral{Literal: 1}, Second: &ast.IntegerLiteral{Literal: 2}},
+				Second: &ast.TypeChoice{First: &ast.IntegerLiteral{Literal: 3}, Second: &ast.IntegerLiteral{Literal: 4}},
+			},
+		}}, parser.ErrorList{}},
+	}
+
+	for _, tst := range tests {
+		trueAst := &ast.CDDL{Rules: []ast.CDDLEntry{&ast.Rule{Name: name, Value: tst.value}}}
+		l := lexer.NewLexer([]byte(tst.src))
+		p := parser.NewParser(l)
+
+		parsed, errs := p.ParseFile()
+		if len(errs) == len(tst.err) {
+			for i := 0; i < len(errs); i++ {
+				assertEqualDiagnostic(t, tst.err[i], errs[i])
+			}
+		}
+		testWalk(t, trueAst, parsed)
+	}
+}
+
+// Test parsing of generic rules according to
+// https://www.rfc-editor.org/rfc/rfc8610#section-3.10
+func TestGeneric(t *testing.T) {
+	tests := []struct {
+		src    string
+		params []string
+		value  ast.Node
+		err    parser.ErrorList
+	}{
+		{`message<t, v> = [t, v]`, []string{"t", "v"}, &ast.Array{Rules: []ast.GroupEntry{
+			&ast.Identifier{Name: "t"}, &ast.Identifier{Name: "v"},
+		}}, parser.ErrorList{}},
+		{`message<t> = [message<t>]`, []string{"t"}, &ast.Array{Rules: []ast.GroupEntry{
+			&ast.Generic{Name: &ast.Identifier{Name: "message"}, Args: []ast.Node{&ast.Identifier{Name: "t"}}},
+		}}, parser.ErrorList{}},
+	}
+
+	for _, tst := range tests {
+		l := lexer.NewLexer([]byte(tst.src))
+		p := parser.NewParser(l)
+
+		parsed, errs := p.ParseFile()
+		if len(errs) != len(tst.err) {
+			t.Fatalf("%s: expected %d errors got %s", tst.src, len(tst.err), errs)
+		}
+		rule := parsed.Rules[0].(*ast.Rule)
+		if len(rule.Params) != len(tst.params) {
+			t.Fatalf("expected %d generic parameters got %d", len(tst.params), len(rule.Params))
+		}
+		for i, param := range rule.Params {
+			if param.Name != tst.params[i] {
+				t.Errorf("expected generic parameter %s got %s", tst.params[i], param.Name)
+			}
+		}
+		testWalk(t, tst.value, rule.Value)
+	}
+}
+
 // Test parsing of the .size control operator according to
 // https://www.rfc-editor.org/rfc/rfc8610#section-3.8.1
+func TestGenericScope(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		// parameters are only visible within their rule
+		{"a<t> = [t] b = t", []string{"parser error: identifier t referenced does not exist"}},
+		// parameters shadow global names
+		{"t = tstr a<t> = [0..t]", nil},
+		{"a<t, t> = [t]", []string{"parser error: duplicate generic parameter t in definition of a"}},
+		{"a = uint a = tstr", []string{"parser error: existing declaration for identifier a at line 1, column 1"}},
+	}
+
+	for _, tst := range tests {
+		_, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != len(tst.errs) {
+			t.Errorf("%s: expected %d errors got %d: %s", tst.src, len(tst.errs), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != tst.errs[i] {
+				t.Errorf("%s: expected `%s` got `%s`", tst.src, tst.errs[i], err)
+			}
+		}
+	}
+}
+
+func TestPreludeNames(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"ts = tdate", nil},
+		{"a = [uri, biguint, number, float16-32]", nil},
+		// schemas may redefine prelude names
+		{"tdate = tstr ts = tdate", nil},
+		{"a = 0..unsigned", []string{"parser error: expected integer upper bound"}},
+		{"a = tdates", []string{"parser error: identifier tdates referenced does not exist"}},
+	}
+
+	for _, tst := range tests {
+		_, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != len(tst.errs) {
+			t.Errorf("%s: expected %d errors got %d: %s", tst.src, len(tst.errs), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != tst.errs[i] {
+				t.Errorf("%s: expected `%s` got `%s`", tst.src, tst.errs[i], err)
+			}
+		}
+	}
+}
+
 func TestOperatorSize(t *testing.T) {
 	name := &ast.Identifier{Name: "item"}
 	basePos := token.Position{Offset: 7, Line: 1, Column: 8}
@@ -497,6 +883,36 @@ func TestOperatorSize(t *testing.T) {
 	}
 }
 
+func TestCBORControl(t *testing.T) {
+	tests := []struct {
+		src string
+		err parser.ErrorList
+	}{
+		{"log = bstr .cborseq [* entry]\nentry = [uint, tstr]", parser.ErrorList{}},
+		{"log = bytes .cborseq entries\nentries = [* uint]", parser.ErrorList{}},
+		{"protected = bstr .cbor header\nheader = {1: int}", parser.ErrorList{}},
+		{"signed = bstr .det payload\npayload = [uint]", parser.ErrorList{}},
+		{"signed = bytes .det uint", parser.ErrorList{}},
+		{"key = tstr .cbor uint", parser.ErrorList{
+			parser.NewError("operator .cbor only supports tokens bstr, bytes", token.Position{Line: 1, Column: 12}, token.Position{Line: 1, Column: 12}),
+		}},
+		{"log = uint .cborseq [* uint]", parser.ErrorList{
+			parser.NewError("operator .cborseq only supports tokens bstr, bytes", token.Position{Line: 1, Column: 12}, token.Position{Line: 1, Column: 12}),
+		}},
+	}
+
+	for _, tst := range tests {
+		_, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != len(tst.err) {
+			t.Errorf("%s: expected %d errors got %v", tst.src, len(tst.err), errs)
+			continue
+		}
+		for i := 0; i < len(errs); i++ {
+			assertEqualDiagnostic(t, tst.err[i], errs[i])
+		}
+	}
+}
+
 func TestParseGroup(t *testing.T) {
 	name := &ast.Identifier{Name: "item"}
 	basePos := token.Position{Offset: 7, Line: 1, Column: 8}
@@ -604,3 +1020,55 @@ func TestE2EFast(t *testing.T) {
 		}
 	}
 }
+
+func TestControlPrecedence(t *testing.T) {
+	// the operand of a control operator ends at a choice rather than taking in the choice
+	tests := []struct {
+		src     string
+		control string
+	}{
+		{"a = tstr .size 3 / bstr", "*ast.SizeOperatorControl"},
+		{`a = tstr .regexp "[a-z]+" / uint`, "*ast.Regexp"},
+		{"a = uint .bits 1 / tstr", "*ast.Bits"},
+		{"a = uint .lt 5 / tstr", "*ast.ComparatorOpControl"},
+		{"a = 1 .plus 2 / tstr", "*ast.ComputedOpControl"},
+		{"a = 0..10 / tstr", "*ast.Range"},
+		{"a = 0.5..1.5 / tstr", "*ast.Range"},
+		{"a = b .. 10 / tstr b = 0", "*ast.Range"},
+	}
+
+	for _, tst := range tests {
+		cddl, errs := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		if len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %s", tst.src, errs)
+			continue
+		}
+		choice, ok := cddl.Rules[0].(*ast.Rule).Value.(*ast.TypeChoice)
+		if !ok {
+			t.Errorf("%s: expected a type choice got %T", tst.src, cddl.Rules[0].(*ast.Rule).Value)
+			continue
+		}
+		if got := fmt.Sprintf("%T", choice.First); got != tst.control {
+			t.Errorf("%s: expected the first alternative to be %s got %s", tst.src, tst.control, got)
+		}
+	}
+}
+
+func TestTrailingComment(t *testing.T) {
+	src := "a = uint ; trailing\nb = tstr"
+	p := parser.NewParser(lexer.NewLexer([]byte(src)))
+
+	cddl, errs := p.ParseFile()
+	if len(errs) != 0 {
+		t.Fatalf("%s: unexpected errors %s", src, errs)
+	}
+	names := []string{}
+	for _, entry := range cddl.Rules {
+		if rule, ok := entry.(*ast.Rule); ok {
+			names = append(names, rule.Name.Name)
+		}
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("%s: expected rules a and b got %v", src, names)
+	}
+}
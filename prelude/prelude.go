@@ -0,0 +1,61 @@
+// Package prelude holds the standard prelude of CDDL defined in
+// https://www.rfc-editor.org/rfc/rfc8610#appendix-D
+//
+// The prelude names are predefined in every schema. The parser and resolver accept
+// references to them and the validator uses their definitions for the names a schema
+// does not define itself.
+package prelude
+
+import "strings"
+
+// Source holds the rules of the standard prelude that are not keywords
+const Source = `
+tdate = #6.0(tstr)
+time = #6.1(number)
+number = int / float
+biguint = #6.2(bstr)
+bignint = #6.3(bstr)
+bigint = biguint / bignint
+integer = int / bigint
+unsigned = uint / biguint
+decfrac = #6.4([e10: int, m: integer])
+bigfloat = #6.5([e2: int, m: integer])
+eb64url = #6.21(any)
+eb64legacy = #6.22(any)
+eb16 = #6.23(any)
+encoded-cbor = #6.24(bstr)
+uri = #6.32(tstr)
+b64url = #6.33(tstr)
+b64legacy = #6.34(tstr)
+regexp = #6.35(tstr)
+mime-message = #6.36(tstr)
+cbor-any = #6.55799(any)
+undefined = #7.23
+float16-32 = float16 / float32
+float32-64 = float32 / float64
+`
+
+var names = func() []string {
+	out := []string{}
+	for _, line := range strings.Split(Source, "\n") {
+		if name, _, ok := strings.Cut(line, " = "); ok {
+			out = append(out, name)
+		}
+	}
+	return out
+}()
+
+// Names returns the names defined by the prelude in order of definition
+func Names() []string {
+	return append([]string{}, names...)
+}
+
+// Defines returns true if the name is defined by the prelude
+func Defines(name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// Recursive rules such as trees are legal as long as every cycle can be left through an
+// optional entry, a zero occurrence or a choice. Rules like `a = [a]` can never be satisfied
+// by a finite data item and are reported as errors.
+
+// IsSatisfiable returns true if the named rule admits at least one finite instance.
+// Undefined names and generic parameters are assumed to be satisfiable.
+func (g *Graph) IsSatisfiable(name string) bool {
+	if !g.Defined(name) {
+		return true
+	}
+	return g.satisfiable[name]
+}
+
+// IsRecursive returns true if the named rule references itself directly or indirectly
+func (g *Graph) IsRecursive(name string) bool {
+	return g.cycle[name] != nil
+}
+
+// Cycles returns the groups of mutually recursive rules. Each group is ordered by definition
+// and the groups are ordered by their first rule.
+func (g *Graph) Cycles() [][]string {
+	out := [][]string{}
+	seen := map[*[]string]bool{}
+	for _, name := range g.names {
+		scc := g.cycle[name]
+		if scc == nil || seen[scc] {
+			continue
+		}
+		seen[scc] = true
+		out = append(out, append([]string{}, *scc...))
+	}
+	return out
+}
+
+// UnsatisfiableCycles returns a reference path for every group of mutually recursive rules
+// with no finite instances. Each path starts and ends with the same rule i.e. [a b a].
+func (g *Graph) UnsatisfiableCycles() [][]string {
+	out := [][]string{}
+	for _, scc := range g.Cycles() {
+		if g.satisfiable[scc[0]] {
+			continue
+		}
+		members := map[string]bool{}
+		for _, name := range scc {
+			members[name] = true
+		}
+		path := g.cyclePath(scc[0], scc[0], members, map[string]bool{})
+		out = append(out, append([]string{scc[0]}, path...))
+	}
+	return out
+}
+
+// checkRecursion reports the unsatisfiable cycles as errors
+func (g *Graph) checkRecursion() parser.ErrorList {
+	errs := parser.ErrorList{}
+	for _, path := range g.UnsatisfiableCycles() {
+		rule := g.rules[path[0]][0]
+		errs = append(errs, newError(fmt.Sprintf("recursive definition of %s has no finite instances: %s", path[0], strings.Join(path, " -> ")), rule.Name.Start(), rule.Name.End()))
+	}
+	return errs
+}
+
+// cyclePath returns the references leading from the name back to the target within the members
+func (g *Graph) cyclePath(name, target string, members, visited map[string]bool) []string {
+	visited[name] = true
+	for _, dep := range g.Dependencies(name) {
+		if dep == target {
+			return []string{dep}
+		}
+		if !members[dep] || visited[dep] {
+			continue
+		}
+		if path := g.cyclePath(dep, target, members, visited); path != nil {
+			return append([]string{dep}, path...)
+		}
+	}
+	return nil
+}
+
+// findCycles computes the strongly connected components of the graph using Tarjan's algorithm
+// and records the components forming cycles
+func (g *Graph) findCycles() {
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	counter := 0
+
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = counter
+		lowlink[name] = counter
+		counter++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, dep := range g.Dependencies(name) {
+			if !g.Defined(dep) {
+				continue
+			}
+			if _, ok := index[dep]; !ok {
+				connect(dep)
+				lowlink[name] = min(lowlink[name], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[name] = min(lowlink[name], index[dep])
+			}
+		}
+
+		if lowlink[name] != index[name] {
+			return
+		}
+		members := map[string]bool{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			members[top] = true
+			if top == name {
+				break
+			}
+		}
+
+		selfReference := false
+		for _, dep := range g.Dependencies(name) {
+			selfReference = selfReference || dep == name
+		}
+		if len(members) == 1 && !selfReference {
+			return
+		}
+
+		scc := []string{}
+		for _, n := range g.names {
+			if members[n] {
+				scc = append(scc, n)
+			}
+		}
+		for _, n := range scc {
+			g.cycle[n] = &scc
+		}
+	}
+
+	for _, name := range g.names {
+		if _, ok := index[name]; !ok {
+			connect(name)
+		}
+	}
+}
+
+// findSatisfiable computes the least fixpoint of the satisfiable rules
+func (g *Graph) findSatisfiable() {
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.names {
+			if g.satisfiable[name] {
+				continue
+			}
+			for _, rule := range g.rules[name] {
+				if g.productive(rule.Value, params(rule)) {
+					g.satisfiable[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// productive returns true if the node admits a finite instance given the rules currently
+// known to be satisfiable
+func (g *Graph) productive(node ast.Node, params map[string]bool) bool {
+	switch val := node.(type) {
+	case nil:
+		return true
+	case *ast.Identifier:
+		return params[val.Name] || g.IsSatisfiable(val.Name)
+	case *ast.Generic:
+		if !g.IsSatisfiable(val.Name.Name) {
+			return false
+		}
+		for _, arg := range val.Args {
+			if !g.productive(arg, params) {
+				return false
+			}
+		}
+		return true
+	case *ast.Entry:
+		if val.Token == token.ARROW_MAP && !g.productive(val.Name, params) {
+			return false
+		}
+		return g.productive(val.Value, params)
+	case *ast.Group:
+		return g.productiveAll(val.Entries, params)
+	case *ast.Array:
+		return g.productiveAll(val.Rules, params)
+	case *ast.Map:
+		for _, entry := range val.Rules {
+			if !g.productive(entry, params) {
+				return false
+			}
+		}
+		return true
+	case *ast.TypeChoice:
+		return g.productive(val.First, params) || g.productive(val.Second, params)
+	case *ast.GroupChoice:
+		return g.productive(val.First, params) || g.productive(val.Second, params)
+	case *ast.Optional:
+		return true
+	case *ast.NMOccurrence:
+		return val.N == nil || val.N.Literal == 0 || g.productive(val.Item, params)
+	case *ast.Unwrap:
+		return g.productive(val.Item, params)
+	case *ast.Enumeration:
+		return g.productive(val.Value, params)
+	case *ast.Tag:
+		return g.productive(val.Item, params)
+	case *ast.SizeOperatorControl:
+		return g.productive(val.Type, params)
+	case *ast.Regexp:
+		return true
+	case *ast.ABNF:
+		return g.productive(val.Base, params)
+	case *ast.CBORControl:
+		return g.productive(val.Base, params)
+	case *ast.DefaultControl:
+		return g.productive(val.Base, params)
+	case *ast.FeatureControl:
+		return g.productive(val.Base, params)
+	case *ast.Bits:
+		return g.productive(val.Base, params)
+	case *ast.ComparatorOpControl:
+		return g.productive(val.Left, params)
+	}
+	return true
+}
+
+func (g *Graph) productiveAll(entries []ast.GroupEntry, params map[string]bool) bool {
+	for _, entry := range entries {
+		if !g.productive(entry, params) {
+			return false
+		}
+	}
+	return true
+}
+
+// params returns the generic parameters of a rule as a set
+func params(rule *ast.Rule) map[string]bool {
+	out := make(map[string]bool, len(rule.Params))
+	for _, param := range rule.Params {
+		out[param.Name] = true
+	}
+	return out
+}
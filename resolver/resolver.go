@@ -0,0 +1,346 @@
+// Package resolver implements name resolution and dependency analysis of CDDL rules.
+//
+// It builds the graph of references between rules, identifies the root rule and the
+// rules reachable from it as described in https://www.rfc-editor.org/rfc/rfc8610#section-2.1
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/prelude"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// Reference represents the use of a name within the definition of a rule
+type Reference struct {
+	// From: the name of the rule containing the reference
+	From string
+
+	// Name: the referenced identifier
+	Name *ast.Identifier
+
+	// Argument: true if the reference is an argument to a generic rule i.e. `tstr` in `message<tstr>`
+	Argument bool
+}
+
+// Graph is the dependency graph between the rules of a CDDL tree. Sockets defined
+// by multiple rules are represented as a single node.
+type Graph struct {
+	// names holds the rule names in order of first definition
+	names []string
+
+	// rules maps the rule names to their definitions
+	rules map[string][]*ast.Rule
+
+	// references maps the rule names to the references in their definitions in source order
+	references map[string][]*Reference
+
+	// dependents maps the referenced names to the rules referencing them
+	dependents map[string][]string
+
+	// reachable holds the names reachable from the root rule
+	reachable map[string]bool
+
+	// satisfiable holds the names of the rules admitting at least one finite instance
+	satisfiable map[string]bool
+
+	// cycle maps the recursive rules to the rules in the same strongly connected component
+	cycle map[string]*[]string
+}
+
+// NewGraph builds the dependency graph of the rules in the provided tree
+func NewGraph(cddl *ast.CDDL) *Graph {
+	g := &Graph{
+		rules:       make(map[string][]*ast.Rule),
+		references:  make(map[string][]*Reference),
+		dependents:  make(map[string][]string),
+		reachable:   make(map[string]bool),
+		satisfiable: make(map[string]bool),
+		cycle:       make(map[string]*[]string),
+	}
+
+	if cddl != nil {
+		for _, entry := range cddl.Rules {
+			rule, ok := entry.(*ast.Rule)
+			if !ok || rule.Name == nil {
+				continue
+			}
+			name := rule.Name.Name
+			if _, ok := g.rules[name]; !ok {
+				g.names = append(g.names, name)
+			}
+			g.rules[name] = append(g.rules[name], rule)
+		}
+	}
+
+	for _, name := range g.names {
+		for _, rule := range g.rules[name] {
+			g.collect(name, params(rule), rule.Value, false)
+		}
+	}
+
+	for _, name := range g.names {
+		seen := map[string]bool{}
+		for _, ref := range g.references[name] {
+			if seen[ref.Name.Name] {
+				continue
+			}
+			seen[ref.Name.Name] = true
+			g.dependents[ref.Name.Name] = append(g.dependents[ref.Name.Name], name)
+		}
+	}
+
+	if root := g.Root(); root != "" {
+		g.mark(root)
+	}
+	g.findCycles()
+	g.findSatisfiable()
+	return g
+}
+
+// Resolve builds the dependency graph of the provided tree and reports references to
+// undefined rules and recursive rules with no finite instances as errors and empty sockets
+// and unreachable rules as warnings.
+func Resolve(cddl *ast.CDDL) (*Graph, parser.ErrorList) {
+	g := NewGraph(cddl)
+	errs := parser.ErrorList{}
+
+	for _, ref := range g.Undefined() {
+		errs = append(errs, newError(fmt.Sprintf("identifier %s referenced does not exist", ref.Name.Name), ref.Name.Start(), ref.Name.End()))
+	}
+	errs = append(errs, g.checkRecursion()...)
+
+	for _, ref := range g.EmptySockets() {
+		errs = append(errs, newWarning(fmt.Sprintf("socket %s is referenced but has no definitions", ref.Name.Name), ref.Name.Start(), ref.Name.End()))
+	}
+
+	root := g.Root()
+	for _, name := range g.names {
+		if name == root || g.reachable[name] {
+			continue
+		}
+		rule := g.rules[name][0]
+		errs = append(errs, newWarning(fmt.Sprintf("rule %s is not reachable from root rule %s", name, root), rule.Name.Start(), rule.Name.End()))
+	}
+
+	return g, errs.Collect()
+}
+
+// Root returns the name of the root rule. This is the first rule defined in the tree.
+func (g *Graph) Root() string {
+	if len(g.names) == 0 {
+		return ""
+	}
+	return g.names[0]
+}
+
+// Rules returns the names of the defined rules in order of first definition
+func (g *Graph) Rules() []string {
+	return append([]string{}, g.names...)
+}
+
+// Defined returns true if the name has at least one definition
+func (g *Graph) Defined(name string) bool {
+	_, ok := g.rules[name]
+	return ok
+}
+
+// Definitions returns the rules defining the name in source order
+func (g *Graph) Definitions(name string) []*ast.Rule {
+	return g.rules[name]
+}
+
+// References returns the references made in the definitions of the named rule in source order
+func (g *Graph) References(name string) []*Reference {
+	return g.references[name]
+}
+
+// Dependencies returns the unique names referenced by the named rule in order of first reference
+func (g *Graph) Dependencies(name string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, ref := range g.references[name] {
+		if !seen[ref.Name.Name] {
+			seen[ref.Name.Name] = true
+			out = append(out, ref.Name.Name)
+		}
+	}
+	return out
+}
+
+// Dependents returns the names of the rules referencing the name in order of definition
+func (g *Graph) Dependents(name string) []string {
+	return append([]string{}, g.dependents[name]...)
+}
+
+// IsReachable returns true if the name is the root rule or is referenced directly or
+// indirectly by the root rule
+func (g *Graph) IsReachable(name string) bool {
+	return g.reachable[name]
+}
+
+// Reachable returns the defined rules reachable from the root rule in order of definition
+func (g *Graph) Reachable() []string {
+	out := []string{}
+	for _, name := range g.names {
+		if g.reachable[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Unused returns the defined rules that are not reachable from the root rule in order of definition
+func (g *Graph) Unused() []string {
+	out := []string{}
+	for _, name := range g.names {
+		if !g.reachable[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// IsPrelude returns true if the name refers to the standard prelude i.e. it is defined by the
+// prelude and not redefined by the tree
+func (g *Graph) IsPrelude(name string) bool {
+	return !g.Defined(name) && prelude.Defines(name)
+}
+
+// Undefined returns the references to names that are neither defined, sockets nor part of the
+// standard prelude in source order
+func (g *Graph) Undefined() []*Reference {
+	out := []*Reference{}
+	for _, name := range g.names {
+		for _, ref := range g.references[name] {
+			if !g.Defined(ref.Name.Name) && !g.IsPrelude(ref.Name.Name) && !isSocket(ref.Name) {
+				out = append(out, ref)
+			}
+		}
+	}
+	return out
+}
+
+// EmptySockets returns the first reference to each socket that has no definitions
+func (g *Graph) EmptySockets() []*Reference {
+	out := []*Reference{}
+	seen := map[string]bool{}
+	for _, name := range g.names {
+		for _, ref := range g.references[name] {
+			if seen[ref.Name.Name] || g.Defined(ref.Name.Name) || !isSocket(ref.Name) {
+				continue
+			}
+			seen[ref.Name.Name] = true
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+func (g *Graph) mark(name string) {
+	if g.reachable[name] {
+		return
+	}
+	g.reachable[name] = true
+	for _, dep := range g.Dependencies(name) {
+		g.mark(dep)
+	}
+}
+
+func (g *Graph) addReference(from string, ident *ast.Identifier, argument bool) {
+	g.references[from] = append(g.references[from], &Reference{From: from, Name: ident, Argument: argument})
+}
+
+// collect records the references made by a node. Generic parameters of the enclosing
+// rule are local names and are not recorded.
+func (g *Graph) collect(from string, params map[string]bool, node ast.Node, argument bool) {
+	switch val := node.(type) {
+	case *ast.Identifier:
+		if !params[val.Name] {
+			g.addReference(from, val, argument)
+		}
+	case *ast.Generic:
+		g.addReference(from, val.Name, argument)
+		for _, arg := range val.Args {
+			g.collect(from, params, arg, true)
+		}
+	case *ast.Entry:
+		// barewords before `:` are member names rather than references
+		if val.Token == token.ARROW_MAP {
+			g.collect(from, params, val.Name, argument)
+		}
+		g.collect(from, params, val.Value, argument)
+	case *ast.Group:
+		for _, entry := range val.Entries {
+			g.collect(from, params, entry, argument)
+		}
+	case *ast.Array:
+		for _, entry := range val.Rules {
+			g.collect(from, params, entry, argument)
+		}
+	case *ast.Map:
+		for _, entry := range val.Rules {
+			g.collect(from, params, entry, argument)
+		}
+	case *ast.TypeChoice:
+		g.collect(from, params, val.First, argument)
+		g.collect(from, params, val.Second, argument)
+	case *ast.GroupChoice:
+		g.collect(from, params, val.First, argument)
+		g.collect(from, params, val.Second, argument)
+	case *ast.Optional:
+		g.collect(from, params, val.Item, argument)
+	case *ast.NMOccurrence:
+		g.collect(from, params, val.Item, argument)
+	case *ast.Unwrap:
+		g.collect(from, params, val.Item, argument)
+	case *ast.Enumeration:
+		g.collect(from, params, val.Value, argument)
+	case *ast.Tag:
+		g.collect(from, params, val.Item, argument)
+	case *ast.Range:
+		g.collect(from, params, val.From, argument)
+		g.collect(from, params, val.To, argument)
+	case *ast.SizeOperatorControl:
+		g.collect(from, params, val.Type, argument)
+		g.collect(from, params, val.Size, argument)
+	case *ast.Regexp:
+		g.collect(from, params, val.Regex, argument)
+	case *ast.ABNF:
+		g.collect(from, params, val.Base, argument)
+		g.collect(from, params, val.Grammar, argument)
+	case *ast.CBORControl:
+		g.collect(from, params, val.Base, argument)
+		g.collect(from, params, val.Controller, argument)
+	case *ast.DefaultControl:
+		g.collect(from, params, val.Base, argument)
+		g.collect(from, params, val.Default, argument)
+	case *ast.FeatureControl:
+		g.collect(from, params, val.Base, argument)
+		g.collect(from, params, val.Feature, argument)
+	case *ast.Bits:
+		g.collect(from, params, val.Base, argument)
+		g.collect(from, params, val.Contstraint, argument)
+	case *ast.ComparatorOpControl:
+		g.collect(from, params, val.Left, argument)
+		g.collect(from, params, val.Right, argument)
+	}
+}
+
+func isSocket(ident *ast.Identifier) bool {
+	return ident.IsSocket() || ident.IsPlug()
+}
+
+func newError(msg string, start, end token.Position) *parser.Error {
+	err := parser.NewError(msg, start, end)
+	err.Prefix = "resolver"
+	return err
+}
+
+func newWarning(msg string, start, end token.Position) *parser.Error {
+	err := parser.NewWarning(msg, start, end)
+	err.Prefix = "resolver"
+	return err
+}
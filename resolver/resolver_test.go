@@ -0,0 +1,223 @@
+package resolver_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/resolver"
+)
+
+func parse(t *testing.T, src string) *ast.CDDL {
+	t.Helper()
+	l := lexer.NewLexer([]byte(src))
+	p := parser.NewParser(l)
+
+	cddl, errs := p.ParseFile()
+	if len(errs) != 0 {
+		t.Fatalf("%s: -> %s", src, errs)
+	}
+	return cddl
+}
+
+func TestGraph(t *testing.T) {
+	src := `
+		message<t, v> = {type: t, value: v}
+		reboot = message<"reboot", reason>
+		reason = tstr / code
+		code = uint
+		tcp-header = {seq: uint, * $$tcp-option}
+		$$tcp-option //= (sack: [+ range])
+		$$tcp-option //= (sack-permitted: true)
+		range = (left: uint, right: uint)
+	`
+	g := resolver.NewGraph(parse(t, src))
+
+	if root := g.Root(); root != "message" {
+		t.Errorf("expected root rule message got %s", root)
+	}
+
+	tests := []struct {
+		name         string
+		dependencies []string
+		dependents   []string
+	}{
+		{"message", []string{}, []string{"reboot"}},
+		{"reboot", []string{"message", "reason"}, []string{}},
+		{"reason", []string{"code"}, []string{"reboot"}},
+		{"tcp-header", []string{"$$tcp-option"}, []string{}},
+		{"$$tcp-option", []string{"range"}, []string{"tcp-header"}},
+		{"range", []string{}, []string{"$$tcp-option"}},
+	}
+	for _, tst := range tests {
+		if deps := g.Dependencies(tst.name); !reflect.DeepEqual(deps, tst.dependencies) {
+			t.Errorf("expected dependencies of %s to be %v got %v", tst.name, tst.dependencies, deps)
+		}
+		if deps := g.Dependents(tst.name); !reflect.DeepEqual(deps, tst.dependents) {
+			t.Errorf("expected dependents of %s to be %v got %v", tst.name, tst.dependents, deps)
+		}
+	}
+
+	refs := g.References("reboot")
+	if len(refs) != 2 || refs[0].Argument || !refs[1].Argument {
+		t.Errorf("expected reference to message and argument reason, got %+v", refs)
+	}
+
+	if defs := g.Definitions("$$tcp-option"); len(defs) != 2 {
+		t.Errorf("expected 2 definitions of $$tcp-option got %d", len(defs))
+	}
+}
+
+func TestReachable(t *testing.T) {
+	src := `
+		root = [header, * item]
+		header = (version: uint)
+		item = tstr / nested
+		nested = {? child: item}
+		orphan = uint
+		orphan-user = [orphan]
+	`
+	g := resolver.NewGraph(parse(t, src))
+
+	if reach := g.Reachable(); !reflect.DeepEqual(reach, []string{"root", "header", "item", "nested"}) {
+		t.Errorf("unexpected reachable rules %v", reach)
+	}
+	if unused := g.Unused(); !reflect.DeepEqual(unused, []string{"orphan", "orphan-user"}) {
+		t.Errorf("unexpected unused rules %v", unused)
+	}
+}
+
+func TestResolveDiagnostics(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"a = [b] b = uint", nil},
+		{"a = {* $$ext}", []string{"resolver warning: socket $$ext is referenced but has no definitions"}},
+		{"a = tstr / $ext $ext /= uint", nil},
+		{"a = uint b = tstr", []string{"resolver warning: rule b is not reachable from root rule a"}},
+		{"a<t> = [t] b = a<uint>", []string{"resolver warning: rule b is not reachable from root rule a"}},
+		{"a = {name => tstr} name = tstr", nil},
+		{"a = {name: tstr}", nil},
+	}
+
+	for _, tst := range tests {
+		_, errs := resolver.Resolve(parse(t, tst.src))
+
+		if len(errs) != len(tst.errs) {
+			t.Errorf("%s: expected %d diagnostics got %d: %s", tst.src, len(tst.errs), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != tst.errs[i] {
+				t.Errorf("%s: expected `%s` got `%s`", tst.src, tst.errs[i], err)
+			}
+			if !parser.IsWarning(err) {
+				t.Errorf("%s: expected warning got %s", tst.src, err)
+			}
+		}
+	}
+}
+
+func TestResolveUndefined(t *testing.T) {
+	// the parser reports the same undefined references. Its errors are ignored here
+	src := "a = [b, c<uint>]"
+	cddl, _ := parser.NewParser(lexer.NewLexer([]byte(src))).ParseFile()
+
+	_, errs := resolver.Resolve(cddl)
+	expected := []string{
+		"resolver error: identifier b referenced does not exist",
+		"resolver error: identifier c referenced does not exist",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors got %d: %s", len(expected), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("expected `%s` got `%s`", expected[i], err)
+		}
+	}
+	if !errs.HasErrors() {
+		t.Error("expected list to contain errors")
+	}
+}
+
+func TestResolvePrelude(t *testing.T) {
+	g, errs := resolver.Resolve(parse(t, "a = [tdate, uri, b] b = number"))
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors got %s", errs)
+	}
+	if !g.IsPrelude("tdate") || g.IsPrelude("b") || g.IsPrelude("tdates") {
+		t.Error("unexpected prelude names")
+	}
+	if !reflect.DeepEqual(g.Rules(), []string{"a", "b"}) {
+		t.Errorf("expected prelude names not to be rules got %v", g.Rules())
+	}
+
+	// rules redefining prelude names take precedence
+	g, _ = resolver.Resolve(parse(t, "a = tdate tdate = tstr"))
+	if g.IsPrelude("tdate") || !g.Defined("tdate") {
+		t.Error("expected tdate to refer to the rule")
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	tests := []struct {
+		src       string
+		name      string
+		recursive bool
+		errs      []string
+	}{
+		{"tree = {value: int, ? left: tree, ? right: tree}", "tree", true, nil},
+		{"list = [* list]", "list", true, nil},
+		{"expr = int / [expr, expr]", "expr", true, nil},
+		{"a = [uint]", "a", false, nil},
+		{"a = [a]", "a", true, []string{"resolver error: recursive definition of a has no finite instances: a -> a"}},
+		{"b = {x: b}", "b", true, []string{"resolver error: recursive definition of b has no finite instances: b -> b"}},
+		{"a = [1*3 a]", "a", true, []string{"resolver error: recursive definition of a has no finite instances: a -> a"}},
+		{"a = [b] b = (c: c) c = {a: a}", "b", true, []string{"resolver error: recursive definition of a has no finite instances: a -> b -> c -> a"}},
+		{"a = [b] b = (c: c) c = {? a: a}", "c", true, nil},
+		{"node<t> = [t, next: node<t>] list = node<uint>", "node", true, []string{
+			"resolver error: recursive definition of node has no finite instances: node -> node",
+			"resolver warning: rule list is not reachable from root rule node",
+		}},
+	}
+
+	for _, tst := range tests {
+		g, errs := resolver.Resolve(parse(t, tst.src))
+		if g.IsRecursive(tst.name) != tst.recursive {
+			t.Errorf("%s: expected recursive %s to be %v", tst.src, tst.name, tst.recursive)
+		}
+		if len(errs) != len(tst.errs) {
+			t.Errorf("%s: expected %d diagnostics got %d: %s", tst.src, len(tst.errs), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != tst.errs[i] {
+				t.Errorf("%s: expected `%s` got `%s`", tst.src, tst.errs[i], err)
+			}
+		}
+	}
+}
+
+func TestCycles(t *testing.T) {
+	src := `
+		a = [b] b = [? c] c = (a: a)
+		d = [* d]
+		e = uint
+	`
+	g := resolver.NewGraph(parse(t, src))
+
+	expected := [][]string{{"a", "b", "c"}, {"d"}}
+	if cycles := g.Cycles(); !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("expected cycles %v got %v", expected, cycles)
+	}
+	if cycles := g.UnsatisfiableCycles(); len(cycles) != 0 {
+		t.Errorf("expected no unsatisfiable cycles got %v", cycles)
+	}
+	if !g.IsSatisfiable("a") || !g.IsSatisfiable("undefined") {
+		t.Error("expected rules to be satisfiable")
+	}
+}
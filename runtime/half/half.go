@@ -0,0 +1,82 @@
+// Package half converts between float64 and IEEE 754 half-precision floats, the 16 bit
+// floats of CBOR major type 7 with additional information 25 and of the CDDL float16 type.
+
+package half
+
+import "math"
+
+// ToFloat64 converts the bits of a half-precision float to a float64. The conversion is
+// exact.
+func ToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var val float64
+	switch exp {
+	case 0:
+		val = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			val = math.Inf(1)
+		} else {
+			val = math.NaN()
+		}
+	default:
+		val = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -val
+	}
+	return val
+}
+
+// FromFloat64 converts a float64 to the bits of the nearest half-precision float, rounding
+// ties to even. Values too large for half precision become infinities. The value is rounded
+// once from its float64 bits as rounding through float32 first may round twice.
+func FromFloat64(f float64) uint16 {
+	bits := math.Float64bits(f)
+	sign := uint16(bits>>48) & 0x8000
+	exp := int(bits>>52&0x7ff) - 1023 + 15
+	mant := bits & (1<<52 - 1)
+
+	switch {
+	case math.IsNaN(f):
+		return sign | 0x7e00
+	case math.IsInf(f, 0) || exp >= 31:
+		return sign | 0x7c00
+	case exp <= 0:
+		if exp < -10 {
+			return sign
+		}
+		// subnormal: shift the mantissa with its implicit bit into place
+		return sign | uint16(roundShift(mant|1<<52, uint(43-exp)))
+	}
+	// a carry out of the mantissa increments the exponent, up to infinity
+	return sign | uint16(roundShift(uint64(exp)<<52|mant, 42))
+}
+
+// roundShift shifts m right rounding to the nearest value, ties to even
+func roundShift(m uint64, shift uint) uint64 {
+	r := m >> shift
+	rem := m & (1<<shift - 1)
+	half := uint64(1) << (shift - 1)
+	if rem > half || (rem == half && r&1 == 1) {
+		r++
+	}
+	return r
+}
+
+// Exact reports whether f is representable as a half-precision float without loss.
+// Infinities and NaNs are representable.
+func Exact(f float64) bool {
+	if math.IsNaN(f) {
+		return true
+	}
+	return ToFloat64(FromFloat64(f)) == f
+}
+
+// Exact32 reports whether f is representable as a single-precision float without loss.
+// Infinities and NaNs are representable.
+func Exact32(f float64) bool {
+	return math.IsNaN(f) || float64(float32(f)) == f
+}
@@ -0,0 +1,94 @@
+package half_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/runtime/half"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		bits  uint16
+		value float64
+	}{
+		{0x0000, 0},
+		{0x8000, math.Copysign(0, -1)},
+		{0x3c00, 1},
+		{0x3e00, 1.5},
+		{0xc400, -4},
+		{0x7bff, 65504},
+		{0x0001, 5.960464477539063e-08},
+		{0x0400, 6.103515625e-05},
+		{0x7c00, math.Inf(1)},
+		{0xfc00, math.Inf(-1)},
+	}
+
+	for _, tst := range tests {
+		if got := half.ToFloat64(tst.bits); got != tst.value || math.Signbit(got) != math.Signbit(tst.value) {
+			t.Errorf("%#04x: expected %v got %v", tst.bits, tst.value, got)
+		}
+		if got := half.FromFloat64(tst.value); got != tst.bits {
+			t.Errorf("%v: expected %#04x got %#04x", tst.value, tst.bits, got)
+		}
+	}
+
+	if !math.IsNaN(half.ToFloat64(0x7e00)) || half.FromFloat64(math.NaN()) != 0x7e00 {
+		t.Errorf("expected NaN to convert to 0x7e00 and back")
+	}
+}
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		value float64
+		bits  uint16
+	}{
+		// halfway between 1 and the next half-precision float rounds to the even 1
+		{1 + math.Ldexp(1, -11), 0x3c00},
+		{1 + 3*math.Ldexp(1, -11), 0x3c02},
+		// just above the halfway point rounds up. Rounding to float32 first would make it a tie
+		{1 + math.Ldexp(1, -11) + math.Ldexp(1, -40), 0x3c01},
+		{-(1 + math.Ldexp(1, -11) + math.Ldexp(1, -40)), 0xbc01},
+		{65519.99, 0x7bff},
+		{math.Ldexp(1, -25), 0x0000},
+		{math.Ldexp(1, -25) + math.Ldexp(1, -60), 0x0001},
+		{math.Ldexp(3, -25), 0x0002},
+		{math.Ldexp(1, -14) - math.Ldexp(1, -25), 0x0400},
+		{65520, 0x7c00},
+		{1e-10, 0x0000},
+	}
+
+	for _, tst := range tests {
+		if got := half.FromFloat64(tst.value); got != tst.bits {
+			t.Errorf("%v: expected %#04x got %#04x", tst.value, tst.bits, got)
+		}
+	}
+}
+
+func TestExact(t *testing.T) {
+	tests := []struct {
+		value   float64
+		exact   bool
+		exact32 bool
+	}{
+		{0.5, true, true},
+		{65504, true, true},
+		{65505, false, true},
+		{0.1, false, false},
+		{1.0 / 3, false, false},
+		{float64(float32(0.1)), false, true},
+		{5.960464477539063e-08, true, true},
+		{math.Inf(-1), true, true},
+		{math.NaN(), true, true},
+		{1e300, false, false},
+	}
+
+	for _, tst := range tests {
+		if got := half.Exact(tst.value); got != tst.exact {
+			t.Errorf("%v: expected Exact %t got %t", tst.value, tst.exact, got)
+		}
+		if got := half.Exact32(tst.value); got != tst.exact32 {
+			t.Errorf("%v: expected Exact32 %t got %t", tst.value, tst.exact32, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package validators
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/HannesKimara/cddlc/iregexp"
+	"golang.org/x/exp/constraints"
+)
+
+// Bits returns a BitsError for the lowest bit set in val whose number is not allowed
+func Bits[T constraints.Unsigned](val T, allowed ...uint) error {
+	for n := uint(0); n < 64; n++ {
+		if uint64(val)&(1<<n) != 0 && !contains(allowed, n) {
+			return &BitsError{Bit: n}
+		}
+	}
+	return nil
+}
+
+// BitsBytes returns a BitsError for the lowest bit set in val whose number is not allowed.
+// Bits are numbered from the least significant bit of the first byte.
+func BitsBytes[T ~[]byte](val T, allowed ...uint) error {
+	for i, b := range val {
+		for n := uint(0); n < 8; n++ {
+			if bit := uint(i)*8 + n; b&(1<<n) != 0 && !contains(allowed, bit) {
+				return &BitsError{Bit: bit}
+			}
+		}
+	}
+	return nil
+}
+
+func contains(bits []uint, bit uint) bool {
+	for _, b := range bits {
+		if b == bit {
+			return true
+		}
+	}
+	return false
+}
+
+// regexps caches the compiled patterns of Regexp
+var regexps sync.Map
+
+// Regexp returns a RegexpError if val does not match the whole of the XSD regular
+// expression pattern. Compiled patterns are cached, and an invalid pattern is returned as
+// the *iregexp.Error describing it.
+func Regexp[T ~string](val T, pattern string) error {
+	var re *regexp.Regexp
+	if cached, ok := regexps.Load(pattern); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		var err error
+		re, err = iregexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		regexps.Store(pattern, re)
+	}
+
+	if !re.MatchString(string(val)) {
+		return &RegexpError{Pattern: pattern, Value: string(val)}
+	}
+	return nil
+}
+
+// Lt returns a ComparisonError unless val is less than limit
+func Lt[T NumericOrdered](val, limit T) error {
+	return compare(val < limit, ".lt", val, limit)
+}
+
+// Le returns a ComparisonError unless val is less than or equal to limit
+func Le[T NumericOrdered](val, limit T) error {
+	return compare(val <= limit, ".le", val, limit)
+}
+
+// Gt returns a ComparisonError unless val is greater than limit
+func Gt[T NumericOrdered](val, limit T) error {
+	return compare(val > limit, ".gt", val, limit)
+}
+
+// Ge returns a ComparisonError unless val is greater than or equal to limit
+func Ge[T NumericOrdered](val, limit T) error {
+	return compare(val >= limit, ".ge", val, limit)
+}
+
+// Eq returns a ComparisonError unless val equals limit
+func Eq[T comparable](val, limit T) error {
+	return compare(val == limit, ".eq", val, limit)
+}
+
+// Ne returns a ComparisonError if val equals limit
+func Ne[T comparable](val, limit T) error {
+	return compare(val != limit, ".ne", val, limit)
+}
+
+func compare(ok bool, control string, val, limit any) error {
+	if ok {
+		return nil
+	}
+	return &ComparisonError{Control: control, Value: val, Limit: limit}
+}
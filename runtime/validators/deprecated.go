@@ -0,0 +1,52 @@
+package validators
+
+import (
+	"reflect"
+	"regexp"
+)
+
+// The functions below are called by code generated with earlier versions of cddlc. They are
+// kept so that such code still compiles and will be removed in a later release.
+
+// RegexpMatches validates a string against a compiled regexp instance.
+//
+// Deprecated: use Regexp, which matches the whole string against an XSD regular expression.
+func RegexpMatches(regex *regexp.Regexp, input string) bool {
+	return regex.Match([]byte(input))
+}
+
+// CheckSize returns whether the size of the value is within the upper bound: the length of
+// text and byte strings or the number of bytes needed by unsigned integers. Other values
+// are measured by the size of their type.
+//
+// Deprecated: use SizeText, SizeBytes or SizeUint, which report the violated bounds.
+func CheckSize(val interface{}, size uint) bool {
+	rv := reflect.ValueOf(val)
+	switch {
+	case rv.Kind() == reflect.String:
+		return SizeText(rv.String(), 0, uint64(size)) == nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		return SizeBytes(rv.Bytes(), 0, uint64(size)) == nil
+	case rv.CanUint():
+		return SizeUint(rv.Uint(), uint64(size)) == nil
+	}
+	return reflect.TypeOf(val).Size() < uintptr(size)
+}
+
+// MustBool panics if out is false
+//
+// Deprecated: the validators return errors, which Valid methods join.
+func MustBool(out bool) {
+	if !out {
+		panic("received false")
+	}
+}
+
+// MustNotErr panics if err is not nil
+//
+// Deprecated: the validators return errors, which Valid methods join.
+func MustNotErr(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,67 @@
+package validators
+
+import (
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+// CBOR returns an EmbeddedError unless val holds the encoding of exactly one well-formed
+// CBOR data item as required by a `.cbor` control
+func CBOR[T ~[]byte](val T) error {
+	if _, err := cbor.Decode(val); err != nil {
+		return &EmbeddedError{Control: ".cbor", Err: err}
+	}
+	return nil
+}
+
+// DeterministicCBOR returns an EmbeddedError unless val holds the core deterministic
+// encoding of exactly one data item as required by a `.det` control on a byte string
+func DeterministicCBOR[T ~[]byte](val T) error {
+	item, err := cbor.Decode(val)
+	if err == nil {
+		err = cbor.CheckDeterministic(item)
+	}
+	if err != nil {
+		return &EmbeddedError{Control: ".det", Err: err}
+	}
+	return nil
+}
+
+// CBORSeq returns an EmbeddedError unless val holds a CBOR sequence of well-formed data
+// items as required by a `.cborseq` control
+func CBORSeq[T ~[]byte](val T) error {
+	d := cbor.NewDecoder(val)
+	for d.More() {
+		if _, err := d.Decode(); err != nil {
+			return &EmbeddedError{Control: ".cborseq", Err: err}
+		}
+	}
+	return nil
+}
+
+// DecodeCBOR decodes the data item embedded in val by a `.cbor` control with unmarshal, such
+// as cbor.Unmarshal of github.com/fxamacker/cbor/v2. Values with an Unmarshal method, as
+// generated for rules with `.default` values, decode themselves so that absent fields take
+// their defaults. Decoded values with a Valid method are validated as well.
+func DecodeCBOR[T any, B ~[]byte](val B, unmarshal func([]byte, any) error) (*T, error) {
+	if err := CBOR(val); err != nil {
+		return nil, err
+	}
+	out := new(T)
+	var err error
+	if u, ok := any(out).(interface {
+		Unmarshal([]byte, func([]byte, any) error) error
+	}); ok {
+		err = u.Unmarshal(val, unmarshal)
+	} else {
+		err = unmarshal(val, out)
+	}
+	if err != nil {
+		return nil, &EmbeddedError{Control: ".cbor", Err: err}
+	}
+	if v, ok := any(out).(interface{ Valid() error }); ok {
+		if err := v.Valid(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package validators
+
+import "fmt"
+
+// SizeError reports a value outside the bounds of a `.size` control. The size of text and
+// byte strings is their length in bytes and the size of an unsigned integer is the number of
+// bytes needed to represent it.
+type SizeError struct {
+	// Kind: one of "text string", "byte string" or "unsigned integer"
+	Kind string
+	// Size: the size of the value
+	Size uint64
+	// Min, Max: the inclusive bounds of the size
+	Min, Max uint64
+}
+
+func (e *SizeError) Error() string {
+	if e.Kind == "unsigned integer" {
+		return fmt.Sprintf("validators: unsigned integer needs %d bytes, expected at most %d", e.Size, e.Max)
+	}
+	if e.Min == e.Max {
+		return fmt.Sprintf("validators: %s has size %d, expected %d", e.Kind, e.Size, e.Max)
+	}
+	return fmt.Sprintf("validators: %s has size %d, expected %d..%d", e.Kind, e.Size, e.Min, e.Max)
+}
+
+// BitsError reports a bit set in a value that is not allowed by a `.bits` control
+type BitsError struct {
+	Bit uint
+}
+
+func (e *BitsError) Error() string {
+	return fmt.Sprintf("validators: bit %d is set but not allowed", e.Bit)
+}
+
+// RegexpError reports a text string that does not match the whole of a `.regexp` control
+type RegexpError struct {
+	Pattern string
+	Value   string
+}
+
+func (e *RegexpError) Error() string {
+	return fmt.Sprintf("validators: %q does not match %q", e.Value, e.Pattern)
+}
+
+// ComparisonError reports a value failing one of the `.lt`, `.le`, `.gt`, `.ge`, `.eq` and
+// `.ne` controls
+type ComparisonError struct {
+	// Control: the name of the control including the dot e.g `.lt`
+	Control string
+	Value   any
+	Limit   any
+}
+
+var comparisons = map[string]string{
+	".lt": "less than",
+	".le": "less than or equal to",
+	".gt": "greater than",
+	".ge": "greater than or equal to",
+	".eq": "equal to",
+	".ne": "different from",
+}
+
+func (e *ComparisonError) Error() string {
+	return fmt.Sprintf("validators: %v is not %s %v", e.Value, comparisons[e.Control], e.Limit)
+}
+
+// RangeError reports a number outside of a range
+type RangeError struct {
+	Value        any
+	Lower, Upper any
+	Exclusive    bool
+}
+
+func (e *RangeError) Error() string {
+	op := ".."
+	if e.Exclusive {
+		op = "..."
+	}
+	return fmt.Sprintf("validators: %v is not within %v%s%v", e.Value, e.Lower, op, e.Upper)
+}
+
+// EmbeddedError reports a byte string of a `.cbor` or `.cborseq` control whose content is not
+// well-formed CBOR or could not be decoded
+type EmbeddedError struct {
+	// Control: the name of the control including the dot e.g `.cbor`
+	Control string
+	Err     error
+}
+
+func (e *EmbeddedError) Error() string {
+	return fmt.Sprintf("validators: invalid embedded CBOR of %s: %s", e.Control, e.Err)
+}
+
+// Unwrap returns the error decoding the byte string
+func (e *EmbeddedError) Unwrap() error {
+	return e.Err
+}
+
+// PrecisionError reports a float that is not representable in the precision of its type
+type PrecisionError struct {
+	Value float64
+	// Bits: the precision of the type e.g 16 for float16
+	Bits int
+}
+
+func (e *PrecisionError) Error() string {
+	return fmt.Sprintf("validators: %v is not representable as float%d", e.Value, e.Bits)
+}
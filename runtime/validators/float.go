@@ -0,0 +1,15 @@
+package validators
+
+import (
+	"github.com/HannesKimara/cddlc/runtime/half"
+	"golang.org/x/exp/constraints"
+)
+
+// Float16 returns a PrecisionError unless val is exactly representable as a half-precision
+// float. Generated types hold float16 values in a float32.
+func Float16[T constraints.Float](val T) error {
+	if !half.Exact(float64(val)) {
+		return &PrecisionError{Value: float64(val), Bits: 16}
+	}
+	return nil
+}
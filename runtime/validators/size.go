@@ -0,0 +1,33 @@
+package validators
+
+import "golang.org/x/exp/constraints"
+
+// SizeText returns a SizeError if the length of val in bytes is not within min..max. A
+// `.size 3` control has equal bounds.
+func SizeText[T ~string](val T, min, max uint64) error {
+	if n := uint64(len(val)); n < min || n > max {
+		return &SizeError{Kind: "text string", Size: n, Min: min, Max: max}
+	}
+	return nil
+}
+
+// SizeBytes returns a SizeError if the length of val is not within min..max
+func SizeBytes[T ~[]byte](val T, min, max uint64) error {
+	if n := uint64(len(val)); n < min || n > max {
+		return &SizeError{Kind: "byte string", Size: n, Min: min, Max: max}
+	}
+	return nil
+}
+
+// SizeUint returns a SizeError if val needs more than max bytes, that is if it is not less
+// than 256^max. Ranges of sizes bound unsigned integers by their upper limit.
+func SizeUint[T constraints.Unsigned](val T, max uint64) error {
+	n := uint64(0)
+	for v := uint64(val); v > 0; v >>= 8 {
+		n++
+	}
+	if n > max {
+		return &SizeError{Kind: "unsigned integer", Size: n, Max: max}
+	}
+	return nil
+}
@@ -1,11 +1,10 @@
-// Package validators implements validation functions for CDDL constraints.
+// Package validators implements validation functions for CDDL constraints. Each check
+// returns nil if the value satisfies the constraint or a typed error describing the violated
+// constraint, so that the Valid methods of generated types can join them.
 
 package validators
 
 import (
-	"reflect"
-	"regexp"
-
 	"golang.org/x/exp/constraints"
 )
 
@@ -37,24 +36,11 @@ func IsGreaterThan[T NumericOrdered](val, limit T) bool {
 	return val > limit
 }
 
-// RegexpMatches validates a string against a compiled regexp instance.
-func RegexpMatches(regex *regexp.Regexp, input string) bool {
-	return regex.Match([]byte(input))
-}
-
-// CheckSize returns whether the size of the value in bytes is less than the upper bound
-func CheckSize(val interface{}, size uint) bool {
-	return reflect.TypeOf(val).Size() < uintptr(size)
-}
-
-func MustBool(out bool) {
-	if !out {
-		panic("received false")
-	}
-}
-
-func MustNotErr(err error) {
-	if err != nil {
-		panic(err)
+// Range returns a RangeError if the val is not within lower..upper, or lower...upper when
+// exclusive is set
+func Range[T NumericOrdered](val, lower, upper T, exclusive bool) error {
+	if val >= lower && (val < upper || !exclusive && val == upper) {
+		return nil
 	}
+	return &RangeError{Value: val, Lower: lower, Upper: upper, Exclusive: exclusive}
 }
@@ -0,0 +1,207 @@
+package validators_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/runtime/validators"
+)
+
+type name string
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		msg  string
+	}{
+		{"text exact", validators.SizeText("abc", 3, 3), ""},
+		{"text short", validators.SizeText("ab", 3, 3), "validators: text string has size 2, expected 3"},
+		{"text bytes", validators.SizeText("ü", 2, 2), ""},
+		{"text range", validators.SizeText(name("alice"), 1, 64), ""},
+		{"text empty", validators.SizeText(name(""), 1, 64), "validators: text string has size 0, expected 1..64"},
+		{"bytes", validators.SizeBytes([]byte{1, 2}, 0, 4), ""},
+		{"bytes long", validators.SizeBytes([]byte{1, 2, 3}, 0, 2), "validators: byte string has size 3, expected 0..2"},
+		{"uint zero", validators.SizeUint(uint(0), 0), ""},
+		{"uint fits", validators.SizeUint(uint16(65535), 2), ""},
+		{"uint large", validators.SizeUint(uint64(65536), 2), "validators: unsigned integer needs 3 bytes, expected at most 2"},
+	}
+
+	for _, tst := range tests {
+		checkError(t, tst.name, tst.err, tst.msg)
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+		want bool
+	}{
+		{"text", validators.CheckSize("abc", 3), true},
+		{"named text", validators.CheckSize(name("abcd"), 3), false},
+		{"bytes", validators.CheckSize([]byte{1, 2, 3}, 2), false},
+		{"uint", validators.CheckSize(uint16(65535), 2), true},
+		{"uint large", validators.CheckSize(uint32(65536), 2), false},
+		{"regexp", validators.RegexpMatches(regexp.MustCompile("^[a-z]+$"), "abc"), true},
+	}
+
+	for _, tst := range tests {
+		if tst.ok != tst.want {
+			t.Errorf("%s: expected %t got %t", tst.name, tst.want, tst.ok)
+		}
+	}
+	validators.MustBool(true)
+	validators.MustNotErr(nil)
+}
+
+func TestControls(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		msg  string
+	}{
+		{"bits", validators.Bits(uint8(0b101), 0, 2), ""},
+		{"bits unset", validators.Bits(uint8(0b100), 0, 2, 4), ""},
+		{"bits extra", validators.Bits(uint(0b1010), 1), "validators: bit 3 is set but not allowed"},
+		{"bytes bits", validators.BitsBytes([]byte{0x01, 0x02}, 0, 9), ""},
+		{"bytes bits extra", validators.BitsBytes([]byte{0x00, 0x04}, 0, 9), "validators: bit 10 is set but not allowed"},
+		{"regexp", validators.Regexp(name("N1"), "[A-Z][0-9]+"), ""},
+		{"regexp anchored", validators.Regexp("xN1", "[A-Z][0-9]+"), `validators: "xN1" does not match "[A-Z][0-9]+"`},
+		{"lt", validators.Lt(3, 4), ""},
+		{"lt equal", validators.Lt(4, 4), "validators: 4 is not less than 4"},
+		{"le", validators.Le(4.5, 4.5), ""},
+		{"gt", validators.Gt(uint(1), 2), "validators: 1 is not greater than 2"},
+		{"ge", validators.Ge(2, 2), ""},
+		{"eq", validators.Eq("a", "a"), ""},
+		{"ne", validators.Ne(name("a"), "a"), "validators: a is not different from a"},
+		{"range", validators.Range(3, 1, 3, false), ""},
+		{"range exclusive", validators.Range(3, 1, 3, true), "validators: 3 is not within 1...3"},
+		{"range below", validators.Range(0.5, 1, 3, false), "validators: 0.5 is not within 1..3"},
+		{"float16", validators.Float16(float32(65504)), ""},
+		{"float16 subnormal", validators.Float16(5.960464477539063e-08), ""},
+		{"float16 inexact", validators.Float16(float32(0.1)), "validators: 0.10000000149011612 is not representable as float16"},
+		{"cbor", validators.CBOR([]byte{0x18, 0x64}), ""},
+		{"cbor trailing", validators.CBOR([]byte{0x01, 0x02}), "validators: invalid embedded CBOR of .cbor: cbor: unexpected data after the first item at offset 1"},
+		{"det", validators.DeterministicCBOR([]byte{0xa2, 0x01, 0x01, 0x02, 0x02}), ""},
+		{"det unsorted", validators.DeterministicCBOR([]byte{0xa2, 0x02, 0x02, 0x01, 0x01}), "validators: invalid embedded CBOR of .det: cbor: map key 1 is not in sorted order at offset 3"},
+		{"det malformed", validators.DeterministicCBOR([]byte{0x18}), "validators: invalid embedded CBOR of .det: cbor: unexpected end of input at offset 0"},
+		{"cborseq", validators.CBORSeq([]byte{0x01, 0x02}), ""},
+		{"cborseq empty", validators.CBORSeq([]byte{}), ""},
+		{"cborseq truncated", validators.CBORSeq([]byte{0x01, 0x18}), "validators: invalid embedded CBOR of .cborseq: cbor: unexpected end of input at offset 1"},
+	}
+
+	for _, tst := range tests {
+		checkError(t, tst.name, tst.err, tst.msg)
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	err := errors.Join(
+		validators.SizeText("abcd", 1, 3),
+		validators.Regexp("a", "b"),
+		validators.Gt(1, 2),
+	)
+
+	var size *validators.SizeError
+	if !errors.As(err, &size) || size.Size != 4 || size.Max != 3 {
+		t.Errorf("expected a size error of size 4, got %v", err)
+	}
+	var re *validators.RegexpError
+	if !errors.As(err, &re) || re.Pattern != "b" {
+		t.Errorf("expected a regexp error for pattern b, got %v", err)
+	}
+	var cmp *validators.ComparisonError
+	if !errors.As(err, &cmp) || cmp.Control != ".gt" {
+		t.Errorf("expected a .gt comparison error, got %v", err)
+	}
+
+	if err := validators.Regexp("a", "("); err == nil || errors.As(err, &re) {
+		t.Errorf("expected a compilation error for an invalid pattern, got %v", err)
+	}
+}
+
+type header struct {
+	Alg int
+}
+
+func (h *header) Valid() error {
+	return validators.Ne(h.Alg, 0)
+}
+
+// defaultHeader is a header whose algorithm defaults to -7
+type defaultHeader struct {
+	header
+}
+
+func (h *defaultHeader) Unmarshal(data []byte, unmarshal func([]byte, any) error) error {
+	*h = defaultHeader{header{Alg: -7}}
+	return unmarshal(data, &h.header)
+}
+
+// unmarshalHeader decodes a map holding the algorithm at key 1
+func unmarshalHeader(data []byte, v any) error {
+	item, err := cbor.Decode(data)
+	if err != nil {
+		return err
+	}
+	if item.Major != cbor.MajorMap {
+		return errors.New("expected a map")
+	}
+	for _, pair := range item.Pairs {
+		if pair.Key.IsInteger() && pair.Key.Arg == 1 && pair.Value.IsInteger() {
+			v.(*header).Alg = int(pair.Value.Int().Int64())
+		}
+	}
+	return nil
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		alg  int
+		msg  string
+	}{
+		{"valid", []byte{0xa1, 0x01, 0x26}, -7, ""},
+		{"invalid value", []byte{0xa1, 0x01, 0x00}, 0, "validators: 0 is not different from 0"},
+		{"not a map", []byte{0x01}, 0, "validators: invalid embedded CBOR of .cbor: expected a map"},
+		{"malformed", []byte{0xa1, 0x01}, 0, "validators: invalid embedded CBOR of .cbor: cbor: unexpected end of input at offset 2"},
+	}
+
+	for _, tst := range tests {
+		h, err := validators.DecodeCBOR[header](tst.data, unmarshalHeader)
+		checkError(t, tst.name, err, tst.msg)
+		if err == nil && h.Alg != tst.alg {
+			t.Errorf("%s: expected algorithm %d got %d", tst.name, tst.alg, h.Alg)
+		}
+	}
+
+	// absent fields of values with an Unmarshal method keep their defaults
+	for _, tst := range []struct {
+		data []byte
+		alg  int
+	}{
+		{[]byte{0xa0}, -7},
+		{[]byte{0xa1, 0x01, 0x38, 0x22}, -35},
+	} {
+		h, err := validators.DecodeCBOR[defaultHeader](tst.data, unmarshalHeader)
+		if err != nil || h.Alg != tst.alg {
+			t.Errorf("%x: expected algorithm %d got %v, %v", tst.data, tst.alg, h, err)
+		}
+	}
+}
+
+func checkError(t *testing.T, name string, err error, msg string) {
+	t.Helper()
+	switch {
+	case msg == "" && err != nil:
+		t.Errorf("%s: unexpected error %s", name, err)
+	case msg != "" && err == nil:
+		t.Errorf("%s: expected error `%s`", name, msg)
+	case msg != "" && err.Error() != msg:
+		t.Errorf("%s: expected `%s` got `%s`", name, msg, err)
+	}
+}
@@ -45,8 +45,9 @@ const (
 	TEXT         // text
 	TEXT_LITERAL // "text"
 
-	BYTES // bytes
-	BSTR  // bstr
+	BYTES         // bytes
+	BSTR          // bstr
+	BYTES_LITERAL // 'bytes', h'6279746573' or b64'Ynl0ZXM='
 
 	BOOL  // bool
 	TRUE  // true
@@ -154,8 +155,9 @@ var tokens = [...]string{
 	TEXT:         "text",
 	TEXT_LITERAL: "text_literal",
 
-	BYTES: "bytes",
-	BSTR:  "bstr",
+	BYTES:         "bytes",
+	BSTR:          "bstr",
+	BYTES_LITERAL: "bytes_literal",
 
 	BOOL:  "bool",
 	TRUE:  "true",
@@ -288,7 +290,7 @@ func (t Token) IsLiteral(literal string) bool {
 	switch t {
 	case INT, UINT, NINT, FLOAT, FLOAT16, FLOAT32, FLOAT64:
 		return literal != tokens[t]
-	case TEXT_LITERAL:
+	case TEXT_LITERAL, BYTES_LITERAL:
 		return true
 	default:
 		return false
@@ -8,10 +8,19 @@ import (
 	"go/format"
 	"go/token"
 	"io"
+	"sort"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/evaluator"
 )
 
 const (
 	VALIDATOR_PKG = "github.com/HannesKimara/cddlc/runtime/validators"
+
+	// SELF is the placeholder argument of validator calls for the value being validated. It
+	// becomes a field selector when the value is the field of a struct and the receiver of
+	// the Valid method once the validators are bundled.
+	SELF = "self"
 )
 
 type structure struct {
@@ -19,10 +28,22 @@ type structure struct {
 	// spec gast.Decl
 
 	validators []gast.CallExpr
+
+	// embedded: the type of the data item a byte string holds by a `.cbor` control, decoded
+	// by the Decode method of the rule
+	embedded gast.Expr
+
+	// defaultValue: the value of a type with a `.default` control
+	defaultValue gast.Expr
+
+	// defaults: the optional fields of a struct with a `.default` value, set by the
+	// constructor of the rule
+	defaults []*gast.KeyValueExpr
 }
 
 func (s *structure) Embed(em *structure) {
 	s.validators = append(s.validators, em.validators...)
+	s.defaults = append(s.defaults, em.defaults...)
 }
 
 func newStructure(node gast.Node) *structure {
@@ -52,7 +73,35 @@ func (s *structure) addValidatorCall(X, sel string, args ...interface{}) {
 	s.validators = append(s.validators, callexpr)
 }
 
-func (s *structure) bindReferences(ref string) {
+// bindField rewrites the validator calls of a field value to apply to the field of the
+// enclosing value
+func (s *structure) bindField(field *gast.Ident) {
+	for n := range s.validators {
+		s.validators[n].Args = bindSelf(s.validators[n].Args, func(self gast.Expr) gast.Expr {
+			return &gast.SelectorExpr{X: self, Sel: gast.NewIdent(field.Name)}
+		})
+	}
+}
+
+// bindSelf replaces the SELF placeholder at the root of each argument
+func bindSelf(args []gast.Expr, bind func(self gast.Expr) gast.Expr) []gast.Expr {
+	bound := make([]gast.Expr, len(args))
+	for n, arg := range args {
+		bound[n] = bindExpr(arg, bind)
+	}
+	return bound
+}
+
+func bindExpr(expr gast.Expr, bind func(self gast.Expr) gast.Expr) gast.Expr {
+	switch val := expr.(type) {
+	case *gast.Ident:
+		if val.Name == SELF {
+			return bind(val)
+		}
+	case *gast.SelectorExpr:
+		return &gast.SelectorExpr{X: bindExpr(val.X, bind), Sel: val.Sel}
+	}
+	return expr
 }
 
 // Generator contains the internal representation of the generation step
@@ -64,20 +113,33 @@ type Generator struct {
 
 	file *gast.File
 	fset *token.FileSet
+
+	// evaluator computes the values of constant rules referenced by operators
+	evaluator *evaluator.Evaluator
+
+	// rules maps the rule names to their definitions for operators taking types as operands
+	rules map[string][]*ast.Rule
+
+	// bitNames holds the rules only referenced as constraints of `.bits` controls. Their bit
+	// numbers are passed to the validators of the controls and they have no declaration.
+	bitNames map[string]bool
 }
 
 // String flushes the generated tree to an output
 func (g *Generator) String(output io.Writer) (err error) {
-	g.file.Imports = append(g.file.Imports, g.imports...)
-	gast.SortImports(g.fset, g.file)
+	// the specs have no positions for ast.SortImports to work with so they are sorted here
+	sort.Slice(g.imports, func(i, j int) bool {
+		return g.imports[i].Path.Value < g.imports[j].Path.Value
+	})
 
-	if len(g.file.Imports) > 0 {
+	if len(g.imports) > 0 {
 		importDecl := &gast.GenDecl{Specs: []gast.Spec{}, Tok: token.IMPORT}
 
-		for _, spec := range g.file.Imports {
+		for _, spec := range g.imports {
 			importDecl.Specs = append(importDecl.Specs, spec)
 		}
 
+		g.file.Imports = append(g.file.Imports, g.imports...)
 		g.file.Decls = append([]gast.Decl{importDecl}, g.file.Decls...)
 	}
 
@@ -90,6 +152,12 @@ func (g *Generator) Package() string {
 }
 
 func (g *Generator) addImport(value string, name string) {
+	for _, im := range g.imports {
+		if im.Path.Value == "\""+value+"\"" {
+			return
+		}
+	}
+
 	var ident *gast.Ident
 	if name != "" { // nullable ident to prevent space on generated output
 		ident = gast.NewIdent(name)
@@ -118,9 +186,10 @@ func NewGenerator(pkgName string) *Generator {
 	}
 
 	gen := &Generator{
-		pkg:  pkgName,
-		file: file,
-		fset: fset,
+		pkg:       pkgName,
+		file:      file,
+		fset:      fset,
+		evaluator: evaluator.NewEvaluator(nil),
 	}
 
 	return gen
@@ -20,12 +20,39 @@ func (g *Generator) transpileBytesType(bt *ast.BytesType) *gast.ArrayType {
 	}
 }
 
+func (g *Generator) transpileBstrType(bt *ast.BstrType) *gast.ArrayType {
+	return &gast.ArrayType{
+		Elt: &gast.Ident{
+			Name: "byte",
+		},
+	}
+}
+
+// transpileFloatType maps float16 and float32 to float32 and other floats to float64. The
+// half precision of float16 is checked by a validator.
+func (g *Generator) transpileFloatType(ft *ast.FloatType) *gast.Ident {
+	if ft.Base == 16 || ft.Base == 32 {
+		return &gast.Ident{
+			Name: "float32",
+		}
+	}
+	return &gast.Ident{
+		Name: "float64",
+	}
+}
+
 func (g *Generator) transpileNullType(nt *ast.NullType) *gast.Ident {
 	return &gast.Ident{
 		Name: "nil",
 	}
 }
 
+func (g *Generator) transpileAnyType(at *ast.AnyType) *gast.Ident {
+	return &gast.Ident{
+		Name: "any",
+	}
+}
+
 func (g *Generator) transpileIntegerType(it *ast.IntegerType) *gast.Ident {
 	return &gast.Ident{
 		Name: "int",
@@ -9,11 +9,15 @@ import (
 	"strings"
 
 	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/evaluator"
 )
 
 func (g *Generator) Visit(node ast.Node) *Generator {
 	switch val := node.(type) {
 	case *ast.Rule:
+		if g.bitNames[val.Name.Name] {
+			return g
+		}
 		var decl gast.Decl
 		var outExpr gast.Expr
 		var declToken token.Token
@@ -31,7 +35,7 @@ func (g *Generator) Visit(node ast.Node) *Generator {
 
 		switch val.Value.(type) {
 
-		case *ast.BooleanLiteral, *ast.FloatLiteral, *ast.IntegerLiteral, *ast.TextLiteral, *ast.UintLiteral:
+		case *ast.BooleanLiteral, *ast.FloatLiteral, *ast.IntegerLiteral, *ast.TextLiteral, *ast.UintLiteral, *ast.ComputedOpControl:
 			declToken = token.VAR
 			specs = []gast.Spec{
 				&gast.ValueSpec{
@@ -62,9 +66,27 @@ func (g *Generator) Visit(node ast.Node) *Generator {
 
 		log.Println("Len("+val.Name.Name+"): ", len(stct.validators))
 
-		valDecl := g.bundleValidators(stct.validators, val)
-		g.file.Decls = append(g.file.Decls, valDecl)
+		// constants are variables which cannot have methods
+		if declToken == token.TYPE {
+			_, isStruct := outExpr.(*gast.StructType)
+			valDecl := g.bundleValidators(stct.validators, val, isStruct)
+			g.file.Decls = append(g.file.Decls, valDecl)
+			if stct.embedded != nil {
+				g.file.Decls = append(g.file.Decls, g.decodeMethod(stct.embedded, val))
+			}
+			if isStruct && len(stct.defaults) > 0 {
+				g.file.Decls = append(g.file.Decls, g.defaultsConstructor(stct.defaults, val), g.unmarshalMethod(val))
+			}
+		}
 	case *ast.CDDL:
+		g.evaluator = evaluator.NewEvaluator(val)
+		g.rules = map[string][]*ast.Rule{}
+		for _, entry := range val.Rules {
+			if rule, ok := entry.(*ast.Rule); ok && rule.Name != nil {
+				g.rules[rule.Name.Name] = append(g.rules[rule.Name.Name], rule)
+			}
+		}
+		g.bitNames = bitNames(val)
 		for _, rule := range val.Rules {
 			g.Visit(rule)
 		}
@@ -73,7 +95,10 @@ func (g *Generator) Visit(node ast.Node) *Generator {
 	return g
 }
 
-func (g *Generator) bundleValidators(validators []gast.CallExpr, rule *ast.Rule) *gast.FuncDecl {
+// bundleValidators returns the Valid method of a rule joining the errors of its validators.
+// The validators of struct fields select the field from the receiver while the validators
+// of other types apply to the value the receiver points to.
+func (g *Generator) bundleValidators(validators []gast.CallExpr, rule *ast.Rule, isStruct bool) *gast.FuncDecl {
 	selfIdent := gast.NewIdent(strings.ToLower(rule.Name.Name))
 
 	funcDecl := &gast.FuncDecl{
@@ -108,29 +133,172 @@ func (g *Generator) bundleValidators(validators []gast.CallExpr, rule *ast.Rule)
 		List: []gast.Stmt{},
 	}
 
-	if len(validators) > 0 {
-		for _, validator := range validators {
-			ex := &gast.ExprStmt{
-				X: &validator,
-			}
-			block.List = append(block.List, ex)
-		}
-	}
-
 	endStmt := &gast.ReturnStmt{
 		Results: []gast.Expr{
 			gast.NewIdent("nil"),
 		},
 	}
 
+	if len(validators) > 0 {
+		bind := func(self gast.Expr) gast.Expr {
+			if isStruct {
+				return selfIdent
+			}
+			return &gast.StarExpr{X: selfIdent}
+		}
+
+		join := &gast.CallExpr{
+			Fun: &gast.SelectorExpr{
+				X:   gast.NewIdent("errors"),
+				Sel: gast.NewIdent("Join"),
+			},
+		}
+		for _, validator := range validators {
+			call := validator
+			call.Args = bindSelf(call.Args, bind)
+			join.Args = append(join.Args, &call)
+		}
+		endStmt.Results = []gast.Expr{join}
+		g.addImport("errors", "")
+	}
+
 	block.List = append(block.List, endStmt)
 	funcDecl.Body = block
 
 	return funcDecl
 }
 
+// decodeMethod returns the Decode method of a rule restricted by a `.cbor` control. It
+// decodes the embedded data item into its generated type.
+func (g *Generator) decodeMethod(embedded gast.Expr, rule *ast.Rule) *gast.FuncDecl {
+	selfIdent := gast.NewIdent(strings.ToLower(rule.Name.Name))
+	unmarshal := &gast.FuncType{
+		Params: &gast.FieldList{List: []*gast.Field{
+			{Type: &gast.ArrayType{Elt: gast.NewIdent("byte")}},
+			{Type: gast.NewIdent("any")},
+		}},
+		Results: &gast.FieldList{List: []*gast.Field{{Type: gast.NewIdent("error")}}},
+	}
+
+	decode := &gast.CallExpr{
+		Fun: &gast.IndexExpr{
+			X:     &gast.SelectorExpr{X: gast.NewIdent("validators"), Sel: gast.NewIdent("DecodeCBOR")},
+			Index: embedded,
+		},
+		Args: []gast.Expr{&gast.StarExpr{X: selfIdent}, gast.NewIdent("unmarshal")},
+	}
+	g.addImport(VALIDATOR_PKG, "")
+
+	return &gast.FuncDecl{
+		Doc: &gast.CommentGroup{
+			List: []*gast.Comment{
+				{
+					Text: "\n// Decode decodes the data item embedded in " + rule.Name.Name + " with unmarshal and returns it once valid",
+				},
+			},
+		},
+		Recv: &gast.FieldList{
+			List: []*gast.Field{{
+				Names: []*gast.Ident{selfIdent},
+				Type:  &gast.StarExpr{X: gast.NewIdent(g.transpileIdentifier(rule.Name).String())},
+			}},
+		},
+		Name: gast.NewIdent("Decode"),
+		Type: &gast.FuncType{
+			Params: &gast.FieldList{List: []*gast.Field{{
+				Names: []*gast.Ident{gast.NewIdent("unmarshal")},
+				Type:  unmarshal,
+			}}},
+			Results: &gast.FieldList{List: []*gast.Field{
+				{Type: &gast.StarExpr{X: embedded}},
+				{Type: gast.NewIdent("error")},
+			}},
+		},
+		Body: &gast.BlockStmt{List: []gast.Stmt{&gast.ReturnStmt{Results: []gast.Expr{decode}}}},
+	}
+}
+
+// defaultsConstructor returns the constructor of a struct rule setting the optional fields
+// with a `.default` value
+func (g *Generator) defaultsConstructor(defaults []*gast.KeyValueExpr, rule *ast.Rule) *gast.FuncDecl {
+	typeName := g.transpileIdentifier(rule.Name).String()
+	elts := make([]gast.Expr, len(defaults))
+	for n, kv := range defaults {
+		elts[n] = kv
+	}
+
+	return &gast.FuncDecl{
+		Doc: &gast.CommentGroup{
+			List: []*gast.Comment{
+				{
+					Text: "\n// New" + typeName + " returns a " + typeName + " whose optional fields take their .default values",
+				},
+			},
+		},
+		Name: gast.NewIdent("New" + typeName),
+		Type: &gast.FuncType{
+			Params:  &gast.FieldList{},
+			Results: &gast.FieldList{List: []*gast.Field{{Type: &gast.StarExpr{X: gast.NewIdent(typeName)}}}},
+		},
+		Body: &gast.BlockStmt{List: []gast.Stmt{&gast.ReturnStmt{Results: []gast.Expr{
+			&gast.UnaryExpr{Op: token.AND, X: &gast.CompositeLit{Type: gast.NewIdent(typeName), Elts: elts}},
+		}}}},
+	}
+}
+
+// unmarshalMethod returns the Unmarshal method of a struct rule with `.default` values. It
+// decodes into a value from the constructor so that absent fields keep their defaults.
+func (g *Generator) unmarshalMethod(rule *ast.Rule) *gast.FuncDecl {
+	selfIdent := gast.NewIdent(strings.ToLower(rule.Name.Name))
+	typeName := g.transpileIdentifier(rule.Name).String()
+	unmarshal := &gast.FuncType{
+		Params: &gast.FieldList{List: []*gast.Field{
+			{Type: &gast.ArrayType{Elt: gast.NewIdent("byte")}},
+			{Type: gast.NewIdent("any")},
+		}},
+		Results: &gast.FieldList{List: []*gast.Field{{Type: gast.NewIdent("error")}}},
+	}
+
+	reset := &gast.AssignStmt{
+		Lhs: []gast.Expr{&gast.StarExpr{X: selfIdent}},
+		Tok: token.ASSIGN,
+		Rhs: []gast.Expr{&gast.StarExpr{X: &gast.CallExpr{Fun: gast.NewIdent("New" + typeName)}}},
+	}
+	decode := &gast.ReturnStmt{Results: []gast.Expr{&gast.CallExpr{
+		Fun:  gast.NewIdent("unmarshal"),
+		Args: []gast.Expr{gast.NewIdent("data"), selfIdent},
+	}}}
+
+	return &gast.FuncDecl{
+		Doc: &gast.CommentGroup{
+			List: []*gast.Comment{
+				{
+					Text: "\n// Unmarshal decodes data into " + rule.Name.Name + " with unmarshal. Absent optional fields take their .default values",
+				},
+			},
+		},
+		Recv: &gast.FieldList{
+			List: []*gast.Field{{
+				Names: []*gast.Ident{selfIdent},
+				Type:  &gast.StarExpr{X: gast.NewIdent(typeName)},
+			}},
+		},
+		Name: gast.NewIdent("Unmarshal"),
+		Type: &gast.FuncType{
+			Params: &gast.FieldList{List: []*gast.Field{
+				{Names: []*gast.Ident{gast.NewIdent("data")}, Type: &gast.ArrayType{Elt: gast.NewIdent("byte")}},
+				{Names: []*gast.Ident{gast.NewIdent("unmarshal")}, Type: unmarshal},
+			}},
+			Results: &gast.FieldList{List: []*gast.Field{{Type: gast.NewIdent("error")}}},
+		},
+		Body: &gast.BlockStmt{List: []gast.Stmt{reset, decode}},
+	}
+}
+
 func (g *Generator) transpileGroupLike(entries []ast.GroupEntry) (*structure, error) {
 	fl := &gast.FieldList{}
+	var validators []gast.CallExpr
+	var defaults []*gast.KeyValueExpr
 	for _, entry := range entries {
 		var field *gast.Field
 		switch val := entry.(type) {
@@ -140,6 +308,7 @@ func (g *Generator) transpileGroupLike(entries []ast.GroupEntry) (*structure, er
 				panic(err)
 			}
 			field = stct.node.(*gast.Field)
+			validators = append(validators, stct.validators...)
 
 		case *ast.Optional:
 			stct, err := g.transpileNode(val.Item)
@@ -149,6 +318,9 @@ func (g *Generator) transpileGroupLike(entries []ast.GroupEntry) (*structure, er
 			if cast, ok := stct.node.(*gast.Field); ok {
 				cast.Tag = &gast.BasicLit{Kind: token.STRING, Value: "`cbor:\",omitempty\"`"}
 				field = cast
+				if stct.defaultValue != nil {
+					defaults = append(defaults, &gast.KeyValueExpr{Key: gast.NewIdent(cast.Names[0].Name), Value: stct.defaultValue})
+				}
 			} else {
 				return nil, err
 			}
@@ -167,6 +339,8 @@ func (g *Generator) transpileGroupLike(entries []ast.GroupEntry) (*structure, er
 		fl.List = append(fl.List, field)
 	}
 	stctRet := newStructure(fl)
+	stctRet.validators = validators
+	stctRet.defaults = defaults
 	return stctRet, nil
 }
 
@@ -200,7 +374,10 @@ func (g *Generator) transpileArray(arr *ast.Array) (*structure, error) {
 	}
 
 	fl.List = append(fl.List, fl2.node.(*gast.FieldList).List...)
-	return newStructure(&gast.StructType{Fields: fl}), nil
+	ret := newStructure(&gast.StructType{Fields: fl})
+	ret.Embed(fl2)
+
+	return ret, nil
 }
 
 func (g *Generator) transpileEntry(entry *ast.Entry) (*structure, error) {
@@ -217,8 +394,10 @@ func (g *Generator) transpileEntry(entry *ast.Entry) (*structure, error) {
 	}
 
 	stctRet := newStructure(field)
+	stctRet.defaultValue = stct.defaultValue
 
 	log.Println("Embed called")
+	stct.bindField(ident)
 	stctRet.Embed(stct)
 
 	return stctRet, nil
@@ -243,14 +422,25 @@ func (g *Generator) transpileNode(node ast.Node) (*structure, error) {
 		return newStructure(g.transpileTstrType(val)), nil
 	case *ast.BytesType:
 		return newStructure(g.transpileBytesType(val)), nil
+	case *ast.BstrType:
+		return newStructure(g.transpileBstrType(val)), nil
 	case *ast.Comment:
 		return newStructure(g.transpileComment(val)), nil
 	case *ast.NullType:
 		return newStructure(g.transpileNullType(val)), nil
+	case *ast.AnyType:
+		return newStructure(g.transpileAnyType(val)), nil
 	case *ast.IntegerType:
 		return newStructure(g.transpileIntegerType(val)), nil
 	case *ast.UintType:
 		return newStructure(g.transpileUintType(val)), nil
+	case *ast.FloatType:
+		stct := newStructure(g.transpileFloatType(val))
+		if val.Base == 16 {
+			stct.addValidatorCall("validators", "Float16", SELF)
+			g.addImport(VALIDATOR_PKG, "")
+		}
+		return stct, nil
 	case *ast.IntegerLiteral:
 		return newStructure(g.transpileIntegerLiteral(val)), nil
 	case *ast.BooleanLiteral:
@@ -263,6 +453,25 @@ func (g *Generator) transpileNode(node ast.Node) (*structure, error) {
 		return newStructure(g.transpileNMOccurence(val)), nil
 	case *ast.SizeOperatorControl:
 		return g.transformSizeOp(val)
+	case *ast.Regexp:
+		return g.transformRegexpOp(val)
+	case *ast.ComparatorOpControl:
+		return g.transpileComparatorOp(val)
+	case *ast.CBORControl:
+		return g.transformCBOROp(val)
+	case *ast.Bits:
+		return g.transformBitsOp(val)
+	case *ast.DefaultControl:
+		return g.transformDefaultOp(val)
+	case *ast.FeatureControl:
+		// features only matter to validators of the schema
+		return g.transpileNode(val.Base)
+	case *ast.ComputedOpControl:
+		lit := evaluator.ToNode(g.evaluator.Eval(val), val.Start())
+		if lit == nil {
+			return nil, fmt.Errorf("transpiler: operator %s at %s does not evaluate to a literal", val.Token, val.Pos)
+		}
+		return g.transpileNode(lit)
 	default:
 		panic(fmt.Sprintf("unexpected type %T", val))
 	}
@@ -1,23 +1,45 @@
 package gogen
 
 import (
-	"errors"
+	"fmt"
 	gast "go/ast"
-	"log"
+	"sort"
+	"strconv"
 
 	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/ast/astutils"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/iregexp"
 	"github.com/HannesKimara/cddlc/token"
 )
 
-func (g *Generator) transformSizeOp(op *ast.SizeOperatorControl) (*structure, error) {
-	var size *ast.IntegerLiteral
+// sizeBounds evaluates the size of a `.size` control to inclusive bounds. Sizes are either
+// an unsigned integer or a range of them.
+func (g *Generator) sizeBounds(op *ast.SizeOperatorControl) (min, max uint64, err error) {
+	size := g.evaluator.Eval(op.Size)
+	if r, ok := size.(*evaluator.Range); ok {
+		from, fromOk := r.From.(*evaluator.Integer)
+		to, toOk := r.To.(*evaluator.Integer)
+		if fromOk && toOk && from.Int.IsUint64() && to.Int.IsUint64() {
+			max = to.Int.Uint64()
+			if r.Exclusive {
+				if max == 0 {
+					return 0, 0, fmt.Errorf("transpiler: size range %s at %s is empty", r, op.Pos)
+				}
+				max--
+			}
+			return from.Int.Uint64(), max, nil
+		}
+	} else if i, ok := size.(*evaluator.Integer); ok && i.Int.IsUint64() {
+		return i.Int.Uint64(), i.Int.Uint64(), nil
+	}
+	return 0, 0, fmt.Errorf("transpiler: size at %s does not evaluate to an unsigned integer or a range of them", op.Pos)
+}
 
-	switch val := op.Size.(type) {
-	case *ast.IntegerLiteral:
-		size = val
-	case *ast.Identifier:
-		// TODO: resolve and cast
-		panic("ident literal size not implemented")
+func (g *Generator) transformSizeOp(op *ast.SizeOperatorControl) (*structure, error) {
+	min, max, err := g.sizeBounds(op)
+	if err != nil {
+		return nil, err
 	}
 
 	baseStct, err := g.transpileNode(op.Type)
@@ -30,17 +52,228 @@ func (g *Generator) transformSizeOp(op *ast.SizeOperatorControl) (*structure, er
 
 	switch op.Type.(type) {
 	case *ast.UintType:
-		stct.addValidatorCall("validators", "CheckSize", "self", size.Literal) // test
+		stct.addValidatorCall("validators", "SizeUint", SELF, max)
 	case *ast.BstrType, *ast.BytesType:
-		stct.addValidatorCall("validators", "CheckSize", "self", size.Literal)
+		stct.addValidatorCall("validators", "SizeBytes", SELF, min, max)
 	case *ast.TstrType:
-		stct.addValidatorCall("validators", "CheckSize", "self", size.Literal)
+		stct.addValidatorCall("validators", "SizeText", SELF, min, max)
 	default:
-		panic("fix later")
+		return nil, fmt.Errorf("transpiler: .size at %s applies to uint, bstr or tstr", op.Pos)
+	}
+	g.addImport(VALIDATOR_PKG, "")
+
+	return stct, nil
+}
+
+func (g *Generator) transformRegexpOp(op *ast.Regexp) (*structure, error) {
+	pattern, ok := g.evaluator.Eval(op.Regex).(*evaluator.Text)
+	if !ok {
+		return nil, fmt.Errorf("transpiler: regular expression at %s does not evaluate to a text string", op.Pos)
+	}
+	if _, err := iregexp.Translate(pattern.Text); err != nil {
+		return nil, fmt.Errorf("transpiler: regular expression at %s: %s", op.Pos, err)
+	}
+
+	stct := newStructure(g.transpileTstrType(op.Base))
+	stct.addValidatorCall("validators", "Regexp", SELF, strconv.Quote(pattern.Text))
+	g.addImport(VALIDATOR_PKG, "")
+
+	return stct, nil
+}
+
+// maxBits bounds the number of bits a range in the constraint of a `.bits` control may allow
+const maxBits = 1 << 16
+
+// transformBitsOp checks that unsigned integers and byte strings of `.bits` controls only set
+// the bits allowed by the constraint
+func (g *Generator) transformBitsOp(op *ast.Bits) (*structure, error) {
+	allowed := map[uint64]bool{}
+	if !g.allowedBits(op.Contstraint, allowed, map[string]bool{}) {
+		return nil, fmt.Errorf("transpiler: bits constraint at %s does not evaluate to unsigned integers, ranges or choices of them", op.Pos)
+	}
+	bits := make([]uint64, 0, len(allowed))
+	for n := range allowed {
+		bits = append(bits, n)
+	}
+	sort.Slice(bits, func(i, j int) bool { return bits[i] < bits[j] })
+
+	baseStct, err := g.transpileNode(op.Base)
+	if err != nil {
+		return nil, err
+	}
+	stct := &structure{
+		node: baseStct.node,
+	}
+	args := []interface{}{SELF}
+	for _, n := range bits {
+		args = append(args, n)
+	}
+
+	switch op.Base.(type) {
+	case *ast.UintType:
+		stct.addValidatorCall("validators", "Bits", args...)
+	case *ast.BstrType, *ast.BytesType:
+		stct.addValidatorCall("validators", "BitsBytes", args...)
+	default:
+		return nil, fmt.Errorf("transpiler: .bits at %s applies to uint, bstr or bytes", op.Pos)
+	}
+	g.addImport(VALIDATOR_PKG, "")
+
+	return stct, nil
+}
+
+// allowedBits adds the bit numbers allowed by a `.bits` constraint: unsigned integers, ranges
+// of them, choices and enumerations of those and rules defining them. It returns false for
+// other constraints.
+func (g *Generator) allowedBits(node ast.Node, allowed map[uint64]bool, seen map[string]bool) bool {
+	switch val := node.(type) {
+	case *ast.TypeChoice:
+		return g.allowedBits(val.First, allowed, seen) && g.allowedBits(val.Second, allowed, seen)
+	case *ast.GroupChoice:
+		return g.allowedBits(val.First, allowed, seen) && g.allowedBits(val.Second, allowed, seen)
+	case *ast.Enumeration:
+		return g.allowedBits(val.Value, allowed, seen)
+	case *ast.Group:
+		for _, entry := range val.Entries {
+			if !g.allowedBits(entry, allowed, seen) {
+				return false
+			}
+		}
+		return true
+	case *ast.Entry:
+		return g.allowedBits(val.Value, allowed, seen)
+	case *ast.Identifier:
+		if rules, ok := g.rules[val.Name]; ok && g.evaluator.Value(val.Name) == nil {
+			if seen[val.Name] {
+				return false
+			}
+			seen[val.Name] = true
+			defer delete(seen, val.Name)
+			for _, rule := range rules {
+				if len(rule.Params) > 0 || !g.allowedBits(rule.Value, allowed, seen) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	switch val := g.evaluator.Eval(node).(type) {
+	case *evaluator.Integer:
+		if !val.Int.IsUint64() {
+			return false
+		}
+		allowed[val.Int.Uint64()] = true
+		return true
+	case *evaluator.Range:
+		from, fromOk := val.From.(*evaluator.Integer)
+		to, toOk := val.To.(*evaluator.Integer)
+		if !fromOk || !toOk || !from.Int.IsUint64() || !to.Int.IsUint64() {
+			return false
+		}
+		lo, hi := from.Int.Uint64(), to.Int.Uint64()
+		if val.Exclusive {
+			if hi == 0 {
+				return true
+			}
+			hi--
+		}
+		if hi >= lo && hi-lo >= maxBits {
+			return false
+		}
+		for n := uint64(0); hi >= lo && n <= hi-lo; n++ {
+			allowed[lo+n] = true
+		}
+		return true
+	}
+	return false
+}
+
+// bitsReferences counts the references to each name and those made by `.bits` constraints
+type bitsReferences struct {
+	all, bits map[string]int
+}
+
+func (r *bitsReferences) Visit(node ast.Node) astutils.Visitor {
+	switch val := node.(type) {
+	case *ast.Identifier:
+		r.all[val.Name]++
+	case *ast.Bits:
+		if ident, ok := val.Contstraint.(*ast.Identifier); ok {
+			r.bits[ident.Name]++
+		}
+	}
+	return r
+}
+
+// bitNames returns the rules only referenced as constraints of `.bits` controls
+func bitNames(cddl *ast.CDDL) map[string]bool {
+	refs := &bitsReferences{all: map[string]int{}, bits: map[string]int{}}
+	for _, entry := range cddl.Rules {
+		if rule, ok := entry.(*ast.Rule); ok && rule.Value != nil {
+			astutils.Walk(refs, rule.Value)
+		}
+	}
+	names := map[string]bool{}
+	for name, n := range refs.bits {
+		if refs.all[name] == n {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// transformCBOROp checks that byte strings of `.cbor` and `.cborseq` controls hold well-formed
+// CBOR, deterministically encoded for `.det`. The type of the data item embedded by `.cbor`
+// and `.det` is kept for the Decode method.
+func (g *Generator) transformCBOROp(op *ast.CBORControl) (*structure, error) {
+	baseStct, err := g.transpileNode(op.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	stct := &structure{
+		node: baseStct.node,
+	}
+	switch op.Token {
+	case token.CBORSEQ:
+		stct.addValidatorCall("validators", "CBORSeq", SELF)
+	default:
+		controller, err := g.transpileNode(op.Controller)
+		if err != nil {
+			return nil, err
+		}
+		stct.embedded = controller.node.(gast.Expr)
+		if op.Token == token.DET {
+			stct.addValidatorCall("validators", "DeterministicCBOR", SELF)
+		} else {
+			stct.addValidatorCall("validators", "CBOR", SELF)
+		}
 	}
 	g.addImport(VALIDATOR_PKG, "")
 
-	log.Println("Len validators: ", len(stct.validators))
+	return stct, nil
+}
+
+// transformDefaultOp keeps the base type of a `.default` control and records its value for
+// the constructor of the enclosing struct
+func (g *Generator) transformDefaultOp(op *ast.DefaultControl) (*structure, error) {
+	stct, err := g.transpileNode(op.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	lit := evaluator.ToNode(g.evaluator.Eval(op.Default), op.Default.Start())
+	switch lit.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.TextLiteral, *ast.BooleanLiteral:
+	default:
+		return nil, fmt.Errorf("transpiler: default value at %s does not evaluate to a number, text or bool", op.Pos)
+	}
+	value, err := g.transpileNode(lit)
+	if err != nil {
+		return nil, err
+	}
+	stct.defaultValue = value.node.(gast.Expr)
 
 	return stct, nil
 }
@@ -55,28 +288,42 @@ func (g *Generator) transpileNMOccurence(nm *ast.NMOccurrence) *gast.ArrayType {
 	}
 }
 
-func (g *Generator) transpileComparatorOp(op ast.ComparatorOpControl) (*structure, error) {
-	_, err := g.transpileNode(op.Left)
-	if err != nil {
-		return nil, err
-	}
+// comparators maps the comparison controls to their runtime validators
+var comparators = map[string]string{
+	".lt": "Lt",
+	".le": "Le",
+	".gt": "Gt",
+	".ge": "Ge",
+	".eq": "Eq",
+	".ne": "Ne",
+}
 
-	_, err = g.transpileNode(op.Right)
+func (g *Generator) transpileComparatorOp(op *ast.ComparatorOpControl) (*structure, error) {
+	baseStct, err := g.transpileNode(op.Left)
 	if err != nil {
 		return nil, err
 	}
 
-	switch op.Operator {
-	case token.LT.String(): // .lt
-	case token.LE.String(): // .le
-	case token.GT.String(): // .gt
-	case token.GE.String(): // .gt
-	case token.EQ.String(): // .eq
-	case token.EQ.String(): // .ne
+	fn, ok := comparators[op.Operator]
+	if !ok {
+		return nil, fmt.Errorf("transpiler: comparator operator `%s` not implemented", op.Operator)
+	}
 
+	var limit string
+	switch val := g.evaluator.Eval(op.Right).(type) {
+	case *evaluator.Integer:
+		limit = val.Int.String()
+	case *evaluator.Float:
+		limit = val.String()
 	default:
-		return nil, errors.New("comparator operator `" + op.Operator + "` not implemented")
+		return nil, fmt.Errorf("transpiler: %s at %s does not compare to a number", op.Operator, op.Pos)
 	}
 
-	return nil, nil
+	stct := &structure{
+		node: baseStct.node,
+	}
+	stct.addValidatorCall("validators", fn, SELF, limit)
+	g.addImport(VALIDATOR_PKG, "")
+
+	return stct, nil
 }
@@ -1,7 +1,14 @@
 package gogen_test
 
 import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/HannesKimara/cddlc/lexer"
@@ -24,3 +31,97 @@ func TestGen(t *testing.T) {
 	gen.Visit(cddl)
 	gen.String(os.Stdout)
 }
+
+// validatorTests holds schemas and parts of the code generated for them
+var validatorTests = []struct {
+	src      string
+	expected []string
+}{
+	{`id = tstr .size 3`, []string{"errors.Join(validators.SizeText(*id, 3, 3))"}},
+	{`max = 64
+name = tstr .size (1..max)`, []string{"validators.SizeText(*name, 1, 64)"}},
+	{`port = uint .size 2`, []string{"validators.SizeUint(*port, 2)"}},
+	{`key = bstr .size (16...33)`, []string{"validators.SizeBytes(*key, 16, 32)"}},
+	{`code = tstr .regexp "[A-Z]+"`, []string{`validators.Regexp(*code, "[A-Z]+")`}},
+	{`person = (name: tstr .size (1..64), age: uint .le 150)`, []string{
+		"validators.SizeText(person.Name, 1, 64)",
+		"validators.Le(person.Age, 150)",
+	}},
+	{`pair = [a: int .gt 0, b: tstr]`, []string{"errors.Join(validators.Gt(pair.A, 0))"}},
+	{`name = tstr`, []string{"return nil"}},
+	{"protected = bstr .cbor header\nheader = (alg: int)", []string{
+		"errors.Join(validators.CBOR(*protected))",
+		"func (protected *Protected) Decode(unmarshal func([]byte, any) error) (*Header, error)",
+		"validators.DecodeCBOR[Header](*protected, unmarshal)",
+	}},
+	{`msg = [body: bstr .cbor uint]`, []string{"validators.CBOR(msg.Body)"}},
+	{"signed = bstr .det payload\npayload = (alg: int)", []string{
+		"errors.Join(validators.DeterministicCBOR(*signed))",
+		"validators.DecodeCBOR[Payload](*signed, unmarshal)",
+	}},
+	{`log = bytes .cborseq [* uint]`, []string{"validators.CBORSeq(*log)"}},
+	{`config = (host: tstr, ? port: uint .default 80, ? mode: tstr .default "r")`, []string{
+		"func NewConfig() *Config",
+		`return &Config{Port: 80, Mode: "r"}`,
+		"func (config *Config) Unmarshal(data []byte, unmarshal func([]byte, any) error) error",
+		"*config = *NewConfig()",
+	}},
+	{`tagged = (name: tstr, label: tstr .feature "labels")`, []string{"Label string", "return nil"}},
+	{`greeting = "hello"`, []string{`var Greeting = "hello"`}},
+	{`reading = [value: float16, scale: float32, offset: float64]`, []string{
+		"Value  float32", "Scale  float32", "Offset float64",
+		"errors.Join(validators.Float16(reading.Value))",
+	}},
+	{"perms = uint .bits flags\nflags = &(r: 0, w: 1, x: 2)", []string{
+		"errors.Join(validators.Bits(*perms, 0, 1, 2))",
+	}},
+	{`mask = bstr .bits (0..3 / 9)`, []string{"validators.BitsBytes(*mask, 0, 1, 2, 3, 9)"}},
+}
+
+func generate(t *testing.T, src string) string {
+	t.Helper()
+	cddl, err := parser.NewParser(lexer.NewLexer([]byte(src))).ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := gogen.NewGenerator("lib")
+	gen.Visit(cddl)
+
+	var out bytes.Buffer
+	if err := gen.String(&out); err != nil {
+		t.Fatalf("%s: %s", src, err)
+	}
+	return out.String()
+}
+
+func TestGenValidators(t *testing.T) {
+	for _, tst := range validatorTests {
+		out := generate(t, tst.src)
+		for _, expected := range tst.expected {
+			if !strings.Contains(out, expected) {
+				t.Errorf("%s: expected `%s` in\n%s", tst.src, expected, out)
+			}
+		}
+		if strings.Contains(out, "validators.") && !strings.Contains(out, `"github.com/HannesKimara/cddlc/runtime/validators"`) {
+			t.Errorf("%s: expected the validators import in\n%s", tst.src, out)
+		}
+	}
+}
+
+// TestGenTypeChecks type checks the code generated for the schemas of TestGenValidators
+// against the runtime validators
+func TestGenTypeChecks(t *testing.T) {
+	fset := token.NewFileSet()
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	for _, tst := range validatorTests {
+		out := generate(t, tst.src)
+		file, err := goparser.ParseFile(fset, "lib.go", out, 0)
+		if err != nil {
+			t.Errorf("%s: %s in\n%s", tst.src, err, out)
+			continue
+		}
+		if _, err := conf.Check("lib", fset, []*ast.File{file}, nil); err != nil {
+			t.Errorf("%s: %s in\n%s", tst.src, err, out)
+		}
+	}
+}
@@ -0,0 +1,936 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/iregexp"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// compileDepth limits the nesting of types compiled from a single rule. Generic rules
+// instantiating themselves with ever larger arguments are left to the interpreter past it.
+const compileDepth = 100
+
+// Program is a schema compiled for validating many data items. Compile resolves references
+// to rules and generic parameters, flattens groups, evaluates constants and compiles regular
+// expressions and ABNF grammars once, lowering the rules into trees of closures.
+//
+// A Program accepts the same data items as a Validator of the schema and returns the same
+// errors, which are found by interpreting the schema again for data items that do not
+// match. A Program is safe for concurrent use.
+type Program struct {
+	cddl  *ast.CDDL
+	defs  map[string]*definition
+	tags  map[uint64]TagChecker
+	rules map[string]matcher
+
+	// deterministic: true if data items must take the core deterministic encoding
+	deterministic bool
+
+	// features: the enabled features, nil if all are
+	features map[string]bool
+
+	// maxItemSize: the largest encoding of a data item of a sequence
+	maxItemSize int
+
+	// validators interpret the schema to describe failures and to match the types left to
+	// the interpreter
+	validators sync.Pool
+}
+
+// matcher reports whether a data item is an instance of a compiled type
+type matcher func(st *state, item *cbor.Item) bool
+
+// seqMatcher matches array elements from pos. It calls k with the position after each way
+// of matching until k accepts one.
+type seqMatcher func(st *state, items []*cbor.Item, pos int, k func(int) bool) bool
+
+// memberMatcher matches the unused entries of a map and calls k once all members are
+// matched. Entries matched are marked as used until k or a later member fails.
+type memberMatcher func(st *state, pairs []*cbor.Pair, used []bool, k func() bool) bool
+
+// state holds the state of matching a single data item with a program
+type state struct {
+	prog *Program
+
+	// calls counts the nested types matched as validation.calls does
+	calls int
+
+	// va interprets the types left to the interpreter
+	va *validation
+}
+
+// interpreter returns a validation for matching types the compiler left to the interpreter
+func (st *state) interpreter() *validation {
+	if st.va == nil {
+		st.va = &validation{v: st.prog.validators.Get().(*Validator), errDepth: -1}
+	}
+	st.va.calls = st.calls
+	return st.va
+}
+
+func (st *state) release() {
+	if st.va != nil {
+		st.prog.validators.Put(st.va.v)
+		st.va = nil
+	}
+}
+
+// Compile lowers the rules of the tree and of the standard prelude into a program. The
+// options are those of NewValidator.
+func Compile(cddl *ast.CDDL, opts ...func(*Validator)) *Program {
+	v := NewValidator(cddl, opts...)
+	prog := &Program{cddl: cddl, defs: v.defs, tags: v.tags, rules: map[string]matcher{}, deterministic: v.deterministic, features: v.features, maxItemSize: v.maxItemSize}
+	prog.validators.New = func() interface{} {
+		pv := newValidator(prog.cddl, prog.defs, prog.tags)
+		pv.deterministic = prog.deterministic
+		pv.features = prog.features
+		pv.maxItemSize = prog.maxItemSize
+		return pv
+	}
+
+	c := &compiler{
+		va:      &validation{v: v, errDepth: -1, quiet: 1},
+		types:   map[compileKey]*compiledType{},
+		seqs:    map[compileKey]*compiledSeq{},
+		members: map[compileKey]*compiledMembers{},
+	}
+	for name, def := range v.defs {
+		if len(def.params) == 0 {
+			prog.rules[name] = c.compileType(def.node, &scope{rule: name, prelude: def.prelude})
+		}
+	}
+	return prog
+}
+
+// Validate reports whether the data item is an instance of the named rule. It returns an
+// *Error describing the mismatch if it is not.
+func (prog *Program) Validate(rule string, item *cbor.Item) error {
+	if match, ok := prog.rules[rule]; ok {
+		st := &state{prog: prog}
+		matched := match(st, item)
+		st.release()
+		if matched {
+			if prog.deterministic {
+				return checkDeterministic(rule, item)
+			}
+			return nil
+		}
+	}
+
+	v := prog.validators.Get().(*Validator)
+	defer prog.validators.Put(v)
+	return v.Validate(rule, item)
+}
+
+// ValidateCBOR decodes data holding a single CBOR data item and validates it against the
+// named rule
+func (prog *Program) ValidateCBOR(rule string, data []byte) error {
+	item, err := cbor.Decode(data)
+	if err != nil {
+		return err
+	}
+	return prog.Validate(rule, item)
+}
+
+// compiler lowers types into matchers. Types, array groups and map groups are compiled once
+// for each scope so that recursive rules refer back to the matcher being compiled.
+type compiler struct {
+	// va resolves references, expands groups and evaluates constants while compiling
+	va *validation
+
+	types   map[compileKey]*compiledType
+	seqs    map[compileKey]*compiledSeq
+	members map[compileKey]*compiledMembers
+
+	// depth counts the nested types being compiled
+	depth int
+}
+
+// compileKey identifies a node in the scope of the generic arguments it is compiled with
+type compileKey struct {
+	node  ast.Node
+	scope string
+}
+
+type compiledType struct {
+	match matcher
+	done  bool
+}
+
+type compiledSeq struct {
+	match seqMatcher
+	done  bool
+}
+
+type compiledMembers struct {
+	match memberMatcher
+	done  bool
+}
+
+// scopeKey identifies the arguments bound by a scope. Arguments referring to parameters of
+// an enclosing scope are followed to the types they are bound to.
+func scopeKey(sc *scope) string {
+	if sc == nil || len(sc.params) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(sc.params))
+	for name := range sc.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		b := sc.params[name]
+		node, bsc := b.node, b.scope
+		for {
+			ident, ok := node.(*ast.Identifier)
+			if !ok {
+				break
+			}
+			bound, ok := bsc.lookup(ident.Name)
+			if !ok {
+				break
+			}
+			node, bsc = bound.node, bound.scope
+		}
+		fmt.Fprintf(&sb, "%s=%p(%s);", name, node, scopeKey(bsc))
+	}
+	return sb.String()
+}
+
+func never(*state, *cbor.Item) bool { return false }
+
+func always(*state, *cbor.Item) bool { return true }
+
+// compileType returns the matcher of a type. Each matcher counts the nesting of types to
+// stop rules referring to themselves without consuming data.
+func (c *compiler) compileType(node ast.Node, sc *scope) matcher {
+	node, sc = c.va.resolve(node, sc)
+	key := compileKey{node: node, scope: scopeKey(sc)}
+	if t, ok := c.types[key]; ok {
+		if t.done {
+			return t.match
+		}
+		return func(st *state, item *cbor.Item) bool { return t.match(st, item) }
+	}
+	if c.depth >= compileDepth {
+		return func(st *state, item *cbor.Item) bool { return st.interpreter().matches(node, sc, item) }
+	}
+
+	t := &compiledType{}
+	c.types[key] = t
+	c.depth++
+	m := c.lower(node, sc)
+	c.depth--
+	t.match = func(st *state, item *cbor.Item) bool {
+		st.calls++
+		ok := st.calls <= maxDepth && m(st, item)
+		st.calls--
+		return ok
+	}
+	t.done = true
+	return t.match
+}
+
+// lower compiles a resolved type following validation.validate
+func (c *compiler) lower(node ast.Node, sc *scope) matcher {
+	switch val := node.(type) {
+	case *ast.AnyType:
+		return always
+	case *ast.UintType:
+		return func(_ *state, item *cbor.Item) bool { return item.Major == cbor.MajorUint }
+	case *ast.NegativeIntegerType:
+		return func(_ *state, item *cbor.Item) bool { return item.Major == cbor.MajorNint }
+	case *ast.IntegerType:
+		return func(_ *state, item *cbor.Item) bool { return item.IsInteger() }
+	case *ast.FloatType:
+		base := val.Base
+		return func(_ *state, item *cbor.Item) bool { return item.IsFloat() && fitsPrecision(base, item) }
+	case *ast.TstrType:
+		return func(_ *state, item *cbor.Item) bool { return item.Major == cbor.MajorText }
+	case *ast.BstrType, *ast.BytesType:
+		return func(_ *state, item *cbor.Item) bool { return item.Major == cbor.MajorBytes }
+	case *ast.BooleanType:
+		return func(_ *state, item *cbor.Item) bool { return item.IsBool() }
+	case *ast.NullType:
+		return func(_ *state, item *cbor.Item) bool { return item.IsNull() }
+	case *ast.BooleanLiteral:
+		return func(_ *state, item *cbor.Item) bool {
+			return item.IsBool() && (item.Arg == cbor.SimpleTrue) == val.Bool
+		}
+	case *ast.IntegerLiteral, *ast.UintLiteral, *ast.FloatLiteral, *ast.TextLiteral, *ast.BytesLiteral,
+		*ast.Range, *ast.ComputedOpControl:
+		value := c.va.constant(node, sc)
+		if value == nil {
+			return never
+		}
+		return compileValue(value)
+	case *ast.TypeChoice:
+		return anyOf(c.compileAll(flattenChoice(val), sc))
+	case *ast.Map:
+		return c.compileMap(val.Rules, sc)
+	case *ast.Array:
+		seq := c.compileSeq(c.va.expand(entryNodes(val.Rules), sc))
+		return func(st *state, item *cbor.Item) bool {
+			if item.Major != cbor.MajorArray {
+				return false
+			}
+			return seq(st, item.Items, 0, func(pos int) bool { return pos == len(item.Items) })
+		}
+	case *ast.Tag:
+		return c.compileTag(val, sc)
+	case *ast.Enumeration:
+		return c.compileEnumeration(val, sc)
+	case *ast.SizeOperatorControl:
+		return c.compileSize(val, sc)
+	case *ast.Regexp:
+		return c.compileRegexp(val, sc)
+	case *ast.ABNF:
+		return c.compileABNF(val, sc)
+	case *ast.CBORControl:
+		if val.Token == token.CBORSEQ {
+			return c.compileCBORSeq(val, sc)
+		}
+		return c.compileCBOR(val, sc)
+	case *ast.Bits:
+		return c.compileBits(val, sc)
+	case *ast.DefaultControl:
+		return c.compileType(val.Base, sc)
+	case *ast.FeatureControl:
+		return c.compileFeature(val, sc)
+	case *ast.ComparatorOpControl:
+		return c.compileComparison(val, sc)
+	}
+	// missing or undefined types, groups used as types and unsupported types never match
+	return never
+}
+
+func (c *compiler) compileAll(nodes []ast.Node, sc *scope) []matcher {
+	out := make([]matcher, len(nodes))
+	for i, node := range nodes {
+		out[i] = c.compileType(node, sc)
+	}
+	return out
+}
+
+func anyOf(alts []matcher) matcher {
+	return func(st *state, item *cbor.Item) bool {
+		for _, alt := range alts {
+			if alt(st, item) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// both returns a matcher for data items matched by the base type and the check of a
+// control operator
+func both(base matcher, check func(item *cbor.Item) bool) matcher {
+	return func(st *state, item *cbor.Item) bool {
+		return base(st, item) && check(item)
+	}
+}
+
+// compileValue returns a matcher for a constant. Integers and integer ranges whose bounds
+// fit in 64 bits compare without big numbers.
+func compileValue(value evaluator.Value) matcher {
+	switch val := value.(type) {
+	case *evaluator.Integer:
+		if major, arg, ok := integerArg(val.Int); ok {
+			return func(_ *state, item *cbor.Item) bool { return item.Major == major && item.Arg == arg }
+		}
+	case *evaluator.Text:
+		return func(_ *state, item *cbor.Item) bool {
+			return item.Major == cbor.MajorText && item.Text == val.Text
+		}
+	case *evaluator.Range:
+		if lower, upper, ok := intBounds(val); ok {
+			return func(_ *state, item *cbor.Item) bool {
+				n, ok := intArg(item)
+				return ok && lower <= n && n <= upper
+			}
+		}
+	}
+	return func(_ *state, item *cbor.Item) bool { return matchesValue(value, item, false) }
+}
+
+// integerArg returns the major type and argument encoding an integer
+func integerArg(n *big.Int) (cbor.Major, uint64, bool) {
+	if n.IsUint64() {
+		return cbor.MajorUint, n.Uint64(), true
+	}
+	arg := new(big.Int).Sub(big.NewInt(-1), n)
+	if n.Sign() < 0 && arg.IsUint64() {
+		return cbor.MajorNint, arg.Uint64(), true
+	}
+	return 0, 0, false
+}
+
+// intBounds returns the inclusive bounds of an integer range if they fit in an int64
+func intBounds(r *evaluator.Range) (int64, int64, bool) {
+	from, ok := r.From.(*evaluator.Integer)
+	if !ok || !from.Int.IsInt64() {
+		return 0, 0, false
+	}
+	to, ok := r.To.(*evaluator.Integer)
+	if !ok || !to.Int.IsInt64() {
+		return 0, 0, false
+	}
+	upper := to.Int.Int64()
+	if r.Exclusive {
+		if upper == math.MinInt64 {
+			return 0, 0, false
+		}
+		upper--
+	}
+	return from.Int.Int64(), upper, true
+}
+
+// intArg returns the value of an integer data item if it fits in an int64
+func intArg(item *cbor.Item) (int64, bool) {
+	switch {
+	case item.Major == cbor.MajorUint && item.Arg <= math.MaxInt64:
+		return int64(item.Arg), true
+	case item.Major == cbor.MajorNint && item.Arg <= math.MaxInt64:
+		return -1 - int64(item.Arg), true
+	}
+	return 0, false
+}
+
+// compileTag follows validation.validateTag
+func (c *compiler) compileTag(tag *ast.Tag, sc *scope) matcher {
+	if tag.Major == nil {
+		return always
+	}
+	if cbor.Major(tag.Major.Literal) != cbor.MajorTag {
+		return func(_ *state, item *cbor.Item) bool { return matchesMajor(tag, item) }
+	}
+
+	number := tag.TagNumber
+	content := always
+	if tag.Item != nil {
+		content = c.compileType(tag.Item, sc)
+	}
+	tags := c.va.v.tags
+	return func(st *state, item *cbor.Item) bool {
+		if item.Major != cbor.MajorTag || (number != nil && item.Arg != number.Literal) {
+			return false
+		}
+		if !content(st, item.Content) {
+			return false
+		}
+		check := tags[item.Arg]
+		return check == nil || check(item.Content) == nil
+	}
+}
+
+// compileEnumeration follows validation.validateEnumeration
+func (c *compiler) compileEnumeration(enum *ast.Enumeration, sc *scope) matcher {
+	node, gsc := c.va.resolve(enum.Value, sc)
+	var entries []ast.Node
+	switch val := node.(type) {
+	case *ast.Group:
+		entries = entryNodes(val.Entries)
+	case *ast.Map:
+		entries = val.Rules
+	default:
+		return never
+	}
+
+	var alts []matcher
+	for _, entry := range c.va.expand(entries, gsc) {
+		for _, alt := range flattenChoice(entry.node) {
+			_, _, alt = occurrence(alt)
+			if e, ok := alt.(*ast.Entry); ok {
+				alt = e.Value
+			}
+			alts = append(alts, c.compileType(alt, entry.scope))
+		}
+	}
+	return anyOf(alts)
+}
+
+// compileSize follows validation.validateSize
+func (c *compiler) compileSize(op *ast.SizeOperatorControl, sc *scope) matcher {
+	base := c.compileType(op.Type, sc)
+	size := c.va.constant(op.Size, sc)
+	if size == nil {
+		return never
+	}
+
+	length := compileLength(size)
+	return both(base, func(item *cbor.Item) bool {
+		switch item.Major {
+		case cbor.MajorText:
+			return length(uint64(len(item.Text)))
+		case cbor.MajorBytes:
+			return length(uint64(len(item.Bytes)))
+		case cbor.MajorUint:
+			return fitsSize(size, item.Arg)
+		}
+		return false
+	})
+}
+
+// compileLength returns a check of lengths against a size or size range
+func compileLength(size evaluator.Value) func(uint64) bool {
+	switch val := size.(type) {
+	case *evaluator.Integer:
+		if !val.Int.IsUint64() {
+			return func(uint64) bool { return false }
+		}
+		n := val.Int.Uint64()
+		return func(length uint64) bool { return length == n }
+	case *evaluator.Range:
+		if lower, upper, ok := intBounds(val); ok {
+			return func(length uint64) bool {
+				return length <= math.MaxInt64 && lower <= int64(length) && int64(length) <= upper
+			}
+		}
+	}
+	return func(length uint64) bool {
+		return matchesValue(size, &cbor.Item{Major: cbor.MajorUint, Arg: length}, false)
+	}
+}
+
+// compileRegexp follows validation.validateRegexp with the pattern compiled once
+func (c *compiler) compileRegexp(op *ast.Regexp, sc *scope) matcher {
+	base := c.compileType(op.Base, sc)
+	pattern, ok := c.va.constant(op.Regex, sc).(*evaluator.Text)
+	if !ok {
+		return never
+	}
+	re, ok := c.va.v.regexps[pattern.Text]
+	if !ok {
+		var err error
+		if re, err = iregexp.Compile(pattern.Text); err != nil {
+			return never
+		}
+		c.va.v.regexps[pattern.Text] = re
+	}
+	return both(base, func(item *cbor.Item) bool { return re.MatchString(item.Text) })
+}
+
+// compileABNF follows validation.validateABNF with the grammar parsed once
+func (c *compiler) compileABNF(op *ast.ABNF, sc *scope) matcher {
+	base := c.compileType(op.Base, sc)
+	grammar, err := c.va.grammar(op, sc)
+	if err != nil {
+		return never
+	}
+	return both(base, func(item *cbor.Item) bool {
+		switch item.Major {
+		case cbor.MajorText:
+			return grammar.Match(item.Text)
+		case cbor.MajorBytes:
+			return grammar.MatchBytes(item.Bytes)
+		}
+		return false
+	})
+}
+
+// compileCBOR follows validation.validateCBOR
+func (c *compiler) compileCBOR(op *ast.CBORControl, sc *scope) matcher {
+	base := c.compileType(op.Base, sc)
+	controller := c.compileType(op.Controller, sc)
+	det := op.Token == token.DET
+	return func(st *state, item *cbor.Item) bool {
+		if !base(st, item) {
+			return false
+		}
+		embedded, err := cbor.Decode(item.Bytes)
+		if err != nil || (det && cbor.CheckDeterministic(embedded) != nil) {
+			return false
+		}
+		return controller(st, embedded)
+	}
+}
+
+// compileCBORSeq follows validation.validateCBORSeq
+func (c *compiler) compileCBORSeq(op *ast.CBORControl, sc *scope) matcher {
+	base := c.compileType(op.Base, sc)
+	controller := c.compileType(op.Controller, sc)
+	return func(st *state, item *cbor.Item) bool {
+		if !base(st, item) {
+			return false
+		}
+		seq, err := decodeSequence(item.Bytes)
+		return err == nil && controller(st, seq)
+	}
+}
+
+// compileFeature follows validation.validateFeature. Types using a disabled feature never
+// match so that the interpreter reports the feature.
+func (c *compiler) compileFeature(op *ast.FeatureControl, sc *scope) matcher {
+	if c.va.v.features == nil {
+		return c.compileType(op.Base, sc)
+	}
+	if name, ok := c.va.featureName(op, sc); ok && c.va.v.features[name] {
+		return c.compileType(op.Base, sc)
+	}
+	return never
+}
+
+// compileBits follows validation.validateBits
+func (c *compiler) compileBits(op *ast.Bits, sc *scope) matcher {
+	base := c.compileType(op.Base, sc)
+	constraint := c.compileType(op.Contstraint, sc)
+	return func(st *state, item *cbor.Item) bool {
+		if !base(st, item) {
+			return false
+		}
+		set, ok := setBits(item)
+		if !ok {
+			return false
+		}
+		for _, n := range set {
+			if !constraint(st, &cbor.Item{Major: cbor.MajorUint, Arg: n}) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// compileComparison follows validation.validateComparison. Comparisons of integers with
+// limits that fit in an int64 do not use big numbers.
+func (c *compiler) compileComparison(op *ast.ComparatorOpControl, sc *scope) matcher {
+	base := c.compileType(op.Left, sc)
+	value := c.va.constant(op.Right, sc)
+	if value == nil {
+		return never
+	}
+
+	if limit, ok := value.(*evaluator.Integer); ok && limit.Int.IsInt64() {
+		n, cmp := limit.Int.Int64(), comparator(op.Token)
+		return both(base, func(item *cbor.Item) bool {
+			if x, ok := intArg(item); ok {
+				return cmp(x, n)
+			}
+			return compares(op.Token, item, value)
+		})
+	}
+	return both(base, func(item *cbor.Item) bool { return compares(op.Token, item, value) })
+}
+
+func comparator(op token.Token) func(x, y int64) bool {
+	switch op {
+	case token.LT:
+		return func(x, y int64) bool { return x < y }
+	case token.LE:
+		return func(x, y int64) bool { return x <= y }
+	case token.GT:
+		return func(x, y int64) bool { return x > y }
+	case token.GE:
+		return func(x, y int64) bool { return x >= y }
+	case token.EQ:
+		return func(x, y int64) bool { return x == y }
+	case token.NE:
+		return func(x, y int64) bool { return x != y }
+	}
+	return func(x, y int64) bool { return false }
+}
+
+// compileSeq compiles the entries of an array group following validation.matchSeq
+func (c *compiler) compileSeq(entries []entry) seqMatcher {
+	if len(entries) == 0 {
+		return func(_ *state, _ []*cbor.Item, pos int, k func(int) bool) bool { return k(pos) }
+	}
+	min, max, node := occurrence(entries[0].node)
+	group, element := c.compileSeqEntry(node, entries[0].scope)
+	rest := c.compileSeq(entries[1:])
+
+	if element != nil {
+		return repeatElement(element, min, max, rest)
+	}
+	return repeatGroup(group, min, max, rest)
+}
+
+// repeatElement matches a single element type as often as possible up to max times, falling
+// back to fewer repetitions down to min as validation.repeatSeq does
+func repeatElement(element matcher, min, max int, rest seqMatcher) seqMatcher {
+	return func(st *state, items []*cbor.Item, pos int, k func(int) bool) bool {
+		count := 0
+		for pos+count < len(items) && (max < 0 || count < max) && element(st, items[pos+count]) {
+			count++
+		}
+		for ; count >= min; count-- {
+			if rest(st, items, pos+count, k) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// repeatGroup matches a group as often as possible up to max times, falling back to fewer
+// repetitions down to min as validation.repeatSeq does
+func repeatGroup(group seqMatcher, min, max int, rest seqMatcher) seqMatcher {
+	var repeat func(st *state, items []*cbor.Item, count, pos int, k func(int) bool) bool
+	repeat = func(st *state, items []*cbor.Item, count, pos int, k func(int) bool) bool {
+		if max < 0 || count < max {
+			matched := group(st, items, pos, func(next int) bool {
+				if next == pos {
+					// the group matched no elements and can be repeated any number of times
+					return k(next)
+				}
+				return repeat(st, items, count+1, next, k)
+			})
+			if matched {
+				return true
+			}
+		}
+		return count >= min && k(pos)
+	}
+	return func(st *state, items []*cbor.Item, pos int, k func(int) bool) bool {
+		return repeat(st, items, 0, pos, func(next int) bool { return rest(st, items, next, k) })
+	}
+}
+
+// compileSeqEntry compiles an entry of an array group following validation.matchSeqEntry.
+// It returns a matcher of a single element if the entry is a type.
+func (c *compiler) compileSeqEntry(node ast.Node, sc *scope) (seqMatcher, matcher) {
+	switch val := node.(type) {
+	case *ast.Group:
+		return c.compileGroupSeq(node, sc, entryNodes(val.Entries)), nil
+	case *ast.GroupChoice:
+		var alts []seqMatcher
+		for _, alt := range flattenChoice(val) {
+			alts = append(alts, c.compileSeq(c.va.expand([]ast.Node{alt}, sc)))
+		}
+		return func(st *state, items []*cbor.Item, pos int, k func(int) bool) bool {
+			for _, alt := range alts {
+				if alt(st, items, pos, k) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case *ast.Entry:
+		node = val.Value
+	case *ast.Unwrap, *ast.Identifier, *ast.Generic:
+		if expanded := c.va.expand([]ast.Node{node}, sc); len(expanded) != 1 || expanded[0].node != node {
+			return c.compileGroupSeq(node, sc, []ast.Node{node}), nil
+		}
+	}
+	return nil, c.compileType(node, sc)
+}
+
+// compileGroupSeq compiles the expansion of nodes standing for a group in an array once for
+// each scope
+func (c *compiler) compileGroupSeq(node ast.Node, sc *scope, nodes []ast.Node) seqMatcher {
+	key := compileKey{node: node, scope: scopeKey(sc)}
+	if s, ok := c.seqs[key]; ok {
+		if s.done {
+			return s.match
+		}
+		return func(st *state, items []*cbor.Item, pos int, k func(int) bool) bool {
+			return s.match(st, items, pos, k)
+		}
+	}
+	if c.depth >= compileDepth {
+		return func(st *state, items []*cbor.Item, pos int, k func(int) bool) bool {
+			va := st.interpreter()
+			va.quiet++
+			defer func() { va.quiet-- }()
+			return va.matchSeq(va.expand(nodes, sc), items, pos, nil, k)
+		}
+	}
+
+	s := &compiledSeq{}
+	c.seqs[key] = s
+	c.depth++
+	s.match = c.compileSeq(c.va.expand(nodes, sc))
+	c.depth--
+	s.done = true
+	return s.match
+}
+
+// compileMap compiles the members of a map following validation.matchMap
+func (c *compiler) compileMap(nodes []ast.Node, sc *scope) matcher {
+	members := c.compileMembers(c.va.literalFirst(c.va.expand(nodes, sc)))
+	return func(st *state, item *cbor.Item) bool {
+		if item.Major != cbor.MajorMap {
+			return false
+		}
+		used := make([]bool, len(item.Pairs))
+		return members(st, item.Pairs, used, func() bool {
+			for _, u := range used {
+				if !u {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// compileMembers compiles the entries of a map group following validation.matchMembers
+func (c *compiler) compileMembers(entries []entry) memberMatcher {
+	if len(entries) == 0 {
+		return func(_ *state, _ []*cbor.Pair, _ []bool, k func() bool) bool { return k() }
+	}
+	min, max, node := occurrence(entries[0].node)
+	sc := entries[0].scope
+	rest := c.compileMembers(entries[1:])
+
+	var first memberMatcher
+	if m, ok := node.(*ast.Entry); ok {
+		first = c.compileMember(m, sc, min, max)
+	} else {
+		first = repeatMembers(c.compileGroupMember(node, sc), min, max)
+	}
+	return func(st *state, pairs []*cbor.Pair, used []bool, k func() bool) bool {
+		return first(st, pairs, used, func() bool { return rest(st, pairs, used, k) })
+	}
+}
+
+// compileMember compiles a member following validation.matchMember
+func (c *compiler) compileMember(m *ast.Entry, sc *scope, min, max int) memberMatcher {
+	key := c.compileMemberKey(m, sc)
+	value := c.compileType(m.Value, sc)
+	literal := c.va.literalKey(m, sc)
+
+	return func(st *state, pairs []*cbor.Pair, used []bool, k func() bool) bool {
+		var taken []int
+		release := func() {
+			for _, i := range taken {
+				used[i] = false
+			}
+		}
+
+		for i, pair := range pairs {
+			if max >= 0 && len(taken) >= max {
+				break
+			}
+			if used[i] || !key(st, pair.Key) {
+				continue
+			}
+			if !value(st, pair.Value) {
+				if literal {
+					release()
+					return false
+				}
+				continue
+			}
+			used[i] = true
+			taken = append(taken, i)
+		}
+
+		if len(taken) < min {
+			release()
+			return false
+		}
+		if k() {
+			return true
+		}
+		release()
+		return false
+	}
+}
+
+// compileMemberKey compiles the key of a member following validation.keyMatches
+func (c *compiler) compileMemberKey(m *ast.Entry, sc *scope) matcher {
+	if n, ok := new(big.Int).SetString(m.Name.Name, 10); ok {
+		if major, arg, ok := integerArg(n); ok {
+			return func(_ *state, key *cbor.Item) bool { return key.Major == major && key.Arg == arg }
+		}
+		return never
+	}
+	if m.Token != token.ARROW_MAP {
+		name := m.Name.Name
+		return func(_ *state, key *cbor.Item) bool { return key.Major == cbor.MajorText && key.Text == name }
+	}
+	return c.compileType(m.Name, sc)
+}
+
+// repeatMembers matches a group in a map as often as possible up to max times, falling
+// back to fewer repetitions down to min as validation.repeatMembers does
+func repeatMembers(group memberMatcher, min, max int) memberMatcher {
+	var repeat func(st *state, pairs []*cbor.Pair, used []bool, count int, k func() bool) bool
+	repeat = func(st *state, pairs []*cbor.Pair, used []bool, count int, k func() bool) bool {
+		if max < 0 || count < max {
+			before := countUsed(used)
+			matched := group(st, pairs, used, func() bool {
+				if countUsed(used) == before {
+					return k()
+				}
+				return repeat(st, pairs, used, count+1, k)
+			})
+			if matched {
+				return true
+			}
+		}
+		return count >= min && k()
+	}
+	return func(st *state, pairs []*cbor.Pair, used []bool, k func() bool) bool {
+		return repeat(st, pairs, used, 0, k)
+	}
+}
+
+// compileGroupMember compiles a group in a map following validation.matchGroupMember
+func (c *compiler) compileGroupMember(node ast.Node, sc *scope) memberMatcher {
+	switch val := node.(type) {
+	case *ast.Group:
+		return c.compileGroupMembers(node, sc, entryNodes(val.Entries))
+	case *ast.GroupChoice:
+		var alts []memberMatcher
+		for _, alt := range flattenChoice(val) {
+			alts = append(alts, c.compileMembers(c.va.expand([]ast.Node{alt}, sc)))
+		}
+		return func(st *state, pairs []*cbor.Pair, used []bool, k func() bool) bool {
+			for _, alt := range alts {
+				if alt(st, pairs, used, k) {
+					return true
+				}
+			}
+			return false
+		}
+	case *ast.Unwrap, *ast.Identifier, *ast.Generic:
+		if expanded := c.va.expand([]ast.Node{node}, sc); len(expanded) != 1 || expanded[0].node != node {
+			return c.compileGroupMembers(node, sc, []ast.Node{node})
+		}
+	}
+	return func(*state, []*cbor.Pair, []bool, func() bool) bool { return false }
+}
+
+// compileGroupMembers compiles the expansion of nodes standing for a group in a map once for
+// each scope
+func (c *compiler) compileGroupMembers(node ast.Node, sc *scope, nodes []ast.Node) memberMatcher {
+	key := compileKey{node: node, scope: scopeKey(sc)}
+	if m, ok := c.members[key]; ok {
+		if m.done {
+			return m.match
+		}
+		return func(st *state, pairs []*cbor.Pair, used []bool, k func() bool) bool {
+			return m.match(st, pairs, used, k)
+		}
+	}
+	if c.depth >= compileDepth {
+		return func(st *state, pairs []*cbor.Pair, used []bool, k func() bool) bool {
+			va := st.interpreter()
+			va.quiet++
+			defer func() { va.quiet-- }()
+			return va.matchMembers(va.expand(nodes, sc), pairs, used, nil, k)
+		}
+	}
+
+	m := &compiledMembers{}
+	c.members[key] = m
+	c.depth++
+	m.match = c.compileMembers(c.va.expand(nodes, sc))
+	c.depth--
+	m.done = true
+	return m.match
+}
@@ -0,0 +1,259 @@
+package validator_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/validator"
+)
+
+// compileSchemas exercise the constructs the compiler lowers. Each schema defines rule a.
+var compileSchemas = []string{
+	"a = uint / nint / float / tstr / bstr / bool / null",
+	"a = 10..20 / -5...5 / 0.5..1.5",
+	`a = "x" / h'01' / true / 3 / -3`,
+	"a = [* a]",
+	"a = [uint, ? tstr, * (bool, int)]",
+	"a = [+ (uint // tstr, tstr)]",
+	"a = [2*4 uint]",
+	"a = [~b, tstr] b = [uint, uint]",
+	"a = {name: tstr, ? age: uint, * label => int} label = tstr",
+	"a = {1: tstr, -2: [+ uint]}",
+	"a = {(x: int // y: tstr)}",
+	"a = {b, c: uint} b = (? d: tstr)",
+	"a = {+ label => uint} label = tstr",
+	"a = #6.32(tstr) / #6.1(uint) / #7.25 / #2",
+	"a = &colors colors = (red: 0, green: 1, blue: 2)",
+	"a = pair<uint> pair<t> = [t, t]",
+	"a = tstr .size (2..4) / bstr .size 3 / uint .size 1",
+	`a = tstr .regexp "[a-c]{2,4}-[0-9]+"`,
+	`a = tstr .abnf "a = 1*DIGIT"`,
+	"a = bstr .cborseq [uint, * (tstr, bool)]",
+	"a = {1: bstr .cbor header} header = {1: int, ? 4: bstr}",
+	"a = bstr .det {1: int, ? 2: float, ? 3: [* uint]}",
+	`a = {? port: uint .default 80, ? mode: ("r" / "w") .default "r"}`,
+	`a = {? ext: tstr .feature "ext", * label => uint .feature ["counts", "detail"]} label = tstr`,
+	"a = uint .bits flags flags = &(r: 0, w: 1, x: 2)",
+	"a = uint .lt 5 / int .ge 1000000 / float .gt 2.5",
+	`a = "ab" .cat "cd"`,
+	"a = any",
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		src  string
+		data []string
+	}{
+		{"a = [* a]", []string{"80", "81 80", "82 80 81 80", "81 01"}},
+		{"a = [uint, * (tstr, bool)]", []string{"81 01", "83 01 6161 f5", "82 01 6161"}},
+		{"a = [* uint, uint]", []string{"81 01", "83 01 02 03", "80"}},
+		{"a = [* (uint, ? tstr), bool]", []string{"83 01 02 f4", "84 01 6161 02 f4", "82 6161 f4"}},
+		{"a = {name: tstr, * tstr => uint}", []string{"a1 646e616d65 6161", "a2 646e616d65 6161 6162 01", "a1 646e616d65 01"}},
+		{"a = {* tstr => uint, x: tstr}", []string{"a2 6178 6161 6179 01", "a1 6179 01"}},
+		{"a = {? 1: tstr, ? 1: uint}", []string{"a1 01 6161", "a1 01 01", "a0"}},
+		{"a = {1*2 (x: uint // y: tstr)}", []string{"a1 6178 01", "a2 6178 01 6179 6161", "a0"}},
+		{"a = pair<uint> pair<t> = [t, t]", []string{"82 01 02", "82 01 6161"}},
+		{"a = nest<tstr> nest<t> = [t, * nest<t>]", []string{"81 6161", "82 6161 81 6162", "82 6161 01", "82 6161 81 01"}},
+		{"a = grow<uint> grow<t> = [t] / grow<[t]>", []string{"81 01", "81 81 81 01", "81 81 6161"}},
+		{"a = uint .size 2", []string{"19ffff", "1a00010000"}},
+		{"a = uint .size (1...3)", []string{"19ffff", "1a00010000"}},
+		{"a = tstr .size (1..3)", []string{"6161", "60", "64 61616161"}},
+		{"a = int .lt -1", []string{"21", "20", "3bffffffffffffffff"}},
+		{"a = uint .ne 18446744073709551615", []string{"1bffffffffffffffff", "01"}},
+		{"a = -18446744073709551616", []string{"3bffffffffffffffff", "01"}},
+		{"a = #6.24(bstr .size 1)", []string{"d818 4101", "d818 4102 01", "d819 4101"}},
+		{"a = #7.25", []string{"f93c00", "fa3f800000"}},
+		{"a = b b = (uint)", []string{"01", "6161"}},
+		{"a = missing", []string{"01"}},
+		{"a = tdate / bigint / undefined", []string{"c0 6161", "c2 4101", "f7", "01"}},
+	}
+
+	for _, tst := range tests {
+		// the parser reports undefined rules, which the validators fail at
+		cddl, _ := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		v, prog := validator.NewValidator(cddl), validator.Compile(cddl)
+		for _, data := range tst.data {
+			b := decodeHex(t, data)
+			want, got := v.ValidateCBOR("a", b), prog.ValidateCBOR("a", b)
+			if fmt.Sprint(want) != fmt.Sprint(got) {
+				t.Errorf("%s: %s: expected `%v` got `%v`", tst.src, data, want, got)
+			}
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	prog := validator.Compile(parse(t, "a = uint b<t> = [t]"))
+
+	if err := prog.ValidateCBOR("missing", decodeHex(t, "01")); err == nil || err.Error() != "rule missing is not defined" {
+		t.Errorf("expected an error for an undefined rule got %v", err)
+	}
+	if err := prog.ValidateCBOR("b", decodeHex(t, "8101")); err == nil {
+		t.Errorf("expected an error for a generic rule")
+	}
+	if err := prog.ValidateCBOR("a", decodeHex(t, "6161")); err == nil || err.Error() != "validator: at /: expected uint, got text string \"a\"" {
+		t.Errorf("expected the error of the interpreter got %v", err)
+	}
+}
+
+// TestCompileGenerated checks that compiled and interpreted validation agree on generated
+// instances and near misses of the schemas
+func TestCompileGenerated(t *testing.T) {
+	schemas := append([]string{}, compileSchemas...)
+	for _, src := range languageSchemas(t) {
+		schemas = append(schemas, src)
+	}
+
+	for _, src := range schemas {
+		cddl := parse(t, src)
+		v, prog := validator.NewValidator(cddl), validator.Compile(cddl)
+		for _, rule := range ruleNames(cddl) {
+			g := validator.NewGenerator(cddl, validator.WithSeed(1))
+			for i := 0; i < 10; i++ {
+				valid, violations, err := g.GenerateInvalid(rule)
+				if err != nil {
+					break
+				}
+				items := []*cbor.Item{valid}
+				for _, violation := range violations {
+					items = append(items, violation.Instance)
+				}
+				for _, item := range items {
+					want, got := v.Validate(rule, item), prog.Validate(rule, item)
+					if fmt.Sprint(want) != fmt.Sprint(got) {
+						t.Errorf("%s: rule %s: %s: expected `%v` got `%v`", src, rule, item, want, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCompileConcurrent(t *testing.T) {
+	cddl := parse(t, `a = {name: tstr .regexp "[a-z]+", ? tags: [* tstr .abnf "t = 1*ALPHA"], * label => uint} label = tstr`)
+	prog := validator.Compile(cddl)
+	g := validator.NewGenerator(cddl, validator.WithSeed(1))
+	valid, violations, err := g.GenerateInvalid("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := prog.Validate("a", valid); err != nil {
+					t.Errorf("%s: unexpected error %s", valid, err)
+				}
+				for _, violation := range violations {
+					if prog.Validate("a", violation.Instance) == nil {
+						t.Errorf("%s: expected an error", violation.Instance)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// BenchmarkValidate compares interpreted and compiled validation of generated instances of
+// the rules of the testdata schemas
+func BenchmarkValidate(b *testing.B) {
+	dir := filepath.Join("..", "testdata", "language")
+	files, err := filepath.Glob(filepath.Join(dir, "*.cddl"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cddl, errs := parser.NewParser(lexer.NewLexer(src)).ParseFile()
+		if len(errs) != 0 {
+			b.Fatalf("%s: %s", file, errs)
+		}
+
+		type instance struct {
+			rule string
+			item *cbor.Item
+		}
+		var instances []instance
+		g := validator.NewGenerator(cddl, validator.WithSeed(1))
+		for _, rule := range ruleNames(cddl) {
+			for i := 0; i < 10; i++ {
+				if item, err := g.Generate(rule); err == nil {
+					instances = append(instances, instance{rule, item})
+				}
+			}
+		}
+		if len(instances) == 0 {
+			continue
+		}
+
+		name := strings.TrimSuffix(filepath.Base(file), ".cddl")
+		v, prog := validator.NewValidator(cddl), validator.Compile(cddl)
+		b.Run(name+"/interpreted", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				in := instances[i%len(instances)]
+				if err := v.Validate(in.rule, in.item); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(name+"/compiled", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				in := instances[i%len(instances)]
+				if err := prog.Validate(in.rule, in.item); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// languageSchemas returns the sources of the testdata schemas
+func languageSchemas(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "language", "*.cddl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, string(src))
+	}
+	return out
+}
+
+// ruleNames returns the names of the rules of the tree without generic parameters
+func ruleNames(cddl *ast.CDDL) []string {
+	seen := map[string]bool{}
+	for _, entry := range cddl.Rules {
+		if rule, ok := entry.(*ast.Rule); ok && rule.Name != nil && len(rule.Params) == 0 {
+			seen[rule.Name.Name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
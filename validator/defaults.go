@@ -0,0 +1,131 @@
+package validator
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// fill is a member absent from a map that takes the default value of its type
+type fill struct {
+	target     *cbor.Item
+	key, value *cbor.Item
+}
+
+// Normalize validates the data item against the named rule like Validate. It returns a copy
+// of the data item in which the absent optional members of maps whose type has a `.default`
+// control take their default value. The data item itself is left unchanged.
+func (v *Validator) Normalize(rule string, item *cbor.Item) (*cbor.Item, error) {
+	va := &validation{v: v, errDepth: -1, normalize: true}
+	if err := v.run(va, rule, item); err != nil {
+		return nil, err
+	}
+	return applyDefaults(item, va.defaults), nil
+}
+
+// Normalize validates the data item against the named rule and fills in default values as
+// Validator.Normalize does
+func (prog *Program) Normalize(rule string, item *cbor.Item) (*cbor.Item, error) {
+	v := prog.validators.Get().(*Validator)
+	defer prog.validators.Put(v)
+	return v.Normalize(rule, item)
+}
+
+// recordDefault records the default value of an absent member if its type has one. The
+// default may be given by one of the alternatives of a type choice as in
+// `? x: (uint .default 1) / tstr`. It fails if the default value cannot be evaluated, does
+// not match the type or if alternatives give different defaults.
+func (va *validation) recordDefault(m *ast.Entry, sc *scope, p path) bool {
+	var defs []defaultSite
+	va.collectDefaults(m.Value, sc, &defs, 0)
+	if len(defs) == 0 {
+		return true
+	}
+
+	key := va.memberKey(m, sc)
+	var value *cbor.Item
+	for _, d := range defs {
+		v := valueItem(va.constant(d.node.Default, d.scope))
+		if key == nil || v == nil {
+			return va.failAt(d.node, d.scope, p, "cannot evaluate the default of member %s", m.Name.Name)
+		}
+		if !va.matches(d.node.Base, d.scope, v) {
+			return va.failAt(d.node, d.scope, p, "default %s of member %s is not a %s", v, m.Name.Name, describe(d.node.Base))
+		}
+		if value != nil && !bytes.Equal(cbor.Encode(value), cbor.Encode(v)) {
+			return va.failAt(d.node, d.scope, p, "member %s has conflicting defaults %s and %s", m.Name.Name, value, v)
+		}
+		value = v
+	}
+	va.defaults = append(va.defaults, fill{target: va.target, key: key, value: value})
+	return true
+}
+
+// defaultSite is a `.default` control and the scope it is resolved in
+type defaultSite struct {
+	node  *ast.DefaultControl
+	scope *scope
+}
+
+// collectDefaults adds the `.default` controls of a type and of the alternatives of its type
+// choices
+func (va *validation) collectDefaults(node ast.Node, sc *scope, defs *[]defaultSite, depth int) {
+	if depth > maxDepth {
+		return
+	}
+	node, sc = va.resolve(node, sc)
+	switch val := node.(type) {
+	case *ast.DefaultControl:
+		*defs = append(*defs, defaultSite{node: val, scope: sc})
+	case *ast.TypeChoice:
+		va.collectDefaults(val.First, sc, defs, depth+1)
+		va.collectDefaults(val.Second, sc, defs, depth+1)
+	}
+}
+
+// memberKey returns the single key matched by a member with a literal key
+func (va *validation) memberKey(m *ast.Entry, sc *scope) *cbor.Item {
+	if n, ok := new(big.Int).SetString(m.Name.Name, 10); ok {
+		return intItem(n)
+	}
+	if m.Token != token.ARROW_MAP {
+		return &cbor.Item{Major: cbor.MajorText, Text: m.Name.Name}
+	}
+	return valueItem(va.constant(m.Name, sc))
+}
+
+// valueItem returns the data item of a constant value or nil for ranges
+func valueItem(value evaluator.Value) *cbor.Item {
+	switch val := value.(type) {
+	case *evaluator.Integer:
+		return intItem(val.Int)
+	case *evaluator.Float:
+		return &cbor.Item{Major: cbor.MajorSimple, Float: val.Float, Width: 64}
+	case *evaluator.Text:
+		return &cbor.Item{Major: cbor.MajorText, Text: val.Text}
+	case *evaluator.Bytes:
+		return &cbor.Item{Major: cbor.MajorBytes, Bytes: val.Bytes}
+	case *evaluator.Bool:
+		return boolItem(val.Bool)
+	}
+	return nil
+}
+
+// applyDefaults copies the data item and adds the recorded members to the copies of their
+// maps. Maps decoded from embedded CBOR are not part of the copy and keep their members.
+func applyDefaults(item *cbor.Item, defaults []fill) *cbor.Item {
+	copies := map[*cbor.Item]*cbor.Item{}
+	out := copyItem(item, copies)
+	for _, f := range defaults {
+		target, ok := copies[f.target]
+		if !ok || hasKey(target, f.key) {
+			continue
+		}
+		target.Pairs = append(target.Pairs, &cbor.Pair{Key: f.key, Value: f.value})
+	}
+	return out
+}
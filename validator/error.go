@@ -0,0 +1,112 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// Error describes why a data item does not conform to a rule. It implements
+// errors.Diagnostic with the positions of the schema type the data item did not match.
+type Error struct {
+	// Path: the location of the offending data item as a JSON pointer i.e. `/items/3/name`.
+	// Empty for the data item validated.
+	Path string
+
+	// Rule: the name of the rule defining the type the data item did not match
+	Rule string
+
+	// Range: the positions of the type in the schema. Zero for types of the standard prelude
+	Range token.PositionRange
+
+	Message string
+
+	// Feature: the name of the disabled feature the data item uses. Empty for mismatches
+	Feature string
+
+	// Alternatives: the failures of the alternatives of a choice the data item was tried against
+	Alternatives []*Alternative
+}
+
+// Alternative describes why a data item did not match one alternative of a choice
+type Alternative struct {
+	// Type: the description of the alternative i.e. `tstr .size 3`
+	Type string
+
+	// Range: the positions of the alternative in the schema
+	Range token.PositionRange
+
+	// Err: the failure of the alternative. Nil if no reason was recorded
+	Err *Error
+}
+
+// String returns the message with the location of the data item
+func (e *Error) String() string {
+	return fmt.Sprintf("validator: at %s: %s", displayPath(e.Path), e.Message)
+}
+
+// Diagnostic returns the message with the rule and its position in the schema
+func (e *Error) Diagnostic() string {
+	if e.Range.Start.Line == 0 {
+		return fmt.Sprintf("%s in rule %s", e, e.Rule)
+	}
+	return fmt.Sprintf("%s in rule %s at %s", e, e.Rule, e.Range.String())
+}
+
+// Start returns the beginning position of the type in the schema
+func (e *Error) Start() token.Position {
+	return e.Range.Start
+}
+
+// End returns the end position of the type in the schema
+func (e *Error) End() token.Position {
+	return e.Range.End
+}
+
+// Error satisfies the error interface. Returns the same value as String
+func (e *Error) Error() string {
+	return e.String()
+}
+
+func displayPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// path holds the reference tokens of the JSON pointer to a data item
+type path []string
+
+func (p path) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(p, "/")
+}
+
+func (p path) index(i int) path {
+	return p.append(strconv.Itoa(i))
+}
+
+// key appends a map key. Keys other than text strings are written in diagnostic notation.
+func (p path) key(k *cbor.Item) path {
+	if k.Major == cbor.MajorText {
+		return p.append(escapePointer(k.Text))
+	}
+	return p.append(escapePointer(k.String()))
+}
+
+func (p path) append(token string) path {
+	out := make(path, len(p), len(p)+1)
+	copy(out, p)
+	return append(out, token)
+}
+
+// escapePointer escapes `~` and `/` in reference tokens as defined in RFC 6901
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
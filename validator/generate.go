@@ -0,0 +1,942 @@
+package validator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/iregexp"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// maxAttempts limits the instances generated for a single call to Generate before giving up
+const maxAttempts = 64
+
+// Generator produces random instances of the rules of a schema. Each instance is validated
+// before it is returned so that constraints the generator cannot aim for directly, such as
+// keys of different members overlapping, are met by generating again.
+type Generator struct {
+	v    *Validator
+	va   *validation
+	rand *rand.Rand
+
+	// depthLimit: the nesting of maps, arrays and tags from which occurrences take their
+	// minimum and choices prefer alternatives that do not nest further
+	depthLimit int
+
+	// maxRepeat: the repetitions added above the minimum of an occurrence
+	maxRepeat int
+
+	// json restricts instances to the JSON data model
+	json bool
+
+	// sites and members record the types and members data items were generated for while
+	// generating invalid instances
+	sites   map[*cbor.Item][]entry
+	members []memberSite
+}
+
+// WithSeed seeds the random source of the generator to reproduce the instances generated
+func WithSeed(seed int64) func(*Generator) {
+	return func(g *Generator) {
+		g.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithDepthLimit sets the nesting from which recursive types are cut short. Defaults to 8.
+func WithDepthLimit(depth int) func(*Generator) {
+	return func(g *Generator) {
+		g.depthLimit = depth
+	}
+}
+
+// WithMaxRepeat sets the repetitions generated above the minimum of occurrences such as `*`
+// and `+`. Defaults to 3.
+func WithMaxRepeat(n int) func(*Generator) {
+	return func(g *Generator) {
+		g.maxRepeat = n
+	}
+}
+
+// WithJSON restricts the instances generated to data items that have a JSON equivalent
+func WithJSON() func(*Generator) {
+	return func(g *Generator) {
+		g.json = true
+	}
+}
+
+// NewGenerator returns a generator for the rules of the tree and the standard prelude
+func NewGenerator(cddl *ast.CDDL, opts ...func(*Generator)) *Generator {
+	g := &Generator{
+		v:          NewValidator(cddl),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		depthLimit: 8,
+		maxRepeat:  3,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.va = &validation{v: g.v, errDepth: -1, json: g.json}
+	return g
+}
+
+// Generate returns a random instance of the named rule
+func (g *Generator) Generate(rule string) (*cbor.Item, error) {
+	def, ok := g.v.defs[rule]
+	if !ok {
+		return nil, fmt.Errorf("rule %s is not defined", rule)
+	}
+	if len(def.params) > 0 {
+		return nil, fmt.Errorf("rule %s is generic and cannot be generated without arguments", rule)
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var item *cbor.Item
+		item, err = g.generate(def.node, &scope{rule: rule}, 0)
+		if err != nil {
+			continue
+		}
+		if err = g.v.validate(rule, item, g.json); err == nil {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("could not generate an instance of %s: %w", rule, err)
+}
+
+func (g *Generator) generate(node ast.Node, sc *scope, depth int) (*cbor.Item, error) {
+	g.va.calls++
+	defer func() { g.va.calls-- }()
+	if g.va.calls > maxDepth || depth > 2*g.depthLimit {
+		return nil, fmt.Errorf("maximum nesting exceeded")
+	}
+
+	node, sc = g.va.resolve(node, sc)
+	item, err := g.generateType(node, sc, depth)
+	if err == nil && g.sites != nil {
+		g.sites[item] = append(g.sites[item], entry{node: node, scope: sc})
+	}
+	return item, err
+}
+
+func (g *Generator) generateType(node ast.Node, sc *scope, depth int) (*cbor.Item, error) {
+	switch val := node.(type) {
+	case nil:
+		return nil, fmt.Errorf("missing type")
+	case *ast.AnyType:
+		return g.anyItem(), nil
+	case *ast.Identifier:
+		return nil, fmt.Errorf("rule %s is not defined", val.Name)
+	case *ast.Generic:
+		return nil, fmt.Errorf("cannot instantiate %s with %d generic arguments", val.Name.Name, len(val.Args))
+	case *ast.UintType:
+		return &cbor.Item{Major: cbor.MajorUint, Arg: g.uint()}, nil
+	case *ast.NegativeIntegerType:
+		return &cbor.Item{Major: cbor.MajorNint, Arg: g.uint()}, nil
+	case *ast.IntegerType:
+		return &cbor.Item{Major: cbor.Major(g.rand.Intn(2)), Arg: g.uint()}, nil
+	case *ast.FloatType:
+		return g.float(val.Token), nil
+	case *ast.TstrType:
+		return &cbor.Item{Major: cbor.MajorText, Text: g.text(g.rand.Intn(9))}, nil
+	case *ast.BstrType, *ast.BytesType:
+		if g.json {
+			return nil, fmt.Errorf("%s has no JSON equivalent", describe(node))
+		}
+		return &cbor.Item{Major: cbor.MajorBytes, Bytes: g.bytes(g.rand.Intn(9))}, nil
+	case *ast.BooleanType:
+		return boolItem(g.rand.Intn(2) == 1), nil
+	case *ast.NullType:
+		return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleNull}, nil
+	case *ast.BooleanLiteral:
+		return boolItem(val.Bool), nil
+	case *ast.IntegerLiteral, *ast.UintLiteral, *ast.FloatLiteral, *ast.TextLiteral, *ast.BytesLiteral,
+		*ast.Range, *ast.ComputedOpControl:
+		value := g.va.constant(node, sc)
+		if value == nil {
+			return nil, fmt.Errorf("cannot evaluate %s", describe(node))
+		}
+		return g.fromValue(value)
+	case *ast.TypeChoice:
+		return g.generateChoice(flattenChoice(val), sc, depth)
+	case *ast.Map:
+		item := &cbor.Item{Major: cbor.MajorMap}
+		return item, g.group(g.va.expand(val.Rules, sc), item, depth+1)
+	case *ast.Array:
+		item := &cbor.Item{Major: cbor.MajorArray}
+		return item, g.group(g.va.expand(entryNodes(val.Rules), sc), item, depth+1)
+	case *ast.Tag:
+		return g.generateTag(val, sc, depth)
+	case *ast.Enumeration:
+		return g.generateEnumeration(val, sc, depth)
+	case *ast.SizeOperatorControl:
+		return g.generateSize(val, sc, depth)
+	case *ast.Regexp:
+		return g.generateRegexp(val, sc)
+	case *ast.ABNF:
+		return g.generateABNF(val, sc)
+	case *ast.CBORControl:
+		if val.Token == token.CBORSEQ {
+			return g.generateCBORSeq(val, sc, depth)
+		}
+		return g.generateCBOR(val, sc, depth)
+	case *ast.Bits:
+		return g.generateBits(val, sc)
+	case *ast.DefaultControl:
+		return g.generate(val.Base, sc, depth)
+	case *ast.FeatureControl:
+		return g.generate(val.Base, sc, depth)
+	case *ast.ComparatorOpControl:
+		return g.generateComparison(val, sc, depth)
+	case *ast.Group, *ast.GroupChoice, *ast.Entry, *ast.Optional, *ast.NMOccurrence, *ast.Unwrap:
+		return nil, fmt.Errorf("group %s cannot be used as a type", describe(node))
+	}
+	return nil, fmt.Errorf("unsupported type %s", describe(node))
+}
+
+// generateChoice tries the alternatives in random order. Past the depth limit alternatives
+// that do not nest are tried first.
+func (g *Generator) generateChoice(alts []ast.Node, sc *scope, depth int) (*cbor.Item, error) {
+	order := g.order(alts, sc, depth)
+	var err error
+	for _, n := range order {
+		var item *cbor.Item
+		if item, err = g.generate(alts[n], sc, depth); err == nil {
+			return item, nil
+		}
+	}
+	return nil, err
+}
+
+// order returns the indices of the alternatives in the order they are tried
+func (g *Generator) order(alts []ast.Node, sc *scope, depth int) []int {
+	order := g.rand.Perm(len(alts))
+	if depth >= g.depthLimit {
+		sort.SliceStable(order, func(i, j int) bool {
+			return !g.nests(alts[order[i]], sc) && g.nests(alts[order[j]], sc)
+		})
+	}
+	return order
+}
+
+// nests reports whether a type or group entry contains further data items
+func (g *Generator) nests(node ast.Node, sc *scope) bool {
+	_, _, node = occurrence(node)
+	if e, ok := node.(*ast.Entry); ok {
+		node = e.Value
+	}
+	node, _ = g.va.resolve(node, sc)
+	switch val := node.(type) {
+	case *ast.Map, *ast.Array, *ast.Group, *ast.GroupChoice, *ast.Unwrap:
+		return true
+	case *ast.Tag:
+		return val.Item != nil
+	}
+	return false
+}
+
+// count returns the number of repetitions of an occurrence
+func (g *Generator) count(min, max, depth int) int {
+	if depth >= g.depthLimit {
+		return min
+	}
+	upper := min + g.maxRepeat
+	if max >= 0 && upper > max {
+		upper = max
+	}
+	if upper <= min {
+		return min
+	}
+	return min + g.rand.Intn(upper-min+1)
+}
+
+// group adds the entries of a group to the elements of an array or the members of a map
+func (g *Generator) group(entries []entry, item *cbor.Item, depth int) error {
+	for _, e := range entries {
+		min, max, node := occurrence(e.node)
+		count := g.count(min, max, depth)
+		for i := 0; i < count; i++ {
+			items, pairs := len(item.Items), len(item.Pairs)
+			if err := g.groupEntry(node, e.scope, item, depth); err != nil {
+				item.Items, item.Pairs = item.Items[:items], item.Pairs[:pairs]
+				if i >= min {
+					break
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Generator) groupEntry(node ast.Node, sc *scope, item *cbor.Item, depth int) error {
+	switch val := node.(type) {
+	case *ast.Group:
+		return g.group(g.va.expand(entryNodes(val.Entries), sc), item, depth)
+	case *ast.GroupChoice:
+		alts := flattenChoice(val)
+		var err error
+		for _, n := range g.order(alts, sc, depth) {
+			items, pairs := len(item.Items), len(item.Pairs)
+			if err = g.group(g.va.expand([]ast.Node{alts[n]}, sc), item, depth); err == nil {
+				return nil
+			}
+			item.Items, item.Pairs = item.Items[:items], item.Pairs[:pairs]
+		}
+		return err
+	case *ast.Unwrap, *ast.Identifier, *ast.Generic:
+		if expanded := g.va.expand([]ast.Node{node}, sc); len(expanded) != 1 || expanded[0].node != node {
+			return g.group(expanded, item, depth)
+		}
+	case *ast.Entry:
+		if item.Major == cbor.MajorMap {
+			return g.member(val, sc, item, depth)
+		}
+		node = val.Value
+	}
+
+	if item.Major == cbor.MajorMap {
+		return fmt.Errorf("%s is not a member of a map", describe(node))
+	}
+	elem, err := g.generate(node, sc, depth)
+	if err != nil {
+		return err
+	}
+	item.Items = append(item.Items, elem)
+	return nil
+}
+
+// member adds a map entry for the member. Keys already in the map are generated again.
+func (g *Generator) member(m *ast.Entry, sc *scope, item *cbor.Item, depth int) error {
+	n, integer := new(big.Int).SetString(m.Name.Name, 10)
+	if integer && g.json {
+		return fmt.Errorf("member %s has an integer key with no JSON equivalent", m.Name.Name)
+	}
+
+	for attempt := 0; attempt < 8; attempt++ {
+		var key *cbor.Item
+		switch {
+		case integer:
+			key = intItem(n)
+		case m.Token != token.ARROW_MAP:
+			key = &cbor.Item{Major: cbor.MajorText, Text: m.Name.Name}
+		default:
+			var err error
+			if key, err = g.generate(m.Name, sc, depth); err != nil {
+				return err
+			}
+		}
+		if hasKey(item, key) {
+			continue
+		}
+
+		value, err := g.generate(m.Value, sc, depth)
+		if err != nil {
+			return err
+		}
+		pair := &cbor.Pair{Key: key, Value: value}
+		item.Pairs = append(item.Pairs, pair)
+		if g.sites != nil {
+			g.members = append(g.members, memberSite{parent: item, pair: pair, entry: entry{node: m, scope: sc}})
+		}
+		return nil
+	}
+	return fmt.Errorf("no unused key for member %s", m.Name.Name)
+}
+
+func hasKey(item *cbor.Item, key *cbor.Item) bool {
+	for _, pair := range item.Pairs {
+		if pair.Key.String() == key.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// generateTag generates tags and items of the major types denoted by `#m` and `#m.n`
+func (g *Generator) generateTag(tag *ast.Tag, sc *scope, depth int) (*cbor.Item, error) {
+	if tag.Major == nil {
+		return g.anyItem(), nil
+	}
+
+	major := cbor.Major(tag.Major.Literal)
+	if g.json && (major == cbor.MajorBytes || major == cbor.MajorTag || (major == cbor.MajorSimple && tag.TagNumber != nil)) {
+		return nil, fmt.Errorf("%s has no JSON equivalent", describe(tag))
+	}
+
+	switch major {
+	case cbor.MajorTag:
+		item := &cbor.Item{Major: cbor.MajorTag, Arg: 100 + uint64(g.rand.Intn(900))}
+		if tag.TagNumber != nil {
+			item.Arg = tag.TagNumber.Literal
+		}
+		if tag.Item == nil {
+			item.Content = g.anyItem()
+		} else {
+			content, err := g.generate(tag.Item, sc, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			item.Content = content
+		}
+		return item, g.tagContent(tag, sc, item)
+	case cbor.MajorSimple:
+		if tag.TagNumber == nil {
+			return g.anyItem(), nil
+		}
+		switch n := tag.TagNumber.Literal; n {
+		case 25:
+			return g.float(token.FLOAT16), nil
+		case 26:
+			return g.float(token.FLOAT32), nil
+		case 27:
+			return g.float(token.FLOAT64), nil
+		default:
+			return &cbor.Item{Major: cbor.MajorSimple, Arg: n}, nil
+		}
+	case cbor.MajorUint, cbor.MajorNint:
+		item := &cbor.Item{Major: major, Arg: g.uint()}
+		if tag.TagNumber != nil {
+			item.Arg = tag.TagNumber.Literal
+		}
+		return item, nil
+	case cbor.MajorBytes:
+		return &cbor.Item{Major: major, Bytes: g.bytes(g.rand.Intn(9))}, nil
+	case cbor.MajorText:
+		return &cbor.Item{Major: major, Text: g.text(g.rand.Intn(9))}, nil
+	case cbor.MajorArray:
+		return &cbor.Item{Major: major}, nil
+	case cbor.MajorMap:
+		return &cbor.Item{Major: major}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", describe(tag))
+}
+
+// generateEnumeration picks one of the values of the members of the group
+func (g *Generator) generateEnumeration(enum *ast.Enumeration, sc *scope, depth int) (*cbor.Item, error) {
+	node, gsc := g.va.resolve(enum.Value, sc)
+	var entries []ast.Node
+	switch val := node.(type) {
+	case *ast.Group:
+		entries = entryNodes(val.Entries)
+	case *ast.Map:
+		entries = val.Rules
+	default:
+		return nil, fmt.Errorf("cannot enumerate %s", describe(node))
+	}
+
+	values := []entry{}
+	for _, e := range g.va.expand(entries, gsc) {
+		for _, alt := range flattenChoice(e.node) {
+			_, _, alt = occurrence(alt)
+			if m, ok := alt.(*ast.Entry); ok {
+				alt = m.Value
+			}
+			values = append(values, entry{node: alt, scope: e.scope})
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s has no values", describe(enum.Value))
+	}
+	value := values[g.rand.Intn(len(values))]
+	return g.generate(value.node, value.scope, depth)
+}
+
+// generateSize generates strings of a length within the size and unsigned integers fitting
+// in its number of bytes
+func (g *Generator) generateSize(op *ast.SizeOperatorControl, sc *scope, depth int) (*cbor.Item, error) {
+	size := g.va.constant(op.Size, sc)
+	if size == nil {
+		return nil, fmt.Errorf("cannot evaluate size %s", describe(op.Size))
+	}
+	lower, upper, ok := bounds(size)
+	if !ok || upper < 0 || lower > upper {
+		return nil, fmt.Errorf("cannot generate size %s", size)
+	}
+	if lower < 0 {
+		lower = 0
+	}
+	// keep strings short for sizes without a practical upper bound
+	length := int(lower)
+	if span := upper - lower; span > 0 {
+		length += g.rand.Intn(int(min64(span, 16)) + 1)
+	}
+
+	base, _ := g.va.resolve(op.Type, sc)
+	switch base.(type) {
+	case *ast.TstrType:
+		return &cbor.Item{Major: cbor.MajorText, Text: g.text(length)}, nil
+	case *ast.BstrType, *ast.BytesType:
+		if g.json {
+			return nil, fmt.Errorf("%s has no JSON equivalent", describe(base))
+		}
+		return &cbor.Item{Major: cbor.MajorBytes, Bytes: g.bytes(length)}, nil
+	case *ast.UintType:
+		if upper >= 8 {
+			return &cbor.Item{Major: cbor.MajorUint, Arg: g.rand.Uint64()}, nil
+		}
+		return &cbor.Item{Major: cbor.MajorUint, Arg: uint64(g.rand.Int63n(1 << (8 * upper)))}, nil
+	}
+	return g.generate(op.Type, sc, depth)
+}
+
+// bounds returns the integer bounds of a size
+func bounds(size evaluator.Value) (int64, int64, bool) {
+	switch val := size.(type) {
+	case *evaluator.Integer:
+		if !val.Int.IsInt64() {
+			return 0, 0, false
+		}
+		return val.Int.Int64(), val.Int.Int64(), true
+	case *evaluator.Range:
+		from, ok := val.From.(*evaluator.Integer)
+		to, ok2 := val.To.(*evaluator.Integer)
+		if !ok || !ok2 || !from.Int.IsInt64() || !to.Int.IsInt64() {
+			return 0, 0, false
+		}
+		upper := to.Int.Int64()
+		if val.Exclusive {
+			upper--
+		}
+		return from.Int.Int64(), upper, true
+	}
+	return 0, 0, false
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// generateRegexp generates a text string matching a regular expression
+func (g *Generator) generateRegexp(op *ast.Regexp, sc *scope) (*cbor.Item, error) {
+	pattern, ok := g.va.constant(op.Regex, sc).(*evaluator.Text)
+	if !ok {
+		return nil, fmt.Errorf("regular expression %s is not a text string", describe(op.Regex))
+	}
+	var re *syntax.Regexp
+	translated, err := iregexp.Translate(pattern.Text)
+	if err == nil {
+		re, err = syntax.Parse(translated, syntax.Perl)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %s", pattern.Text, err)
+	}
+
+	var sb strings.Builder
+	if err := g.regexpString(re.Simplify(), &sb); err != nil {
+		return nil, err
+	}
+	return &cbor.Item{Major: cbor.MajorText, Text: sb.String()}, nil
+}
+
+// generateABNF generates a text or byte string matching an ABNF grammar. Recursive grammars
+// may nest too deep for a random choice of alternatives, so generation is tried a few times.
+func (g *Generator) generateABNF(op *ast.ABNF, sc *scope) (*cbor.Item, error) {
+	grammar, err := g.va.grammar(op, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	var chars []rune
+	for attempt := 0; attempt < 8; attempt++ {
+		if chars, err = grammar.Generate(g.rand, g.maxRepeat); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if op.Token == token.ABNF {
+		return &cbor.Item{Major: cbor.MajorText, Text: string(chars)}, nil
+	}
+	b := make([]byte, len(chars))
+	for i, c := range chars {
+		if c > 0xff {
+			return nil, fmt.Errorf("ABNF grammar %s generated character %U which is not a byte", describe(op.Grammar), c)
+		}
+		b[i] = byte(c)
+	}
+	return &cbor.Item{Major: cbor.MajorBytes, Bytes: b}, nil
+}
+
+// tagContent replaces generated content failing the checker of its tag by a sample of the
+// content the tag expects, if the type of the content allows it
+func (g *Generator) tagContent(tag *ast.Tag, sc *scope, item *cbor.Item) error {
+	check := g.v.tags[item.Arg]
+	if check == nil {
+		return nil
+	}
+	err := check(item.Content)
+	if err == nil {
+		return nil
+	}
+	if sample := g.tagSample(item.Arg); sample != nil && (tag.Item == nil || g.va.matches(tag.Item, sc, sample)) {
+		if g.sites != nil {
+			// the sample stands for the content generated for the type of the tag
+			g.sites[sample] = g.sites[item.Content]
+			delete(g.sites, item.Content)
+		}
+		item.Content = sample
+		return nil
+	}
+	return fmt.Errorf("invalid content of tag %d: %s", item.Arg, err)
+}
+
+// tagSample returns random content following the semantics of the standard tags
+func (g *Generator) tagSample(number uint64) *cbor.Item {
+	b := g.bytes(1 + g.rand.Intn(8))
+	switch number {
+	case 0:
+		t := time.Unix(g.rand.Int63n(4102444800), 0).UTC()
+		return &cbor.Item{Major: cbor.MajorText, Text: t.Format(time.RFC3339)}
+	case 1:
+		return &cbor.Item{Major: cbor.MajorUint, Arg: uint64(g.rand.Int63n(4102444800))}
+	case 32:
+		return &cbor.Item{Major: cbor.MajorText, Text: "https://example.com/" + g.text(g.rand.Intn(9))}
+	case 33:
+		return &cbor.Item{Major: cbor.MajorText, Text: base64.RawURLEncoding.EncodeToString(b)}
+	case 34:
+		return &cbor.Item{Major: cbor.MajorText, Text: base64.StdEncoding.EncodeToString(b)}
+	case 35:
+		return &cbor.Item{Major: cbor.MajorText, Text: g.text(1+g.rand.Intn(8)) + "[0-9]*"}
+	}
+	return nil
+}
+
+// generateCBOR generates a data item for the controller and embeds its encoding, or its
+// deterministic encoding for `.det`, in a byte string
+func (g *Generator) generateCBOR(op *ast.CBORControl, sc *scope, depth int) (*cbor.Item, error) {
+	embedded, err := g.generate(op.Controller, sc, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	if op.Token == token.DET {
+		return &cbor.Item{Major: cbor.MajorBytes, Bytes: cbor.EncodeDeterministic(embedded)}, nil
+	}
+	return &cbor.Item{Major: cbor.MajorBytes, Bytes: cbor.Encode(embedded)}, nil
+}
+
+// generateCBORSeq generates an array for the controller and encodes its elements as the
+// CBOR sequence held by a byte string
+func (g *Generator) generateCBORSeq(op *ast.CBORControl, sc *scope, depth int) (*cbor.Item, error) {
+	seq, err := g.generate(op.Controller, sc, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	if seq.Major != cbor.MajorArray {
+		return nil, fmt.Errorf("controller %s of %s is not an array", describe(op.Controller), op.Token)
+	}
+	b := []byte{}
+	for _, elem := range seq.Items {
+		b = append(b, cbor.Encode(elem)...)
+	}
+	return &cbor.Item{Major: cbor.MajorBytes, Bytes: b}, nil
+}
+
+func (g *Generator) regexpString(re *syntax.Regexp, sb *strings.Builder) error {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return fmt.Errorf("regular expression %s matches nothing", re)
+	case syntax.OpLiteral:
+		sb.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		sb.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteRune(rune('a' + g.rand.Intn(26)))
+	case syntax.OpCapture:
+		return g.regexpString(re.Sub[0], sb)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			min, max = 0, -1
+		case syntax.OpPlus:
+			min, max = 1, -1
+		case syntax.OpQuest:
+			min, max = 0, 1
+		}
+		for i, n := 0, g.count(min, max, 0); i < n; i++ {
+			if err := g.regexpString(re.Sub[0], sb); err != nil {
+				return err
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if err := g.regexpString(sub, sb); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		return g.regexpString(re.Sub[g.rand.Intn(len(re.Sub))], sb)
+	}
+	// anchors, word boundaries and empty matches generate nothing
+	return nil
+}
+
+// classRune picks a rune of a character class, preferring printable ASCII characters
+func (g *Generator) classRune(ranges []rune) rune {
+	printable := []rune{}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < 0x20 {
+			lo = 0x20
+		}
+		if hi > 0x7e {
+			hi = 0x7e
+		}
+		if lo <= hi {
+			printable = append(printable, lo, hi)
+		}
+	}
+	if len(printable) > 0 {
+		ranges = printable
+	}
+	for {
+		n := g.rand.Intn(len(ranges)/2) * 2
+		r := ranges[n] + rune(g.rand.Intn(int(ranges[n+1]-ranges[n])+1))
+		if utf8.ValidRune(r) {
+			return r
+		}
+	}
+}
+
+// generateBits sets a random subset of the bits allowed by the control type
+func (g *Generator) generateBits(op *ast.Bits, sc *scope) (*cbor.Item, error) {
+	allowed := []uint64{}
+	for n := uint64(0); n < 64; n++ {
+		if g.va.matches(op.Contstraint, sc, &cbor.Item{Major: cbor.MajorUint, Arg: n}) {
+			allowed = append(allowed, n)
+		}
+	}
+	set := []uint64{}
+	for _, n := range allowed {
+		if g.rand.Intn(2) == 1 {
+			set = append(set, n)
+		}
+	}
+
+	base, _ := g.va.resolve(op.Base, sc)
+	switch base.(type) {
+	case *ast.UintType:
+		item := &cbor.Item{Major: cbor.MajorUint}
+		for _, n := range set {
+			item.Arg |= 1 << n
+		}
+		return item, nil
+	case *ast.BstrType, *ast.BytesType:
+		if g.json {
+			return nil, fmt.Errorf("%s has no JSON equivalent", describe(base))
+		}
+		item := &cbor.Item{Major: cbor.MajorBytes, Bytes: []byte{}}
+		for _, n := range set {
+			for uint64(len(item.Bytes)) <= n/8 {
+				item.Bytes = append(item.Bytes, 0)
+			}
+			item.Bytes[n/8] |= 1 << (n % 8)
+		}
+		return item, nil
+	}
+	return nil, fmt.Errorf("bits of %s are not defined", describe(base))
+}
+
+// generateComparison generates numbers of the base type and falls back to numbers derived
+// from the compared value if none satisfies the comparison
+func (g *Generator) generateComparison(op *ast.ComparatorOpControl, sc *scope, depth int) (*cbor.Item, error) {
+	value := g.va.constant(op.Right, sc)
+	if value == nil {
+		return nil, fmt.Errorf("cannot evaluate %s", describe(op.Right))
+	}
+	g.va.quiet++
+	defer func() { g.va.quiet-- }()
+
+	for attempt := 0; attempt < 16; attempt++ {
+		item, err := g.generate(op.Left, sc, depth)
+		if err != nil {
+			return nil, err
+		}
+		if g.va.validateComparison(op, sc, item, nil) {
+			return item, nil
+		}
+	}
+
+	step := int64(g.rand.Intn(10))
+	switch op.Token {
+	case token.LT, token.NE:
+		step = -1 - step
+	case token.LE:
+		step = -step
+	case token.GT:
+		step = 1 + step
+	case token.EQ:
+		step = 0
+	}
+
+	base, _ := g.va.resolve(op.Left, sc)
+	switch val := value.(type) {
+	case *evaluator.Integer:
+		n := new(big.Int).Add(val.Int, big.NewInt(step))
+		if ft, ok := base.(*ast.FloatType); ok {
+			f, _ := new(big.Float).SetInt(n).Float64()
+			return floatItem(f, ft.Token), nil
+		}
+		return intItem(n), nil
+	case *evaluator.Float:
+		f := val.Float + float64(step)/4
+		if ft, ok := base.(*ast.FloatType); ok {
+			return floatItem(f, ft.Token), nil
+		}
+		if op.Token == token.LT || op.Token == token.LE {
+			f = math.Floor(f)
+		} else {
+			f = math.Ceil(f)
+		}
+		n, _ := big.NewFloat(f).Int(nil)
+		return intItem(n), nil
+	}
+	return nil, fmt.Errorf("%s is not a number", value)
+}
+
+// fromValue returns a data item for a value, picking one at random from ranges
+func (g *Generator) fromValue(value evaluator.Value) (*cbor.Item, error) {
+	switch val := value.(type) {
+	case *evaluator.Integer:
+		return intItem(val.Int), nil
+	case *evaluator.Float:
+		return &cbor.Item{Major: cbor.MajorSimple, Float: val.Float, Width: 64}, nil
+	case *evaluator.Text:
+		return &cbor.Item{Major: cbor.MajorText, Text: val.Text}, nil
+	case *evaluator.Bytes:
+		if g.json {
+			return nil, fmt.Errorf("%s has no JSON equivalent", val)
+		}
+		return &cbor.Item{Major: cbor.MajorBytes, Bytes: val.Bytes}, nil
+	case *evaluator.Bool:
+		return boolItem(val.Bool), nil
+	case *evaluator.Range:
+		return g.fromRange(val)
+	}
+	return nil, fmt.Errorf("cannot generate %s", value)
+}
+
+func (g *Generator) fromRange(r *evaluator.Range) (*cbor.Item, error) {
+	if from, ok := r.From.(*evaluator.Integer); ok {
+		to, ok := r.To.(*evaluator.Integer)
+		if !ok {
+			return nil, fmt.Errorf("cannot generate range %s", r)
+		}
+		span := new(big.Int).Sub(to.Int, from.Int)
+		if !r.Exclusive {
+			span.Add(span, big.NewInt(1))
+		}
+		if span.Sign() <= 0 {
+			return nil, fmt.Errorf("range %s is empty", r)
+		}
+		return intItem(new(big.Int).Add(from.Int, new(big.Int).Rand(g.rand, span))), nil
+	}
+
+	from, ok := r.From.(*evaluator.Float)
+	to, ok2 := r.To.(*evaluator.Float)
+	if !ok || !ok2 || from.Float > to.Float || (r.Exclusive && from.Float == to.Float) {
+		return nil, fmt.Errorf("cannot generate range %s", r)
+	}
+	f := from.Float + g.rand.Float64()*(to.Float-from.Float)
+	return &cbor.Item{Major: cbor.MajorSimple, Float: f, Width: 64}, nil
+}
+
+// anyItem returns a random scalar
+func (g *Generator) anyItem() *cbor.Item {
+	switch g.rand.Intn(5) {
+	case 0:
+		return &cbor.Item{Major: cbor.MajorUint, Arg: g.uint()}
+	case 1:
+		return &cbor.Item{Major: cbor.MajorNint, Arg: g.uint()}
+	case 2:
+		return boolItem(g.rand.Intn(2) == 1)
+	case 3:
+		if !g.json {
+			return &cbor.Item{Major: cbor.MajorBytes, Bytes: g.bytes(g.rand.Intn(9))}
+		}
+	}
+	return &cbor.Item{Major: cbor.MajorText, Text: g.text(g.rand.Intn(9))}
+}
+
+// uint returns a random argument favouring small values
+func (g *Generator) uint() uint64 {
+	switch g.rand.Intn(3) {
+	case 0:
+		return uint64(g.rand.Intn(24))
+	case 1:
+		return uint64(g.rand.Intn(1000))
+	}
+	return uint64(g.rand.Int63n(1 << 32))
+}
+
+// float returns a random float with a quarter step, exactly representable in every width
+func (g *Generator) float(tok token.Token) *cbor.Item {
+	return floatItem(float64(g.rand.Intn(2001)-1000)/4, tok)
+}
+
+func floatItem(f float64, tok token.Token) *cbor.Item {
+	item := &cbor.Item{Major: cbor.MajorSimple, Float: f, Width: 64}
+	switch tok {
+	case token.FLOAT16:
+		item.Width = 16
+	case token.FLOAT32:
+		item.Width = 32
+	}
+	return item
+}
+
+// text returns a string of lowercase letters of the given length in bytes
+func (g *Generator) text(length int) string {
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = byte('a' + g.rand.Intn(26))
+	}
+	return string(b)
+}
+
+func (g *Generator) bytes(length int) []byte {
+	b := make([]byte, length)
+	g.rand.Read(b)
+	return b
+}
+
+func boolItem(b bool) *cbor.Item {
+	if b {
+		return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleTrue}
+	}
+	return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleFalse}
+}
+
+// intItem returns the data item of an integer. Integers outside the CBOR range become the
+// nearest integer that fits.
+func intItem(n *big.Int) *cbor.Item {
+	if n.Sign() >= 0 {
+		if !n.IsUint64() {
+			return &cbor.Item{Major: cbor.MajorUint, Arg: math.MaxUint64}
+		}
+		return &cbor.Item{Major: cbor.MajorUint, Arg: n.Uint64()}
+	}
+	// -1-n
+	arg := new(big.Int).Neg(n)
+	arg.Sub(arg, big.NewInt(1))
+	if !arg.IsUint64() {
+		return &cbor.Item{Major: cbor.MajorNint, Arg: math.MaxUint64}
+	}
+	return &cbor.Item{Major: cbor.MajorNint, Arg: arg.Uint64()}
+}
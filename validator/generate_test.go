@@ -0,0 +1,152 @@
+package validator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/validator"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []string{
+		"a = uint",
+		"a = int / float16 / null",
+		"a = 10..20",
+		"a = -5...5",
+		"a = 0.5..1.5",
+		`a = "x" / h'01' / true`,
+		"a = tstr .size (2 .. 4)",
+		"a = bstr .size 3",
+		"a = uint .size 1",
+		`a = tstr .regexp "[a-c]{2,4}-[0-9]+(x|yz)?"`,
+		`a = tstr .regexp "\\w+@\\w+\\.(com|org)"`,
+		`a = tstr .abnf "a = 1*ALPHA %x40 1*(ALPHA / DIGIT)"`,
+		"a = bytes .abnfb 'oid = 1*arc\narc = *%x80-FF %x00-7F'",
+		"a = bstr .cborseq [uint, * tstr]",
+		"a = bstr .cbor [uint, tstr]",
+		"a = bstr .det {1: uint, 2: tstr, 10: float, 100: [* int]}",
+		`a = {? port: uint .default 80, host: tstr .default "localhost"}`,
+		`a = [uint, ? tstr .feature "label"]`,
+		"a = uint .bits flags flags = &(r: 0, w: 1, x: 2)",
+		"a = uint .lt 5",
+		"a = int .ge 1000000",
+		"a = float .gt 2.5",
+		"a = &colors colors = (red: 0, green: 1, blue: 2)",
+		"a = {name: tstr, ? age: uint, * label => int} label = tstr",
+		"a = {1: tstr, 2: [+ uint]}",
+		"a = [uint, * (tstr, bool)]",
+		"a = [2*4 uint]",
+		"a = {(x: int // y: tstr)}",
+		"a = #6.32(tstr)",
+		"a = #6.1(uint) / #7.25 / #2",
+		"a = pair<uint> pair<t> = [t, t]",
+		`a = "ab" .cat "cd"`,
+		"a = any",
+		"a = tree tree = {value: uint, ? children: [* tree]}",
+		"a = list list = [uint, list] / null",
+		"a = nested nested = [* nested]",
+	}
+
+	for _, src := range tests {
+		g := validator.NewGenerator(parse(t, src), validator.WithSeed(1))
+		for n := 0; n < 20; n++ {
+			if _, err := g.Generate("a"); err != nil {
+				t.Errorf("%s: %s", src, err)
+				break
+			}
+		}
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	cddl := parse(t, "a = {id: uint, tags: [* tstr], ? extra: bstr / float}")
+
+	generated := func() []string {
+		g := validator.NewGenerator(cddl, validator.WithSeed(42))
+		out := []string{}
+		for n := 0; n < 10; n++ {
+			item, err := g.Generate("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, item.String())
+		}
+		return out
+	}
+
+	first, second := generated(), generated()
+	if strings.Join(first, "\n") != strings.Join(second, "\n") {
+		t.Errorf("expected the same instances for the same seed got\n%s\nand\n%s", first, second)
+	}
+}
+
+func TestGenerateDepthLimit(t *testing.T) {
+	g := validator.NewGenerator(parse(t, "a = tree tree = {* label => tree} label = tstr"),
+		validator.WithSeed(3), validator.WithDepthLimit(2), validator.WithMaxRepeat(4))
+
+	var depth func(item *cbor.Item) int
+	depth = func(item *cbor.Item) int {
+		max := 0
+		for _, pair := range item.Pairs {
+			if d := depth(pair.Value); d > max {
+				max = d
+			}
+		}
+		return max + 1
+	}
+
+	for n := 0; n < 50; n++ {
+		item, err := g.Generate("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := depth(item); d > 3 {
+			t.Errorf("expected nesting of at most 3 got %d: %s", d, item)
+		}
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	src := "a = {name: tstr, ? data: bstr / [* uint], ? 1: tstr, flag: bool / #6.1(uint) / null}"
+	g := validator.NewGenerator(parse(t, src), validator.WithSeed(7), validator.WithJSON())
+	v := validator.NewValidator(parse(t, src))
+
+	for n := 0; n < 20; n++ {
+		item, err := g.Generate("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := validator.EncodeJSON(item)
+		if err != nil {
+			t.Fatalf("%s: %s", item, err)
+		}
+		if err := v.ValidateJSON("a", data); err != nil {
+			t.Errorf("%s: %s", data, err)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"a = uint .lt 0", "could not generate an instance of a"},
+		{"a = b", "could not generate an instance of a"},
+		{"a = 5..1", "could not generate an instance of a"},
+		{"a<t> = [t]", "rule a is generic"},
+		{"b = uint", "rule a is not defined"},
+	}
+
+	for _, tst := range tests {
+		// references to undefined rules are reported by the parser
+		cddl, _ := parser.NewParser(lexer.NewLexer([]byte(tst.src))).ParseFile()
+		_, err := validator.NewGenerator(cddl, validator.WithSeed(1)).Generate("a")
+		if err == nil || !strings.HasPrefix(err.Error(), tst.err) {
+			t.Errorf("%s: expected error `%s` got %v", tst.src, tst.err, err)
+		}
+	}
+}
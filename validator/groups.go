@@ -0,0 +1,345 @@
+package validator
+
+import (
+	"math/big"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// entry is a group entry with the scope its references resolve in
+type entry struct {
+	node  ast.Node
+	scope *scope
+}
+
+func entryNodes(entries []ast.GroupEntry) []ast.Node {
+	nodes := make([]ast.Node, 0, len(entries))
+	for _, e := range entries {
+		nodes = append(nodes, e)
+	}
+	return nodes
+}
+
+// occurrence returns the bounds of an occurrence indicator and the entry it applies to. An
+// upper bound of -1 is unbounded.
+func occurrence(node ast.Node) (int, int, ast.Node) {
+	switch val := node.(type) {
+	case *ast.Optional:
+		return 0, 1, val.Item
+	case *ast.NMOccurrence:
+		min, max := 0, -1
+		if val.N != nil {
+			min = int(val.N.Literal)
+		}
+		if val.M != nil {
+			max = int(val.M.Literal)
+		}
+		return min, max, val.Item
+	}
+	return 1, 1, node
+}
+
+// expand inlines parenthesized groups, references to groups and unwrapped maps, arrays and
+// groups into the surrounding entries
+func (va *validation) expand(nodes []ast.Node, sc *scope) []entry {
+	out := []entry{}
+	for _, node := range nodes {
+		switch val := node.(type) {
+		case *ast.Group:
+			out = append(out, va.expand(entryNodes(val.Entries), sc)...)
+			continue
+		case *ast.Unwrap:
+			target, tsc := va.resolve(val.Item, sc)
+			switch t := target.(type) {
+			case *ast.Map:
+				out = append(out, va.expand(t.Rules, tsc)...)
+				continue
+			case *ast.Array:
+				out = append(out, va.expand(entryNodes(t.Rules), tsc)...)
+				continue
+			case *ast.Group:
+				out = append(out, va.expand(entryNodes(t.Entries), tsc)...)
+				continue
+			}
+		case *ast.Identifier, *ast.Generic:
+			target, tsc := va.resolve(node, sc)
+			switch t := target.(type) {
+			case *ast.Group:
+				out = append(out, va.expand(entryNodes(t.Entries), tsc)...)
+				continue
+			case *ast.GroupChoice:
+				out = append(out, entry{node: t, scope: tsc})
+				continue
+			}
+		}
+		out = append(out, entry{node: node, scope: sc})
+	}
+	return out
+}
+
+// matchArray matches the elements of an array in order against the entries of its group
+func (va *validation) matchArray(nodes []ast.Node, sc *scope, item *cbor.Item, p path) bool {
+	return va.matchSeq(va.expand(nodes, sc), item.Items, 0, p, func(pos int) bool {
+		if pos < len(item.Items) {
+			return va.fail(p.index(pos), "unexpected array element %s", got(item.Items[pos]))
+		}
+		return true
+	})
+}
+
+// matchSeq matches the entries against the items from pos onwards. It calls k with the
+// position after each way of matching until k accepts one.
+func (va *validation) matchSeq(entries []entry, items []*cbor.Item, pos int, p path, k func(int) bool) bool {
+	if len(entries) == 0 {
+		return k(pos)
+	}
+	min, max, node := occurrence(entries[0].node)
+	rest := entries[1:]
+	return va.repeatSeq(node, entries[0].scope, min, max, 0, items, pos, p, func(next int) bool {
+		return va.matchSeq(rest, items, next, p, k)
+	})
+}
+
+// repeatSeq matches the entry as often as possible up to max times, falling back to fewer
+// repetitions down to min
+func (va *validation) repeatSeq(node ast.Node, sc *scope, min, max, count int, items []*cbor.Item, pos int, p path, k func(int) bool) bool {
+	if max < 0 || count < max {
+		mark := len(va.defaults)
+		matched := va.matchSeqEntry(node, sc, items, pos, p, func(next int) bool {
+			if next == pos {
+				// the entry matched no elements and can be repeated any number of times
+				return k(next)
+			}
+			return va.repeatSeq(node, sc, min, max, count+1, items, next, p, k)
+		})
+		if matched {
+			return true
+		}
+		va.defaults = va.defaults[:mark]
+	}
+	return count >= min && k(pos)
+}
+
+func (va *validation) matchSeqEntry(node ast.Node, sc *scope, items []*cbor.Item, pos int, p path, k func(int) bool) bool {
+	switch val := node.(type) {
+	case *ast.Group:
+		return va.matchSeq(va.expand(entryNodes(val.Entries), sc), items, pos, p, k)
+	case *ast.GroupChoice:
+		mark := len(va.defaults)
+		for _, alt := range flattenChoice(val) {
+			if va.matchSeq(va.expand([]ast.Node{alt}, sc), items, pos, p, k) {
+				return true
+			}
+			va.defaults = va.defaults[:mark]
+		}
+		return false
+	case *ast.Entry:
+		node = val.Value
+	case *ast.Unwrap, *ast.Identifier, *ast.Generic:
+		if expanded := va.expand([]ast.Node{node}, sc); len(expanded) != 1 || expanded[0].node != node {
+			return va.matchSeq(expanded, items, pos, p, k)
+		}
+	}
+
+	if pos >= len(items) {
+		return va.fail(p, "missing array element %s", describe(node))
+	}
+	mark := len(va.defaults)
+	if va.validate(node, sc, items[pos], p.index(pos)) && k(pos+1) {
+		return true
+	}
+	va.defaults = va.defaults[:mark]
+	return false
+}
+
+// matchMap matches the entries of a map against the members of its group. Members with
+// literal keys are matched first so that members keyed by a type such as `* tstr => any`
+// only take the remaining entries.
+func (va *validation) matchMap(nodes []ast.Node, sc *scope, item *cbor.Item, p path) bool {
+	sorted := va.literalFirst(va.expand(nodes, sc))
+	used := make([]bool, len(item.Pairs))
+	prevTarget := va.target
+	va.target = item
+	defer func() { va.target = prevTarget }()
+	return va.matchMembers(sorted, item.Pairs, used, p, func() bool {
+		for i, pair := range item.Pairs {
+			if used[i] {
+				continue
+			}
+			// keep the failure of a value whose key matched a member
+			if va.errDepth <= len(p) {
+				va.fail(p.key(pair.Key), "unexpected member %s", pair.Key)
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// literalFirst orders members with literal keys before the other entries of a map
+func (va *validation) literalFirst(entries []entry) []entry {
+	literal := make([]bool, len(entries))
+	for i, e := range entries {
+		_, _, inner := occurrence(e.node)
+		if m, ok := inner.(*ast.Entry); ok {
+			literal[i] = va.literalKey(m, e.scope)
+		}
+	}
+	sorted := make([]entry, 0, len(entries))
+	for pass := 0; pass < 2; pass++ {
+		for i, e := range entries {
+			if literal[i] == (pass == 0) {
+				sorted = append(sorted, e)
+			}
+		}
+	}
+	return sorted
+}
+
+// matchMembers matches the entries against the unused map entries and calls k once all
+// entries are matched. Entries matched are marked as used until k or a later entry fails.
+func (va *validation) matchMembers(entries []entry, pairs []*cbor.Pair, used []bool, p path, k func() bool) bool {
+	if len(entries) == 0 {
+		return k()
+	}
+	min, max, node := occurrence(entries[0].node)
+	sc := entries[0].scope
+	next := func() bool {
+		return va.matchMembers(entries[1:], pairs, used, p, k)
+	}
+	if m, ok := node.(*ast.Entry); ok {
+		return va.matchMember(m, sc, min, max, pairs, used, p, next)
+	}
+	return va.repeatMembers(node, sc, min, max, 0, pairs, used, p, next)
+}
+
+// matchMember takes every unused map entry whose key matches the member up to max entries.
+// A value not matching the member's type fails the member if its key is a literal. When
+// normalizing, an absent member with a literal key records its default value.
+func (va *validation) matchMember(m *ast.Entry, sc *scope, min, max int, pairs []*cbor.Pair, used []bool, p path, k func() bool) bool {
+	if _, ok := new(big.Int).SetString(m.Name.Name, 10); ok && va.json {
+		if min > 0 {
+			return va.fail(p, "member %s has an integer key with no JSON equivalent", m.Name.Name)
+		}
+		return k()
+	}
+
+	literal := va.literalKey(m, sc)
+	taken := []int{}
+	mark := len(va.defaults)
+	release := func() {
+		for _, i := range taken {
+			used[i] = false
+		}
+		va.defaults = va.defaults[:mark]
+	}
+
+	for i, pair := range pairs {
+		if max >= 0 && len(taken) >= max {
+			break
+		}
+		if used[i] || !va.keyMatches(m, sc, pair.Key) {
+			continue
+		}
+		before := len(va.defaults)
+		if !va.validate(m.Value, sc, pair.Value, p.key(pair.Key)) {
+			if literal {
+				release()
+				return false
+			}
+			va.defaults = va.defaults[:before]
+			continue
+		}
+		used[i] = true
+		taken = append(taken, i)
+	}
+
+	if len(taken) < min {
+		release()
+		return va.fail(p, "missing member %s", m.Name.Name)
+	}
+	if len(taken) == 0 && literal && va.normalize && !va.recordDefault(m, sc, p) {
+		return false
+	}
+	if k() {
+		return true
+	}
+	release()
+	return false
+}
+
+// repeatMembers matches a group in a map as often as possible up to max times, falling
+// back to fewer repetitions down to min
+func (va *validation) repeatMembers(node ast.Node, sc *scope, min, max, count int, pairs []*cbor.Pair, used []bool, p path, k func() bool) bool {
+	if max < 0 || count < max {
+		mark := len(va.defaults)
+		before := countUsed(used)
+		matched := va.matchGroupMember(node, sc, pairs, used, p, func() bool {
+			if countUsed(used) == before {
+				return k()
+			}
+			return va.repeatMembers(node, sc, min, max, count+1, pairs, used, p, k)
+		})
+		if matched {
+			return true
+		}
+		va.defaults = va.defaults[:mark]
+	}
+	return count >= min && k()
+}
+
+func (va *validation) matchGroupMember(node ast.Node, sc *scope, pairs []*cbor.Pair, used []bool, p path, k func() bool) bool {
+	switch val := node.(type) {
+	case *ast.Group:
+		return va.matchMembers(va.expand(entryNodes(val.Entries), sc), pairs, used, p, k)
+	case *ast.GroupChoice:
+		mark := len(va.defaults)
+		for _, alt := range flattenChoice(val) {
+			if va.matchMembers(va.expand([]ast.Node{alt}, sc), pairs, used, p, k) {
+				return true
+			}
+			va.defaults = va.defaults[:mark]
+		}
+		return false
+	case *ast.Unwrap, *ast.Identifier, *ast.Generic:
+		if expanded := va.expand([]ast.Node{node}, sc); len(expanded) != 1 || expanded[0].node != node {
+			return va.matchMembers(expanded, pairs, used, p, k)
+		}
+	}
+	return va.fail(p, "%s is not a member of a map", describe(node))
+}
+
+func countUsed(used []bool) int {
+	n := 0
+	for _, u := range used {
+		if u {
+			n++
+		}
+	}
+	return n
+}
+
+// keyMatches reports whether a map key matches the key of a member. Bare words before `:`
+// denote text keys and numbers denote integer keys. Names before `=>` refer to a type.
+func (va *validation) keyMatches(m *ast.Entry, sc *scope, key *cbor.Item) bool {
+	if n, ok := new(big.Int).SetString(m.Name.Name, 10); ok {
+		return key.IsInteger() && key.Int().Cmp(n) == 0
+	}
+	if m.Token != token.ARROW_MAP {
+		return key.Major == cbor.MajorText && key.Text == m.Name.Name
+	}
+	return va.matches(m.Name, sc, key)
+}
+
+// literalKey reports whether a member matches a single key
+func (va *validation) literalKey(m *ast.Entry, sc *scope) bool {
+	if m.Token != token.ARROW_MAP {
+		return true
+	}
+	if _, ok := new(big.Int).SetString(m.Name.Name, 10); ok {
+		return true
+	}
+	return va.constant(m.Name, sc) != nil
+}
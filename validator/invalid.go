@@ -0,0 +1,422 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// ViolationKind is the kind of constraint an invalid instance violates
+type ViolationKind int
+
+const (
+	// WrongType: a data item of a major type the type does not allow
+	WrongType ViolationKind = iota
+
+	// MissingMember: a map without the entry of a required member
+	MissingMember
+
+	// ExtraElement: an array with an element after those its group allows
+	ExtraElement
+
+	// SizeViolation: a string or unsigned integer outside the size of a `.size` control
+	SizeViolation
+
+	// OutOfRange: a number outside a range or failing a comparison control
+	OutOfRange
+
+	// WrongTag: a tag with a different tag number
+	WrongTag
+
+	// PatternMismatch: a text string not matching a `.regexp` control
+	PatternMismatch
+)
+
+var violationKinds = [...]string{
+	WrongType:       "wrong type",
+	MissingMember:   "missing member",
+	ExtraElement:    "extra element",
+	SizeViolation:   "size violation",
+	OutOfRange:      "out of range",
+	WrongTag:        "wrong tag",
+	PatternMismatch: "pattern mismatch",
+}
+
+func (k ViolationKind) String() string {
+	if int(k) < len(violationKinds) {
+		return violationKinds[k]
+	}
+	return fmt.Sprintf("violation(%d)", int(k))
+}
+
+// Violation is an invalid instance derived from a valid one by breaking a single constraint
+type Violation struct {
+	Kind ViolationKind
+
+	// Path: the JSON pointer to the data item changed. For missing members, the map.
+	Path string
+
+	// Rule: the name of the rule defining the constraint
+	Rule string
+
+	// Constraint: the description of the type or member violated i.e. `tstr .size 3`
+	Constraint string
+
+	// Range: the positions of the constraint in the schema. Zero for the standard prelude
+	Range token.PositionRange
+
+	// Instance: the invalid instance
+	Instance *cbor.Item
+}
+
+func (v *Violation) String() string {
+	return fmt.Sprintf("%s at %s: %s", v.Kind, displayPath(v.Path), v.Constraint)
+}
+
+// memberSite is a map entry generated for a member
+type memberSite struct {
+	parent *cbor.Item
+	pair   *cbor.Pair
+	entry  entry
+}
+
+// GenerateInvalid generates a valid instance of the named rule and derives invalid
+// instances from it. Each invalid instance breaks one constraint the valid instance was
+// generated for: a required member is dropped, a size or range is exceeded, an item gets a
+// different major type or tag number, or an array gets an extra element. Changes that leave
+// the instance valid, such as dropping an optional member, are left out.
+func (g *Generator) GenerateInvalid(rule string) (*cbor.Item, []*Violation, error) {
+	g.sites, g.members = map[*cbor.Item][]entry{}, nil
+	defer func() { g.sites, g.members = nil, nil }()
+
+	valid, err := g.Generate(rule)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := map[*cbor.Item]path{}
+	collectPaths(valid, nil, paths)
+
+	mu := &mutation{g: g, rule: rule, root: valid, paths: paths}
+	for _, m := range g.members {
+		if _, ok := paths[m.parent]; ok {
+			mu.dropMember(m)
+		}
+	}
+	// visit the data items in document order so that violations are reproducible
+	walkItems(valid, func(item *cbor.Item) {
+		sites := g.sites[item]
+		if len(sites) == 0 {
+			return
+		}
+		mu.wrongType(item, sites[len(sites)-1])
+		for _, site := range sites {
+			mu.violate(item, site)
+		}
+	})
+	return valid, mu.violations, nil
+}
+
+// collectPaths records the path of each value in the data item. Map keys have no path.
+func collectPaths(item *cbor.Item, p path, paths map[*cbor.Item]path) {
+	paths[item] = p
+	switch item.Major {
+	case cbor.MajorArray:
+		for i, elem := range item.Items {
+			collectPaths(elem, p.index(i), paths)
+		}
+	case cbor.MajorMap:
+		for _, pair := range item.Pairs {
+			collectPaths(pair.Value, p.key(pair.Key), paths)
+		}
+	case cbor.MajorTag:
+		if item.Content != nil {
+			collectPaths(item.Content, p, paths)
+		}
+	}
+}
+
+// walkItems calls fn for the data item and the values nested in it in document order
+func walkItems(item *cbor.Item, fn func(*cbor.Item)) {
+	fn(item)
+	for _, elem := range item.Items {
+		walkItems(elem, fn)
+	}
+	for _, pair := range item.Pairs {
+		walkItems(pair.Value, fn)
+	}
+	if item.Content != nil {
+		walkItems(item.Content, fn)
+	}
+}
+
+// mutation derives the invalid instances of a valid instance
+type mutation struct {
+	g          *Generator
+	rule       string
+	root       *cbor.Item
+	paths      map[*cbor.Item]path
+	violations []*Violation
+}
+
+// apply copies the instance, changes the copy of the target and keeps the copy if it no
+// longer validates
+func (mu *mutation) apply(kind ViolationKind, target *cbor.Item, site entry, change func(*cbor.Item)) bool {
+	copies := map[*cbor.Item]*cbor.Item{}
+	instance := copyItem(mu.root, copies)
+	change(copies[target])
+	if mu.g.v.validate(mu.rule, instance, mu.g.json) == nil {
+		return false
+	}
+
+	v := &Violation{
+		Kind:       kind,
+		Path:       mu.paths[target].String(),
+		Constraint: describe(site.node),
+		Range:      rangeOf(site.node, site.scope),
+		Instance:   instance,
+	}
+	if site.scope != nil {
+		v.Rule = site.scope.rule
+	}
+	mu.violations = append(mu.violations, v)
+	return true
+}
+
+func (mu *mutation) dropMember(m memberSite) {
+	index := -1
+	for i, pair := range m.parent.Pairs {
+		if pair == m.pair {
+			index = i
+		}
+	}
+	mu.apply(MissingMember, m.parent, m.entry, func(item *cbor.Item) {
+		item.Pairs = append(item.Pairs[:index:index], item.Pairs[index+1:]...)
+	})
+}
+
+// wrongType replaces the data item with the first item of another kind that makes the
+// instance invalid
+func (mu *mutation) wrongType(item *cbor.Item, site entry) {
+	for _, candidate := range mu.candidates() {
+		if candidate.Kind() == item.Kind() {
+			continue
+		}
+		replacement := candidate
+		if mu.apply(WrongType, item, site, func(target *cbor.Item) { *target = *replacement }) {
+			return
+		}
+	}
+}
+
+// candidates returns an item of each kind available in the data model
+func (mu *mutation) candidates() []*cbor.Item {
+	items := []*cbor.Item{
+		{Major: cbor.MajorText, Text: "x"},
+		{Major: cbor.MajorUint, Arg: 1},
+		{Major: cbor.MajorNint, Arg: 0},
+		{Major: cbor.MajorSimple, Float: 0.5, Width: 64},
+		boolItem(true),
+		{Major: cbor.MajorSimple, Arg: cbor.SimpleNull},
+		{Major: cbor.MajorArray},
+		{Major: cbor.MajorMap},
+	}
+	if !mu.g.json {
+		items = append(items, &cbor.Item{Major: cbor.MajorBytes, Bytes: []byte{0}})
+	}
+	return items
+}
+
+// violate breaks the constraint of the type the data item was generated for
+func (mu *mutation) violate(item *cbor.Item, site entry) {
+	va := mu.g.va
+	switch val := site.node.(type) {
+	case *ast.Array:
+		// only arrays with a bounded group have too many elements. Repeating the last
+		// element keeps the instance valid apart from the count.
+		if _, bounded := va.maxElements(va.expand(entryNodes(val.Rules), site.scope), 0); !bounded || len(item.Items) == 0 {
+			return
+		}
+		mu.apply(ExtraElement, item, site, func(target *cbor.Item) {
+			extra := copyItem(target.Items[len(target.Items)-1], map[*cbor.Item]*cbor.Item{})
+			target.Items = append(target.Items, extra)
+		})
+	case *ast.Tag:
+		if val.TagNumber != nil && item.Major == cbor.MajorTag {
+			mu.apply(WrongTag, item, site, func(target *cbor.Item) { target.Arg++ })
+		}
+	case *ast.SizeOperatorControl:
+		lower, upper, ok := bounds(va.constant(val.Size, site.scope))
+		if !ok {
+			return
+		}
+		for _, length := range []int64{upper + 1, lower - 1} {
+			if length >= 0 && length <= 1<<16 && mu.apply(SizeViolation, item, site, resize(length)) {
+				return
+			}
+		}
+	case *ast.Regexp:
+		for _, text := range []string{item.Text + "\x00", "", "\x00"} {
+			replacement := text
+			if mu.apply(PatternMismatch, item, site, func(target *cbor.Item) { target.Text = replacement }) {
+				return
+			}
+		}
+	case *ast.ABNF:
+		// a trailing NUL or the empty string fall outside most grammars
+		texts := []string{item.Text + "\x00", ""}
+		bytes := [][]byte{append(append([]byte{}, item.Bytes...), 0), {}}
+		for i := range texts {
+			text, b := texts[i], bytes[i]
+			change := func(target *cbor.Item) { target.Text = text }
+			if item.Major == cbor.MajorBytes {
+				change = func(target *cbor.Item) { target.Bytes = b }
+			}
+			if mu.apply(PatternMismatch, item, site, change) {
+				return
+			}
+		}
+	case *ast.Range:
+		r, ok := va.constant(val, site.scope).(*evaluator.Range)
+		if !ok {
+			return
+		}
+		for _, value := range []evaluator.Value{step(r.To, 1), step(r.From, -1)} {
+			if mu.applyValue(item, site, value) {
+				return
+			}
+		}
+	case *ast.ComparatorOpControl:
+		value := va.constant(val.Right, site.scope)
+		for _, delta := range []int64{0, 1, -1} {
+			if mu.applyValue(item, site, step(value, delta)) {
+				return
+			}
+		}
+	}
+}
+
+// applyValue replaces a number with a value of the same kind
+// maxElements returns the largest number of array elements the entries of a group allow.
+// The second result is false if the number is unbounded.
+func (va *validation) maxElements(entries []entry, depth int) (int, bool) {
+	total := 0
+	for _, e := range entries {
+		_, max, node := occurrence(e.node)
+		if max < 0 {
+			return 0, false
+		}
+		n, ok := va.entryElements(node, e.scope, depth)
+		if !ok {
+			return 0, false
+		}
+		total += max * n
+	}
+	return total, true
+}
+
+// entryElements returns the largest number of array elements a single group entry allows.
+// Groups nested deeper than the recursion limit are taken to be unbounded.
+func (va *validation) entryElements(node ast.Node, sc *scope, depth int) (int, bool) {
+	if depth > maxDepth {
+		return 0, false
+	}
+	if choice, ok := node.(*ast.GroupChoice); ok {
+		first, ok := va.entryElements(choice.First, sc, depth+1)
+		if !ok {
+			return 0, false
+		}
+		second, ok := va.entryElements(choice.Second, sc, depth+1)
+		return max(first, second), ok
+	}
+	entries := va.expand([]ast.Node{node}, sc)
+	if len(entries) == 1 && entries[0].node == node {
+		return 1, true
+	}
+	return va.maxElements(entries, depth+1)
+}
+
+func (mu *mutation) applyValue(item *cbor.Item, site entry, value evaluator.Value) bool {
+	var replacement *cbor.Item
+	switch val := value.(type) {
+	case *evaluator.Integer:
+		if !item.IsInteger() {
+			f, _ := new(big.Float).SetInt(val.Int).Float64()
+			replacement = &cbor.Item{Major: cbor.MajorSimple, Float: f, Width: item.Width}
+			break
+		}
+		replacement = intItem(val.Int)
+	case *evaluator.Float:
+		if item.IsInteger() {
+			replacement = intItem(new(big.Int).SetInt64(int64(math.Ceil(val.Float))))
+			break
+		}
+		replacement = &cbor.Item{Major: cbor.MajorSimple, Float: val.Float, Width: item.Width}
+	default:
+		return false
+	}
+	return mu.apply(OutOfRange, item, site, func(target *cbor.Item) { *target = *replacement })
+}
+
+// step adds delta to a number
+func step(value evaluator.Value, delta int64) evaluator.Value {
+	switch val := value.(type) {
+	case *evaluator.Integer:
+		return &evaluator.Integer{Int: new(big.Int).Add(val.Int, big.NewInt(delta))}
+	case *evaluator.Float:
+		return &evaluator.Float{Float: val.Float + float64(delta)}
+	}
+	return nil
+}
+
+// resize sets the length of strings and the byte width of unsigned integers
+func resize(length int64) func(*cbor.Item) {
+	return func(target *cbor.Item) {
+		switch target.Major {
+		case cbor.MajorText:
+			text := []byte(target.Text)
+			for int64(len(text)) < length {
+				text = append(text, 'x')
+			}
+			target.Text = string(text[:length])
+		case cbor.MajorBytes:
+			b := append([]byte{}, target.Bytes...)
+			for int64(len(b)) < length {
+				b = append(b, 0)
+			}
+			target.Bytes = b[:length]
+		case cbor.MajorUint:
+			// the smallest value needing length bytes
+			if length > 0 && length <= 8 {
+				target.Arg = 1 << (8 * (length - 1))
+			}
+		}
+	}
+}
+
+// copyItem copies a data item recording the copy of each nested item
+func copyItem(item *cbor.Item, copies map[*cbor.Item]*cbor.Item) *cbor.Item {
+	c := *item
+	copies[item] = &c
+	if item.Items != nil {
+		c.Items = make([]*cbor.Item, len(item.Items))
+		for i, elem := range item.Items {
+			c.Items[i] = copyItem(elem, copies)
+		}
+	}
+	if item.Pairs != nil {
+		c.Pairs = make([]*cbor.Pair, len(item.Pairs))
+		for i, pair := range item.Pairs {
+			c.Pairs[i] = &cbor.Pair{Key: copyItem(pair.Key, copies), Value: copyItem(pair.Value, copies)}
+		}
+	}
+	if item.Content != nil {
+		c.Content = copyItem(item.Content, copies)
+	}
+	return &c
+}
@@ -0,0 +1,134 @@
+package validator_test
+
+import (
+	"testing"
+
+	"github.com/HannesKimara/cddlc/validator"
+)
+
+func TestGenerateInvalid(t *testing.T) {
+	tests := []struct {
+		src        string
+		violations []string
+	}{
+		{"a = {name: tstr, id: uint}", []string{
+			"missing member at /: member name",
+			"missing member at /: member id",
+			"wrong type at /: map",
+			"wrong type at /name: tstr",
+			"wrong type at /id: uint",
+		}},
+		{"a = [uint, tstr]", []string{
+			"wrong type at /: array",
+			"extra element at /: array",
+			"wrong type at /0: uint",
+			"wrong type at /1: tstr",
+		}},
+		// unbounded arrays have no extra elements
+		{"a = [* tstr]", []string{
+			"wrong type at /: array",
+			"wrong type at /0: tstr",
+		}},
+		{"a = [uint, ? (tstr // bool)]", []string{
+			"wrong type at /: array",
+			"extra element at /: array",
+			"wrong type at /0: uint",
+			"wrong type at /1: tstr",
+		}},
+		{"a = tstr .size (2 .. 4)", []string{
+			"wrong type at /: tstr .size (2..4)",
+			"size violation at /: tstr .size (2..4)",
+		}},
+		{"a = 10 .. 20", []string{
+			"wrong type at /: 10..20",
+			"out of range at /: 10..20",
+		}},
+		{"a = uint .le 5", []string{
+			"wrong type at /: uint .le 5",
+			"out of range at /: uint .le 5",
+		}},
+		{"a = #6.32(tstr)", []string{
+			"wrong type at /: #6.32(tstr)",
+			"wrong tag at /: #6.32(tstr)",
+			"wrong type at /: tstr",
+		}},
+		{`a = tstr .regexp "[a-z]+"`, []string{
+			`wrong type at /: tstr .regexp "[a-z]+"`,
+			`pattern mismatch at /: tstr .regexp "[a-z]+"`,
+		}},
+		{`a = tstr .abnf "a = 1*DIGIT"`, []string{
+			`wrong type at /: tstr .abnf "a = 1*DIGIT"`,
+			`pattern mismatch at /: tstr .abnf "a = 1*DIGIT"`,
+		}},
+		{"a = bstr .abnfb 'a = %x00-7F'", []string{
+			"wrong type at /: bstr .abnfb h'61203d20257830302d3746'",
+			"pattern mismatch at /: bstr .abnfb h'61203d20257830302d3746'",
+		}},
+		{"a = any", []string{}},
+	}
+
+	for _, tst := range tests {
+		g := validator.NewGenerator(parse(t, tst.src), validator.WithSeed(1))
+		v := validator.NewValidator(parse(t, tst.src))
+
+		valid, violations, err := g.GenerateInvalid("a")
+		if err != nil {
+			t.Errorf("%s: %s", tst.src, err)
+			continue
+		}
+		if err := v.Validate("a", valid); err != nil {
+			t.Errorf("%s: expected a valid instance got %s", tst.src, err)
+		}
+
+		if len(violations) != len(tst.violations) {
+			t.Errorf("%s: expected %d violations got %d: %s", tst.src, len(tst.violations), len(violations), violations)
+			continue
+		}
+		for i, violation := range violations {
+			if violation.String() != tst.violations[i] {
+				t.Errorf("%s: expected `%s` got `%s`", tst.src, tst.violations[i], violation)
+			}
+			if err := v.Validate("a", violation.Instance); err == nil {
+				t.Errorf("%s: %s: expected an invalid instance got %s", tst.src, violation, violation.Instance)
+			}
+		}
+	}
+}
+
+func TestGenerateInvalidLocation(t *testing.T) {
+	src := "a = {items: [* item]}\nitem = {id: uint .size 2}"
+	g := validator.NewGenerator(parse(t, src), validator.WithSeed(1), validator.WithMaxRepeat(1))
+
+	for n := 0; n < 10; n++ {
+		_, violations, err := g.GenerateInvalid("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, violation := range violations {
+			if violation.Kind != validator.SizeViolation {
+				continue
+			}
+			if violation.Path != "/items/0/id" || violation.Rule != "item" || violation.Range.Start.Line != 2 {
+				t.Errorf("unexpected location of %s: %s in rule %s at %s", violation, violation.Path, violation.Rule, violation.Range.Start)
+			}
+			return
+		}
+	}
+	t.Errorf("expected a size violation")
+}
+
+func TestGenerateInvalidOptional(t *testing.T) {
+	g := validator.NewGenerator(parse(t, "a = {name: tstr, ? age: uint}"), validator.WithSeed(1))
+
+	for n := 0; n < 10; n++ {
+		_, violations, err := g.GenerateInvalid("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, violation := range violations {
+			if violation.Kind == validator.MissingMember && violation.Constraint != "member name" {
+				t.Errorf("dropping an optional member keeps the instance valid: %s", violation)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+// ValidateJSON decodes a JSON document and validates it against the named rule using the
+// mapping of https://www.rfc-editor.org/rfc/rfc8610#appendix-E. Numbers match integer and
+// float types by value and objects are maps with text keys. Types without a JSON
+// equivalent such as byte strings and tags are reported as such.
+func (v *Validator) ValidateJSON(rule string, data []byte) error {
+	item, err := DecodeJSON(data)
+	if err != nil {
+		return err
+	}
+	return v.validate(rule, item, true)
+}
+
+// DecodeJSON converts a JSON document to a data item. Numbers with an integral value become
+// integers when they fit in the CBOR integer range and floats otherwise. The members of
+// objects keep their order in the document.
+func DecodeJSON(data []byte) (*cbor.Item, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	item, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("json: unexpected data after the document at offset %d", dec.InputOffset())
+	}
+	return item, nil
+}
+
+func decodeJSONValue(dec *json.Decoder) (*cbor.Item, error) {
+	offset := int(dec.InputOffset())
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("json: %w", err)
+	}
+
+	switch val := tok.(type) {
+	case json.Delim:
+		switch val {
+		case '[':
+			item := &cbor.Item{Major: cbor.MajorArray, Offset: offset}
+			for dec.More() {
+				elem, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				item.Items = append(item.Items, elem)
+			}
+			_, err := dec.Token()
+			return item, err
+		case '{':
+			item := &cbor.Item{Major: cbor.MajorMap, Offset: offset}
+			for dec.More() {
+				keyOffset := int(dec.InputOffset())
+				key, err := dec.Token()
+				if err != nil {
+					return nil, fmt.Errorf("json: %w", err)
+				}
+				value, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				item.Pairs = append(item.Pairs, &cbor.Pair{
+					Key:   &cbor.Item{Major: cbor.MajorText, Text: key.(string), Offset: keyOffset},
+					Value: value,
+				})
+			}
+			_, err := dec.Token()
+			return item, err
+		}
+	case string:
+		return &cbor.Item{Major: cbor.MajorText, Text: val, Offset: offset}, nil
+	case bool:
+		item := &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleFalse, Offset: offset}
+		if val {
+			item.Arg = cbor.SimpleTrue
+		}
+		return item, nil
+	case nil:
+		return &cbor.Item{Major: cbor.MajorSimple, Arg: cbor.SimpleNull, Offset: offset}, nil
+	case json.Number:
+		return jsonNumber(val, offset)
+	}
+	return nil, fmt.Errorf("json: unexpected token %v at offset %d", tok, offset)
+}
+
+var (
+	maxUint = new(big.Int).SetUint64(math.MaxUint64)
+	minNint = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64))
+)
+
+func jsonNumber(n json.Number, offset int) (*cbor.Item, error) {
+	f, _, err := big.ParseFloat(string(n), 10, 256, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("json: invalid number %s at offset %d", n, offset)
+	}
+
+	if f.IsInt() {
+		i, _ := f.Int(nil)
+		switch {
+		case i.Sign() >= 0 && i.Cmp(maxUint) <= 0:
+			return &cbor.Item{Major: cbor.MajorUint, Arg: i.Uint64(), Offset: offset}, nil
+		case i.Sign() < 0 && i.Cmp(minNint) >= 0:
+			// -1-n
+			arg := new(big.Int).Neg(i)
+			arg.Sub(arg, big.NewInt(1))
+			return &cbor.Item{Major: cbor.MajorNint, Arg: arg.Uint64(), Offset: offset}, nil
+		}
+	}
+
+	value, accuracy := f.Float64()
+	if math.IsInf(value, 0) && accuracy != big.Exact {
+		return nil, fmt.Errorf("json: number %s at offset %d is out of range", n, offset)
+	}
+	return &cbor.Item{Major: cbor.MajorSimple, Float: value, Width: 64, Offset: offset}, nil
+}
+
+// EncodeJSON converts a data item to a JSON document. Maps must have text keys and items
+// without a JSON equivalent such as byte strings, tags and non-finite floats are reported.
+func EncodeJSON(item *cbor.Item) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeJSONValue(&buf, item); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeJSONValue(buf *bytes.Buffer, item *cbor.Item) error {
+	switch {
+	case item.IsInteger():
+		buf.WriteString(item.Int().String())
+	case item.IsFloat():
+		if math.IsNaN(item.Float) || math.IsInf(item.Float, 0) {
+			return fmt.Errorf("json: %s has no JSON equivalent", item)
+		}
+		s := strconv.FormatFloat(item.Float, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		buf.WriteString(s)
+	case item.IsBool(), item.IsNull():
+		buf.WriteString(item.String())
+	case item.Major == cbor.MajorText:
+		encoded, _ := json.Marshal(item.Text)
+		buf.Write(encoded)
+	case item.Major == cbor.MajorArray:
+		buf.WriteByte('[')
+		for n, elem := range item.Items {
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSONValue(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case item.Major == cbor.MajorMap:
+		buf.WriteByte('{')
+		for n, pair := range item.Pairs {
+			if pair.Key.Major != cbor.MajorText {
+				return fmt.Errorf("json: map key %s is not a text string", pair.Key)
+			}
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			encoded, _ := json.Marshal(pair.Key.Text)
+			buf.Write(encoded)
+			buf.WriteByte(':')
+			if err := encodeJSONValue(buf, pair.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("json: %s %s has no JSON equivalent", item.Kind(), item)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package validator_test
+
+import (
+	"testing"
+
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/validator"
+)
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		src  string
+		data string
+		err  string
+	}{
+		// numbers
+		{"a = uint", "1", ""},
+		{"a = uint", "1.0", ""},
+		{"a = uint", "1e2", ""},
+		{"a = uint", "-1", "validator: at /: expected uint, got negative integer -1"},
+		{"a = int", "1.5", "validator: at /: expected int, got float 1.5"},
+		{"a = float", "1.5", ""},
+		{"a = float", "1", ""},
+		{"a = float16", "0.5", ""},
+		{"a = float16", "0.1", "validator: at /: float 0.1 is not representable as float16"},
+		{"a = 1.0", "1", ""},
+		{"a = 0.0..1.0", "1", ""},
+		{"a = 0..10", "2.5", "validator: at /: expected 0..10, got float 2.5"},
+		{"a = float .lt 2.0", "1", ""},
+		{"a = uint", "18446744073709551616", "validator: at /: expected uint, got float 1.8446744073709552e+19"},
+
+		// text, booleans and null
+		{"a = tstr", `"x"`, ""},
+		{`a = tstr .regexp "[a-z]+"`, `"abc"`, ""},
+		{"a = bool", "true", ""},
+		{"a = null", "null", ""},
+		{"a = tstr / null", "null", ""},
+
+		// objects and arrays
+		{"a = {name: tstr, ? age: uint}", `{"name": "x", "age": 3}`, ""},
+		{"a = {name: tstr, ? age: uint}", `{"age": 3}`, "validator: at /: missing member name"},
+		{"a = {name: tstr, ? age: uint}", `{"name": "x", "age": -3}`, "validator: at /age: expected uint, got negative integer -3"},
+		{"a = {name: tstr}", `{"name": "x", "extra": 1}`, `validator: at /extra: unexpected member "extra"`},
+		{"a = {* label => uint} label = tstr", `{"x": 1, "y": 2}`, ""},
+		{"a = [* {id: uint}]", `[{"id": 1}, {"id": 2}]`, ""},
+		{"a = [* {id: uint}]", `[{"id": 1}, {"id": "2"}]`, `validator: at /1/id: expected uint, got text string "2"`},
+		{"a = [uint, tstr]", `[1, "a"]`, ""},
+
+		// constructs without a JSON equivalent
+		{"a = bstr", `"AAE="`, "validator: at /: bstr has no JSON equivalent"},
+		{"a = h'00'", `"00"`, "validator: at /: h'00' has no JSON equivalent"},
+		{"a = #6.32(tstr)", `"x"`, "validator: at /: #6.32(tstr) has no JSON equivalent"},
+		{"a = {1: tstr}", `{"1": "x"}`, "validator: at /: member 1 has an integer key with no JSON equivalent"},
+		{"a = {? 1: tstr, name: tstr}", `{"name": "x"}`, ""},
+		{"a = tstr / bstr", `"x"`, ""},
+	}
+
+	for _, tst := range tests {
+		v := validator.NewValidator(parse(t, tst.src))
+		err := v.ValidateJSON("a", []byte(tst.data))
+		switch {
+		case tst.err == "" && err != nil:
+			t.Errorf("%s: %s: unexpected error %s", tst.src, tst.data, err)
+		case tst.err != "" && err == nil:
+			t.Errorf("%s: %s: expected error `%s`", tst.src, tst.data, tst.err)
+		case err != nil && err.Error() != tst.err:
+			t.Errorf("%s: %s: expected `%s` got `%s`", tst.src, tst.data, tst.err, err)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		diag string
+	}{
+		{`{"b": 1, "a": [true, null, -2, 0.5]}`, `{"b": 1, "a": [true, null, -2, 0.5]}`},
+		{`"x"`, `"x"`},
+		{"-18446744073709551616", "-18446744073709551616"},
+		{"1E400", ""},
+		{`{"a": 1`, ""},
+		{"1 2", ""},
+	}
+
+	for _, tst := range tests {
+		item, err := validator.DecodeJSON([]byte(tst.data))
+		if tst.diag == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error", tst.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.data, err)
+			continue
+		}
+		if item.String() != tst.diag {
+			t.Errorf("%s: expected `%s` got `%s`", tst.data, tst.diag, item)
+		}
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		json string
+	}{
+		{"a2 6161 01 6162 83 f5 f6 20", `{"a":1,"b":[true,null,-1]}`},
+		{"f93e00", "1.5"},
+		{"fb4000000000000000", "2.0"},
+		{"62 0a22", `"\n\""`},
+		{"4101", ""},
+		{"c1 01", ""},
+		{"a1 01 01", ""},
+		{"f97c00", ""},
+	}
+
+	for _, tst := range tests {
+		item, err := cbor.Decode(decodeHex(t, tst.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := validator.EncodeJSON(item)
+		if tst.json == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error got %s", tst.data, data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tst.data, err)
+			continue
+		}
+		if string(data) != tst.json {
+			t.Errorf("%s: expected `%s` got `%s`", tst.data, tst.json, data)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+// SequenceError describes a data item of a CBOR sequence that is malformed or does not
+// conform to a rule
+type SequenceError struct {
+	// Index: the position of the data item in the sequence starting at 0
+	Index int
+
+	// Offset: the byte offset of the data item in the sequence
+	Offset int
+
+	// Err: the *Error of the validation or the *cbor.SyntaxError of a malformed data item
+	Err error
+}
+
+func (e *SequenceError) Error() string {
+	return fmt.Sprintf("item %d at offset %d: %s", e.Index, e.Offset, e.Err)
+}
+
+// Unwrap returns the failure of the data item
+func (e *SequenceError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateSequence reads a CBOR sequence, https://www.rfc-editor.org/rfc/rfc8742, from r
+// and validates each of its data items against the named rule. Data items are read and
+// validated one at a time so the sequence is never held in memory.
+//
+// fail is called with each data item that does not conform and returns whether to go on
+// with the next. A nil fail stops at the first failure, which is returned. A malformed data
+// item or one larger than the limit set by WithMaxItemSize ends the sequence and is returned
+// as a *SequenceError. Otherwise ValidateSequence returns nil once the sequence is read.
+func (v *Validator) ValidateSequence(rule string, r io.Reader, fail func(*SequenceError) bool) error {
+	return validateSequence(v.defs, v.Validate, v.maxItemSize, rule, r, fail)
+}
+
+// ValidateSequence validates the data items of a CBOR sequence as Validator.ValidateSequence
+func (prog *Program) ValidateSequence(rule string, r io.Reader, fail func(*SequenceError) bool) error {
+	return validateSequence(prog.defs, prog.Validate, prog.maxItemSize, rule, r, fail)
+}
+
+func validateSequence(defs map[string]*definition, validate func(string, *cbor.Item) error, maxItemSize int, rule string, r io.Reader, fail func(*SequenceError) bool) error {
+	// report undefined rules for empty sequences as well
+	if _, err := lookupRule(defs, rule); err != nil {
+		return err
+	}
+
+	seq := cbor.NewReader(r, cbor.WithMaxItemSize(maxItemSize))
+	for index := 0; ; index++ {
+		offset := seq.Offset()
+		item, err := seq.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var syntax *cbor.SyntaxError
+		if errors.As(err, &syntax) {
+			return &SequenceError{Index: index, Offset: offset, Err: err}
+		}
+		if err != nil {
+			return err
+		}
+
+		err = validate(rule, item)
+		if err == nil {
+			continue
+		}
+		seqErr := &SequenceError{Index: index, Offset: offset, Err: err}
+		if fail == nil {
+			return seqErr
+		}
+		if !fail(seqErr) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package validator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp/syntax"
+	"time"
+
+	"github.com/HannesKimara/cddlc/cbor"
+)
+
+// TagChecker checks the content of a tag beyond the type the schema gives it i.e. that the
+// text string of tag 0 is an RFC 3339 date and time. It returns an error describing content
+// that does not follow the semantics of the tag.
+type TagChecker func(content *cbor.Item) error
+
+// WithTagChecker checks the content of the tag number with check whenever a tag type of the
+// schema matches it. It replaces the checker of a standard tag and a nil check disables it.
+func WithTagChecker(number uint64, check TagChecker) func(*Validator) {
+	return func(v *Validator) {
+		v.tags[number] = check
+	}
+}
+
+// standardTags returns the checkers of the tags of the standard prelude defined by
+// https://www.rfc-editor.org/rfc/rfc8949#section-3.4
+func standardTags() map[uint64]TagChecker {
+	return map[uint64]TagChecker{
+		0:  checkDateTime,
+		1:  checkEpoch,
+		2:  checkBignum,
+		3:  checkBignum,
+		4:  checkFraction,
+		5:  checkFraction,
+		32: checkURI,
+		33: checkBase64(base64.RawURLEncoding.Strict(), "base64url"),
+		34: checkBase64(base64.StdEncoding.Strict(), "base64"),
+		35: checkRegexp,
+	}
+}
+
+// checkTag runs the checker of the tag number of a tagged data item on its content
+func (va *validation) checkTag(item *cbor.Item, p path) bool {
+	check := va.v.tags[item.Arg]
+	if check == nil {
+		return true
+	}
+	if err := check(item.Content); err != nil {
+		return va.fail(p, "invalid content of tag %d: %s", item.Arg, err)
+	}
+	return true
+}
+
+// checkDateTime accepts the date and time strings of RFC 3339
+func checkDateTime(content *cbor.Item) error {
+	if content.Major != cbor.MajorText {
+		return fmt.Errorf("expected a text string, got %s", content.Kind())
+	}
+	if _, err := time.Parse(time.RFC3339Nano, content.Text); err != nil {
+		return fmt.Errorf("%q is not an RFC 3339 date and time", content.Text)
+	}
+	return nil
+}
+
+// checkEpoch accepts integers and finite floating point numbers of seconds since the epoch
+func checkEpoch(content *cbor.Item) error {
+	switch {
+	case content.IsInteger():
+		return nil
+	case content.IsFloat():
+		if math.IsNaN(content.Float) || math.IsInf(content.Float, 0) {
+			return fmt.Errorf("%s is not a finite number of seconds", content)
+		}
+		return nil
+	}
+	return fmt.Errorf("expected a number, got %s", content.Kind())
+}
+
+// checkBignum accepts the byte strings holding the magnitude of bignums
+func checkBignum(content *cbor.Item) error {
+	if content.Major != cbor.MajorBytes {
+		return fmt.Errorf("expected a byte string, got %s", content.Kind())
+	}
+	return nil
+}
+
+// checkFraction accepts the arrays of an integer exponent and an integer or bignum mantissa
+// of decimal fractions and bigfloats
+func checkFraction(content *cbor.Item) error {
+	if content.Major != cbor.MajorArray || len(content.Items) != 2 {
+		return fmt.Errorf("expected an array of exponent and mantissa, got %s", content.Kind())
+	}
+	if !content.Items[0].IsInteger() {
+		return fmt.Errorf("exponent %s is not an integer", content.Items[0])
+	}
+	m := content.Items[1]
+	if m.IsInteger() {
+		return nil
+	}
+	if m.Major == cbor.MajorTag && (m.Arg == 2 || m.Arg == 3) && m.Content.Major == cbor.MajorBytes {
+		return nil
+	}
+	return fmt.Errorf("mantissa %s is not an integer or bignum", m)
+}
+
+// checkURI accepts absolute URIs
+func checkURI(content *cbor.Item) error {
+	if content.Major != cbor.MajorText {
+		return fmt.Errorf("expected a text string, got %s", content.Kind())
+	}
+	u, err := url.Parse(content.Text)
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("%q is not a URI", content.Text)
+	}
+	return nil
+}
+
+// checkBase64 accepts text strings in the encoding with no bits set past the data encoded
+func checkBase64(enc *base64.Encoding, name string) TagChecker {
+	return func(content *cbor.Item) error {
+		if content.Major != cbor.MajorText {
+			return fmt.Errorf("expected a text string, got %s", content.Kind())
+		}
+		if _, err := enc.DecodeString(content.Text); err != nil {
+			return fmt.Errorf("%q is not %s encoded", content.Text, name)
+		}
+		return nil
+	}
+}
+
+// checkRegexp accepts the regular expressions of tag 35 supported by the regexp package
+func checkRegexp(content *cbor.Item) error {
+	if content.Major != cbor.MajorText {
+		return fmt.Errorf("expected a text string, got %s", content.Kind())
+	}
+	if _, err := syntax.Parse(content.Text, syntax.Perl); err != nil {
+		return fmt.Errorf("%q is not a regular expression", content.Text)
+	}
+	return nil
+}
@@ -0,0 +1,750 @@
+package validator
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/HannesKimara/cddlc/abnf"
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/iregexp"
+	"github.com/HannesKimara/cddlc/runtime/half"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// validate reports whether the data item at path is an instance of the type
+func (va *validation) validate(node ast.Node, sc *scope, item *cbor.Item, p path) bool {
+	va.calls++
+	defer func() { va.calls-- }()
+	if va.calls > maxDepth {
+		return va.fail(p, "maximum rule nesting exceeded")
+	}
+
+	node, sc = va.resolve(node, sc)
+	prevNode, prevScope := va.node, va.scope
+	va.node, va.scope = node, sc
+	defer func() { va.node, va.scope = prevNode, prevScope }()
+
+	switch val := node.(type) {
+	case nil:
+		return va.fail(p, "missing type")
+	case *ast.AnyType:
+		return true
+	case *ast.Identifier:
+		if def, ok := va.v.defs[val.Name]; ok && len(def.params) > 0 {
+			return va.fail(p, "rule %s expects %d generic arguments", val.Name, len(def.params))
+		}
+		return va.fail(p, "rule %s is not defined", val.Name)
+	case *ast.Generic:
+		return va.fail(p, "cannot instantiate %s with %d generic arguments", val.Name.Name, len(val.Args))
+	case *ast.UintType:
+		return va.expect(item.Major == cbor.MajorUint, node, item, p)
+	case *ast.NegativeIntegerType:
+		return va.expect(item.Major == cbor.MajorNint, node, item, p)
+	case *ast.IntegerType:
+		return va.expect(item.IsInteger(), node, item, p)
+	case *ast.FloatType:
+		if va.json && item.IsInteger() {
+			return true
+		}
+		if item.IsFloat() && !fitsPrecision(val.Base, item) {
+			return va.fail(p, "%s is not representable as %s", got(item), val.Token)
+		}
+		return va.expect(item.IsFloat(), node, item, p)
+	case *ast.TstrType:
+		return va.expect(item.Major == cbor.MajorText, node, item, p)
+	case *ast.BstrType, *ast.BytesType:
+		if va.json {
+			return va.noJSON(node, p)
+		}
+		return va.expect(item.Major == cbor.MajorBytes, node, item, p)
+	case *ast.BooleanType:
+		return va.expect(item.IsBool(), node, item, p)
+	case *ast.NullType:
+		return va.expect(item.IsNull(), node, item, p)
+	case *ast.BooleanLiteral:
+		return va.expect(item.IsBool() && (item.Arg == cbor.SimpleTrue) == val.Bool, node, item, p)
+	case *ast.IntegerLiteral, *ast.UintLiteral, *ast.FloatLiteral, *ast.TextLiteral, *ast.BytesLiteral,
+		*ast.Range, *ast.ComputedOpControl:
+		value := va.constant(node, sc)
+		if value == nil {
+			return va.fail(p, "cannot evaluate %s", describe(node))
+		}
+		if _, ok := value.(*evaluator.Bytes); ok && va.json {
+			return va.noJSON(node, p)
+		}
+		return va.expect(matchesValue(value, item, va.json), node, item, p)
+	case *ast.TypeChoice:
+		return va.validateChoice(val, sc, item, p)
+	case *ast.Map:
+		if item.Major != cbor.MajorMap {
+			return va.expect(false, node, item, p)
+		}
+		return va.matchMap(val.Rules, sc, item, p)
+	case *ast.Array:
+		if item.Major != cbor.MajorArray {
+			return va.expect(false, node, item, p)
+		}
+		return va.matchArray(entryNodes(val.Rules), sc, item, p)
+	case *ast.Tag:
+		return va.validateTag(val, sc, item, p)
+	case *ast.Enumeration:
+		return va.validateEnumeration(val, sc, item, p)
+	case *ast.SizeOperatorControl:
+		return va.validate(val.Type, sc, item, p) && va.validateSize(val, sc, item, p)
+	case *ast.Regexp:
+		return va.validate(val.Base, sc, item, p) && va.validateRegexp(val, sc, item, p)
+	case *ast.ABNF:
+		return va.validate(val.Base, sc, item, p) && va.validateABNF(val, sc, item, p)
+	case *ast.CBORControl:
+		if val.Token == token.CBORSEQ {
+			return va.validate(val.Base, sc, item, p) && va.validateCBORSeq(val, sc, item, p)
+		}
+		return va.validate(val.Base, sc, item, p) && va.validateCBOR(val, sc, item, p)
+	case *ast.Bits:
+		return va.validate(val.Base, sc, item, p) && va.validateBits(val, sc, item, p)
+	case *ast.DefaultControl:
+		return va.validate(val.Base, sc, item, p)
+	case *ast.FeatureControl:
+		return va.validate(val.Base, sc, item, p) && va.validateFeature(val, sc, p)
+	case *ast.ComparatorOpControl:
+		return va.validate(val.Left, sc, item, p) && va.validateComparison(val, sc, item, p)
+	case *ast.Group, *ast.GroupChoice, *ast.Entry, *ast.Optional, *ast.NMOccurrence, *ast.Unwrap:
+		return va.fail(p, "group %s cannot be used as a type", describe(node))
+	}
+	return va.fail(p, "unsupported type %s", describe(node))
+}
+
+// noJSON records a failure for types that cannot be represented in JSON
+func (va *validation) noJSON(node ast.Node, p path) bool {
+	return va.fail(p, "%s has no JSON equivalent", describe(node))
+}
+
+// matches reports whether the data item is an instance of the type without recording failures
+func (va *validation) matches(node ast.Node, sc *scope, item *cbor.Item) bool {
+	va.quiet++
+	defer func() { va.quiet-- }()
+	return va.validate(node, sc, item, nil)
+}
+
+// expect records a mismatch between the type and the data item unless ok is true
+func (va *validation) expect(ok bool, node ast.Node, item *cbor.Item, p path) bool {
+	if ok {
+		return true
+	}
+	return va.fail(p, "expected %s, got %s", describe(node), got(item))
+}
+
+// validateChoice reports whether the data item matches any alternative of the choice.
+// The failure of each alternative is kept in the reported error. An alternative using a
+// disabled feature explains the failure best. Otherwise, when no alternative got past the
+// data item itself, their failures are replaced by a single mismatch.
+func (va *validation) validateChoice(choice *ast.TypeChoice, sc *scope, item *cbor.Item, p path) bool {
+	prevErr, prevDepth := va.err, va.errDepth
+
+	var (
+		alts     []*Alternative
+		deepest  *Error
+		feature  *Error
+		maxDepth = -1
+	)
+	mark := len(va.defaults)
+	for _, alt := range flattenChoice(choice) {
+		va.err, va.errDepth = nil, -1
+		if va.validate(alt, sc, item, p) {
+			va.err, va.errDepth = prevErr, prevDepth
+			return true
+		}
+		va.defaults = va.defaults[:mark]
+		alts = append(alts, &Alternative{Type: describe(alt), Range: rangeOf(alt, sc), Err: va.err})
+		if va.err != nil && va.err.Feature != "" && feature == nil {
+			feature = va.err
+		}
+		if va.err != nil && va.errDepth >= maxDepth {
+			deepest, maxDepth = va.err, va.errDepth
+		}
+	}
+	va.err, va.errDepth = prevErr, prevDepth
+	if va.quiet > 0 {
+		return false
+	}
+
+	var err *Error
+	switch {
+	case feature != nil:
+		// the data item matches an alternative but for a disabled feature
+		copied := *feature
+		err = &copied
+		if maxDepth < len(p) {
+			maxDepth = len(p)
+		}
+	case deepest != nil && maxDepth > len(p):
+		copied := *deepest
+		err = &copied
+	default:
+		err = va.newError(choice, sc, p, "expected %s, got %s", describe(choice), got(item))
+		maxDepth = len(p)
+	}
+	err.Alternatives = alts
+	if maxDepth >= va.errDepth {
+		va.err, va.errDepth = err, maxDepth
+	}
+	return false
+}
+
+func flattenChoice(node ast.Node) []ast.Node {
+	switch val := node.(type) {
+	case *ast.TypeChoice:
+		return append(flattenChoice(val.First), flattenChoice(val.Second)...)
+	case *ast.GroupChoice:
+		return append(flattenChoice(val.First), flattenChoice(val.Second)...)
+	}
+	return []ast.Node{node}
+}
+
+// validateTag matches tags and the major types denoted by `#m` and `#m.n`
+func (va *validation) validateTag(tag *ast.Tag, sc *scope, item *cbor.Item, p path) bool {
+	if tag.Major == nil {
+		return true
+	}
+
+	major := cbor.Major(tag.Major.Literal)
+	if va.json && (major == cbor.MajorBytes || major == cbor.MajorTag || (major == cbor.MajorSimple && tag.TagNumber != nil)) {
+		return va.noJSON(tag, p)
+	}
+	if major != cbor.MajorTag {
+		return va.expect(matchesMajor(tag, item), tag, item, p)
+	}
+
+	if item.Major != cbor.MajorTag || (tag.TagNumber != nil && item.Arg != tag.TagNumber.Literal) {
+		return va.expect(false, tag, item, p)
+	}
+	if tag.Item != nil && !va.validate(tag.Item, sc, item.Content, p) {
+		return false
+	}
+	return va.checkTag(item, p)
+}
+
+// matchesMajor reports whether the data item has the major type and, for `#m.n`, the
+// argument given by a major type that is not a tag
+func matchesMajor(tag *ast.Tag, item *cbor.Item) bool {
+	major := cbor.Major(tag.Major.Literal)
+	if item.Major != major || tag.TagNumber == nil {
+		return item.Major == major
+	}
+	n := tag.TagNumber.Literal
+	switch major {
+	case cbor.MajorSimple:
+		if n >= 25 && n <= 27 {
+			return item.Width == 16<<(n-25)
+		}
+		return !item.IsFloat() && item.Arg == n
+	case cbor.MajorUint, cbor.MajorNint:
+		return item.Arg == n
+	}
+	return true
+}
+
+// fitsPrecision reports whether a float is encoded in at most the precision in bits or is
+// exactly representable in it. A zero precision allows any float.
+func fitsPrecision(base int, item *cbor.Item) bool {
+	if base == 0 || item.Width <= base {
+		return true
+	}
+	switch base {
+	case 16:
+		return half.Exact(item.Float)
+	case 32:
+		return half.Exact32(item.Float)
+	}
+	return true
+}
+
+// validateEnumeration matches the values of the members of the group i.e. `&(a: 1, b: 2)`
+func (va *validation) validateEnumeration(enum *ast.Enumeration, sc *scope, item *cbor.Item, p path) bool {
+	node, gsc := va.resolve(enum.Value, sc)
+	var entries []ast.Node
+	switch val := node.(type) {
+	case *ast.Group:
+		entries = entryNodes(val.Entries)
+	case *ast.Map:
+		entries = val.Rules
+	default:
+		return va.fail(p, "cannot enumerate %s", describe(node))
+	}
+
+	for _, entry := range va.expand(entries, gsc) {
+		for _, alt := range flattenChoice(entry.node) {
+			_, _, alt = occurrence(alt)
+			if e, ok := alt.(*ast.Entry); ok {
+				alt = e.Value
+			}
+			if va.matches(alt, entry.scope, item) {
+				return true
+			}
+		}
+	}
+	return va.fail(p, "expected one of the values of %s, got %s", describe(enum.Value), got(item))
+}
+
+// validateSize checks the length of strings and the byte width of unsigned integers
+func (va *validation) validateSize(op *ast.SizeOperatorControl, sc *scope, item *cbor.Item, p path) bool {
+	size := va.constant(op.Size, sc)
+	if size == nil {
+		return va.fail(p, "cannot evaluate size %s", describe(op.Size))
+	}
+
+	var length *big.Int
+	switch item.Major {
+	case cbor.MajorText:
+		length = big.NewInt(int64(len(item.Text)))
+	case cbor.MajorBytes:
+		length = big.NewInt(int64(len(item.Bytes)))
+	case cbor.MajorUint:
+		if fitsSize(size, item.Arg) {
+			return true
+		}
+		return va.fail(p, "value %s does not fit in %s bytes", item, widthBound(size))
+	default:
+		return va.fail(p, "size of %s is not defined", item.Kind())
+	}
+
+	if matchesValue(size, &cbor.Item{Major: cbor.MajorUint, Arg: length.Uint64()}, false) {
+		return true
+	}
+	return va.fail(p, "size %s is not within %s", length, size)
+}
+
+// fitsSize reports whether an unsigned integer fits in the bytes given by the size or the
+// upper bound of a size range. An unsigned integer fits in n bytes if it is less than 256^n.
+func fitsSize(size evaluator.Value, arg uint64) bool {
+	n := 0
+	for v := arg; v > 0; v >>= 8 {
+		n++
+	}
+	bound, ok := widthBound(size).(*evaluator.Integer)
+	return ok && big.NewInt(int64(n)).Cmp(bound.Int) <= 0
+}
+
+// widthBound returns the largest number of bytes allowed by a size or size range
+func widthBound(size evaluator.Value) evaluator.Value {
+	r, ok := size.(*evaluator.Range)
+	if !ok {
+		return size
+	}
+	if upper, ok := r.To.(*evaluator.Integer); ok && r.Exclusive {
+		return &evaluator.Integer{Int: new(big.Int).Sub(upper.Int, big.NewInt(1))}
+	}
+	return r.To
+}
+
+// validateRegexp matches text strings against the whole of an XSD regular expression
+func (va *validation) validateRegexp(op *ast.Regexp, sc *scope, item *cbor.Item, p path) bool {
+	pattern, ok := va.constant(op.Regex, sc).(*evaluator.Text)
+	if !ok {
+		return va.fail(p, "regular expression %s is not a text string", describe(op.Regex))
+	}
+
+	re, ok := va.v.regexps[pattern.Text]
+	if !ok {
+		var err error
+		re, err = iregexp.Compile(pattern.Text)
+		if err != nil {
+			return va.fail(p, "invalid regular expression %q: %s", pattern.Text, err)
+		}
+		va.v.regexps[pattern.Text] = re
+	}
+	if re.MatchString(item.Text) {
+		return true
+	}
+	return va.fail(p, "%s does not match %q", got(item), pattern.Text)
+}
+
+// validateABNF matches text or byte strings against the whole of an ABNF grammar. Byte
+// strings are matched a byte per character.
+func (va *validation) validateABNF(op *ast.ABNF, sc *scope, item *cbor.Item, p path) bool {
+	grammar, err := va.grammar(op, sc)
+	if err != nil {
+		return va.fail(p, "%s", err)
+	}
+
+	switch item.Major {
+	case cbor.MajorText:
+		if grammar.Match(item.Text) {
+			return true
+		}
+	case cbor.MajorBytes:
+		if grammar.MatchBytes(item.Bytes) {
+			return true
+		}
+	}
+	return va.fail(p, "%s does not match the ABNF grammar %s", got(item), describe(op.Grammar))
+}
+
+// grammar evaluates and parses the grammar of an ABNF control, caching parsed grammars
+func (va *validation) grammar(op *ast.ABNF, sc *scope) (*abnf.Grammar, error) {
+	var source string
+	switch val := va.constant(op.Grammar, sc).(type) {
+	case *evaluator.Text:
+		source = val.Text
+	case *evaluator.Bytes:
+		source = string(val.Bytes)
+	default:
+		return nil, fmt.Errorf("ABNF grammar %s is not a text or byte string", describe(op.Grammar))
+	}
+
+	grammar, ok := va.v.grammars[source]
+	if !ok {
+		var err error
+		grammar, err = abnf.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ABNF grammar %q: %s", source, err)
+		}
+		va.v.grammars[source] = grammar
+	}
+	return grammar, nil
+}
+
+// validateCBOR decodes the content of a byte string as a single data item and matches it
+// against the controller. Failures within the embedded data item are located at the path of
+// the byte string extended by the path within the data item. The `.det` control requires
+// the core deterministic encoding of the data item as well.
+func (va *validation) validateCBOR(op *ast.CBORControl, sc *scope, item *cbor.Item, p path) bool {
+	embedded, err := cbor.Decode(item.Bytes)
+	if err != nil {
+		return va.fail(p, "invalid embedded CBOR: %s", err)
+	}
+	if op.Token == token.DET {
+		if err := cbor.CheckDeterministic(embedded); err != nil {
+			return va.fail(p, "embedded CBOR is not deterministically encoded: %s", err)
+		}
+	}
+	return va.validate(op.Controller, sc, embedded, p)
+}
+
+// validateCBORSeq decodes the content of a byte string as a CBOR sequence and matches its
+// data items as the elements of an array against the controller. Elements are located at
+// their index in the sequence.
+func (va *validation) validateCBORSeq(op *ast.CBORControl, sc *scope, item *cbor.Item, p path) bool {
+	seq, err := decodeSequence(item.Bytes)
+	if err != nil {
+		return va.fail(p, "invalid CBOR sequence: %s", err)
+	}
+	return va.validate(op.Controller, sc, seq, p)
+}
+
+// decodeSequence decodes the data items of a CBOR sequence into an array
+func decodeSequence(data []byte) (*cbor.Item, error) {
+	seq := &cbor.Item{Major: cbor.MajorArray, Items: []*cbor.Item{}}
+	d := cbor.NewDecoder(data)
+	for d.More() {
+		item, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		seq.Items = append(seq.Items, item)
+	}
+	return seq, nil
+}
+
+// validateFeature reports a data item matching the base type of a `.feature` control as
+// using the feature unless it is enabled
+func (va *validation) validateFeature(op *ast.FeatureControl, sc *scope, p path) bool {
+	if va.v.features == nil {
+		return true
+	}
+	name, ok := va.featureName(op, sc)
+	if !ok {
+		return va.fail(p, "cannot evaluate the feature %s", describe(op.Feature))
+	}
+	if va.v.features[name] {
+		return true
+	}
+	if va.quiet == 0 && len(p) >= va.errDepth {
+		va.err = va.newError(op, sc, p, "uses feature %s", name)
+		va.err.Feature = name
+		va.errDepth = len(p)
+	}
+	return false
+}
+
+// featureName evaluates the name of a feature given as a text string or as the first
+// element of an array
+func (va *validation) featureName(op *ast.FeatureControl, sc *scope) (string, bool) {
+	node, fsc := va.resolve(op.Feature, sc)
+	if arr, ok := node.(*ast.Array); ok {
+		if len(arr.Rules) == 0 {
+			return "", false
+		}
+		node = arr.Rules[0]
+	}
+	name, ok := va.constant(node, fsc).(*evaluator.Text)
+	if !ok {
+		return "", false
+	}
+	return name.Text, true
+}
+
+// validateBits checks that the numbers of the bits set in an unsigned integer or byte string
+// are instances of the control type. Bits of byte strings are numbered from the least
+// significant bit of the first byte.
+func (va *validation) validateBits(op *ast.Bits, sc *scope, item *cbor.Item, p path) bool {
+	set, ok := setBits(item)
+	if !ok {
+		return va.fail(p, "bits of %s are not defined", item.Kind())
+	}
+	for _, n := range set {
+		if !va.matches(op.Contstraint, sc, &cbor.Item{Major: cbor.MajorUint, Arg: n}) {
+			return va.fail(p, "bit %d is not allowed by %s", n, describe(op.Contstraint))
+		}
+	}
+	return true
+}
+
+// setBits returns the numbers of the bits set in an unsigned integer or byte string. The
+// second result is false for other data items.
+func setBits(item *cbor.Item) ([]uint64, bool) {
+	var set []uint64
+	switch item.Major {
+	case cbor.MajorUint:
+		for n := uint64(0); n < 64; n++ {
+			if item.Arg&(1<<n) != 0 {
+				set = append(set, n)
+			}
+		}
+	case cbor.MajorBytes:
+		for i, b := range item.Bytes {
+			for n := 0; n < 8; n++ {
+				if b&(1<<n) != 0 {
+					set = append(set, uint64(i*8+n))
+				}
+			}
+		}
+	default:
+		return nil, false
+	}
+	return set, true
+}
+
+// validateComparison applies the comparison control operators `.lt`, `.le`, `.gt`, `.ge`,
+// `.eq` and `.ne` to numbers
+func (va *validation) validateComparison(op *ast.ComparatorOpControl, sc *scope, item *cbor.Item, p path) bool {
+	value := va.constant(op.Right, sc)
+	if value == nil {
+		return va.fail(p, "cannot evaluate %s", describe(op.Right))
+	}
+	if compares(op.Token, item, value) {
+		return true
+	}
+	return va.fail(p, "%s is not %s %s", got(item), op.Token, value)
+}
+
+// compares applies a comparison control operator to a numeric data item and value
+func compares(op token.Token, item *cbor.Item, value evaluator.Value) bool {
+	cmp, ok := compareNumber(item, value)
+	if !ok {
+		return false
+	}
+	switch op {
+	case token.LT:
+		return cmp < 0
+	case token.LE:
+		return cmp <= 0
+	case token.GT:
+		return cmp > 0
+	case token.GE:
+		return cmp >= 0
+	case token.EQ:
+		return cmp == 0
+	case token.NE:
+		return cmp != 0
+	}
+	return false
+}
+
+// matchesValue reports whether the data item equals a value or lies within a range. Numbers
+// of either kind compare by value if numeric is true as JSON does not tell integers and
+// floats apart.
+func matchesValue(value evaluator.Value, item *cbor.Item, numeric bool) bool {
+	switch val := value.(type) {
+	case *evaluator.Integer:
+		return item.IsInteger() && item.Int().Cmp(val.Int) == 0
+	case *evaluator.Float:
+		if numeric {
+			cmp, ok := compareNumber(item, val)
+			return ok && cmp == 0
+		}
+		return item.IsFloat() && item.Float == val.Float
+	case *evaluator.Text:
+		return item.Major == cbor.MajorText && item.Text == val.Text
+	case *evaluator.Bytes:
+		return item.Major == cbor.MajorBytes && bytes.Equal(item.Bytes, val.Bytes)
+	case *evaluator.Bool:
+		return item.IsBool() && (item.Arg == cbor.SimpleTrue) == val.Bool
+	case *evaluator.Range:
+		// integer ranges match integers and float ranges match floats, or any number if numeric
+		if _, ok := val.From.(*evaluator.Integer); ok != item.IsInteger() && (ok || !numeric) {
+			return false
+		}
+		lower, ok := compareNumber(item, val.From)
+		if !ok || lower < 0 {
+			return false
+		}
+		upper, ok := compareNumber(item, val.To)
+		return ok && (upper < 0 || (upper == 0 && !val.Exclusive))
+	}
+	return false
+}
+
+// compareNumber compares a numeric data item with a numeric value. The second result is
+// false if either is not a number or the float is NaN.
+func compareNumber(item *cbor.Item, value evaluator.Value) (int, bool) {
+	var x *big.Float
+	switch {
+	case item.IsInteger():
+		if v, ok := value.(*evaluator.Integer); ok {
+			return item.Int().Cmp(v.Int), true
+		}
+		x = new(big.Float).SetInt(item.Int())
+	case item.IsFloat():
+		if math.IsNaN(item.Float) {
+			return 0, false
+		}
+		x = big.NewFloat(item.Float)
+	default:
+		return 0, false
+	}
+
+	var y *big.Float
+	switch v := value.(type) {
+	case *evaluator.Integer:
+		y = new(big.Float).SetInt(v.Int)
+	case *evaluator.Float:
+		if math.IsNaN(v.Float) {
+			return 0, false
+		}
+		y = big.NewFloat(v.Float)
+	default:
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+// describe returns a short description of a type for messages
+func describe(node ast.Node) string {
+	switch val := node.(type) {
+	case *ast.Identifier:
+		return val.Name
+	case *ast.Generic:
+		return val.Name.Name + "<...>"
+	case *ast.AnyType:
+		return "any"
+	case *ast.UintType:
+		return val.Token.String()
+	case *ast.NegativeIntegerType:
+		return val.Token.String()
+	case *ast.IntegerType:
+		return val.Token.String()
+	case *ast.FloatType:
+		return val.Token.String()
+	case *ast.TstrType:
+		return val.Token.String()
+	case *ast.BstrType:
+		return val.Token.String()
+	case *ast.BytesType:
+		return val.Token.String()
+	case *ast.BooleanType:
+		return "bool"
+	case *ast.NullType:
+		return "null"
+	case *ast.BooleanLiteral:
+		return fmt.Sprint(val.Bool)
+	case *ast.TypeChoice:
+		alts := []string{}
+		for _, alt := range flattenChoice(val) {
+			alts = append(alts, describe(alt))
+		}
+		return strings.Join(alts, " / ")
+	case *ast.Map:
+		return "map"
+	case *ast.Array:
+		return "array"
+	case *ast.Group:
+		if len(val.Entries) == 1 && !isMember(val.Entries[0]) {
+			return "(" + describe(val.Entries[0]) + ")"
+		}
+		return "group"
+	case *ast.GroupChoice:
+		return "group"
+	case *ast.Entry:
+		return "member " + val.Name.Name
+	case *ast.Tag:
+		return describeTag(val)
+	case *ast.Enumeration:
+		return "&" + describe(val.Value)
+	case *ast.SizeOperatorControl:
+		return describe(val.Type) + " .size " + describe(val.Size)
+	case *ast.Regexp:
+		return "tstr .regexp " + describe(val.Regex)
+	case *ast.ABNF:
+		return describe(val.Base) + " " + val.Token.String() + " " + describe(val.Grammar)
+	case *ast.CBORControl:
+		return describe(val.Base) + " " + val.Token.String() + " " + describe(val.Controller)
+	case *ast.Bits:
+		return describe(val.Base) + " .bits " + describe(val.Contstraint)
+	case *ast.DefaultControl:
+		return describe(val.Base) + " .default " + describe(val.Default)
+	case *ast.FeatureControl:
+		return describe(val.Base) + " .feature " + describe(val.Feature)
+	case *ast.ComparatorOpControl:
+		return describe(val.Left) + " " + val.Token.String() + " " + describe(val.Right)
+	case *ast.ComputedOpControl:
+		return describe(val.Left) + " " + val.Token.String() + " " + describe(val.Right)
+	}
+	if v := evaluator.NewEvaluator(nil).Eval(node); v != nil {
+		return v.String()
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+func describeTag(tag *ast.Tag) string {
+	if tag.Major == nil {
+		return "#"
+	}
+	s := fmt.Sprintf("#%d", tag.Major.Literal)
+	if tag.TagNumber != nil {
+		s += fmt.Sprintf(".%d", tag.TagNumber.Literal)
+	}
+	if tag.Item != nil {
+		s += "(" + describe(tag.Item) + ")"
+	}
+	return s
+}
+
+// got describes a data item for messages. Scalars are shown with their value.
+func got(item *cbor.Item) string {
+	switch item.Major {
+	case cbor.MajorArray, cbor.MajorMap:
+		return item.Kind()
+	case cbor.MajorTag:
+		return fmt.Sprintf("tag %d", item.Arg)
+	case cbor.MajorText:
+		if utf8.RuneCountInString(item.Text) > 32 {
+			return item.Kind() + " " + fmt.Sprintf("%q...", string([]rune(item.Text)[:32]))
+		}
+	case cbor.MajorBytes:
+		if len(item.Bytes) > 32 {
+			return item.Kind() + fmt.Sprintf(" of length %d", len(item.Bytes))
+		}
+	case cbor.MajorSimple:
+		if !item.IsFloat() {
+			return item.String()
+		}
+	}
+	return item.Kind() + " " + item.String()
+}
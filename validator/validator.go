@@ -0,0 +1,380 @@
+// Package validator decides whether data items conform to the rules of a CDDL schema
+// following the semantics of https://www.rfc-editor.org/rfc/rfc8610.
+//
+// A Validator interprets the schema for each data item. Compile lowers a schema into a
+// Program once for validating many data items, possibly from several goroutines.
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/HannesKimara/cddlc/abnf"
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	"github.com/HannesKimara/cddlc/evaluator"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/prelude"
+	"github.com/HannesKimara/cddlc/token"
+)
+
+// maxDepth limits the nesting of rule references followed for a single data item. It stops
+// rules that refer to themselves without consuming data.
+const maxDepth = 1000
+
+// DefaultMaxItemSize is the largest encoding of a data item ValidateSequence reads unless
+// changed with WithMaxItemSize
+const DefaultMaxItemSize = 16 << 20
+
+// Validator validates data items against the rules of a schema. A Validator caches compiled
+// regular expressions and ABNF grammars and is not safe for concurrent use.
+type Validator struct {
+	defs     map[string]*definition
+	eval     *evaluator.Evaluator
+	regexps  map[string]*regexp.Regexp
+	grammars map[string]*abnf.Grammar
+
+	// tags: the checkers of the content of tags by tag number
+	tags map[uint64]TagChecker
+
+	// deterministic: true if data items must take the core deterministic encoding
+	deterministic bool
+
+	// features: the enabled features of `.feature` controls. Nil enables every feature
+	features map[string]bool
+
+	// maxItemSize: the largest encoding of a data item of a sequence
+	maxItemSize int
+}
+
+// definition holds the merged definitions of a rule
+type definition struct {
+	params []*ast.Identifier
+	node   ast.Node
+
+	// prelude: true for rules of the standard prelude
+	prelude bool
+}
+
+// NewValidator returns a validator for the rules of the tree. Rules of the standard prelude
+// in https://www.rfc-editor.org/rfc/rfc8610#appendix-D not defined by the schema are
+// available as well. The content of the standard tags of the prelude is checked unless
+// changed with WithTagChecker.
+func NewValidator(cddl *ast.CDDL, opts ...func(*Validator)) *Validator {
+	defs := definitions(cddl)
+	for name, def := range preludeDefinitions() {
+		if _, ok := defs[name]; !ok {
+			defs[name] = def
+		}
+	}
+	v := newValidator(cddl, defs, standardTags())
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// newValidator returns a validator for merged definitions of the rules of the tree. The
+// definitions and tag checkers are only read and may be shared between validators.
+func newValidator(cddl *ast.CDDL, defs map[string]*definition, tags map[uint64]TagChecker) *Validator {
+	return &Validator{
+		defs:     defs,
+		eval:     evaluator.NewEvaluator(cddl),
+		regexps:  map[string]*regexp.Regexp{},
+		grammars: map[string]*abnf.Grammar{},
+		tags:     tags,
+
+		maxItemSize: DefaultMaxItemSize,
+	}
+}
+
+// Validate reports whether the data item is an instance of the named rule. It returns an
+// *Error describing the mismatch if it is not.
+func (v *Validator) Validate(rule string, item *cbor.Item) error {
+	return v.validate(rule, item, false)
+}
+
+func (v *Validator) validate(rule string, item *cbor.Item, json bool) error {
+	return v.run(&validation{v: v, errDepth: -1, json: json}, rule, item)
+}
+
+// run matches the data item against the named rule with the validation
+func (v *Validator) run(va *validation, rule string, item *cbor.Item) error {
+	def, err := lookupRule(v.defs, rule)
+	if err != nil {
+		return err
+	}
+
+	if va.validate(def.node, &scope{rule: rule}, item, nil) {
+		if v.deterministic && !va.json {
+			return checkDeterministic(rule, item)
+		}
+		return nil
+	}
+	if va.err == nil {
+		return &Error{Rule: rule, Message: fmt.Sprintf("does not match %s", rule)}
+	}
+	return va.err
+}
+
+// WithDeterministic requires data items validated from CBOR to take the core deterministic
+// encoding of https://www.rfc-editor.org/rfc/rfc8949#section-4.2.1 once they match the rule
+func WithDeterministic() func(*Validator) {
+	return func(v *Validator) {
+		v.deterministic = true
+	}
+}
+
+// WithFeatures enables the named features of `.feature` controls. Once given, data items
+// using any other feature are reported as using it. Every feature is enabled without it.
+func WithFeatures(names ...string) func(*Validator) {
+	return func(v *Validator) {
+		if v.features == nil {
+			v.features = map[string]bool{}
+		}
+		for _, name := range names {
+			v.features[name] = true
+		}
+	}
+}
+
+// WithMaxItemSize limits the size of the encoding of each data item read by ValidateSequence
+// to n bytes. A larger data item ends the sequence, so a forged length cannot make the
+// reader buffer more. Sizes that are not positive keep DefaultMaxItemSize.
+func WithMaxItemSize(n int) func(*Validator) {
+	return func(v *Validator) {
+		if n > 0 {
+			v.maxItemSize = n
+		}
+	}
+}
+
+// checkDeterministic returns an *Error unless the data item takes the core deterministic
+// encoding
+func checkDeterministic(rule string, item *cbor.Item) error {
+	if err := cbor.CheckDeterministic(item); err != nil {
+		return &Error{Rule: rule, Message: fmt.Sprintf("data item is not deterministically encoded: %s", err)}
+	}
+	return nil
+}
+
+// lookupRule returns the definition of a rule that data items can be validated against
+func lookupRule(defs map[string]*definition, rule string) (*definition, error) {
+	def, ok := defs[rule]
+	if !ok {
+		return nil, fmt.Errorf("rule %s is not defined", rule)
+	}
+	if len(def.params) > 0 {
+		return nil, fmt.Errorf("rule %s is generic and cannot be validated without arguments", rule)
+	}
+	return def, nil
+}
+
+// ValidateCBOR decodes data holding a single CBOR data item and validates it against the
+// named rule
+func (v *Validator) ValidateCBOR(rule string, data []byte) error {
+	item, err := cbor.Decode(data)
+	if err != nil {
+		return err
+	}
+	return v.Validate(rule, item)
+}
+
+// definitions merges the rules extended with `/=` and `//=` into a single choice
+func definitions(cddl *ast.CDDL) map[string]*definition {
+	defs := map[string]*definition{}
+	if cddl == nil {
+		return defs
+	}
+
+	for _, entry := range cddl.Rules {
+		rule, ok := entry.(*ast.Rule)
+		if !ok || rule.Name == nil || rule.Value == nil {
+			continue
+		}
+		def, ok := defs[rule.Name.Name]
+		if !ok {
+			defs[rule.Name.Name] = &definition{params: rule.Params, node: rule.Value}
+			continue
+		}
+		if rule.Token == token.GROUP_CHOICE_ASSIGN {
+			def.node = &ast.GroupChoice{Pos: rule.Pos, Token: token.GROUP_CHOICE, First: def.node, Second: rule.Value}
+		} else {
+			def.node = &ast.TypeChoice{Pos: rule.Pos, Token: token.TYPE_CHOICE, First: def.node, Second: rule.Value}
+		}
+	}
+	return defs
+}
+
+var (
+	preludeOnce sync.Once
+	preludeDefs map[string]*definition
+)
+
+func preludeDefinitions() map[string]*definition {
+	preludeOnce.Do(func() {
+		cddl, errs := parser.NewParser(lexer.NewLexer([]byte(prelude.Source))).ParseFile()
+		if errs.HasErrors() {
+			panic(fmt.Sprintf("validator: invalid prelude: %s", errs))
+		}
+		preludeDefs = definitions(cddl)
+		for _, def := range preludeDefs {
+			def.prelude = true
+		}
+	})
+	return preludeDefs
+}
+
+// scope holds the rule a node is defined in and binds the generic parameters of the rule
+// to its arguments
+type scope struct {
+	rule    string
+	prelude bool
+	params  map[string]*binding
+}
+
+// binding holds an argument of a generic rule and the scope it is resolved in
+type binding struct {
+	node  ast.Node
+	scope *scope
+}
+
+func (sc *scope) lookup(name string) (*binding, bool) {
+	if sc == nil {
+		return nil, false
+	}
+	b, ok := sc.params[name]
+	return b, ok
+}
+
+// validation holds the state of validating a single data item
+type validation struct {
+	v *Validator
+
+	// json restricts validation to the JSON data model
+	json bool
+
+	// err holds the deepest failure found. errDepth is the length of the path it was found
+	// at or, for choices, the deepest path reached by one of the alternatives.
+	err      *Error
+	errDepth int
+
+	// node and scope hold the type being matched to locate failures in the schema
+	node  ast.Node
+	scope *scope
+
+	// quiet suppresses recording failures while matching map keys
+	quiet int
+
+	// calls counts the nested validate calls to stop unproductive recursion
+	calls int
+
+	// normalize records the absent members of maps that take a default value in defaults.
+	// target is the map whose members are being matched.
+	normalize bool
+	defaults  []fill
+	target    *cbor.Item
+}
+
+// fail records a failure of the type being matched at the path unless a deeper failure is
+// already known. Later failures at the same depth replace earlier ones as they come from
+// alternatives that matched further.
+func (va *validation) fail(p path, format string, args ...interface{}) bool {
+	return va.failAt(va.node, va.scope, p, format, args...)
+}
+
+// failAt records a failure of the given type
+func (va *validation) failAt(node ast.Node, sc *scope, p path, format string, args ...interface{}) bool {
+	if va.quiet == 0 && len(p) >= va.errDepth {
+		va.err = va.newError(node, sc, p, format, args...)
+		va.errDepth = len(p)
+	}
+	return false
+}
+
+func (va *validation) newError(node ast.Node, sc *scope, p path, format string, args ...interface{}) *Error {
+	err := &Error{Path: p.String(), Message: fmt.Sprintf(format, args...), Range: rangeOf(node, sc)}
+	if sc != nil {
+		err.Rule = sc.rule
+	}
+	return err
+}
+
+// rangeOf returns the positions of a node in the schema
+func rangeOf(node ast.Node, sc *scope) token.PositionRange {
+	if node == nil || (sc != nil && sc.prelude) {
+		return token.PositionRange{}
+	}
+	return token.PositionRange{Start: node.Start(), End: node.End()}
+}
+
+// resolve follows references to rules, generic parameters and parenthesized types. It
+// stops at groups and at identifiers that cannot be resolved.
+func (va *validation) resolve(node ast.Node, sc *scope) (ast.Node, *scope) {
+	for i := 0; i < maxDepth; i++ {
+		switch val := node.(type) {
+		case *ast.Identifier:
+			if b, ok := sc.lookup(val.Name); ok {
+				node, sc = b.node, b.scope
+				continue
+			}
+			def, ok := va.v.defs[val.Name]
+			if !ok || len(def.params) > 0 {
+				return node, sc
+			}
+			node, sc = def.node, &scope{rule: val.Name, prelude: def.prelude}
+		case *ast.Generic:
+			def, ok := va.v.defs[val.Name.Name]
+			if !ok || len(def.params) != len(val.Args) {
+				return node, sc
+			}
+			bound := &scope{rule: val.Name.Name, prelude: def.prelude, params: map[string]*binding{}}
+			for n, param := range def.params {
+				bound.params[param.Name] = &binding{node: val.Args[n], scope: sc}
+			}
+			node, sc = def.node, bound
+		case *ast.Group:
+			if len(val.Entries) != 1 || isMember(val.Entries[0]) {
+				return node, sc
+			}
+			node = val.Entries[0]
+		default:
+			return node, sc
+		}
+	}
+	return node, sc
+}
+
+// isMember returns true if the group entry is a member rather than a parenthesized type
+func isMember(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.Entry, *ast.Optional, *ast.NMOccurrence, *ast.Unwrap, *ast.GroupChoice:
+		return true
+	}
+	return false
+}
+
+// constant returns the value of a node denoting a single value or range. It returns nil
+// for other nodes.
+func (va *validation) constant(node ast.Node, sc *scope) evaluator.Value {
+	switch val := node.(type) {
+	case *ast.Identifier:
+		if b, ok := sc.lookup(val.Name); ok {
+			return va.constant(b.node, b.scope)
+		}
+	case *ast.Group:
+		if len(val.Entries) == 1 && !isMember(val.Entries[0]) {
+			return va.constant(val.Entries[0], sc)
+		}
+		return nil
+	case *ast.Range:
+		from, to := va.constant(val.From, sc), va.constant(val.To, sc)
+		if from == nil || to == nil {
+			return nil
+		}
+		return &evaluator.Range{From: from, To: to, Exclusive: val.Token == token.EXCLUSIVE_BOUND}
+	}
+	return va.v.eval.Eval(node)
+}
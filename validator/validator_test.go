@@ -0,0 +1,604 @@
+package validator_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/HannesKimara/cddlc/ast"
+	"github.com/HannesKimara/cddlc/cbor"
+	cddlerrors "github.com/HannesKimara/cddlc/errors"
+	"github.com/HannesKimara/cddlc/lexer"
+	"github.com/HannesKimara/cddlc/parser"
+	"github.com/HannesKimara/cddlc/validator"
+)
+
+func parse(t *testing.T, src string) *ast.CDDL {
+	t.Helper()
+	l := lexer.NewLexer([]byte(src))
+	p := parser.NewParser(l)
+
+	cddl, errs := p.ParseFile()
+	if len(errs) != 0 {
+		t.Fatalf("%s: -> %s", src, errs)
+	}
+	return cddl
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatalf("invalid hex %s: %s", s, err)
+	}
+	return data
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		src  string
+		data string
+		err  string
+	}{
+		// prelude types and literals
+		{"a = uint", "01", ""},
+		{"a = uint", "20", "validator: at /: expected uint, got negative integer -1"},
+		{"a = int", "20", ""},
+		{"a = nint", "01", "validator: at /: expected nint, got unsigned integer 1"},
+		{"a = tstr", "6161", ""},
+		{"a = tstr", "01", "validator: at /: expected tstr, got unsigned integer 1"},
+		{"a = bstr", "4101", ""},
+		{"a = bool", "f5", ""},
+		{"a = true", "f4", "validator: at /: expected true, got false"},
+		{"a = float", "f93e00", ""},
+		{"a = 1.5", "f93e00", ""},
+		{"a = float", "01", "validator: at /: expected float, got unsigned integer 1"},
+		{"a = float16", "f93e00", ""},
+		{"a = float16", "fb3ff8000000000000", ""},
+		{"a = float16", "fa47c35000", "validator: at /: float 100000.0 is not representable as float16"},
+		{"a = float16", "fb3fb999999999999a", "validator: at /: float 0.1 is not representable as float16"},
+		{"a = float16", "01", "validator: at /: expected float16, got unsigned integer 1"},
+		{"a = float32", "fa3dcccccd", ""},
+		{"a = float32", "fb3fb99999a0000000", ""},
+		{"a = float32", "fb3fb999999999999a", "validator: at /: float 0.1 is not representable as float32"},
+		{"a = float64", "fb3fb999999999999a", ""},
+		{"a = #7.25", "fb3ff8000000000000", "validator: at /: expected #7.25, got float 1.5"},
+		{"a = nil", "f6", ""},
+		{"a = any", "a0", ""},
+		{`a = "x"`, "6178", ""},
+		{`a = "x"`, "6179", `validator: at /: expected "x", got text string "y"`},
+		{"a = 1", "01", ""},
+		{"a = -1", "20", ""},
+		{"a = v v = 3", "03", ""},
+
+		// ranges and choices
+		{"a = 0..10", "0a", ""},
+		{"a = 0..10", "0b", "validator: at /: expected 0..10, got unsigned integer 11"},
+		{"a = 0...10", "0a", "validator: at /: expected 0...10, got unsigned integer 10"},
+		{"a = 0.0..1.0", "f93800", ""},
+		{"a = 0..10", "f93800", "validator: at /: expected 0..10, got float 0.5"},
+		{"a = uint / tstr", "6161", ""},
+		{"a = uint / tstr", "f5", "validator: at /: expected uint / tstr, got true"},
+		{"a = uint a /= tstr", "6161", ""},
+
+		// maps
+		{"a = {x: uint, ? y: tstr}", "a1 6178 01", ""},
+		{"a = {x: uint, ? y: tstr}", "a2 6179 6161 6178 01", ""},
+		{"a = {x: uint, ? y: tstr}", "a0", "validator: at /: missing member x"},
+		{"a = {x: uint, ? y: tstr}", "a1 6178 6161", `validator: at /x: expected uint, got text string "a"`},
+		{"a = {x: uint, ? y: tstr}", "a2 6178 01 617a 01", `validator: at /z: unexpected member "z"`},
+		{"a = {x: uint}", "80", "validator: at /: expected map, got array"},
+		{"a = {1: tstr, 2: uint}", "a2 01 6161 02 02", ""},
+		{"a = {* label => int} label = tstr", "a2 6161 01 6162 02", ""},
+		{"a = {* label => int} label = tstr", "a1 6161 6161", `validator: at /a: expected int, got text string "a"`},
+		{"a = {x: uint, * label => tstr} label = tstr", "a2 6178 01 6179 6179", ""},
+		{"a = {+ label => int} label = tstr", "a0", "validator: at /: missing member label"},
+		{"a = {version: v} v = 1", "a1 6776657273696f6e 01", ""},
+		{"a = {k => uint} k = 7", "a1 07 01", ""},
+		{"a = {(x: int // y: tstr)}", "a1 6179 6161", ""},
+		{"a = {(x: int // y: tstr)}", "a1 617a 6161", "validator: at /: missing member y"},
+		{"a = {~b, z: uint} b = {x: uint}", "a2 6178 01 617a 02", ""},
+		{"a = {b, z: uint} b = (x: uint)", "a2 617a 02 6178 01", ""},
+		{"a = {t: 1, x: uint} / {t: 2, y: tstr}", "a2 6174 02 6179 6161", ""},
+		{"a = {t: 1, x: uint} / {t: 2, y: tstr}", "a2 6174 02 6179 01", "validator: at /y: expected tstr, got unsigned integer 1"},
+		{"a = {items: [* {name: tstr}]}", "a1 656974656d73 81 a1 646e616d65 01", "validator: at /items/0/name: expected tstr, got unsigned integer 1"},
+
+		// arrays
+		{"a = [uint, tstr]", "82 01 6161", ""},
+		{"a = [uint, tstr]", "82 01 02", "validator: at /1: expected tstr, got unsigned integer 2"},
+		{"a = [uint, tstr]", "81 01", "validator: at /: missing array element tstr"},
+		{"a = [uint, tstr]", "83 01 6161 01", "validator: at /2: unexpected array element unsigned integer 1"},
+		{"a = [* uint]", "80", ""},
+		{"a = [* uint]", "83 01 02 03", ""},
+		{"a = [+ uint]", "80", "validator: at /: missing array element uint"},
+		{"a = [* uint, tstr]", "83 01 02 6161", ""},
+		{"a = [? uint, tstr]", "81 6161", ""},
+		{"a = [+ (uint, tstr)]", "84 01 6161 02 6162", ""},
+		{"a = [+ (uint, tstr)]", "83 01 6161 02", "validator: at /2: unexpected array element unsigned integer 2"},
+		{"a = [b, uint] b = (x: tstr, y: tstr)", "83 6161 6162 01", ""},
+		{"a = [x: uint, y: tstr]", "82 01 6161", ""},
+		{"a = [~b, tstr] b = [uint, uint]", "83 01 02 6161", ""},
+		{"a = [* any]", "82 01 6161", ""},
+		{"a = [* a]", "81 81 80", ""},
+
+		// generics
+		{"a = pair<uint> pair<t> = [t, t]", "82 01 02", ""},
+		{"a = pair<uint> pair<t> = [t, t]", "82 01 6161", `validator: at /1: expected uint, got text string "a"`},
+		{"a = r<1, 5> r<lo, hi> = lo .. hi", "03", ""},
+
+		// tags and major types
+		{"a = #6.32(tstr)", "d820 6d68747470733a2f2f612e6f7267", ""},
+		{"a = #6.32(tstr)", "d820 6161", `validator: at /: invalid content of tag 32: "a" is not a URI`},
+		{"a = #6.32(tstr)", "c1 6161", "validator: at /: expected #6.32(tstr), got tag 1"},
+		{"a = #6.32(tstr)", "d820 01", "validator: at /: expected tstr, got unsigned integer 1"},
+		{"a = #6(tstr)", "d864 6161", ""},
+		{"a = #6(tstr)", "c1 6161", "validator: at /: invalid content of tag 1: expected a number, got text string"},
+		{"a = #0", "01", ""},
+		{"a = #7.25", "f93e00", ""},
+		{"a = #7.25", "fa3fc00000", "validator: at /: expected #7.25, got float 1.5"},
+
+		// control operators
+		{"a = tstr .size (1..3)", "6161", ""},
+		{"a = tstr .size (1..3)", "60", "validator: at /: size 0 is not within 1..3"},
+		{"a = bstr .size 2", "420102", ""},
+		{"a = uint .size 1", "18ff", ""},
+		{"a = uint .size 1", "190100", "validator: at /: value 256 does not fit in 1 bytes"},
+		{`a = tstr .regexp "[a-z]+"`, "6161", ""},
+		{`a = tstr .regexp "[a-z]+"`, "62 3161", `validator: at /: text string "1a" does not match "[a-z]+"`},
+		{`a = tstr .regexp "[a-z-[aeiou]]+"`, "63 626364", ""},
+		{`a = tstr .regexp "[a-z-[aeiou]]+"`, "63 626164", `validator: at /: text string "bad" does not match "[a-z-[aeiou]]+"`},
+		{`a = tstr .regexp "^a$"`, "63 5e6124", ""},
+		{`a = tstr .abnf "a = 1*DIGIT"`, "62 3132", ""},
+		{`a = tstr .abnf "a = 1*DIGIT"`, "62 3161", `validator: at /: text string "1a" does not match the ABNF grammar "a = 1*DIGIT"`},
+		{"a = bytes .abnfb (\"oid\" .det rules)\nrules = '\n  oid = 1*arc\n  arc = *%x80-FF %x00-7F\n'", "44 2b068101", ""},
+		{"a = bytes .abnfb (\"oid\" .det rules)\nrules = '\n  oid = 1*arc\n  arc = *%x80-FF %x00-7F\n'", "42 2b81", `validator: at /: byte string h'2b81' does not match the ABNF grammar ("oid" .det rules)`},
+		{"a = bstr .cbor uint", "42 1864", ""},
+		{"a = {1: bstr .cbor header} header = {1: int, ? 4: bstr}", "a1 01 45 a2 01 26 04 40", ""},
+		{"a = {1: bstr .cbor header} header = {1: int, ? 4: bstr}", "a1 01 44 a1 01 6161", `validator: at /1/1: expected int, got text string "a"`},
+		{"a = bstr .cbor uint", "42 0101", "validator: at /: invalid embedded CBOR: cbor: unexpected data after the first item at offset 1"},
+		{"a = bstr .cbor uint", "42 6161", `validator: at /: expected uint, got text string "a"`},
+		{"a = bstr .det {1: uint, 2: uint}", "45 a2 0101 0202", ""},
+		{"a = bstr .det {1: uint, 2: uint}", "45 a2 0202 0101", "validator: at /: embedded CBOR is not deterministically encoded: cbor: map key 1 is not in sorted order at offset 3"},
+		{"a = bstr .det uint", "42 1801", "validator: at /: embedded CBOR is not deterministically encoded: cbor: argument 1 of unsigned integer is not in its shortest form at offset 0"},
+		{"a = bstr .det float", "43 f93e00", ""},
+		{"a = bstr .det float", "45 fa3fc00000", "validator: at /: embedded CBOR is not deterministically encoded: cbor: float 1.5 is not in its shortest form at offset 0"},
+		{"a = bstr .cborseq [* uint]", "43 011864", ""},
+		{"a = bstr .cborseq [* uint]", "40", ""},
+		{"a = bstr .cborseq [uint, uint]", "43 01 6161", `validator: at /1: expected uint, got text string "a"`},
+		{"a = bstr .cborseq [uint, tstr]", "41 01", "validator: at /: missing array element tstr"},
+		{"a = bstr .cborseq [* uint]", "42 0118", "validator: at /: invalid CBOR sequence: cbor: unexpected end of input at offset 1"},
+		{"a = uint .lt 10", "09", ""},
+		{"a = uint .lt 10", "0a", "validator: at /: unsigned integer 10 is not .lt 10"},
+		{"a = int .ge -5", "24", ""},
+		{"a = uint .bits flags flags = &(r: 0, w: 1)", "03", ""},
+		{"a = uint .bits flags flags = &(r: 0, w: 1)", "04", "validator: at /: bit 2 is not allowed by flags"},
+		{"a = bstr .bits flags flags = 0 / 9", "42 0102", ""},
+		{"a = &colors colors = (red: 0, green: 1)", "01", ""},
+		{"a = &colors colors = (red: 0, green: 1)", "02", "validator: at /: expected one of the values of colors, got unsigned integer 2"},
+		{"a = 1 .plus 2", "03", ""},
+	}
+
+	for _, tst := range tests {
+		cddl := parse(t, tst.src)
+		err := validator.NewValidator(cddl).ValidateCBOR("a", decodeHex(t, tst.data))
+		switch {
+		case tst.err == "" && err != nil:
+			t.Errorf("%s: %s: unexpected error %s", tst.src, tst.data, err)
+		case tst.err != "" && err == nil:
+			t.Errorf("%s: %s: expected error `%s`", tst.src, tst.data, tst.err)
+		case err != nil && err.Error() != tst.err:
+			t.Errorf("%s: %s: expected `%s` got `%s`", tst.src, tst.data, tst.err, err)
+		}
+
+		compiled := validator.Compile(cddl).ValidateCBOR("a", decodeHex(t, tst.data))
+		if fmt.Sprint(compiled) != fmt.Sprint(err) {
+			t.Errorf("%s: %s: compiled validation returned `%v`, interpreted `%v`", tst.src, tst.data, compiled, err)
+		}
+	}
+}
+
+func TestPrelude(t *testing.T) {
+	tests := []struct {
+		src  string
+		data string
+		ok   bool
+	}{
+		{"a = tdate", "c0 74323031332d30332d32315432303a30343a30305a", true},
+		{"a = tdate", "c0 6161", false},
+		{"a = tdate", "c1 01", false},
+		{"a = undefined", "f7", true},
+		{"a = number", "f93e00", true},
+		{"a = float16-32", "fa3dcccccd", true},
+		{"a = float16-32", "fb3fb999999999999a", false},
+		{"a = [* bigint]", "82 c2 4101 c3 4101", true},
+		{"a = tdate tdate = uint", "c0 6161", false},
+	}
+
+	for _, tst := range tests {
+		cddl := parse(t, tst.src)
+		err := validator.NewValidator(cddl).ValidateCBOR("a", decodeHex(t, tst.data))
+		if (err == nil) != tst.ok {
+			t.Errorf("%s: %s: expected valid %t got %v", tst.src, tst.data, tst.ok, err)
+		}
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	v := validator.NewValidator(parse(t, "a = uint b<t> = [t]"))
+
+	if err := v.ValidateCBOR("missing", decodeHex(t, "01")); err == nil || err.Error() != "rule missing is not defined" {
+		t.Errorf("expected an error for an undefined rule got %v", err)
+	}
+	if err := v.ValidateCBOR("b", decodeHex(t, "8101")); err == nil {
+		t.Errorf("expected an error for a generic rule")
+	}
+	if err := v.ValidateCBOR("a", decodeHex(t, "1901")); err == nil || !strings.HasPrefix(err.Error(), "cbor: ") {
+		t.Errorf("expected a decoding error got %v", err)
+	}
+
+	err := v.ValidateCBOR("a", decodeHex(t, "6161"))
+	verr, ok := err.(*validator.Error)
+	if !ok {
+		t.Fatalf("expected a *validator.Error got %T", err)
+	}
+	if verr.Path != "" || verr.Rule != "a" {
+		t.Errorf("expected the root of rule a got path `%s` in rule %s", verr.Path, verr.Rule)
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	src := "a = {items: [* item]}\nitem = {name: tstr .size 3 / uint}"
+	v := validator.NewValidator(parse(t, src))
+
+	// {"items": [{"name": "long"}]}
+	err := v.ValidateCBOR("a", decodeHex(t, "a1 656974656d73 81 a1 646e616d65 646c6f6e67"))
+	verr, ok := err.(*validator.Error)
+	if !ok {
+		t.Fatalf("expected a *validator.Error got %T", err)
+	}
+	var _ cddlerrors.Diagnostic = verr
+
+	if verr.Path != "/items/0/name" {
+		t.Errorf("expected path /items/0/name got %s", verr.Path)
+	}
+	if verr.Rule != "item" {
+		t.Errorf("expected rule item got %s", verr.Rule)
+	}
+	if verr.Start().Line != 2 || verr.Start().Column != 15 {
+		t.Errorf("expected the choice at 2:15 got %s", verr.Start())
+	}
+	if verr.Message != "expected tstr .size 3 / uint, got text string \"long\"" {
+		t.Errorf("unexpected message %s", verr.Message)
+	}
+
+	expected := []string{
+		"tstr .size 3: size 4 is not within 3",
+		"uint: expected uint, got text string \"long\"",
+	}
+	if len(verr.Alternatives) != len(expected) {
+		t.Fatalf("expected %d alternatives got %d", len(expected), len(verr.Alternatives))
+	}
+	for i, alt := range verr.Alternatives {
+		if alt.Err == nil {
+			t.Errorf("alternative %s: expected an error", alt.Type)
+			continue
+		}
+		if got := alt.Type + ": " + alt.Err.Message; got != expected[i] {
+			t.Errorf("alternative %d: expected `%s` got `%s`", i, expected[i], got)
+		}
+		if alt.Err.Path != "/items/0/name" {
+			t.Errorf("alternative %d: expected path /items/0/name got %s", i, alt.Err.Path)
+		}
+	}
+}
+
+func TestErrorPointer(t *testing.T) {
+	v := validator.NewValidator(parse(t, "a = {* label => uint} label = tstr"))
+
+	// {"a/b~": "x"}
+	err := v.ValidateCBOR("a", decodeHex(t, "a1 64612f627e 6178"))
+	if err == nil || err.(*validator.Error).Path != "/a~1b~0" {
+		t.Errorf("expected an escaped path got %v", err)
+	}
+}
+
+func TestValidateSequence(t *testing.T) {
+	tests := []struct {
+		data     string
+		failures []string
+		err      string
+	}{
+		{"", nil, ""},
+		{"01 6161 80", nil, ""},
+		{"01 21 1864 f5", []string{
+			"item 1 at offset 1: validator: at /: expected uint / tstr / array, got negative integer -2",
+			"item 3 at offset 4: validator: at /: expected uint / tstr / array, got true",
+		}, ""},
+		{"6161 20 1901", []string{"item 1 at offset 2: validator: at /: expected uint / tstr / array, got negative integer -1"},
+			"item 2 at offset 3: cbor: unexpected end of input at offset 5"},
+	}
+
+	cddl := parse(t, "a = uint / tstr / [* a]")
+	v, prog := validator.NewValidator(cddl), validator.Compile(cddl)
+	for _, validate := range []func(string, io.Reader, func(*validator.SequenceError) bool) error{v.ValidateSequence, prog.ValidateSequence} {
+		for _, tst := range tests {
+			var failures []string
+			err := validate("a", bytes.NewReader(decodeHex(t, tst.data)), func(err *validator.SequenceError) bool {
+				failures = append(failures, err.Error())
+				return true
+			})
+			if fmt.Sprint(failures) != fmt.Sprint(tst.failures) {
+				t.Errorf("%s: expected failures %q got %q", tst.data, tst.failures, failures)
+			}
+			if (err == nil && tst.err != "") || (err != nil && err.Error() != tst.err) {
+				t.Errorf("%s: expected error `%s` got `%v`", tst.data, tst.err, err)
+			}
+		}
+	}
+
+	// a nil callback stops at the first failure
+	err := v.ValidateSequence("a", bytes.NewReader(decodeHex(t, "01 21 22")), nil)
+	var seqErr *validator.SequenceError
+	if !errors.As(err, &seqErr) || seqErr.Index != 1 || seqErr.Offset != 1 {
+		t.Fatalf("expected the failure of item 1 got %v", err)
+	}
+	var verr *validator.Error
+	if !errors.As(err, &verr) || verr.Rule != "a" {
+		t.Errorf("expected a *validator.Error of rule a got %v", seqErr.Err)
+	}
+
+	if err := prog.ValidateSequence("missing", bytes.NewReader(nil), nil); err == nil || err.Error() != "rule missing is not defined" {
+		t.Errorf("expected an error for an undefined rule got %v", err)
+	}
+}
+
+func TestSequenceMaxItemSize(t *testing.T) {
+	tests := []struct {
+		opts []func(*validator.Validator)
+		data string
+		err  string
+	}{
+		// the default limit rejects a forged length before reading the content
+		{nil, "01 5b0000000100000000", "item 1 at offset 1: cbor: data item exceeds the maximum size at offset 1"},
+		{[]func(*validator.Validator){validator.WithMaxItemSize(4)}, "43010203 44010203", "item 1 at offset 4: cbor: data item exceeds the maximum size at offset 4"},
+		{[]func(*validator.Validator){validator.WithMaxItemSize(4)}, "43010203 01", ""},
+		{[]func(*validator.Validator){validator.WithMaxItemSize(0)}, "44010203", "item 0 at offset 0: cbor: unexpected end of input at offset 4"},
+	}
+
+	cddl := parse(t, "a = uint / bstr")
+	for _, tst := range tests {
+		v, prog := validator.NewValidator(cddl, tst.opts...), validator.Compile(cddl, tst.opts...)
+		for _, validate := range []func(string, io.Reader, func(*validator.SequenceError) bool) error{v.ValidateSequence, prog.ValidateSequence} {
+			err := validate("a", bytes.NewReader(decodeHex(t, tst.data)), nil)
+			if (err == nil && tst.err != "") || (err != nil && err.Error() != tst.err) {
+				t.Errorf("%s: expected error `%s` got `%v`", tst.data, tst.err, err)
+			}
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		src  string
+		data string
+		err  string
+	}{
+		{"a = tdate", "c0 76323031332d30332d32315432303a30343a30302e355a", ""},
+		{"a = tdate", "c0 6a323031332d30332d3231", `validator: at /: invalid content of tag 0: "2013-03-21" is not an RFC 3339 date and time`},
+		{"a = time", "c1 1a514b67b0", ""},
+		{"a = time", "c1 fb41d452d9ec200000", ""},
+		{"a = #6.1(any)", "c1 f97c00", "validator: at /: invalid content of tag 1: Infinity is not a finite number of seconds"},
+		{"a = bigint", "c2 420100", ""},
+		{"a = #6.2(any)", "c2 01", "validator: at /: invalid content of tag 2: expected a byte string, got unsigned integer"},
+		{"a = decfrac", "c4 82 21 196ab3", ""},
+		{"a = bigfloat", "c5 82 20 c2 4101", ""},
+		{"a = #6.4(any)", "c4 82 f93c00 01", "validator: at /: invalid content of tag 4: exponent 1.0 is not an integer"},
+		{"a = #6.5(any)", "c5 82 01 6161", `validator: at /: invalid content of tag 5: mantissa "a" is not an integer or bignum`},
+		{"a = uri", "d820 6d68747470733a2f2f612e6f7267", ""},
+		{"a = uri", "d820 652f70617468", `validator: at /: invalid content of tag 32: "/path" is not a URI`},
+		{"a = b64url", "d821 63615f38", ""},
+		{"a = b64url", "d821 6461513d3d", `validator: at /: invalid content of tag 33: "aQ==" is not base64url encoded`},
+		{"a = b64legacy", "d822 6461513d3d", ""},
+		{"a = b64legacy", "d822 626151", `validator: at /: invalid content of tag 34: "aQ" is not base64 encoded`},
+		{"a = regexp", "d823 665b612d7a5d2b", ""},
+		{"a = regexp", "d823 625b61", `validator: at /: invalid content of tag 35: "[a" is not a regular expression`},
+		{"a = #6.100(tstr)", "d864 6161", ""},
+	}
+
+	for _, tst := range tests {
+		cddl := parse(t, tst.src)
+		err := validator.NewValidator(cddl).ValidateCBOR("a", decodeHex(t, tst.data))
+		switch {
+		case tst.err == "" && err != nil:
+			t.Errorf("%s: %s: unexpected error %s", tst.src, tst.data, err)
+		case tst.err != "" && err == nil:
+			t.Errorf("%s: %s: expected error `%s`", tst.src, tst.data, tst.err)
+		case err != nil && err.Error() != tst.err:
+			t.Errorf("%s: %s: expected `%s` got `%s`", tst.src, tst.data, tst.err, err)
+		}
+		compiled := validator.Compile(cddl).ValidateCBOR("a", decodeHex(t, tst.data))
+		if fmt.Sprint(compiled) != fmt.Sprint(err) {
+			t.Errorf("%s: %s: compiled validation returned `%v`, interpreted `%v`", tst.src, tst.data, compiled, err)
+		}
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	cddl := parse(t, "a = {? 1: uint, ? 2: uint}")
+	det := validator.WithDeterministic()
+
+	for _, v := range []interface {
+		ValidateCBOR(string, []byte) error
+	}{validator.NewValidator(cddl, det), validator.Compile(cddl, det)} {
+		if err := v.ValidateCBOR("a", decodeHex(t, "a2 0101 0202")); err != nil {
+			t.Errorf("unexpected error %s", err)
+		}
+		err := v.ValidateCBOR("a", decodeHex(t, "a2 0202 0101"))
+		if err == nil || err.Error() != "validator: at /: data item is not deterministically encoded: cbor: map key 1 is not in sorted order at offset 3" {
+			t.Errorf("expected the encoding to be rejected got %v", err)
+		}
+		// mismatches of the schema are reported first
+		err = v.ValidateCBOR("a", decodeHex(t, "a1 1801 6161"))
+		if err == nil || err.Error() != `validator: at /1: expected uint, got text string "a"` {
+			t.Errorf("expected the schema mismatch got %v", err)
+		}
+	}
+
+	// any encoding is accepted without the option
+	if err := validator.NewValidator(cddl).ValidateCBOR("a", decodeHex(t, "a2 0202 0101")); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestDetMeanings(t *testing.T) {
+	// .det concatenates byte string values as in RFC 9165 and requires the deterministic
+	// encoding of byte strings of the bstr type
+	cddl := parse(t, "a = [greeting, signed]\ngreeting = 'hello\n' .det '  world'\nsigned = bstr .det uint")
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"82 4b68656c6c6f0a776f726c64 4101", ""},
+		{"82 4d68656c6c6f0a2020776f726c64 4101", "validator: at /0: expected h'68656c6c6f0a' .det h'2020776f726c64', got byte string h'68656c6c6f0a2020776f726c64'"},
+		{"82 4b68656c6c6f0a776f726c64 421801", "validator: at /1: embedded CBOR is not deterministically encoded: cbor: argument 1 of unsigned integer is not in its shortest form at offset 0"},
+	}
+
+	for _, tst := range tests {
+		for _, v := range []interface {
+			ValidateCBOR(string, []byte) error
+		}{validator.NewValidator(cddl), validator.Compile(cddl)} {
+			err := v.ValidateCBOR("a", decodeHex(t, tst.data))
+			if (err == nil && tst.err != "") || (err != nil && err.Error() != tst.err) {
+				t.Errorf("%s: expected error `%s` got `%v`", tst.data, tst.err, err)
+			}
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	cddl := parse(t, `a = {kind: uint, ? ext: tstr .feature "ext", ? alg: int / tstr .feature ["named-alg", "text"]}`)
+	tests := []struct {
+		data    string
+		enabled []string
+		err     string
+		feature string
+	}{
+		{"a2 646b696e64 01 63657874 6178", []string{"ext"}, "", ""},
+		{"a2 646b696e64 01 63657874 6178", nil, "validator: at /ext: uses feature ext", "ext"},
+		{"a2 646b696e64 01 63616c67 6161", []string{"ext"}, "validator: at /alg: uses feature named-alg", "named-alg"},
+		{"a2 646b696e64 01 63616c67 6161", []string{"named-alg"}, "", ""},
+		{"a2 646b696e64 01 63616c67 01", nil, "", ""},
+		{"a2 646b696e64 01 63616c67 f5", nil, "validator: at /alg: expected int / tstr .feature array, got true", ""},
+	}
+
+	for _, tst := range tests {
+		opt := validator.WithFeatures(tst.enabled...)
+		for _, v := range []interface {
+			ValidateCBOR(string, []byte) error
+		}{validator.NewValidator(cddl, opt), validator.Compile(cddl, opt)} {
+			err := v.ValidateCBOR("a", decodeHex(t, tst.data))
+			if tst.err == "" {
+				if err != nil {
+					t.Errorf("%s: unexpected error %s", tst.data, err)
+				}
+				continue
+			}
+			var verr *validator.Error
+			if !errors.As(err, &verr) || verr.Error() != tst.err || verr.Feature != tst.feature {
+				t.Errorf("%s: expected `%s` using feature %q got %v", tst.data, tst.err, tst.feature, err)
+			}
+		}
+	}
+
+	// every feature is enabled without the option
+	if err := validator.NewValidator(cddl).ValidateCBOR("a", decodeHex(t, "a2 646b696e64 01 63657874 6178")); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		src      string
+		data     string
+		expected string
+		err      string
+	}{
+		{"a = {? port: uint .default 80, host: tstr}", "a1 64686f7374 61 61", `{"host": "a", "port": 80}`, ""},
+		{"a = {? port: uint .default 80, host: tstr}", "a2 64686f7374 6161 64706f7274 1901bb", `{"host": "a", "port": 443}`, ""},
+		{"a = {? 1: port} port = uint .default 80", "a0", "{1: 80}", ""},
+		{"a = [* {? mode: mode}] mode = (\"r\" / \"w\") .default \"r\"", "82 a0 a1 646d6f6465 6177", `[{"mode": "r"}, {"mode": "w"}]`, ""},
+		{"a = {? opts: {? retries: uint .default 3}}", "a1 646f707473 a0", `{"opts": {"retries": 3}}`, ""},
+		{"a = {? opts: {? retries: uint .default 3}}", "a0", "{}", ""},
+		{"a = {? n: uint .default 1, kind: 1} / {? n: uint .default 2, kind: 2}", "a1 646b696e64 02", `{"kind": 2, "n": 2}`, ""},
+		// defaults of the alternatives of a type choice
+		{"a = {? x: (uint .default 1) / tstr}", "a0", `{"x": 1}`, ""},
+		{"a = {? x: tstr / num} num = (uint .default 1)", "a0", `{"x": 1}`, ""},
+		{"a = {? x: (uint .default 1) / (int .default 1)}", "a0", `{"x": 1}`, ""},
+		{"a = {? x: (uint .default 1) / (tstr .default \"a\")}", "a0", "", `validator: at /: member x has conflicting defaults 1 and "a"`},
+		{"a = {? port: uint .default -1}", "a0", "", "validator: at /: default -1 of member port is not a uint"},
+		{"a = {? port: uint .default 80}", "a1 64706f7274 6161", "", `validator: at /port: expected uint, got text string "a"`},
+	}
+
+	for _, tst := range tests {
+		cddl := parse(t, tst.src)
+		item, err := cbor.Decode(decodeHex(t, tst.data))
+		if err != nil {
+			t.Fatalf("%s: %s", tst.data, err)
+		}
+		before := item.String()
+		for _, v := range []interface {
+			Normalize(string, *cbor.Item) (*cbor.Item, error)
+		}{validator.NewValidator(cddl), validator.Compile(cddl)} {
+			out, err := v.Normalize("a", item)
+			if tst.err != "" {
+				if err == nil || err.Error() != tst.err {
+					t.Errorf("%s: %s: expected `%s` got `%v`", tst.src, tst.data, tst.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %s: unexpected error %s", tst.src, tst.data, err)
+				continue
+			}
+			if out.String() != tst.expected {
+				t.Errorf("%s: %s: expected %s got %s", tst.src, tst.data, tst.expected, out)
+			}
+			if item.String() != before {
+				t.Errorf("%s: %s: the data item changed to %s", tst.src, tst.data, item)
+			}
+		}
+	}
+}
+
+func TestTagChecker(t *testing.T) {
+	cddl := parse(t, "a = #6.100(uint) / #6.32(tstr)")
+	even := validator.WithTagChecker(100, func(content *cbor.Item) error {
+		if content.Arg%2 != 0 {
+			return errors.New("odd number")
+		}
+		return nil
+	})
+
+	for _, v := range []interface {
+		ValidateCBOR(string, []byte) error
+	}{validator.NewValidator(cddl, even), validator.Compile(cddl, even)} {
+		if err := v.ValidateCBOR("a", decodeHex(t, "d864 02")); err != nil {
+			t.Errorf("unexpected error %s", err)
+		}
+		err := v.ValidateCBOR("a", decodeHex(t, "d864 03"))
+		var verr *validator.Error
+		if !errors.As(err, &verr) || len(verr.Alternatives) != 2 || verr.Alternatives[0].Err.Message != "invalid content of tag 100: odd number" {
+			t.Errorf("expected the failure of the tag checker got %v", err)
+		}
+	}
+
+	// a nil checker disables the checks of a standard tag
+	v := validator.NewValidator(cddl, validator.WithTagChecker(32, nil))
+	if err := v.ValidateCBOR("a", decodeHex(t, "d820 6161")); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}